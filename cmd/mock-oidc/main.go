@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"rbac-analyzer/internal/oidc/oidctest"
+)
+
+// mock-oidc — локальный OIDC-провайдер для проверки входа через SSO (rbac-server с DEV_MODE=1).
+// Каждый authorize сразу «входит» пользователем из флагов, без формы.
+// Отдельная команда, чтобы тестовый IdP не попадал в бинарник сервера.
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	fs := flag.NewFlagSet("mock-oidc", flag.ContinueOnError)
+	addr := fs.String("addr", "127.0.0.1:9000", "listen address")
+	issuer := fs.String("issuer", "http://127.0.0.1:9000", "issuer URL (as configured in the org SSO settings)")
+	clientID := fs.String("client-id", "rbac-analyzer", "client_id")
+	secret := fs.String("client-secret", "dev-secret", "client_secret")
+	sub := fs.String("sub", "mock-user-1", "subject of the signed-in user")
+	email := fs.String("email", "dev@example.com", "email of the signed-in user")
+	groups := fs.String("groups", "rbac-admins", "comma-separated groups of the signed-in user")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	p, err := oidctest.New(strings.TrimRight(*issuer, "/"), *clientID, *secret)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	var gs []string
+	for _, g := range strings.Split(*groups, ",") {
+		if g = strings.TrimSpace(g); g != "" {
+			gs = append(gs, g)
+		}
+	}
+	p.SetUser(oidctest.User{Subject: *sub, Email: *email, Groups: gs})
+
+	fmt.Printf("mock OIDC provider %s (client %s) listening on %s\n", p.Issuer, *clientID, *addr)
+	srv := &http.Server{Addr: *addr, Handler: p.Handler(), ReadHeaderTimeout: 5 * time.Second}
+	if err := srv.ListenAndServe(); err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"rbac-analyzer/internal/policy"
+)
+
+// Коды выхода `rbac-analyzer check` (для CI).
+const (
+	exitOK       = 0
+	exitError    = 1 // некорректные флаги, политика или манифесты
+	exitDenied   = 2 // есть нарушения уровня deny
+	exitWarnings = 3 // только warn, и задан -fail-on-warn
+)
+
+// runCheck — `rbac-analyzer check -input-dir dir -policy policy.yaml`.
+func runCheck(args []string) int {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	inputDir := fs.String("input-dir", "", "Directory with RBAC YAML manifests")
+	policyPath := fs.String("policy", "", "Policy file (YAML)")
+	outputFmt := fs.String("output", "text", "Output format: text|json")
+	rulesPath := fs.String("rules", "", "CEL rules file with custom dangerous-permission checks (YAML)")
+	failOnWarn := fs.Bool("fail-on-warn", false, "Exit with code 3 when only warnings are found")
+
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if *inputDir == "" || *policyPath == "" {
+		fmt.Fprintln(os.Stderr, "error: -input-dir and -policy are required")
+		return exitError
+	}
+
+	pol, err := policy.LoadFile(*policyPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "policy error:", err)
+		return exitError
+	}
+
+	customRules, err := loadRules(*rulesPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "rules error:", err)
+		return exitError
+	}
+
+	_, subjectPerms, err := loadSubjectPerms(*inputDir, customRules)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "load error:", err)
+		return exitError
+	}
+
+	res := policy.Evaluate(pol, subjectPerms)
+
+	switch *outputFmt {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(res); err != nil {
+			fmt.Fprintln(os.Stderr, "output error:", err)
+			return exitError
+		}
+	case "text":
+		printCheckText(res)
+	default:
+		fmt.Fprintln(os.Stderr, "unknown output format:", *outputFmt)
+		return exitError
+	}
+
+	switch {
+	case res.Denied > 0:
+		return exitDenied
+	case res.Warned > 0 && *failOnWarn:
+		return exitWarnings
+	default:
+		return exitOK
+	}
+}
+
+func printCheckText(res policy.Result) {
+	for _, v := range res.Violations {
+		fmt.Printf("[%s] %s: %s\n", strings.ToUpper(v.Level), v.RuleID, v.Message)
+		if len(v.Chain) > 0 {
+			fmt.Printf("    %s\n", strings.Join(v.Chain, " -> "))
+		}
+		if v.Permission != "" {
+			fmt.Printf("    permission: %s\n", v.Permission)
+		}
+		for _, r := range v.Roles {
+			fmt.Printf("    - %s\n", r)
+		}
+	}
+	if len(res.Violations) > 0 {
+		fmt.Println()
+	}
+	fmt.Printf("%d denied, %d warning(s)\n", res.Denied, res.Warned)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const checkRBAC = `
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: secret-reader
+rules:
+- apiGroups: [""]
+  resources: ["secrets"]
+  verbs: ["get"]
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: alice-secrets
+subjects:
+- kind: User
+  name: alice
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: secret-reader
+`
+
+// check запускает `rbac-analyzer check` над checkRBAC с политикой policy и возвращает код выхода.
+func check(t *testing.T, policy string, extra ...string) int {
+	t.Helper()
+	dir := t.TempDir()
+	input := filepath.Join(dir, "rbac")
+	if err := os.Mkdir(input, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(input, "rbac.yaml"), []byte(checkRBAC), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	policyPath := filepath.Join(dir, "policy.yaml")
+	if err := os.WriteFile(policyPath, []byte(policy), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// отчёт check печатает в stdout — в тестах он не нужен
+	stdout := os.Stdout
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = devNull
+	defer func() {
+		os.Stdout = stdout
+		devNull.Close()
+	}()
+
+	return runCheck(append([]string{"-input-dir", input, "-policy", policyPath}, extra...))
+}
+
+func TestCheckExitCodes(t *testing.T) {
+	const (
+		deny = `
+rules:
+  - id: cluster-secrets-read
+    match: {scope: cluster, verbs: [get], resources: [Secrets]}
+`
+		warn = `
+rules:
+  - id: cluster-secrets-read
+    level: warn
+    match: {scope: cluster, resources: [secrets]}
+`
+		clean = `
+rules:
+  - id: no-pods-exec
+    match: {resources: [pods/exec]}
+`
+	)
+	for _, c := range []struct {
+		name   string
+		policy string
+		args   []string
+		want   int
+	}{
+		{"deny", deny, nil, exitDenied},
+		{"warn only", warn, nil, exitOK},
+		{"warn with -fail-on-warn", warn, []string{"-fail-on-warn"}, exitWarnings},
+		{"deny with -fail-on-warn", deny, []string{"-fail-on-warn"}, exitDenied},
+		{"no violations", clean, []string{"-fail-on-warn"}, exitOK},
+		{"json output", deny, []string{"-output", "json"}, exitDenied},
+		{"bad policy", "rules:\n  - level: deny\n", nil, exitError},
+		{"bad output", clean, []string{"-output", "xml"}, exitError},
+	} {
+		if got := check(t, c.policy, c.args...); got != c.want {
+			t.Errorf("%s: exit code %d, want %d", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"rbac-analyzer/internal/loader"
+	"rbac-analyzer/internal/rbac"
+)
+
+// runConsolidate — `rbac-analyzer consolidate -input-dir dir`: дубликаты ролей
+// и предложения заменить копии Role общей ClusterRole.
+func runConsolidate(args []string) int {
+	fs := flag.NewFlagSet("consolidate", flag.ContinueOnError)
+	inputDir := fs.String("input-dir", "", "Directory with RBAC YAML manifests")
+	minCopies := fs.Int("min-copies", 2, "Suggest a shared ClusterRole when the same Role exists in at least N namespaces")
+	includeSystem := fs.Bool("include-system", false, "Include system:* roles maintained by the API server")
+	outputFmt := fs.String("output", "text", "Output format: text|yaml|json")
+
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if *inputDir == "" {
+		fmt.Fprintln(os.Stderr, "error: -input-dir is required")
+		return exitError
+	}
+
+	data, err := loader.LoadFromDir(*inputDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "load error:", err)
+		return exitError
+	}
+
+	rep := rbac.Consolidate(data.Roles, data.ClusterRoles, data.RoleBindings, rbac.ConsolidateOptions{
+		MinCopies:     *minCopies,
+		IncludeSystem: *includeSystem,
+	})
+
+	switch *outputFmt {
+	case "text":
+		printConsolidateText(rep)
+	case "yaml":
+		err = printConsolidateYAML(os.Stdout, rep)
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(rep)
+	default:
+		fmt.Fprintln(os.Stderr, "unknown output format:", *outputFmt)
+		return exitError
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "output error:", err)
+		return exitError
+	}
+	return exitOK
+}
+
+func printConsolidateText(rep rbac.ConsolidationReport) {
+	fmt.Println("Identical roles")
+	fmt.Println("===============")
+	for _, d := range rep.Duplicates {
+		fmt.Printf("- %d permission(s): %s\n", d.Permissions, strings.Join(d.Roles, ", "))
+	}
+
+	fmt.Println()
+	fmt.Println("Subset roles")
+	fmt.Println("============")
+	for _, s := range rep.Subsets {
+		fmt.Printf("- %s is covered by %s\n", s.Role, s.Superset)
+	}
+
+	fmt.Println()
+	fmt.Println("Suggestions")
+	fmt.Println("===========")
+	for _, s := range rep.Suggestions {
+		action := "create"
+		if s.ReuseExisting {
+			action = "reuse existing"
+		}
+		fmt.Printf("- %s ClusterRole %s for %d role(s): %s\n",
+			action, s.ClusterRole.Metadata.Name, len(s.Replaces), strings.Join(s.Replaces, ", "))
+		fmt.Printf("    rebind %d RoleBinding(s) to the ClusterRole\n", len(s.RoleBindings))
+	}
+
+	fmt.Printf("\n%d duplicate group(s), %d subset role(s), %d suggestion(s)\n",
+		len(rep.Duplicates), len(rep.Subsets), len(rep.Suggestions))
+}
+
+// printConsolidateYAML — предлагаемые ClusterRole и RoleBinding; что удалить — в комментариях.
+func printConsolidateYAML(w io.Writer, rep rbac.ConsolidationReport) error {
+	for _, s := range rep.Suggestions {
+		fmt.Fprintf(w, "# shared ClusterRole %s replaces:\n", s.ClusterRole.Metadata.Name)
+		for _, r := range s.Replaces {
+			fmt.Fprintf(w, "#   delete %s\n", r)
+		}
+		fmt.Fprintln(w, "# RoleBindings below replace the existing ones with the same name (roleRef is immutable: delete and re-create)")
+
+		var docs []any
+		if !s.ReuseExisting {
+			docs = append(docs, s.ClusterRole)
+		}
+		for _, rb := range s.RoleBindings {
+			docs = append(docs, rb)
+		}
+		for _, d := range docs {
+			fmt.Fprintln(w, "---")
+			enc := yaml.NewEncoder(w)
+			enc.SetIndent(2)
+			if err := enc.Encode(d); err != nil {
+				return err
+			}
+			if err := enc.Close(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
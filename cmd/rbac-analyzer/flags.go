@@ -0,0 +1,19 @@
+package main
+
+import "strings"
+
+// stringList — повторяемый флаг: `-namespace a -namespace b` или `-namespace a,b`.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(v string) error {
+	for _, part := range strings.Split(v, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			*l = append(*l, part)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"rbac-analyzer/internal/celrules"
+	"rbac-analyzer/internal/loader"
+	"rbac-analyzer/internal/rbac"
+)
+
+// loadSubjectPerms читает манифесты из каталога, строит эффективные права
+// и применяет пользовательские правила (-rules).
+func loadSubjectPerms(dir string, rules []rbac.CustomRule) (*loader.Data, rbac.SubjectPermissions, error) {
+	data, err := loader.LoadFromDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	subjectPerms := rbac.BuildSubjectPermissions(
+		data.Roles,
+		data.ClusterRoles,
+		data.RoleBindings,
+		data.ClusterRoleBindings,
+	)
+	if err := rbac.ApplyCustomRules(subjectPerms, rules); err != nil {
+		return nil, nil, err
+	}
+	return data, subjectPerms, nil
+}
+
+// loadRules компилирует файл CEL-правил; пустой путь — без правил.
+func loadRules(path string) ([]rbac.CustomRule, error) {
+	if path == "" {
+		return nil, nil
+	}
+	return celrules.LoadFile(path)
+}
@@ -1,63 +1,166 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
 
-	"rbac-analyzer/internal/loader"
+	"rbac-analyzer/internal/opa"
 	"rbac-analyzer/internal/output"
 	"rbac-analyzer/internal/rbac"
+	"rbac-analyzer/internal/report"
 )
 
 func main() {
+	// === SUBCOMMANDS ===
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "check":
+			os.Exit(runCheck(os.Args[2:]))
+		case "recommend":
+			os.Exit(runRecommend(os.Args[2:]))
+		case "usage":
+			os.Exit(runUsage(os.Args[2:]))
+		case "consolidate":
+			os.Exit(runConsolidate(os.Args[2:]))
+		case "simulate":
+			os.Exit(runSimulate(os.Args[2:]))
+		}
+	}
+
 	// === FLAGS ===
 	inputDir := flag.String("input-dir", "", "Directory with RBAC YAML manifests")
-	outputFmt := flag.String("output", "table", "Output format: table|json")
+	outputFmt := flag.String("output", "table", "Output format: table|json|html|csv|markdown|dot|mermaid|opa-input|compliance|risk")
 	dangerOnly := flag.Bool("danger-only", false, "Show only dangerous permissions")
 	title := flag.String("title", "RBAC Analysis Report", "Report title")
+	csvGranularity := flag.String("csv-granularity", output.CSVPerPermission, "CSV rows: permission|role")
+	csvBOM := flag.Bool("csv-bom", false, "Prepend UTF-8 BOM to CSV (for Excel)")
+	maxBytes := flag.Int("max-bytes", 0, "Truncate markdown output to N bytes (0 = unlimited, GitHub comments: 65536)")
+	regoQuery := flag.String("rego-query", opa.DefaultQuery, "Rego query that returns findings")
+	riskTop := flag.Int("risk-top", 20, "Number of subjects in -output risk (0 = all)")
+	rulesPath := flag.String("rules", "", "CEL rules file with custom dangerous-permission checks (YAML)")
+	diffBase := flag.String("diff-base", "", "Directory with base RBAC manifests: markdown output shows changes vs base")
+
+	var namespaces, subjects, kinds, roles stringList
+	flag.Var(&namespaces, "namespace", "Only roles effective in namespace (repeatable, glob: tenant-*)")
+	flag.Var(&subjects, "subject", "Only subject, e.g. ServiceAccount:ns/name or name (repeatable, glob)")
+	flag.Var(&kinds, "kind", "Only subject kind: User|Group|ServiceAccount (repeatable)")
+	flag.Var(&roles, "role", "Only role, name or ns/name (repeatable, glob)")
+	// прежние флаги графов (dot/mermaid) — синонимы общих фильтров
+	flag.Var(&subjects, "graph-subject", "Alias for -subject")
+	flag.Var(&roles, "graph-role", "Alias for -role")
+	flag.Var(&namespaces, "graph-namespace", "Alias for -namespace")
+	var regoPaths stringList
+	flag.Var(&regoPaths, "rego", "Evaluate local .rego file or directory and merge findings (repeatable)")
 
 	flag.Parse()
 
+	filters := output.Filters{
+		DangerOnly: *dangerOnly,
+		Namespaces: namespaces,
+		Subjects:   subjects,
+		Kinds:      kinds,
+		Roles:      roles,
+	}
+
 	// === VALIDATION ===
 	if *inputDir == "" {
 		fmt.Fprintln(os.Stderr, "error: -input-dir is required")
 		os.Exit(1)
 	}
+	if _, err := output.ParseCSVGranularity(*csvGranularity); err != nil {
+		fmt.Fprintln(os.Stderr, "error: -csv-granularity:", err)
+		os.Exit(1)
+	}
+
+	// === LOAD + ANALYZE ===
+	customRules, err := loadRules(*rulesPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "rules error:", err)
+		os.Exit(1)
+	}
 
-	// === LOAD RBAC ===
-	data, err := loader.LoadFromDir(*inputDir)
+	data, subjectPerms, err := loadSubjectPerms(*inputDir, customRules)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, "load error:", err)
 		os.Exit(1)
 	}
 
-	// === ANALYZE ===
-	subjectPerms := rbac.BuildSubjectPermissions(
-		data.Roles,
-		data.ClusterRoles,
-		data.RoleBindings,
-		data.ClusterRoleBindings,
-	)
+	// === REGO (необязательно) ===
+	if len(regoPaths) > 0 {
+		in := opa.BuildInput(data, subjectPerms)
+		findings, err := opa.Eval(context.Background(), regoPaths, *regoQuery, in)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "rego error:", err)
+			os.Exit(1)
+		}
+		if n := opa.Merge(subjectPerms, findings); n > 0 {
+			fmt.Fprintf(os.Stderr, "warning: %d rego finding(s) did not match any subject/role\n", n)
+		}
+	}
+
+	// === DIFF (markdown для PR) ===
+	if *diffBase != "" {
+		if *outputFmt != "markdown" {
+			fmt.Fprintln(os.Stderr, "error: -diff-base requires -output markdown")
+			os.Exit(1)
+		}
+
+		_, basePerms, err := loadSubjectPerms(*diffBase, customRules)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "load error:", err)
+			os.Exit(1)
+		}
+
+		diff := rbac.DiffSubjectPermissions(
+			output.Filter(basePerms, filters),
+			output.Filter(subjectPerms, filters),
+		)
+		if err := output.PrintMarkdownDiff(os.Stdout, diff, *title, output.MarkdownOptions{MaxBytes: *maxBytes}); err != nil {
+			fmt.Fprintln(os.Stderr, "output error:", err)
+			os.Exit(1)
+		}
+		return
+	}
 
 	// === OUTPUT ===
 	switch *outputFmt {
 	case "table":
-		output.PrintTable(
-			os.Stdout,
-			subjectPerms,
-			*dangerOnly,
-			*title,
-		)
+		err = output.PrintTable(os.Stdout, subjectPerms, filters, *title)
 	case "json":
-		output.PrintJSON(
-			os.Stdout,
-			subjectPerms,
-			*dangerOnly,
-			*title,
-		)
+		err = output.PrintJSON(os.Stdout, subjectPerms, filters, report.Meta{
+			Title:       *title,
+			InputSHA256: data.SHA256,
+			Workloads:   data.Workloads,
+		})
+	case "html":
+		err = output.PrintHTML(os.Stdout, subjectPerms, filters, *title)
+	case "csv":
+		err = output.PrintCSV(os.Stdout, subjectPerms, filters,
+			output.CSVOptions{Granularity: *csvGranularity, BOM: *csvBOM})
+	case "markdown":
+		err = output.PrintMarkdown(os.Stdout, subjectPerms, filters, *title,
+			output.MarkdownOptions{MaxBytes: *maxBytes})
+	case "dot":
+		err = output.PrintDOT(os.Stdout, subjectPerms, filters)
+	case "mermaid":
+		err = output.PrintMermaid(os.Stdout, subjectPerms, filters)
+	case "compliance":
+		err = output.PrintCompliance(os.Stdout, subjectPerms, data.Workloads, filters, *title)
+	case "risk":
+		err = output.PrintRisk(os.Stdout, subjectPerms, data.Workloads, filters, *title, *riskTop)
+	case "opa-input":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(opa.BuildInput(data, output.Filter(subjectPerms, filters)))
 	default:
 		fmt.Fprintln(os.Stderr, "unknown output format:", *outputFmt)
 		os.Exit(1)
 	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "output error:", err)
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"rbac-analyzer/internal/audit"
+	"rbac-analyzer/internal/output"
+	"rbac-analyzer/internal/rbac"
+)
+
+// runRecommend — `rbac-analyzer recommend -input-dir dir -audit-log audit.log`.
+// Сравнивает эффективные права с audit-логом и предлагает минимальные Role.
+func runRecommend(args []string) int {
+	fs := flag.NewFlagSet("recommend", flag.ContinueOnError)
+	inputDir := fs.String("input-dir", "", "Directory with RBAC YAML manifests")
+	since := fs.String("since", "", "Window start: RFC3339 or duration back from now (2160h)")
+	until := fs.String("until", "", "Window end: RFC3339 or duration back from now")
+	outputFmt := fs.String("output", "yaml", "Output format: yaml|report|json")
+
+	var auditLogs, subjects stringList
+	fs.Var(&auditLogs, "audit-log", "Audit log file, JSON lines, .gz supported (repeatable)")
+	fs.Var(&subjects, "subject", "Only subject, e.g. ServiceAccount:ns/name or name (repeatable, glob)")
+
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if *inputDir == "" || len(auditLogs) == 0 {
+		fmt.Fprintln(os.Stderr, "error: -input-dir and -audit-log are required")
+		return exitError
+	}
+
+	win, err := parseWindow(*since, *until)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return exitError
+	}
+
+	_, subjectPerms, err := loadSubjectPerms(*inputDir, nil)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "load error:", err)
+		return exitError
+	}
+	usage, err := audit.ReadFiles(auditLogs, win)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "audit log error:", err)
+		return exitError
+	}
+
+	recs := audit.Recommend(output.Filter(subjectPerms, output.Filters{Subjects: subjects}), usage)
+
+	switch *outputFmt {
+	case "yaml":
+		for _, rec := range recs {
+			b, err := rec.YAML()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "output error:", err)
+				return exitError
+			}
+			os.Stdout.Write(b)
+		}
+	case "report":
+		printRecommendReport(recs)
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(recs); err != nil {
+			fmt.Fprintln(os.Stderr, "output error:", err)
+			return exitError
+		}
+	default:
+		fmt.Fprintln(os.Stderr, "unknown output format:", *outputFmt)
+		return exitError
+	}
+	return exitOK
+}
+
+func parseWindow(since, until string) (audit.Window, error) {
+	now := time.Now().UTC()
+	var win audit.Window
+	var err error
+	if win.Since, err = audit.ParseTime(since, now); err != nil {
+		return win, err
+	}
+	if win.Until, err = audit.ParseTime(until, now); err != nil {
+		return win, err
+	}
+	return win, nil
+}
+
+func printRecommendReport(recs []audit.Recommendation) {
+	total := 0
+	for _, rec := range recs {
+		total += len(rec.Unused)
+		fmt.Printf("=== %s ===\n", rec.Subject)
+		fmt.Printf("  used: %d distinct request(s), unused permissions: %d\n", rec.Used, len(rec.Unused))
+		for _, p := range rec.Unused {
+			fmt.Printf("    - %s via %s: %s\n", p.Role, p.Binding, p.Permission)
+		}
+		if len(rec.Uncovered) > 0 {
+			fmt.Printf("  not covered by current RBAC: %d\n", len(rec.Uncovered))
+			for _, a := range rec.Uncovered {
+				fmt.Printf("    - %s\n", accessLine(a.Access))
+			}
+		}
+	}
+	fmt.Printf("\n%d subject(s), %d unused permission(s)\n", len(recs), total)
+}
+
+func accessLine(a audit.Access) string {
+	return rbac.CanonicalPermissionKey(a.Namespace, a.Verb, a.APIGroup, a.Resource, nil)
+}
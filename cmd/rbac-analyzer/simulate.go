@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"rbac-analyzer/internal/loader"
+	"rbac-analyzer/internal/output"
+	"rbac-analyzer/internal/rbac"
+	"rbac-analyzer/internal/simulate"
+)
+
+// runSimulate — `rbac-analyzer simulate -base dir [-apply dir|file] [-delete Kind/ns/name]`:
+// что изменится в эффективных правах, если применить манифесты и удалить объекты.
+func runSimulate(args []string) int {
+	fs := flag.NewFlagSet("simulate", flag.ContinueOnError)
+	baseDir := fs.String("base", "", "Directory with the current RBAC YAML manifests")
+	applyPath := fs.String("apply", "", "Directory or file with new or changed manifests")
+	var deletes stringList
+	fs.Var(&deletes, "delete", "Object to delete: Kind/name or Kind/namespace/name (repeatable)")
+	rulesFile := fs.String("rules", "", "YAML file with custom CEL danger rules")
+	outputFmt := fs.String("output", "text", "Output format: text|markdown|json")
+	title := fs.String("title", "RBAC what-if", "Report title (markdown)")
+	failOnDanger := fs.Bool("fail-on-danger", false, "Exit with code 2 if any subject becomes dangerous")
+
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if *baseDir == "" {
+		fmt.Fprintln(os.Stderr, "error: -base is required")
+		return exitError
+	}
+	if *applyPath == "" && len(deletes) == 0 {
+		fmt.Fprintln(os.Stderr, "error: nothing to simulate: set -apply and/or -delete")
+		return exitError
+	}
+
+	var ch simulate.Change
+	for _, d := range deletes {
+		ref, err := loader.ParseObjectRef(d)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			return exitError
+		}
+		ch.Delete = append(ch.Delete, ref)
+	}
+
+	rules, err := loadRules(*rulesFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "rules error:", err)
+		return exitError
+	}
+	base, err := loader.LoadFromDir(*baseDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "load error:", err)
+		return exitError
+	}
+	if *applyPath != "" {
+		if ch.Apply, err = loader.LoadFromDir(*applyPath); err != nil {
+			fmt.Fprintln(os.Stderr, "load error:", err)
+			return exitError
+		}
+	}
+
+	res, err := simulate.Run(base, ch, rules)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "simulate error:", err)
+		return exitError
+	}
+
+	switch *outputFmt {
+	case "text":
+		printSimulateText(res)
+	case "markdown":
+		err = output.PrintMarkdownDiff(os.Stdout, res.Diff, *title, output.MarkdownOptions{})
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(res)
+	default:
+		fmt.Fprintln(os.Stderr, "unknown output format:", *outputFmt)
+		return exitError
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "output error:", err)
+		return exitError
+	}
+
+	for _, ref := range res.NotFound {
+		fmt.Fprintln(os.Stderr, "warning: not found in base:", ref)
+	}
+	if *failOnDanger && res.DangerIncreased() {
+		return exitDenied
+	}
+	return exitOK
+}
+
+// printSimulateText: сначала смена статуса опасности, затем изменения прав по субъектам.
+func printSimulateText(res simulate.Result) {
+	fmt.Println("Changes")
+	fmt.Println("=======")
+	for _, ref := range res.Applied {
+		fmt.Println("  apply ", ref)
+	}
+	for _, ref := range res.Deleted {
+		fmt.Println("  delete", ref)
+	}
+
+	var becomes, stops []rbac.SubjectDiff
+	for _, sd := range res.Diff.Subjects {
+		switch {
+		case !sd.BaseDangerous && sd.TargetDangerous:
+			becomes = append(becomes, sd)
+		case sd.BaseDangerous && !sd.TargetDangerous:
+			stops = append(stops, sd)
+		}
+	}
+
+	if len(becomes) > 0 {
+		fmt.Println()
+		fmt.Println("!!! BECOMES DANGEROUS")
+		for _, sd := range becomes {
+			fmt.Printf("  %s: %s\n", sd.SubjectKey, strings.Join(sd.TargetReasons, "; "))
+		}
+	}
+	if len(stops) > 0 {
+		fmt.Println()
+		fmt.Println("No longer dangerous")
+		for _, sd := range stops {
+			fmt.Printf("  %s\n", sd.SubjectKey)
+		}
+	}
+
+	fmt.Println()
+	fmt.Println("Permission changes")
+	fmt.Println("==================")
+	for _, sd := range res.Diff.Subjects {
+		if len(sd.Added) == 0 && len(sd.Removed) == 0 {
+			continue
+		}
+		fmt.Println(sd.SubjectKey)
+		for _, p := range sd.Added {
+			fmt.Println("  +", p)
+		}
+		for _, p := range sd.Removed {
+			fmt.Println("  -", p)
+		}
+	}
+
+	s := res.Diff.Summary
+	fmt.Printf("\n%d subject(s) changed, +%d/-%d permission(s), %d become dangerous, %d no longer dangerous\n",
+		s.SubjectsChanged, s.PermsAdded, s.PermsRemoved, s.DangerIncreased, s.DangerDecreased)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"rbac-analyzer/internal/audit"
+	"rbac-analyzer/internal/output"
+)
+
+// runUsage — `rbac-analyzer usage -input-dir dir -audit-log audit.log`:
+// какие роли и биндинги использовались за окно audit-лога.
+func runUsage(args []string) int {
+	fs := flag.NewFlagSet("usage", flag.ContinueOnError)
+	inputDir := fs.String("input-dir", "", "Directory with RBAC YAML manifests")
+	since := fs.String("since", "", "Window start: RFC3339 or duration back from now (2160h)")
+	until := fs.String("until", "", "Window end: RFC3339 or duration back from now")
+	staleDays := fs.Int("stale-days", 90, "Bindings unused for this many days are removal candidates")
+	outputFmt := fs.String("output", "text", "Output format: text|json")
+	candidatesOnly := fs.Bool("candidates-only", false, "Show only bindings that are removal candidates")
+
+	var auditLogs, subjects stringList
+	fs.Var(&auditLogs, "audit-log", "Audit log file, JSON lines, .gz supported (repeatable)")
+	fs.Var(&subjects, "subject", "Only subject, e.g. ServiceAccount:ns/name or name (repeatable, glob)")
+
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if *inputDir == "" || len(auditLogs) == 0 {
+		fmt.Fprintln(os.Stderr, "error: -input-dir and -audit-log are required")
+		return exitError
+	}
+
+	win, err := parseWindow(*since, *until)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return exitError
+	}
+
+	_, subjectPerms, err := loadSubjectPerms(*inputDir, nil)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "load error:", err)
+		return exitError
+	}
+	usage, err := audit.ReadFiles(auditLogs, win)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "audit log error:", err)
+		return exitError
+	}
+
+	rep := audit.BuildUsageReport(
+		output.Filter(subjectPerms, output.Filters{Subjects: subjects}),
+		usage, win, time.Now().UTC(), time.Duration(*staleDays)*24*time.Hour,
+	)
+
+	if *candidatesOnly {
+		var keep []audit.BindingUsage
+		for _, b := range rep.Bindings {
+			if b.RemovalCandidate {
+				keep = append(keep, b)
+			}
+		}
+		rep.Bindings = keep
+	}
+
+	switch *outputFmt {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(rep); err != nil {
+			fmt.Fprintln(os.Stderr, "output error:", err)
+			return exitError
+		}
+	case "text":
+		printUsageText(rep, *candidatesOnly)
+	default:
+		fmt.Fprintln(os.Stderr, "unknown output format:", *outputFmt)
+		return exitError
+	}
+	return exitOK
+}
+
+func printUsageText(rep audit.UsageReport, bindingsOnly bool) {
+	fmt.Println("Bindings")
+	fmt.Println("========")
+	for _, b := range rep.Bindings {
+		mark := ""
+		if b.RemovalCandidate {
+			mark = fmt.Sprintf("  [REMOVE? unused > %dd]", rep.StaleAfterDays)
+		}
+		fmt.Printf("%-8s %-20s %s -> %s%s\n", strings.ToUpper(b.Status), fmtLastUsed(b.LastUsed), b.Binding, b.Role, mark)
+		fmt.Printf("         subjects: %s\n", strings.Join(b.Subjects, ", "))
+	}
+
+	if !bindingsOnly {
+		fmt.Println()
+		fmt.Println("Permissions")
+		fmt.Println("===========")
+		for _, r := range rep.Roles {
+			fmt.Printf("=== %s: %s via %s [%s] ===\n", r.Subject, r.Role, r.Binding, r.Status)
+			for _, p := range r.Permissions {
+				fmt.Printf("  %6d  %-20s %s\n", p.Count, fmtLastUsed(p.LastUsed), p.Permission)
+			}
+		}
+	}
+
+	fmt.Printf("\n%d used, %d partial, %d unused, %d removal candidate(s)\n",
+		rep.Counts[audit.StatusUsed], rep.Counts[audit.StatusPartial],
+		rep.Counts[audit.StatusUnused], rep.Counts["removalCandidates"])
+}
+
+func fmtLastUsed(t *time.Time) string {
+	if t == nil {
+		return "never"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -2,30 +2,44 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"path"
 	"strings"
 	"time"
 
+	"rbac-analyzer/internal/celrules"
 	"rbac-analyzer/internal/config"
 	"rbac-analyzer/internal/db"
 	"rbac-analyzer/internal/httpapi"
+	"rbac-analyzer/internal/migrate"
+	"rbac-analyzer/internal/report"
 	"rbac-analyzer/internal/store"
+	"rbac-analyzer/internal/store/memstore"
+	"rbac-analyzer/migrations"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(os.Args[2:]))
+	}
+
 	cfg := config.Load()
+	dbURL := flag.String("db", cfg.DatabaseURL, "Database URL: postgres://..., memory:// or file:///path/state.json (overrides DATABASE_URL)")
+	flag.Parse()
+	cfg.DatabaseURL = *dbURL
 
 	ctx := context.Background()
-	pool, err := db.Connect(ctx, cfg.DatabaseURL)
+	st, closeStore, err := openStore(ctx, cfg)
 	if err != nil {
 		panic(err)
 	}
-	defer pool.Close()
-
-	st := store.New(pool)
+	defer closeStore()
 
 	// Static web handler (marketing + auth + app) из embed FS
 	web := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -33,6 +47,23 @@ func main() {
 	})
 
 	srv := httpapi.NewServer(cfg, st, web)
+	if cfg.RulesFile != "" {
+		// файл читается один раз: правила и их хеш должны относиться к одному содержимому
+		b, err := os.ReadFile(cfg.RulesFile)
+		if err != nil {
+			panic(err)
+		}
+		srv.Rules, err = celrules.Parse(b)
+		if err != nil {
+			panic(err)
+		}
+		// изменение пользовательских правил тоже делает сохранённые результаты устаревшими
+		sum := sha256.Sum256(b)
+		srv.EngineVersion = report.EngineVersion(hex.EncodeToString(sum[:6]))
+	}
+	if cfg.ReanalyzeOnStart {
+		srv.StartReanalyze()
+	}
 
 	httpSrv := &http.Server{
 		Addr:              cfg.Addr,
@@ -46,6 +77,30 @@ func main() {
 	}
 }
 
+// openStore выбирает хранилище по адресу: встроенное (memory://, file://) или PostgreSQL.
+// Для PostgreSQL проверяется схема (см. checkSchema).
+func openStore(ctx context.Context, cfg config.Config) (store.Repository, func(), error) {
+	if memstore.IsURL(cfg.DatabaseURL) {
+		st, err := memstore.OpenURL(cfg.DatabaseURL)
+		return st, func() {}, err
+	}
+
+	pool, err := db.Connect(ctx, cfg.DatabaseURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	m, err := migrate.New(pool, migrations.FS)
+	if err != nil {
+		pool.Close()
+		return nil, nil, err
+	}
+	if err := checkSchema(ctx, m, cfg.MigrateOnStart); err != nil {
+		pool.Close()
+		return nil, nil, err
+	}
+	return store.New(pool), pool.Close, nil
+}
+
 func serveWeb(w http.ResponseWriter, r *http.Request) {
 	p := r.URL.Path
 
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"rbac-analyzer/internal/config"
+	"rbac-analyzer/internal/db"
+	"rbac-analyzer/internal/migrate"
+	"rbac-analyzer/internal/store/memstore"
+	"rbac-analyzer/migrations"
+)
+
+// runMigrate — `rbac-server migrate up|down|status`: управление схемой базы (DATABASE_URL).
+func runMigrate(args []string) int {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	to := fs.Int("to", 0, "up: apply migrations up to this version (0 = latest)")
+	steps := fs.Int("steps", 1, "down: number of migrations to roll back")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: rbac-server migrate [flags] up|down|status")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 1
+	}
+
+	cfg := config.Load()
+	if memstore.IsURL(cfg.DatabaseURL) {
+		if _, err := memstore.OpenURL(cfg.DatabaseURL); err != nil {
+			fmt.Fprintln(os.Stderr, "db error:", err)
+			return 1
+		}
+		fmt.Println("embedded store (memory://, file://) needs no migrations")
+		return 0
+	}
+
+	ctx := context.Background()
+	pool, err := db.Connect(ctx, cfg.DatabaseURL)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "db error:", err)
+		return 1
+	}
+	defer pool.Close()
+
+	m, err := migrate.New(pool, migrations.FS)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "migrations error:", err)
+		return 1
+	}
+
+	switch fs.Arg(0) {
+	case "up":
+		done, err := m.Up(ctx, *to)
+		for _, mg := range done {
+			fmt.Printf("applied %03d_%s\n", mg.Version, mg.Name)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "migrate up:", err)
+			return 1
+		}
+		if len(done) == 0 {
+			fmt.Println("schema is up to date")
+		}
+
+	case "down":
+		done, err := m.Down(ctx, *steps)
+		for _, mg := range done {
+			fmt.Printf("rolled back %03d_%s\n", mg.Version, mg.Name)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "migrate down:", err)
+			return 1
+		}
+
+	case "status":
+		st, err := m.Status(ctx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "migrate status:", err)
+			return 1
+		}
+		for _, s := range st {
+			state := "pending"
+			if s.Applied {
+				state = "applied " + s.AppliedAt.UTC().Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%03d_%-20s %s\n", s.Version, s.Name, state)
+		}
+
+	default:
+		fs.Usage()
+		return 1
+	}
+	return 0
+}
+
+// checkSchema вызывается при старте сервера: с MIGRATE_ON_START=1 применяет миграции,
+// иначе отказывается работать со схемой, в которой применены не все миграции.
+func checkSchema(ctx context.Context, m *migrate.Migrator, autoMigrate bool) error {
+	if autoMigrate {
+		done, err := m.Up(ctx, 0)
+		for _, mg := range done {
+			fmt.Printf("applied migration %03d_%s\n", mg.Version, mg.Name)
+		}
+		return err
+	}
+	return m.Check(ctx)
+}
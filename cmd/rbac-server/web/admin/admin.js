@@ -1,5 +1,31 @@
 // ---------- ADMIN API ----------
-async function api(url, opts = {}) {
+// access token живёт 15 минут: при 401 он один раз обновляется по refresh token.
+// Параллельные запросы ждут одно обновление — повторный refresh token сервер считает кражей.
+let refreshing = null;
+function refreshSession() {
+  if (!refreshing) {
+    refreshing = (async () => {
+      const rt = localStorage.getItem("adminRefreshToken");
+      if (!rt) return false;
+      const r = await fetch("/api/auth/refresh", {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ refreshToken: rt }),
+      });
+      if (!r.ok) {
+        localStorage.removeItem("adminRefreshToken");
+        return false;
+      }
+      const res = await r.json();
+      localStorage.setItem("adminToken", res.token);
+      localStorage.setItem("adminRefreshToken", res.refreshToken);
+      return true;
+    })().finally(() => { refreshing = null; });
+  }
+  return refreshing;
+}
+
+async function api(url, opts = {}, retried = false) {
   opts.headers = opts.headers || {};
 
   const token = localStorage.getItem("adminToken");
@@ -12,6 +38,7 @@ async function api(url, opts = {}) {
 
   const r = await fetch(url, opts);
 
+  if (r.status === 401 && !retried && await refreshSession()) return api(url, opts, true);
   if (r.status === 401 || r.status === 403) {
     localStorage.removeItem("adminToken");
     window.location.href = "/admin";
@@ -27,8 +54,15 @@ function el(id) {
 }
 
 // ---------- LOGOUT ----------
-function logout() {
+async function logout() {
+  try {
+    await fetch("/api/auth/logout", {
+      method: "POST",
+      headers: { "Authorization": "Bearer " + (localStorage.getItem("adminToken") || "") },
+    });
+  } catch {}
   localStorage.removeItem("adminToken");
+  localStorage.removeItem("adminRefreshToken");
   window.location.href = "/admin";
 }
 
@@ -58,6 +92,8 @@ async function loadUsers() {
         <button class="btn secondary" data-id="${u.id}">
           ${u.isAdmin ? "Revoke" : "Make admin"}
         </button>
+        <button class="btn secondary" data-sessions="${u.id}">Sign out</button>
+        <button class="btn secondary" data-mfa="${u.id}">Reset 2FA</button>
       </td>
     `;
 
@@ -65,6 +101,22 @@ async function loadUsers() {
       await api(`/api/admin/users/${u.id}/toggle-admin`, { method: "POST" });
       await loadUsers();
     };
+    // отзыв всех сессий пользователя (записывается в журнал)
+    tr.querySelector("[data-sessions]").onclick = async () => {
+      if (!confirm(`Sign out ${u.email} on all devices?`)) return;
+      const r = await api(`/api/admin/users/${u.id}/sessions`, { method: "DELETE" });
+      alert(`Revoked sessions: ${r.revoked}`);
+    };
+    // сброс 2FA (потерян телефон и коды восстановления), записывается в журнал
+    tr.querySelector("[data-mfa]").onclick = async () => {
+      if (!confirm(`Reset two-factor authentication for ${u.email}?`)) return;
+      try {
+        await api(`/api/admin/users/${u.id}/mfa`, { method: "DELETE" });
+        alert("2FA reset. The user can sign in with the password and set up 2FA again.");
+      } catch (e) {
+        alert(e.message);
+      }
+    };
 
     body.appendChild(tr);
   }
@@ -73,7 +125,7 @@ async function loadUsers() {
 // ---------- ORGS ----------
 async function loadOrgs() {
   const body = el("orgsBody");
-  body.innerHTML = `<tr><td colspan="3" class="muted">Loading…</td></tr>`;
+  body.innerHTML = `<tr><td colspan="4" class="muted">Loading…</td></tr>`;
 
   const res = await api("/api/admin/orgs");
   body.innerHTML = "";
@@ -84,11 +136,65 @@ async function loadOrgs() {
       <td>${o.name}</td>
       <td>${o.ownerEmail}</td>
       <td>${fmtDate(o.createdAt)}</td>
+      <td><button class="btn secondary">Access</button></td>
     `;
+    tr.querySelector("button").onclick = () => loadAccess(o.id, o.name);
     body.appendChild(tr);
   }
 }
 
+// ---------- CLUSTER ACCESS ----------
+// эффективные роли участников на кластерах организации и откуда они взялись
+async function loadAccess(orgId, orgName) {
+  const body = el("accessBody");
+  el("accessOrg").textContent = orgName;
+  body.innerHTML = `<tr><td colspan="4" class="muted">Loading…</td></tr>`;
+
+  const res = await api(`/api/admin/orgs/${encodeURIComponent(orgId)}/grants`);
+  body.innerHTML = "";
+
+  if (!res.effective.length) {
+    body.innerHTML = `<tr><td colspan="4" class="muted">No clusters</td></tr>`;
+    return;
+  }
+  for (const g of res.effective) {
+    const tr = document.createElement("tr");
+    tr.innerHTML = `<td></td><td></td><td></td><td class="muted"></td>`;
+    tr.children[0].textContent = `${g.email} (${g.orgRole})`;
+    tr.children[1].textContent = g.clusterName;
+    tr.children[2].textContent = g.role;
+    tr.children[3].textContent = g.sources.join(", ");
+    body.appendChild(tr);
+  }
+}
+
+// ---------- REANALYZE ----------
+async function loadReanalyze() {
+  const res = await api("/api/admin/reanalyze");
+  el("engineVersion").textContent = res.engineVersion;
+  el("staleScans").textContent = res.stale;
+
+  const j = res.job;
+  if (j.running) {
+    el("reanalyzeJob").textContent = `Running: ${j.done} done, ${j.failed} failed`;
+  } else if (j.finishedAt) {
+    el("reanalyzeJob").textContent =
+      `Finished ${fmtDate(j.finishedAt)}: ${j.done} done, ${j.failed} failed` +
+      (j.lastError ? ` (last error: ${j.lastError})` : "");
+  } else {
+    el("reanalyzeJob").textContent = "";
+  }
+}
+
+async function startReanalyze() {
+  try {
+    await api("/api/admin/reanalyze", { method: "POST" });
+  } catch (e) {
+    alert(e.message);
+  }
+  await loadReanalyze();
+}
+
 // ---------- INIT ----------
 window.addEventListener("DOMContentLoaded", async () => {
   // если токен отсутствует → login
@@ -114,7 +220,10 @@ window.addEventListener("DOMContentLoaded", async () => {
   el("logoutBtn").onclick = logout;
   el("reloadUsers").onclick = loadUsers;
   el("reloadOrgs").onclick = loadOrgs;
+  el("reloadReanalyze").onclick = loadReanalyze;
+  el("startReanalyze").onclick = startReanalyze;
 
   await loadUsers();
   await loadOrgs();
+  await loadReanalyze();
 });
@@ -1,3 +1,16 @@
+// mfaToken — пароль принят, но у аккаунта включена 2FA: следующий шаг — код
+let mfaToken = "";
+
+async function post(url, body) {
+  const r = await fetch(url, {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify(body),
+  });
+  if (!r.ok) throw new Error(await r.text());
+  return r.json();
+}
+
 async function login() {
   const email = document.getElementById("email").value;
   const password = document.getElementById("password").value;
@@ -6,14 +19,18 @@ async function login() {
   status.textContent = "Checking…";
 
   try {
-    const r = await fetch("/api/auth/login", {
-      method: "POST",
-      headers: { "Content-Type": "application/json" },
-      body: JSON.stringify({ email, password }),
-    });
-
-    if (!r.ok) throw new Error(await r.text());
-    const res = await r.json();
+    let res;
+    if (mfaToken) {
+      res = await post("/api/auth/mfa", { mfaToken, code: document.getElementById("mfaCode").value.trim() });
+    } else {
+      res = await post("/api/auth/login", { email, password });
+      if (res.mfaRequired) {
+        mfaToken = res.mfaToken;
+        document.getElementById("mfaCode").style.display = "";
+        status.textContent = "Enter the code from your authenticator app.";
+        return;
+      }
+    }
 
     const payload = JSON.parse(atob(res.token.split(".")[1]));
     if (!payload.admin) {
@@ -21,8 +38,14 @@ async function login() {
     }
 
     localStorage.setItem("adminToken", res.token);
+    localStorage.setItem("adminRefreshToken", res.refreshToken);
     window.location.href = "/admin/dashboard";
   } catch (e) {
+    // mfaToken истёк — начинаем вход заново
+    if (mfaToken && e.message.includes("expired")) {
+      mfaToken = "";
+      document.getElementById("mfaCode").style.display = "none";
+    }
     status.textContent = e.message;
   }
 }
@@ -1,10 +1,37 @@
-async function api(url, opts = {}) {
+// access token живёт 15 минут: при 401 он один раз обновляется по refresh token.
+// Параллельные запросы ждут одно обновление — повторный refresh token сервер считает кражей.
+let refreshing = null;
+function refreshSession() {
+  if (!refreshing) {
+    refreshing = (async () => {
+      const rt = localStorage.getItem("refreshToken");
+      if (!rt) return false;
+      const r = await fetch("/api/auth/refresh", {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ refreshToken: rt }),
+      });
+      if (!r.ok) {
+        localStorage.removeItem("refreshToken");
+        return false;
+      }
+      const res = await r.json();
+      localStorage.setItem("token", res.token);
+      localStorage.setItem("refreshToken", res.refreshToken);
+      return true;
+    })().finally(() => { refreshing = null; });
+  }
+  return refreshing;
+}
+
+async function api(url, opts = {}, retried = false) {
   opts.headers = opts.headers || {};
   const token = localStorage.getItem("token");
   if (token) opts.headers["Authorization"] = "Bearer " + token;
 
   const r = await fetch(url, opts);
   if (r.status === 401) {
+    if (!retried && await refreshSession()) return api(url, opts, true);
     localStorage.removeItem("token");
     window.location.href = "/login";
     return;
@@ -22,8 +49,15 @@ function el(id) { return document.getElementById(id); }
 function fmt(iso) { try { return new Date(iso).toLocaleString(); } catch { return iso || ""; } }
 function esc(s){return String(s||"").replaceAll("&","&amp;").replaceAll("<","&lt;").replaceAll(">","&gt;").replaceAll('"',"&quot;").replaceAll("'","&#039;");}
 
-function logout() {
+async function logout() {
+  try {
+    await fetch("/api/auth/logout", {
+      method: "POST",
+      headers: { "Authorization": "Bearer " + (localStorage.getItem("token") || "") },
+    });
+  } catch {}
   localStorage.removeItem("token");
+  localStorage.removeItem("refreshToken");
   window.location.href = "/login";
 }
 
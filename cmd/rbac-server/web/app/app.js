@@ -1,12 +1,41 @@
-async function api(url, opts = {}) {
+// access token живёт 15 минут: при 401 он один раз обновляется по refresh token.
+// Параллельные запросы ждут одно обновление — повторный refresh token сервер считает кражей.
+let refreshing = null;
+function refreshSession() {
+  if (!refreshing) {
+    refreshing = (async () => {
+      const rt = localStorage.getItem("refreshToken");
+      if (!rt) return false;
+      const r = await fetch("/api/auth/refresh", {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ refreshToken: rt }),
+      });
+      if (!r.ok) {
+        localStorage.removeItem("refreshToken");
+        return false;
+      }
+      const res = await r.json();
+      localStorage.setItem("token", res.token);
+      localStorage.setItem("refreshToken", res.refreshToken);
+      return true;
+    })().finally(() => { refreshing = null; });
+  }
+  return refreshing;
+}
+
+async function api(url, opts = {}, retried = false) {
   opts.headers = opts.headers || {};
   const token = localStorage.getItem("token");
   if (token) opts.headers["Authorization"] = "Bearer " + token;
+  const orgId = localStorage.getItem("orgId");
+  if (orgId) opts.headers["X-Org-ID"] = orgId;
 
   const r = await fetch(url, opts);
 
-  // если сессия протухла / нет токена
+  // access token истёк — обновляем; сессия отозвана / нет токена — на вход
   if (r.status === 401) {
+    if (!retried && await refreshSession()) return api(url, opts, true);
     localStorage.removeItem("token");
     window.location.href = "/login";
     return;
@@ -19,11 +48,27 @@ async function api(url, opts = {}) {
 function el(id) { return document.getElementById(id); }
 function msg(id, t) { el(id).textContent = t; }
 function requireAuth() {
+  // ссылка-приглашение (/app?invite=…) переживает вход/регистрацию
+  const invite = new URLSearchParams(window.location.search).get("invite");
+  if (invite) {
+    localStorage.setItem("pendingInvite", invite);
+    history.replaceState(null, "", "/app");
+  }
   const token = localStorage.getItem("token");
-  if (!token) window.location.href = "/login";
+  if (!token) window.location.href = invite ? "/register" : "/login";
 }
-function logout() {
+async function logout(all = false) {
+  // сессия отзывается на сервере: refresh token больше не действует
+  try {
+    await fetch("/api/auth/logout", {
+      method: "POST",
+      headers: { "Authorization": "Bearer " + (localStorage.getItem("token") || ""), "Content-Type": "application/json" },
+      body: JSON.stringify({ all }),
+    });
+  } catch {}
   localStorage.removeItem("token");
+  localStorage.removeItem("refreshToken");
+  localStorage.removeItem("orgId");
   window.location.href = "/login";
 }
 
@@ -77,8 +122,147 @@ function openProfileModal() {
   applyAvatarTo(el("avatarBig"), p.avatar || "");
   applyAvatarTo(el("navAvatar"), p.avatar || "");
   msg("profileStatus", "");
+  msg("passwordStatus", "");
   el("profileModal").classList.remove("hidden");
   el("profileModal").setAttribute("aria-hidden", "false");
+  loadMFA();
+  loadSessions();
+}
+
+// сессии пользователя: можно выйти на другом устройстве или везде
+async function loadSessions() {
+  const box = el("sessionsList");
+  try {
+    const res = await api("/api/app/sessions");
+    box.innerHTML = "";
+    for (const ss of res.sessions || []) {
+      const row = document.createElement("div");
+      row.className = "row";
+      const label = document.createElement("span");
+      label.textContent = `${ss.userAgent || "unknown device"} · ${ss.ip} · last used ${fmtDate(ss.lastUsedAt)}` +
+        (ss.id === res.current ? " (this device)" : "");
+      row.appendChild(label);
+      if (ss.id !== res.current) {
+        row.appendChild(actionBtn("Revoke", async () => {
+          try {
+            await api(`/api/app/sessions/${encodeURIComponent(ss.id)}`, { method: "DELETE" });
+            await loadSessions();
+          } catch (e) {
+            msg("profileStatus", e.message);
+          }
+        }));
+      }
+      box.appendChild(row);
+    }
+  } catch (e) {
+    box.textContent = e.message;
+  }
+}
+
+// ---------- 2FA ----------
+// кнопки показываются по состоянию: Set up -> Confirm -> (New recovery codes | Turn off)
+async function loadMFA() {
+  el("mfaEnrollBox").style.display = "none";
+  el("mfaRecovery").style.display = "none";
+  try {
+    const res = await api("/api/app/mfa");
+    const required = (res.requiredBy || []).length ? ` Required by: ${res.requiredBy.join(", ")}.` : "";
+    el("mfaState").textContent = res.enabled
+      ? `On ✓ Recovery codes left: ${res.recoveryCodesLeft}.` + required
+      : "Off." + required;
+    showMFAButtons(res.enabled ? "on" : "off");
+  } catch (e) {
+    el("mfaState").textContent = e.message;
+  }
+}
+
+function showMFAButtons(state) {
+  const visible = { off: ["mfaEnroll"], enrolling: ["mfaEnable"], on: ["mfaNewCodes", "mfaDisable"] }[state];
+  for (const id of ["mfaEnroll", "mfaEnable", "mfaNewCodes", "mfaDisable"]) {
+    el(id).style.display = visible.includes(id) ? "" : "none";
+  }
+  el("mfaCode").style.display = state === "off" ? "none" : "";
+}
+
+function showRecoveryCodes(codes) {
+  el("mfaRecovery").textContent = "Save these recovery codes — each works once and they are shown only now:\n\n" + codes.join("\n");
+  el("mfaRecovery").style.display = "";
+}
+
+async function mfaEnroll() {
+  try {
+    const res = await api("/api/app/mfa/enroll", { method: "POST" });
+    el("mfaSecret").textContent = res.secret.replace(/(.{4})/g, "$1 ").trim();
+    el("mfaUri").href = res.otpauthUrl;
+    el("mfaEnrollBox").style.display = "";
+    showMFAButtons("enrolling");
+  } catch (e) {
+    el("mfaState").textContent = e.message;
+  }
+}
+
+async function mfaAction(url, method) {
+  const code = el("mfaCode").value.trim();
+  try {
+    const res = await api(url, {
+      method,
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ code }),
+    });
+    el("mfaCode").value = "";
+    await loadMFA();
+    if (res.recoveryCodes) showRecoveryCodes(res.recoveryCodes);
+    await loadMe();
+  } catch (e) {
+    el("mfaState").textContent = e.message;
+  }
+}
+
+// политика организации (owner): без 2FA участники не видят данные
+async function saveOrgMFA() {
+  try {
+    const res = await api("/api/app/org/mfa", {
+      method: "PUT",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ require: el("orgRequireMfa").checked }),
+    });
+    const without = res.membersWithoutMfa || [];
+    msg("teamStatus", res.requireMfa && without.length
+      ? `2FA required ✓ Members without 2FA (no access until they set it up): ${without.join(", ")}`
+      : "Saved ✓");
+  } catch (e) {
+    msg("teamStatus", e.message);
+  }
+}
+
+// смена пароля: другие сессии сервер отзывает, текущая остаётся
+async function changePassword() {
+  msg("passwordStatus", "Changing…");
+  try {
+    const res = await api("/api/app/password", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({
+        currentPassword: el("currentPassword").value,
+        newPassword: el("newPassword").value,
+      }),
+    });
+    el("currentPassword").value = "";
+    el("newPassword").value = "";
+    msg("passwordStatus", `Password changed ✓ (signed out on ${res.revokedSessions} other device(s))`);
+    await loadSessions();
+  } catch (e) {
+    msg("passwordStatus", e.message);
+  }
+}
+
+async function resendVerification() {
+  try {
+    await api("/api/app/verify-email", { method: "POST" });
+    msg("meStatus", "Confirmation email sent ✓");
+  } catch (e) {
+    msg("meStatus", e.message);
+  }
 }
 
 function closeProfileModal() {
@@ -114,17 +298,356 @@ async function saveProfile() {
 }
 
 // ---------- ME ----------
+let myRole = "";
+
 async function loadMe() {
   msg("meStatus", "Loading session…");
   try {
-    const res = await api("/api/app/me");
+    let res;
+    try {
+      res = await api("/api/app/me");
+    } catch (e) {
+      // выбранная ранее организация больше недоступна — вернуться к организации по умолчанию
+      if (!localStorage.getItem("orgId")) throw e;
+      localStorage.removeItem("orgId");
+      res = await api("/api/app/me");
+    }
     msg("meStatus", "Session OK ✓");
     el("meBox").textContent = safe(res);
+    myRole = res.role || "";
+    el("verifyBox").style.display = res.emailVerified ? "none" : "";
+    el("mfaSetupBox").style.display = res.mfaSetupRequired ? "" : "none";
+    el("orgRequireMfa").checked = !!res.org.RequireMFA;
+    el("saveOrgMfa").disabled = myRole !== "owner";
+    renderOrgs(res.orgs || [], res.org.ID);
   } catch (e) {
     msg("meStatus", e.message);
   }
 }
 
+// ---------- TEAM ----------
+function renderOrgs(orgs, currentId) {
+  const sel = el("orgSelect");
+  sel.innerHTML = "";
+  for (const m of orgs) {
+    const o = document.createElement("option");
+    o.value = m.org.ID;
+    o.textContent = `${m.org.Name} (${m.role})`;
+    o.selected = m.org.ID === currentId;
+    sel.appendChild(o);
+  }
+  document.querySelectorAll(".adminOnly").forEach(n => {
+    n.style.display = (myRole === "admin" || myRole === "owner") ? "" : "none";
+  });
+}
+
+async function switchOrg() {
+  localStorage.setItem("orgId", el("orgSelect").value);
+  el("summary").textContent = "No data";
+  el("report").textContent = "No data";
+  setSelectedScan("");
+  await loadMe();
+  await loadTeam();
+  await loadClusters();
+}
+
+async function createOrg() {
+  const name = el("newOrgName").value.trim();
+  if (!name) return;
+  try {
+    const m = await api("/api/app/orgs", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ name }),
+    });
+    el("newOrgName").value = "";
+    localStorage.setItem("orgId", m.org.ID);
+    await loadMe();
+    await switchOrg();
+  } catch (e) {
+    msg("teamStatus", e.message);
+  }
+}
+
+async function memberAction(method, userId, body) {
+  try {
+    await api(`/api/app/org/members/${encodeURIComponent(userId)}`, {
+      method,
+      headers: { "Content-Type": "application/json" },
+      body: body ? JSON.stringify(body) : undefined,
+    });
+    msg("teamStatus", "Saved ✓");
+    if (method === "DELETE" && userId === currentUserId()) {
+      // вышли из организации — вернуться к организации по умолчанию
+      localStorage.removeItem("orgId");
+      await loadMe();
+      await loadClusters();
+    }
+    await loadTeam();
+  } catch (e) {
+    msg("teamStatus", e.message);
+  }
+}
+
+async function transferOwnership(userId, email) {
+  if (!confirm(`Transfer ownership to ${email}? You will become an admin.`)) return;
+  try {
+    await api("/api/app/org/transfer", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ userId }),
+    });
+    await loadMe();
+    await loadTeam();
+  } catch (e) {
+    msg("teamStatus", e.message);
+  }
+}
+
+function actionBtn(text, onClick) {
+  const b = document.createElement("button");
+  b.className = "btn secondary";
+  b.textContent = text;
+  b.onclick = onClick;
+  return b;
+}
+
+let lastMembers = [];
+let lastTeams = [];
+
+async function loadTeam() {
+  const body = el("membersBody");
+  try {
+    const res = await api("/api/app/org/members");
+    lastMembers = res.members || [];
+    const me = currentUserId();
+    const canManage = myRole === "admin" || myRole === "owner";
+    body.innerHTML = "";
+
+    for (const m of res.members || []) {
+      const tr = document.createElement("tr");
+      tr.innerHTML = `<td></td><td></td><td class="muted"></td><td></td>`;
+      tr.children[0].textContent = m.email + (m.userId === me ? " (you)" : "");
+      tr.children[1].textContent = m.role;
+      tr.children[2].textContent = fmtDate(m.joinedAt);
+
+      const actions = tr.children[3];
+      if (m.role !== "owner") {
+        if (canManage) {
+          const next = m.role === "admin" ? "member" : "admin";
+          actions.appendChild(actionBtn(`Make ${next}`, () => memberAction("PATCH", m.userId, { role: next })));
+        }
+        if (canManage || m.userId === me) {
+          actions.appendChild(actionBtn(m.userId === me ? "Leave" : "Remove", () => memberAction("DELETE", m.userId)));
+        }
+        if (myRole === "owner") {
+          actions.appendChild(actionBtn("Make owner", () => transferOwnership(m.userId, m.email)));
+        }
+      }
+      body.appendChild(tr);
+    }
+    msg("teamStatus", "");
+  } catch (e) {
+    body.innerHTML = "";
+    msg("teamStatus", e.message);
+  }
+
+  await loadTeams();
+  if (myRole === "admin" || myRole === "owner") {
+    await loadInvitations();
+    await loadSSO();
+  }
+}
+
+// SSO организации: смотрят admin+, сохраняет и отключает только owner
+async function loadSSO() {
+  try {
+    const res = await api("/api/app/org/sso");
+    const c = res.sso || {};
+    el("ssoRedirect").textContent = "Redirect URI for your IdP: " + (c.redirectUrl || res.redirectUrl);
+    el("ssoIssuer").value = c.issuer || "";
+    el("ssoClientId").value = c.clientId || "";
+    el("ssoClientSecret").value = "";
+    el("ssoClientSecret").placeholder = c.hasSecret ? "Client secret (saved)" : "Client secret";
+    el("ssoDomains").value = (c.domains || []).join(", ");
+    el("ssoGroupsClaim").value = c.groupsClaim || "";
+    el("ssoDefaultRole").value = c.defaultRole || "";
+    el("ssoGroupRoles").value = Object.entries(c.groupRoles || {}).map(([g, r]) => `${g}=${r}`).join(", ");
+    el("ssoEnforce").checked = !!c.enforce;
+    for (const id of ["saveSso", "deleteSso"]) el(id).disabled = myRole !== "owner";
+  } catch (e) {
+    msg("teamStatus", e.message);
+  }
+}
+
+async function saveSSO() {
+  const groupRoles = {};
+  for (const pair of el("ssoGroupRoles").value.split(",")) {
+    const [g, r] = pair.split("=").map(v => (v || "").trim());
+    if (g) groupRoles[g] = r || "member";
+  }
+  try {
+    await api("/api/app/org/sso", {
+      method: "PUT",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({
+        issuer: el("ssoIssuer").value.trim(),
+        clientId: el("ssoClientId").value.trim(),
+        clientSecret: el("ssoClientSecret").value,
+        domains: el("ssoDomains").value.split(",").map(d => d.trim()).filter(Boolean),
+        groupsClaim: el("ssoGroupsClaim").value.trim(),
+        groupRoles,
+        defaultRole: el("ssoDefaultRole").value,
+        enforce: el("ssoEnforce").checked,
+      }),
+    });
+    msg("teamStatus", "SSO saved ✓");
+    await loadSSO();
+  } catch (e) {
+    msg("teamStatus", e.message);
+  }
+}
+
+async function deleteSSO() {
+  if (!confirm("Disable single sign-on for this organization?")) return;
+  try {
+    await api("/api/app/org/sso", { method: "DELETE" });
+    msg("teamStatus", "SSO disabled");
+    await loadSSO();
+  } catch (e) {
+    msg("teamStatus", e.message);
+  }
+}
+
+// команды — получатели грантов на кластеры
+async function loadTeams() {
+  const box = el("teamsList");
+  try {
+    const res = await api("/api/app/org/teams");
+    lastTeams = res.teams || [];
+    const canManage = myRole === "admin" || myRole === "owner";
+    box.innerHTML = "";
+    if (!lastTeams.length) {
+      box.textContent = "No teams";
+      return;
+    }
+
+    const emails = Object.fromEntries(lastMembers.map(m => [m.userId, m.email]));
+    for (const t of lastTeams) {
+      const row = document.createElement("div");
+      row.className = "row";
+      const label = document.createElement("span");
+      label.textContent = `${t.name}: ${t.memberIds.map(id => emails[id] || id).join(", ") || "—"}`;
+      row.appendChild(label);
+
+      if (canManage) {
+        const sel = document.createElement("select");
+        for (const m of lastMembers) {
+          const o = document.createElement("option");
+          o.value = m.userId;
+          o.textContent = m.email;
+          sel.appendChild(o);
+        }
+        row.appendChild(sel);
+        const teamPath = `/api/app/org/teams/${encodeURIComponent(t.id)}`;
+        const memberPath = () => `${teamPath}/members/${encodeURIComponent(sel.value)}`;
+        row.appendChild(actionBtn("Add", () => teamAction("PUT", memberPath())));
+        row.appendChild(actionBtn("Remove", () => teamAction("DELETE", memberPath())));
+        row.appendChild(actionBtn("Delete team", () => teamAction("DELETE", teamPath)));
+      }
+      box.appendChild(row);
+    }
+  } catch (e) {
+    box.textContent = e.message;
+  }
+}
+
+async function teamAction(method, url, body) {
+  try {
+    await api(url, {
+      method,
+      headers: { "Content-Type": "application/json" },
+      body: body ? JSON.stringify(body) : undefined,
+    });
+    msg("teamStatus", "Saved ✓");
+    await loadTeams();
+  } catch (e) {
+    msg("teamStatus", e.message);
+  }
+}
+
+async function createTeam() {
+  const name = el("teamName").value.trim();
+  if (!name) return;
+  el("teamName").value = "";
+  await teamAction("POST", "/api/app/org/teams", { name });
+}
+
+async function loadInvitations() {
+  const box = el("invitationsList");
+  try {
+    const res = await api("/api/app/org/invitations");
+    box.innerHTML = "";
+    const list = res.invitations || [];
+    if (!list.length) {
+      box.textContent = "No pending invitations";
+      return;
+    }
+    for (const inv of list) {
+      const row = document.createElement("div");
+      row.className = "row";
+      const t = document.createElement("span");
+      t.textContent = `${inv.email} — ${inv.role}, expires ${fmtDate(inv.expiresAt)}`;
+      row.appendChild(t);
+      row.appendChild(actionBtn("Revoke", async () => {
+        try {
+          await api(`/api/app/org/invitations/${encodeURIComponent(inv.id)}`, { method: "DELETE" });
+          await loadInvitations();
+        } catch (e) {
+          msg("teamStatus", e.message);
+        }
+      }));
+      box.appendChild(row);
+    }
+  } catch (e) {
+    box.textContent = e.message;
+  }
+}
+
+async function inviteMember() {
+  try {
+    const res = await api("/api/app/org/invitations", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ email: el("inviteEmail").value.trim(), role: el("inviteRole").value }),
+    });
+    el("inviteEmail").value = "";
+    msg("teamStatus", res.emailSent
+      ? "Invitation sent ✓"
+      : "Email could not be sent, share the link: " + res.acceptUrl);
+    await loadInvitations();
+  } catch (e) {
+    msg("teamStatus", e.message);
+  }
+}
+
+// приглашение, сохранённое в requireAuth: принять после входа и переключиться на организацию
+async function acceptPendingInvite() {
+  const token = localStorage.getItem("pendingInvite");
+  if (!token) return;
+  localStorage.removeItem("pendingInvite");
+  try {
+    const m = await api("/api/app/invitations/accept", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ token }),
+    });
+    localStorage.setItem("orgId", m.org.ID);
+  } catch (e) {
+    alert("Invitation: " + e.message);
+  }
+}
+
 // ---------- CLUSTERS ----------
 async function loadClusters() {
   try {
@@ -133,20 +656,117 @@ async function loadClusters() {
     sel.innerHTML = "";
 
     const clusters = res.clusters || [];
+    clusterRoles = res.roles || {};
     for (const c of clusters) {
       const o = document.createElement("option");
       o.value = c.ID || c.id;
-      o.textContent = c.Name || c.name;
+      o.textContent = `${c.Name || c.name}${c.Restricted ? " 🔒" : ""} (${clusterRoles[o.value] || "viewer"})`;
+      o.dataset.restricted = c.Restricted ? "1" : "";
       sel.appendChild(o);
     }
 
     msg("clusterStatus", clusters.length ? "Clusters loaded ✓" : "No clusters yet");
+    await loadGrants();
     if (sel.value) await loadScanHistory();
   } catch (e) {
     msg("clusterStatus", e.message);
   }
 }
 
+// ---------- CLUSTER ACCESS ----------
+let clusterRoles = {};
+
+// гранты выбранного кластера — только для его admin
+async function loadGrants() {
+  const panel = el("grantsPanel");
+  const sel = el("clusterSelect");
+  const id = sel.value;
+  if (!id || clusterRoles[id] !== "admin") {
+    panel.style.display = "none";
+    return;
+  }
+  panel.style.display = "";
+  el("clusterRestricted").checked = !!sel.selectedOptions[0].dataset.restricted;
+
+  // получатели: участники и команды
+  const subj = el("grantSubject");
+  subj.innerHTML = "";
+  for (const m of lastMembers) {
+    const o = document.createElement("option");
+    o.value = "user:" + m.userId;
+    o.textContent = m.email;
+    subj.appendChild(o);
+  }
+  for (const t of lastTeams) {
+    const o = document.createElement("option");
+    o.value = "team:" + t.id;
+    o.textContent = "team " + t.name;
+    subj.appendChild(o);
+  }
+
+  const box = el("grantsList");
+  try {
+    const res = await api(`/api/app/clusters/${encodeURIComponent(id)}/grants`);
+    box.innerHTML = "";
+    if (!res.grants.length) {
+      box.textContent = "No grants";
+      return;
+    }
+    const emails = Object.fromEntries(lastMembers.map(m => [m.userId, m.email]));
+    const teams = Object.fromEntries(lastTeams.map(t => [t.id, t.name]));
+    for (const g of res.grants) {
+      const row = document.createElement("div");
+      row.className = "row";
+      const label = document.createElement("span");
+      const who = g.subjectType === "team" ? "team " + (teams[g.subjectId] || g.subjectId) : (emails[g.subjectId] || g.subjectId);
+      label.textContent = `${who} — ${g.role}`;
+      row.appendChild(label);
+      row.appendChild(actionBtn("Revoke", () => grantAction("DELETE",
+        `/api/app/clusters/${encodeURIComponent(id)}/grants/${g.subjectType}/${encodeURIComponent(g.subjectId)}`)));
+      box.appendChild(row);
+    }
+  } catch (e) {
+    box.textContent = e.message;
+  }
+}
+
+async function grantAction(method, url, body) {
+  try {
+    await api(url, {
+      method,
+      headers: { "Content-Type": "application/json" },
+      body: body ? JSON.stringify(body) : undefined,
+    });
+    msg("clusterStatus", "Saved ✓");
+    await loadGrants();
+  } catch (e) {
+    msg("clusterStatus", e.message);
+  }
+}
+
+async function addGrant() {
+  const id = el("clusterSelect").value;
+  const [subjectType, subjectId] = el("grantSubject").value.split(":");
+  await grantAction("PUT", `/api/app/clusters/${encodeURIComponent(id)}/grants`,
+    { subjectType, subjectId, role: el("grantRole").value });
+}
+
+async function setRestricted() {
+  const id = el("clusterSelect").value;
+  try {
+    await api(`/api/app/clusters/${encodeURIComponent(id)}`, {
+      method: "PATCH",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ restricted: el("clusterRestricted").checked }),
+    });
+    await loadClusters();
+    el("clusterSelect").value = id;
+    await loadGrants();
+  } catch (e) {
+    msg("clusterStatus", e.message);
+  }
+}
+
 async function createCluster() {
   try {
     await api("/api/app/clusters", {
@@ -155,6 +775,7 @@ async function createCluster() {
       body: JSON.stringify({
         name: el("clusterName").value,
         notes: el("clusterNotes").value,
+        restricted: el("newClusterRestricted").checked,
       }),
     });
     msg("clusterStatus", "Cluster added ✓");
@@ -262,6 +883,8 @@ async function uploadScan() {
   const fd = new FormData();
   fd.append("rbac", file);
   fd.append("clusterId", clusterId);
+  const auditFile = el("auditFile").files[0];
+  if (auditFile) fd.append("audit", auditFile);
 
   msg("scanStatus", "Uploading & analyzing…");
 
@@ -351,6 +974,39 @@ function downloadReport() {
   setTimeout(() => URL.revokeObjectURL(url), 500);
 }
 
+// экспорт сохранённого скана (html/csv/...) — сервер отдаёт файл, качаем как blob
+async function exportScan(ext, query = "", retried = false) {
+  const id = (el("scanId").value || "").trim();
+  if (!id) { alert("Select scan from history first"); return; }
+
+  try {
+    const r = await fetch(`/api/app/scans/${encodeURIComponent(id)}/export.${ext}${query ? "?" + query : ""}`, {
+      headers: {
+        "Authorization": "Bearer " + (localStorage.getItem("token") || ""),
+        "X-Org-ID": localStorage.getItem("orgId") || "",
+      },
+    });
+    if (r.status === 401) {
+      if (!retried && await refreshSession()) return exportScan(ext, query, true);
+      window.location.href = "/login";
+      return;
+    }
+    if (!r.ok) throw new Error(await r.text());
+
+    const url = URL.createObjectURL(await r.blob());
+    const a = document.createElement("a");
+    a.href = url;
+    a.download = `rbac-report-${id}.${ext}`;
+    document.body.appendChild(a);
+    a.click();
+    a.remove();
+
+    setTimeout(() => URL.revokeObjectURL(url), 500);
+  } catch (e) {
+    el("report").textContent = e.message;
+  }
+}
+
 // ---------- INIT ----------
 window.addEventListener("DOMContentLoaded", async () => {
   requireAuth();
@@ -364,7 +1020,16 @@ window.addEventListener("DOMContentLoaded", async () => {
   el("saveProfile").onclick = saveProfile;
 
   // logout
-  el("logoutBtn").onclick = logout;
+  el("logoutBtn").onclick = () => logout();
+  el("logoutAll").onclick = () => logout(true);
+  el("changePassword").onclick = changePassword;
+  el("resendVerify").onclick = resendVerification;
+  el("mfaSetupBtn").onclick = openProfileModal;
+  el("mfaEnroll").onclick = mfaEnroll;
+  el("mfaEnable").onclick = () => mfaAction("/api/app/mfa/enable", "POST");
+  el("mfaNewCodes").onclick = () => mfaAction("/api/app/mfa/recovery-codes", "POST");
+  el("mfaDisable").onclick = () => mfaAction("/api/app/mfa", "DELETE");
+  el("saveOrgMfa").onclick = saveOrgMFA;
 
   // clusters
   el("createCluster").onclick = createCluster;
@@ -379,11 +1044,28 @@ window.addEventListener("DOMContentLoaded", async () => {
   // report tools
   el("toggleReport").onclick = toggleReport;
   el("downloadReport").onclick = downloadReport;
+  el("exportHtml").onclick = () => exportScan("html");
+  el("exportCsv").onclick = () => exportScan("csv", "bom=1");
+
+  // team
+  el("orgSelect").addEventListener("change", switchOrg);
+  el("createOrg").onclick = createOrg;
+  el("inviteMember").onclick = inviteMember;
+  el("createTeam").onclick = createTeam;
+  el("saveSso").onclick = saveSSO;
+  el("deleteSso").onclick = deleteSSO;
+
+  // cluster access
+  el("addGrant").onclick = addGrant;
+  el("clusterRestricted").addEventListener("change", setRestricted);
 
   // misc
   el("clearSummary").onclick = () => { el("summary").textContent = "No data"; };
   el("clusterSelect").addEventListener("change", loadScanHistory);
+  el("clusterSelect").addEventListener("change", loadGrants);
 
+  await acceptPendingInvite();
   await loadMe();
+  await loadTeam();
   await loadClusters();
 });
@@ -6,7 +6,9 @@ async function api(url, opts = {}) {
   const r = await fetch(url, opts);
   if (!r.ok) {
     const t = await r.text();
-    throw new Error(t || ("HTTP " + r.status));
+    let msg = t;
+    try { msg = JSON.parse(t).error || t; } catch {}
+    throw new Error(msg || ("HTTP " + r.status));
   }
   return r.status === 204 ? null : r.json();
 }
@@ -23,6 +25,11 @@ function redirectIfAuthed() {
   if (token) window.location.href = "/app";
 }
 
+// приглашение в организацию (ссылка /app?invite=…) принимается в /app после входа
+function pendingInvite() {
+  return localStorage.getItem("pendingInvite") || "";
+}
+
 // ---------- LOGIN ----------
 async function doLogin() {
   setMsg("status", "Signing in…");
@@ -35,13 +42,183 @@ async function doLogin() {
         password: el("password").value,
       }),
     });
+    if (res.mfaRequired) {
+      showMFAStep(res.mfaToken);
+      return;
+    }
+    localStorage.setItem("token", res.token);
+    localStorage.setItem("refreshToken", res.refreshToken);
+    window.location.href = "/app";
+  } catch (e) {
+    setMsg("status", e.message, true);
+  }
+}
+
+// ---------- 2FA ----------
+// пароль (или SSO) принят, сервер ждёт код: mfaToken живёт 5 минут
+function showMFAStep(mfaToken) {
+  el("mfaBox").style.display = "";
+  el("mfaCode").focus();
+  setMsg("status", "Enter the code from your authenticator app.");
+  el("mfaBtn").onclick = () => doMFA(mfaToken);
+}
+
+async function doMFA(mfaToken) {
+  try {
+    const res = await api("/api/auth/mfa", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ mfaToken, code: el("mfaCode").value.trim() }),
+    });
     localStorage.setItem("token", res.token);
+    localStorage.setItem("refreshToken", res.refreshToken);
+    if (res.recoveryCodesLeft !== undefined) {
+      alert(`Recovery code used. ${res.recoveryCodesLeft} left — generate new ones in Profile if you lost your device.`);
+    }
     window.location.href = "/app";
   } catch (e) {
     setMsg("status", e.message, true);
   }
 }
 
+// ---------- EMAIL LINKS ----------
+async function doForgot() {
+  const email = el("email").value.trim();
+  if (!email) {
+    setMsg("status", "Enter your email to reset the password.", true);
+    return;
+  }
+  try {
+    await api("/api/auth/password/forgot", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ email }),
+    });
+    setMsg("status", "If this account exists, we have sent a reset link to " + email + ".");
+  } catch (e) {
+    setMsg("status", e.message, true);
+  }
+}
+
+async function doReset(token) {
+  try {
+    await api("/api/auth/password/reset", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ token, password: el("newPassword").value }),
+    });
+    // сервер завершил все сессии — входим заново с новым паролем
+    localStorage.removeItem("token");
+    localStorage.removeItem("refreshToken");
+    el("resetBox").style.display = "none";
+    setMsg("status", "Password changed ✓ Sign in with the new password.");
+  } catch (e) {
+    setMsg("status", e.message, true);
+  }
+}
+
+// ссылки из писем: /login?verify=… подтверждает email, /login?reset=… задаёт новый пароль.
+// Возвращает true, если страница занята этой ссылкой и не нужно уходить в /app.
+function handleEmailLink() {
+  const q = new URLSearchParams(window.location.search);
+  const verify = q.get("verify"), reset = q.get("reset");
+  if (!verify && !reset) return false;
+  history.replaceState(null, "", "/login");
+
+  if (reset) {
+    el("resetBox").style.display = "";
+    setMsg("status", "Choose a new password.");
+    el("resetBtn").addEventListener("click", () => doReset(reset));
+    return true;
+  }
+  setMsg("status", "Confirming email…");
+  api("/api/auth/verify-email", {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({ token: verify }),
+  }).then(() => {
+    setMsg("status", "Email confirmed ✓");
+    setTimeout(redirectIfAuthed, 1500);
+  }).catch(e => setMsg("status", "Email confirmation: " + e.message, true));
+  return true;
+}
+
+// ---------- SSO ----------
+// вход через IdP организации: сервер находит организацию по домену email
+function doSSO() {
+  const email = el("email").value.trim();
+  if (!email) {
+    setMsg("status", "Enter your work email to sign in with SSO.", true);
+    return;
+  }
+  window.location.href = "/api/auth/oidc/start?email=" + encodeURIComponent(email);
+}
+
+// привязка IdP к существующему аккаунту: подтверждение паролем или из текущей сессии
+function showLinkStep(email, orgId) {
+  el("linkBox").style.display = "";
+  el("email").value = email;
+  setMsg("status", `An account for ${email} already exists. Confirm it is yours to link SSO sign-in.`);
+  el("linkBtn").onclick = async () => {
+    try {
+      const res = await api("/api/auth/oidc/link", {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ password: el("linkPassword").value }),
+      });
+      if (orgId) localStorage.setItem("orgId", orgId);
+      if (res.mfaRequired) {
+        el("linkBox").style.display = "none";
+        showMFAStep(res.mfaToken);
+        return;
+      }
+      localStorage.setItem("token", res.token);
+      localStorage.setItem("refreshToken", res.refreshToken);
+      window.location.href = "/app";
+    } catch (e) {
+      setMsg("status", e.message, true);
+    }
+  };
+  if (localStorage.getItem("token")) {
+    el("linkSessionBtn").style.display = "";
+    el("linkSessionBtn").onclick = async () => {
+      try {
+        await api("/api/app/sso/link", { method: "POST" });
+        if (orgId) localStorage.setItem("orgId", orgId);
+        window.location.href = "/app";
+      } catch (e) {
+        setMsg("status", e.message, true);
+      }
+    };
+  }
+}
+
+// результат SSO приходит во фрагменте /login#token=…&refreshToken=…&orgId=…,
+// #mfaToken=…&orgId=… (нужен код 2FA), #ssoLink=1&email=… (подтвердить привязку) или #ssoError=…
+function handleSSOResult() {
+  const h = new URLSearchParams(window.location.hash.slice(1));
+  history.replaceState(null, "", "/login");
+  if (h.get("ssoError")) {
+    setMsg("status", "SSO: " + h.get("ssoError"), true);
+    return false;
+  }
+  if (h.get("ssoLink")) {
+    showLinkStep(h.get("email") || "", h.get("orgId"));
+    return true;
+  }
+  if (h.get("mfaToken")) {
+    if (h.get("orgId")) localStorage.setItem("orgId", h.get("orgId"));
+    showMFAStep(h.get("mfaToken"));
+    return true;
+  }
+  if (!h.get("token")) return false;
+  localStorage.setItem("token", h.get("token"));
+  localStorage.setItem("refreshToken", h.get("refreshToken") || "");
+  if (h.get("orgId")) localStorage.setItem("orgId", h.get("orgId"));
+  window.location.href = "/app";
+  return true;
+}
+
 // ---------- REGISTER ----------
 async function doRegister() {
   setMsg("status", "Creating account…");
@@ -53,29 +230,52 @@ async function doRegister() {
         email: el("email").value.trim(),
         password: el("password").value,
         orgName: (el("orgName") ? el("orgName").value.trim() : ""),
+        inviteToken: pendingInvite(),
       }),
     });
     localStorage.setItem("token", res.token);
+    localStorage.setItem("refreshToken", res.refreshToken);
+    // при регистрации по приглашению пользователь уже в организации
+    localStorage.removeItem("pendingInvite");
     window.location.href = "/app";
   } catch (e) {
     setMsg("status", e.message, true);
   }
 }
 
+async function loadInviteInfo() {
+  try {
+    const inv = await api("/api/invitations/info?token=" + encodeURIComponent(pendingInvite()));
+    el("email").value = inv.email;
+    if (el("orgName")) el("orgName").closest(".row").style.display = "none";
+    setMsg("status", `You are invited to "${inv.orgName}" as ${inv.role}.`);
+  } catch (e) {
+    localStorage.removeItem("pendingInvite");
+    setMsg("status", "Invitation: " + e.message, true);
+  }
+}
+
 window.addEventListener("DOMContentLoaded", () => {
-  // If already authed -> go app
-  redirectIfAuthed();
+  // возврат с IdP: новый токен важнее сохранённого
+  if (document.getElementById("loginBtn") && window.location.hash && handleSSOResult()) return;
 
   // login page?
   const loginBtn = document.getElementById("loginBtn");
   if (loginBtn) {
     loginBtn.addEventListener("click", doLogin);
+    el("ssoBtn").addEventListener("click", doSSO);
+    el("forgotBtn").addEventListener("click", doForgot);
+    if (!handleEmailLink()) redirectIfAuthed();
     return;
   }
 
+  // If already authed -> go app
+  redirectIfAuthed();
+
   // register page?
   const regBtn = document.getElementById("registerBtn");
   if (regBtn) {
     regBtn.addEventListener("click", doRegister);
+    if (pendingInvite()) loadInviteInfo();
   }
 });
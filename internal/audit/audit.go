@@ -0,0 +1,199 @@
+// Package audit читает выгруженные audit-логи Kubernetes API (JSON lines, audit.k8s.io/v1)
+// и считает, какие права субъекты реально использовали. Работает полностью офлайн.
+package audit
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"rbac-analyzer/internal/rbac"
+)
+
+// Event — нужная часть audit.k8s.io/v1 Event.
+type Event struct {
+	Stage string `json:"stage"`
+	Verb  string `json:"verb"`
+	User  struct {
+		Username string   `json:"username"`
+		Groups   []string `json:"groups"`
+	} `json:"user"`
+	ImpersonatedUser *struct {
+		Username string   `json:"username"`
+		Groups   []string `json:"groups"`
+	} `json:"impersonatedUser"`
+	ObjectRef *struct {
+		Resource    string `json:"resource"`
+		Subresource string `json:"subresource"`
+		Namespace   string `json:"namespace"`
+		Name        string `json:"name"`
+		APIGroup    string `json:"apiGroup"`
+	} `json:"objectRef"`
+	ResponseStatus *struct {
+		Code int `json:"code"`
+	} `json:"responseStatus"`
+	RequestReceivedTimestamp time.Time `json:"requestReceivedTimestamp"`
+}
+
+// Window — интервал времени; нулевые границы не ограничивают.
+type Window struct {
+	Since time.Time
+	Until time.Time
+}
+
+func (w Window) contains(t time.Time) bool {
+	return (w.Since.IsZero() || !t.Before(w.Since)) && (w.Until.IsZero() || t.Before(w.Until))
+}
+
+// ReadFiles читает audit-логи (".gz" распаковывается) и собирает Usage за окно.
+func ReadFiles(paths []string, win Window) (*Usage, error) {
+	u := NewUsage()
+	for _, p := range paths {
+		if err := readFile(p, win, u); err != nil {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+	}
+	return u, nil
+}
+
+func readFile(path string, win Window, u *Usage) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	return Read(r, win, u)
+}
+
+// Read разбирает поток JSON lines. Учитываются только завершённые (ResponseComplete,
+// а для watch — ResponseStarted) и не отклонённые (401/403) запросы к ресурсам API.
+func Read(r io.Reader, win Window, u *Usage) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64<<10), 16<<20)
+
+	line := 0
+	for sc.Scan() {
+		line++
+		b := sc.Bytes()
+		if len(strings.TrimSpace(string(b))) == 0 {
+			continue
+		}
+
+		var ev Event
+		if err := json.Unmarshal(b, &ev); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if !countable(ev) || !win.contains(ev.RequestReceivedTimestamp) {
+			continue
+		}
+
+		username, groups := ev.User.Username, ev.User.Groups
+		if ev.ImpersonatedUser != nil && ev.ImpersonatedUser.Username != "" {
+			username, groups = ev.ImpersonatedUser.Username, ev.ImpersonatedUser.Groups
+		}
+
+		resource := ev.ObjectRef.Resource
+		if ev.ObjectRef.Subresource != "" {
+			resource += "/" + ev.ObjectRef.Subresource
+		}
+		a := Access{
+			Namespace: ev.ObjectRef.Namespace,
+			APIGroup:  ev.ObjectRef.APIGroup,
+			Resource:  resource,
+			Verb:      ev.Verb,
+			Name:      ev.ObjectRef.Name,
+		}
+		u.Add(SubjectFromUsername(username), a, ev.RequestReceivedTimestamp)
+		// обращение засчитывается и группам вызывающего: так видно, используются ли
+		// групповые биндинги (права могли прийти и от личного биндинга — это оценка сверху)
+		for _, g := range groups {
+			u.Add(rbac.SubjectRef{Kind: rbac.SubjectKindGroup, Name: g}, a, ev.RequestReceivedTimestamp)
+		}
+	}
+	return sc.Err()
+}
+
+func countable(ev Event) bool {
+	if ev.ObjectRef == nil || ev.ObjectRef.Resource == "" || ev.User.Username == "" {
+		return false
+	}
+	// Каждое обращение учитывается один раз: watch — на ResponseStarted (долгий watch может
+	// не завершиться в окне лога), остальные — на ResponseComplete. Watch, отклонённый
+	// до начала ответа, ResponseStarted не пишет — его учитываем по ResponseComplete.
+	switch ev.Stage {
+	case "ResponseComplete":
+		if ev.Verb == "watch" && (ev.ResponseStatus == nil || ev.ResponseStatus.Code < 300) {
+			return false
+		}
+	case "ResponseStarted":
+		if ev.Verb != "watch" {
+			return false
+		}
+	default:
+		return false
+	}
+	if ev.ResponseStatus != nil && (ev.ResponseStatus.Code == 401 || ev.ResponseStatus.Code == 403) {
+		return false
+	}
+	return true
+}
+
+// SubjectFromUsername: "system:serviceaccount:ns:name" -> ServiceAccount, остальное -> User.
+func SubjectFromUsername(username string) rbac.SubjectRef {
+	if rest, ok := strings.CutPrefix(username, "system:serviceaccount:"); ok {
+		if ns, name, ok := strings.Cut(rest, ":"); ok {
+			return rbac.SubjectRef{Kind: rbac.SubjectKindServiceAccount, Name: name, Namespace: ns}
+		}
+	}
+	return rbac.SubjectRef{Kind: rbac.SubjectKindUser, Name: username}
+}
+
+// ParseTime принимает RFC3339 или длительность назад от now ("720h").
+func ParseTime(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("bad time %q: want RFC3339 or duration (720h)", s)
+	}
+	return now.Add(-d), nil
+}
+
+// ReadStream — то же для загруженного файла (сервер): поток читается построчно,
+// gzip определяется по сигнатуре.
+func ReadStream(in io.Reader, win Window) (*Usage, error) {
+	br := bufio.NewReader(in)
+	var r io.Reader = br
+	if sig, _ := br.Peek(2); len(sig) == 2 && sig[0] == 0x1f && sig[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	u := NewUsage()
+	if err := Read(r, win, u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
@@ -0,0 +1,66 @@
+package audit
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"rbac-analyzer/internal/rbac"
+)
+
+func TestReadCountsWatchOnce(t *testing.T) {
+	const log = `{"stage":"ResponseStarted","verb":"watch","user":{"username":"system:serviceaccount:prod:app"},"objectRef":{"resource":"pods","namespace":"prod"},"requestReceivedTimestamp":"2024-05-01T10:00:00Z"}
+{"stage":"ResponseComplete","verb":"watch","user":{"username":"system:serviceaccount:prod:app"},"objectRef":{"resource":"pods","namespace":"prod"},"responseStatus":{"code":200},"requestReceivedTimestamp":"2024-05-01T10:00:00Z"}
+{"stage":"ResponseComplete","verb":"watch","user":{"username":"system:serviceaccount:prod:app"},"objectRef":{"resource":"secrets","namespace":"prod"},"responseStatus":{"code":404},"requestReceivedTimestamp":"2024-05-01T10:00:01Z"}
+{"stage":"ResponseStarted","verb":"get","user":{"username":"system:serviceaccount:prod:app"},"objectRef":{"resource":"pods","namespace":"prod"},"requestReceivedTimestamp":"2024-05-01T10:00:02Z"}
+{"stage":"ResponseComplete","verb":"get","user":{"username":"system:serviceaccount:prod:app"},"objectRef":{"resource":"pods","namespace":"prod"},"responseStatus":{"code":200},"requestReceivedTimestamp":"2024-05-01T10:00:02Z"}
+`
+	u := NewUsage()
+	if err := Read(strings.NewReader(log), Window{}, u); err != nil {
+		t.Fatal(err)
+	}
+	app := rbac.SubjectRef{Kind: rbac.SubjectKindServiceAccount, Name: "app", Namespace: "prod"}
+	counts := map[string]int{}
+	for _, a := range u.Accesses(app) {
+		counts[a.Verb+" "+a.Resource] = a.Count
+	}
+	want := map[string]int{"watch pods": 1, "watch secrets": 1, "get pods": 1}
+	if !reflect.DeepEqual(counts, want) {
+		t.Fatalf("counts %v, want %v", counts, want)
+	}
+}
+
+func TestRecommendKeepsResourceNames(t *testing.T) {
+	app := rbac.SubjectRef{Kind: rbac.SubjectKindServiceAccount, Name: "default", Namespace: "prod"}
+	roles := []rbac.EffectiveRole{{
+		Permissions: []rbac.Permission{
+			{Namespace: "prod", Verb: "get", Resource: "secrets", ResourceNames: []string{"tls", "db"}},
+			{Namespace: "prod", Verb: "get", Resource: "configmaps"},
+		},
+	}}
+	rec := recommendSubject(app, roles, []AccessStat{
+		{Access: Access{Namespace: "prod", Resource: "secrets", Verb: "get", Name: "tls"}, Count: 1},
+		{Access: Access{Namespace: "prod", Resource: "configmaps", Verb: "get", Name: "settings"}, Count: 1},
+	})
+	if len(rec.Roles) != 1 {
+		t.Fatalf("roles: %+v", rec.Roles)
+	}
+	want := []rbac.PolicyRule{
+		{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"get"}},
+		{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"get"}, ResourceNames: []string{"tls"}},
+	}
+	if got := rec.Roles[0].Rules; !reflect.DeepEqual(got, want) {
+		t.Fatalf("rules:\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestRoleNameIncludesNamespace(t *testing.T) {
+	a := roleName(rbac.SubjectRef{Kind: rbac.SubjectKindServiceAccount, Name: "default", Namespace: "team-a"})
+	b := roleName(rbac.SubjectRef{Kind: rbac.SubjectKindServiceAccount, Name: "default", Namespace: "team-b"})
+	if a == b {
+		t.Fatalf("default service accounts share role name %q", a)
+	}
+	if got := roleName(rbac.SubjectRef{Kind: rbac.SubjectKindUser, Name: "Alice@Example.com"}); got != "alice-example.com-least-privilege" {
+		t.Fatalf("user role name %q", got)
+	}
+}
@@ -0,0 +1,269 @@
+package audit
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"rbac-analyzer/internal/rbac"
+)
+
+const rbacAPIVersion = "rbac.authorization.k8s.io/v1"
+
+// UnusedPermission — право из эффективных ролей, которое не понадобилось ни одному обращению.
+type UnusedPermission struct {
+	Role       string `json:"role"`
+	Binding    string `json:"binding"`
+	Permission string `json:"permission"`
+}
+
+// Recommendation — минимальные роли для субъекта по фактическому использованию.
+type Recommendation struct {
+	Subject string `json:"subject"`
+
+	Roles               []rbac.Role               `json:"roles"`
+	RoleBindings        []rbac.RoleBinding        `json:"roleBindings"`
+	ClusterRoles        []rbac.ClusterRole        `json:"clusterRoles"`
+	ClusterRoleBindings []rbac.ClusterRoleBinding `json:"clusterRoleBindings"`
+
+	Used   int                `json:"used"`   // различных обращений, разрешённых текущими правами
+	Unused []UnusedPermission `json:"unused"` // права, которые можно убрать
+
+	// Uncovered — обращения, которых нет в текущих правах (права через группы,
+	// изменённый RBAC или отказ, не попавший в лог как 403).
+	Uncovered []AccessStat `json:"uncovered,omitempty"`
+}
+
+// Recommend сравнивает эффективные права пользователей и ServiceAccount'ов с Usage.
+// Группы пропускаются: обращение нельзя однозначно отнести к группе.
+// Порядок результата стабилен (по субъекту).
+func Recommend(sp rbac.SubjectPermissions, u *Usage) []Recommendation {
+	refs := make([]rbac.SubjectRef, 0, len(sp))
+	for s := range sp {
+		if s.Kind != rbac.SubjectKindGroup {
+			refs = append(refs, s)
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].String() < refs[j].String() })
+
+	out := make([]Recommendation, 0, len(refs))
+	for _, s := range refs {
+		out = append(out, recommendSubject(s, sp[s], u.Accesses(s)))
+	}
+	return out
+}
+
+func recommendSubject(s rbac.SubjectRef, roles []rbac.EffectiveRole, accesses []AccessStat) Recommendation {
+	rec := Recommendation{
+		Subject:             s.String(),
+		Roles:               []rbac.Role{},
+		RoleBindings:        []rbac.RoleBinding{},
+		ClusterRoles:        []rbac.ClusterRole{},
+		ClusterRoleBindings: []rbac.ClusterRoleBinding{},
+		Unused:              []UnusedPermission{},
+	}
+
+	// namespace ("" — кластерные ресурсы) -> apiGroup -> resource -> verb -> resourceNames;
+	// пустое имя — глагол нужен без ограничения resourceNames
+	needed := map[string]map[string]map[string]map[string]map[string]bool{}
+	for _, a := range accesses {
+		covered, byName := coverage(roles, a.Access)
+		if !covered {
+			rec.Uncovered = append(rec.Uncovered, a)
+			continue
+		}
+		rec.Used++
+		groups := needed[a.Namespace]
+		if groups == nil {
+			groups = map[string]map[string]map[string]map[string]bool{}
+			needed[a.Namespace] = groups
+		}
+		res := groups[a.APIGroup]
+		if res == nil {
+			res = map[string]map[string]map[string]bool{}
+			groups[a.APIGroup] = res
+		}
+		if res[a.Resource] == nil {
+			res[a.Resource] = map[string]map[string]bool{}
+		}
+		verb := strings.ToLower(a.Verb)
+		if res[a.Resource][verb] == nil {
+			res[a.Resource][verb] = map[string]bool{}
+		}
+		// доступ был разрешён только правами с resourceNames — рекомендация их сохраняет
+		name := ""
+		if byName {
+			name = a.Name
+		}
+		res[a.Resource][verb][name] = true
+	}
+
+	for _, r := range roles {
+		for _, p := range r.Permissions {
+			used := false
+			for _, a := range accesses {
+				if Covers(p, a.Access) {
+					used = true
+					break
+				}
+			}
+			if !used {
+				rec.Unused = append(rec.Unused, UnusedPermission{
+					Role:       r.RoleLabel(),
+					Binding:    r.BindingLabel(),
+					Permission: rbac.CanonicalPermissionKey(p.Namespace, p.Verb, p.APIGroup, p.Resource, p.ResourceNames),
+				})
+			}
+		}
+	}
+
+	name := roleName(s)
+	subject := rbac.Subject{Kind: string(s.Kind), Name: s.Name, Namespace: s.Namespace}
+	roleRef := func(kind string) rbac.RoleRef {
+		return rbac.RoleRef{APIGroup: "rbac.authorization.k8s.io", Kind: kind, Name: name}
+	}
+
+	for _, ns := range sortedKeys(needed) {
+		rules := buildRules(needed[ns])
+		if ns == "" {
+			rec.ClusterRoles = append(rec.ClusterRoles, rbac.ClusterRole{
+				APIVersion: rbacAPIVersion, Kind: "ClusterRole",
+				Metadata: rbac.ObjectMeta{Name: name}, Rules: rules,
+			})
+			rec.ClusterRoleBindings = append(rec.ClusterRoleBindings, rbac.ClusterRoleBinding{
+				APIVersion: rbacAPIVersion, Kind: "ClusterRoleBinding",
+				Metadata: rbac.ObjectMeta{Name: name},
+				Subjects: []rbac.Subject{subject}, RoleRef: roleRef("ClusterRole"),
+			})
+			continue
+		}
+		rec.Roles = append(rec.Roles, rbac.Role{
+			APIVersion: rbacAPIVersion, Kind: "Role",
+			Metadata: rbac.ObjectMeta{Name: name, Namespace: ns}, Rules: rules,
+		})
+		rec.RoleBindings = append(rec.RoleBindings, rbac.RoleBinding{
+			APIVersion: rbacAPIVersion, Kind: "RoleBinding",
+			Metadata: rbac.ObjectMeta{Name: name, Namespace: ns},
+			Subjects: []rbac.Subject{subject}, RoleRef: roleRef("Role"),
+		})
+	}
+	return rec
+}
+
+// coverage: разрешено ли обращение текущими правами и только ли правами с resourceNames.
+func coverage(roles []rbac.EffectiveRole, a Access) (covered, byName bool) {
+	for _, r := range roles {
+		for _, p := range r.Permissions {
+			if !Covers(p, a) {
+				continue
+			}
+			if len(p.ResourceNames) == 0 {
+				return true, false
+			}
+			covered = true
+		}
+	}
+	return covered, covered
+}
+
+// buildRules: ресурсы одной apiGroup с одинаковыми глаголами и resourceNames
+// объединяются в одно правило.
+func buildRules(groups map[string]map[string]map[string]map[string]bool) []rbac.PolicyRule {
+	var rules []rbac.PolicyRule
+	for _, g := range sortedKeys(groups) {
+		// "verbs|names" -> ресурсы
+		byKey := map[string][]string{}
+		for _, res := range sortedKeys(groups[g]) {
+			byNames := map[string][]string{}
+			for _, verb := range sortedKeys(groups[g][res]) {
+				names := ""
+				if ns := groups[g][res][verb]; !ns[""] {
+					names = strings.Join(sortedKeys(ns), ",")
+				}
+				byNames[names] = append(byNames[names], verb)
+			}
+			for names, verbs := range byNames {
+				key := strings.Join(verbs, ",") + "|" + names
+				byKey[key] = append(byKey[key], res)
+			}
+		}
+		for _, key := range sortedKeys(byKey) {
+			verbs, names, _ := strings.Cut(key, "|")
+			rule := rbac.PolicyRule{
+				APIGroups: []string{g},
+				Resources: byKey[key],
+				Verbs:     strings.Split(verbs, ","),
+			}
+			if names != "" {
+				rule.ResourceNames = strings.Split(names, ",")
+			}
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+var invalidName = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+// roleName — DNS-совместимое имя роли для субъекта. У ServiceAccount в имя входит
+// namespace ("ns.name"): иначе default из разных namespace получили бы одну ClusterRole.
+// В namespace точек не бывает, поэтому разделитель однозначен.
+func roleName(s rbac.SubjectRef) string {
+	n := s.Name
+	if s.Namespace != "" {
+		n = s.Namespace + "." + s.Name
+	}
+	n = invalidName.ReplaceAllString(strings.ToLower(n), "-")
+	n = strings.Trim(n, "-.")
+	if n == "" {
+		n = "subject"
+	}
+	return n + "-least-privilege"
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// YAML — рекомендация в виде multi-document YAML; неиспользуемые права — комментарием в начале.
+func (rec Recommendation) YAML() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("# " + rec.Subject + "\n")
+	if len(rec.Unused) > 0 {
+		buf.WriteString("# unused permissions (can be removed):\n")
+		for _, p := range rec.Unused {
+			buf.WriteString("#   " + p.Role + " via " + p.Binding + ": " + p.Permission + "\n")
+		}
+	}
+	if rec.Used == 0 {
+		buf.WriteString("# no recorded usage in the audit window: consider removing all bindings\n")
+	}
+
+	var docs []any
+	for i := range rec.ClusterRoles {
+		docs = append(docs, rec.ClusterRoles[i], rec.ClusterRoleBindings[i])
+	}
+	for i := range rec.Roles {
+		docs = append(docs, rec.Roles[i], rec.RoleBindings[i])
+	}
+	for _, d := range docs {
+		buf.WriteString("---\n")
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(d); err != nil {
+			return nil, err
+		}
+		if err := enc.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
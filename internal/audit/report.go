@@ -0,0 +1,189 @@
+package audit
+
+import (
+	"sort"
+	"time"
+
+	"rbac-analyzer/internal/rbac"
+)
+
+// Статусы использования роли/биндинга за окно audit-лога.
+const (
+	StatusUsed    = "used"    // использованы все права
+	StatusPartial = "partial" // часть прав
+	StatusUnused  = "unused"  // ни одного обращения
+)
+
+// DefaultStaleAfter — биндинг без обращений дольше этого срока — кандидат на удаление
+// (квартальный пересмотр доступа).
+const DefaultStaleAfter = 90 * 24 * time.Hour
+
+// PermissionUsage — использование одного права.
+type PermissionUsage struct {
+	Permission string     `json:"permission"`
+	Count      int        `json:"count"`
+	LastUsed   *time.Time `json:"lastUsed,omitempty"`
+}
+
+// RoleUsage — использование EffectiveRole конкретным субъектом.
+type RoleUsage struct {
+	Subject     string            `json:"subject"`
+	Role        string            `json:"role"`
+	Binding     string            `json:"binding"`
+	Status      string            `json:"status"`
+	LastUsed    *time.Time        `json:"lastUsed,omitempty"`
+	Permissions []PermissionUsage `json:"permissions"`
+}
+
+// BindingUsage — использование биндинга всеми его субъектами.
+type BindingUsage struct {
+	Binding          string     `json:"binding"`
+	Role             string     `json:"role"`
+	Subjects         []string   `json:"subjects"`
+	Status           string     `json:"status"`
+	LastUsed         *time.Time `json:"lastUsed,omitempty"`
+	RemovalCandidate bool       `json:"removalCandidate"` // не использовался дольше StaleAfterDays
+}
+
+// UsageReport — отчёт по использованию прав за окно audit-лога.
+type UsageReport struct {
+	Since          *time.Time     `json:"since,omitempty"`
+	Until          *time.Time     `json:"until,omitempty"`
+	StaleAfterDays int            `json:"staleAfterDays"`
+	Counts         map[string]int `json:"counts"` // used / partial / unused / removalCandidates (по биндингам)
+	Bindings       []BindingUsage `json:"bindings"`
+	Roles          []RoleUsage    `json:"roles"`
+}
+
+// BuildUsageReport размечает каждую роль и биндинг как used/partial/unused.
+// Кандидат на удаление — биндинг без обращений после now-staleAfter; окно лога
+// должно покрывать этот срок, иначе вывод «не использовался» слишком оптимистичен.
+func BuildUsageReport(sp rbac.SubjectPermissions, u *Usage, win Window, now time.Time, staleAfter time.Duration) UsageReport {
+	if staleAfter <= 0 {
+		staleAfter = DefaultStaleAfter
+	}
+	rep := UsageReport{
+		Since:          timePtr(win.Since),
+		Until:          timePtr(win.Until),
+		StaleAfterDays: int(staleAfter / (24 * time.Hour)),
+		Counts:         map[string]int{StatusUsed: 0, StatusPartial: 0, StatusUnused: 0, "removalCandidates": 0},
+		Bindings:       []BindingUsage{},
+		Roles:          []RoleUsage{},
+	}
+
+	refs := make([]rbac.SubjectRef, 0, len(sp))
+	for s := range sp {
+		refs = append(refs, s)
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].String() < refs[j].String() })
+
+	// право биндинга использовано, если его использовал хотя бы один субъект биндинга
+	type bindingAgg struct {
+		BindingUsage
+		perms map[string]bool // permission -> used
+	}
+	bindings := map[string]*bindingAgg{}
+	var order []string
+
+	for _, s := range refs {
+		accesses := u.Accesses(s)
+		for _, r := range sp[s] {
+			ru := roleUsage(s, r, accesses)
+			rep.Roles = append(rep.Roles, ru)
+
+			key := r.BindingLabel() + "\x00" + r.RoleLabel()
+			b := bindings[key]
+			if b == nil {
+				b = &bindingAgg{
+					BindingUsage: BindingUsage{Binding: r.BindingLabel(), Role: r.RoleLabel()},
+					perms:        map[string]bool{},
+				}
+				bindings[key] = b
+				order = append(order, key)
+			}
+			b.Subjects = append(b.Subjects, s.String())
+			for _, p := range ru.Permissions {
+				b.perms[p.Permission] = b.perms[p.Permission] || p.Count > 0
+			}
+			b.LastUsed = latest(b.LastUsed, ru.LastUsed)
+		}
+	}
+
+	sort.Strings(order)
+	for _, key := range order {
+		b := bindings[key]
+		used := 0
+		for _, ok := range b.perms {
+			if ok {
+				used++
+			}
+		}
+		b.Status = status(used, len(b.perms))
+		b.RemovalCandidate = b.LastUsed == nil || b.LastUsed.Before(now.Add(-staleAfter))
+		rep.Counts[b.Status]++
+		if b.RemovalCandidate {
+			rep.Counts["removalCandidates"]++
+		}
+		rep.Bindings = append(rep.Bindings, b.BindingUsage)
+	}
+	return rep
+}
+
+func roleUsage(s rbac.SubjectRef, r rbac.EffectiveRole, accesses []AccessStat) RoleUsage {
+	ru := RoleUsage{
+		Subject:     s.String(),
+		Role:        r.RoleLabel(),
+		Binding:     r.BindingLabel(),
+		Permissions: make([]PermissionUsage, 0, len(r.Permissions)),
+	}
+	used := 0
+	for _, p := range r.Permissions {
+		pu := PermissionUsage{
+			Permission: rbac.CanonicalPermissionKey(p.Namespace, p.Verb, p.APIGroup, p.Resource, p.ResourceNames),
+		}
+		for _, a := range accesses {
+			if !Covers(p, a.Access) {
+				continue
+			}
+			pu.Count += a.Count
+			last := a.LastUsed
+			pu.LastUsed = latest(pu.LastUsed, &last)
+		}
+		if pu.Count > 0 {
+			used++
+		}
+		ru.LastUsed = latest(ru.LastUsed, pu.LastUsed)
+		ru.Permissions = append(ru.Permissions, pu)
+	}
+	ru.Status = status(used, len(r.Permissions))
+	return ru
+}
+
+func status(used, total int) string {
+	switch {
+	case used == 0:
+		return StatusUnused
+	case used == total:
+		return StatusUsed
+	default:
+		return StatusPartial
+	}
+}
+
+func latest(a, b *time.Time) *time.Time {
+	if b == nil {
+		return a
+	}
+	if a == nil || b.After(*a) {
+		t := *b
+		return &t
+	}
+	return a
+}
+
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,105 @@
+package audit
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"rbac-analyzer/internal/rbac"
+)
+
+// Access — одно обращение к API (без учёта времени).
+type Access struct {
+	Namespace string `json:"namespace,omitempty"`
+	APIGroup  string `json:"apiGroup"`
+	Resource  string `json:"resource"` // с подресурсом: pods/exec
+	Verb      string `json:"verb"`
+	Name      string `json:"name,omitempty"`
+}
+
+// AccessStat — сколько раз и когда последний раз было обращение.
+type AccessStat struct {
+	Access
+	Count    int       `json:"count"`
+	LastUsed time.Time `json:"lastUsed"`
+}
+
+// Usage — фактическое использование API по субъектам.
+type Usage struct {
+	Subjects map[rbac.SubjectRef]map[Access]*AccessStat
+}
+
+// NewUsage — пустой Usage.
+func NewUsage() *Usage {
+	return &Usage{Subjects: map[rbac.SubjectRef]map[Access]*AccessStat{}}
+}
+
+// Add учитывает одно обращение.
+func (u *Usage) Add(s rbac.SubjectRef, a Access, at time.Time) {
+	m := u.Subjects[s]
+	if m == nil {
+		m = map[Access]*AccessStat{}
+		u.Subjects[s] = m
+	}
+	st := m[a]
+	if st == nil {
+		st = &AccessStat{Access: a}
+		m[a] = st
+	}
+	st.Count++
+	if at.After(st.LastUsed) {
+		st.LastUsed = at
+	}
+}
+
+// Accesses — обращения субъекта в стабильном порядке.
+func (u *Usage) Accesses(s rbac.SubjectRef) []AccessStat {
+	out := make([]AccessStat, 0, len(u.Subjects[s]))
+	for _, st := range u.Subjects[s] {
+		out = append(out, *st)
+	}
+	sort.Slice(out, func(i, j int) bool { return accessKey(out[i].Access) < accessKey(out[j].Access) })
+	return out
+}
+
+func accessKey(a Access) string {
+	return strings.Join([]string{a.Namespace, a.APIGroup, a.Resource, a.Verb, a.Name}, "\x00")
+}
+
+// Covers — разрешает ли право обращение (с учётом "*", подресурсов и resourceNames).
+func Covers(p rbac.Permission, a Access) bool {
+	if !p.ClusterScope && p.Namespace != a.Namespace {
+		return false
+	}
+	if p.Verb != "*" && !strings.EqualFold(p.Verb, a.Verb) {
+		return false
+	}
+	if p.APIGroup != "*" && p.APIGroup != a.APIGroup {
+		return false
+	}
+	if !coversResource(p.Resource, a.Resource) {
+		return false
+	}
+	if len(p.ResourceNames) > 0 {
+		for _, n := range p.ResourceNames {
+			if n == a.Name {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// coversResource: "*" — всё, "*/scale" — подресурс scale любого ресурса, "pods/*" — любые подресурсы pods.
+func coversResource(have, want string) bool {
+	if have == "*" || have == want {
+		return true
+	}
+	hr, hs, hasSub := strings.Cut(have, "/")
+	wr, ws, wantSub := strings.Cut(want, "/")
+	if !hasSub || !wantSub {
+		return false
+	}
+	return (hr == "*" || hr == wr) && (hs == "*" || hs == ws)
+}
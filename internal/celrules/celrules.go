@@ -0,0 +1,175 @@
+// Package celrules загружает пользовательские правила опасности на CEL
+// (Common Expression Language) и превращает их в rbac.CustomRule.
+package celrules
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"gopkg.in/yaml.v3"
+
+	"rbac-analyzer/internal/rbac"
+)
+
+// File — файл правил.
+//
+//	rules:
+//	  - id: nodes-proxy
+//	    severity: critical
+//	    message: Can proxy to kubelet API via nodes/proxy
+//	    expr: perm.resource == "nodes/proxy" && perm.verb in ["get", "create", "*"]
+//	  - id: argo-app-write
+//	    severity: high
+//	    message: Can modify Argo CD applications
+//	    expr: >
+//	      perm.apiGroup == "argoproj.io" && perm.resource == "applications" &&
+//	      perm.verb in ["create", "update", "patch", "*"] && !role.clusterScope
+//
+// Выражение вычисляется для каждого права роли и должно вернуть bool. Переменные:
+//
+//	perm:    apiGroup, resource, verb, resourceNames, namespace, clusterScope
+//	role:    kind, name, namespace, clusterScope, boundVia, bindingName, bindingNamespace
+//	subject: kind, name, namespace
+//
+// "*" в правах роли не раскрывается: сравнивайте с ним явно, если это нужно.
+type File struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// Rule — одно правило из файла.
+type Rule struct {
+	ID       string `yaml:"id"`
+	Severity string `yaml:"severity"`
+	Message  string `yaml:"message"`
+	Expr     string `yaml:"expr"`
+}
+
+var severities = map[string]bool{
+	rbac.SeverityLow:      true,
+	rbac.SeverityMedium:   true,
+	rbac.SeverityHigh:     true,
+	rbac.SeverityCritical: true,
+}
+
+// LoadFile читает и компилирует файл правил.
+func LoadFile(path string) ([]rbac.CustomRule, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(b)
+}
+
+// Parse разбирает файл правил и компилирует выражения.
+// Ошибки компиляции возвращаются сразу, с id правила.
+func Parse(b []byte) ([]rbac.CustomRule, error) {
+	var f File
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("parse rules: %w", err)
+	}
+
+	env, err := cel.NewEnv(
+		cel.Variable("perm", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("role", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("subject", cel.MapType(cel.StringType, cel.DynType)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]rbac.CustomRule, 0, len(f.Rules))
+	seen := map[string]bool{}
+	for i, r := range f.Rules {
+		if r.ID == "" {
+			return nil, fmt.Errorf("rule #%d: id required", i+1)
+		}
+		if seen[r.ID] {
+			return nil, fmt.Errorf("rule %s: duplicate id", r.ID)
+		}
+		seen[r.ID] = true
+
+		sev := strings.ToLower(strings.TrimSpace(r.Severity))
+		if sev == "" {
+			sev = rbac.SeverityHigh
+		}
+		if !severities[sev] {
+			return nil, fmt.Errorf("rule %s: severity must be low, medium, high or critical", r.ID)
+		}
+		if strings.TrimSpace(r.Expr) == "" {
+			return nil, fmt.Errorf("rule %s: expr required", r.ID)
+		}
+
+		ast, iss := env.Compile(r.Expr)
+		if iss != nil && iss.Err() != nil {
+			return nil, fmt.Errorf("rule %s: %w", r.ID, iss.Err())
+		}
+		// поля perm/role/subject имеют тип dyn, поэтому `role.clusterScope` — тоже dyn:
+		// такие выражения допускаются, bool проверяется при вычислении
+		if ot := ast.OutputType(); !ot.IsExactType(cel.BoolType) && !ot.IsExactType(cel.DynType) {
+			return nil, fmt.Errorf("rule %s: expr must return bool, got %s", r.ID, ot)
+		}
+		prg, err := env.Program(ast)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", r.ID, err)
+		}
+
+		id := r.ID
+		msg := r.Message
+		if msg == "" {
+			msg = "matches rule " + id
+		}
+		out = append(out, rbac.CustomRule{
+			ID:       id,
+			Severity: sev,
+			Message:  msg,
+			Match: func(s rbac.SubjectRef, role rbac.EffectiveRole, p rbac.Permission) (bool, error) {
+				val, _, err := prg.Eval(activation(s, role, p))
+				if err != nil {
+					return false, fmt.Errorf("rule %s: %w", id, err)
+				}
+				ok, isBool := val.Value().(bool)
+				if !isBool {
+					return false, fmt.Errorf("rule %s: expr must return bool, got %s", id, val.Type().TypeName())
+				}
+				return ok, nil
+			},
+		})
+	}
+	return out, nil
+}
+
+func activation(s rbac.SubjectRef, r rbac.EffectiveRole, p rbac.Permission) map[string]any {
+	resourceNames := p.ResourceNames
+	if resourceNames == nil {
+		resourceNames = []string{}
+	}
+	return map[string]any{
+		"perm": map[string]any{
+			"apiGroup":      p.APIGroup,
+			"resource":      p.Resource,
+			"verb":          p.Verb,
+			"resourceNames": resourceNames,
+			"namespace":     p.Namespace,
+			"clusterScope":  p.ClusterScope,
+		},
+		"role": map[string]any{
+			"kind":             r.SourceKind,
+			"name":             r.SourceName,
+			"namespace":        r.SourceNamespace,
+			"clusterScope":     r.ClusterScope,
+			"boundVia":         r.BoundVia,
+			"bindingName":      r.BindingName,
+			"bindingNamespace": r.BindingNS,
+		},
+		"subject": map[string]any{
+			"kind":      string(s.Kind),
+			"name":      s.Name,
+			"namespace": s.Namespace,
+		},
+	}
+}
@@ -0,0 +1,48 @@
+package celrules
+
+import (
+	"strings"
+	"testing"
+
+	"rbac-analyzer/internal/rbac"
+)
+
+func TestParseDynBool(t *testing.T) {
+	rules, err := Parse([]byte(`
+rules:
+  - id: cluster-wide
+    expr: role.clusterScope
+  - id: not-bool
+    expr: perm.verb
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := rbac.SubjectRef{Kind: rbac.SubjectKindUser, Name: "alice"}
+	role := rbac.EffectiveRole{SourceKind: "ClusterRole", SourceName: "view", ClusterScope: true}
+	p := rbac.Permission{Verb: "get", Resource: "pods", ClusterScope: true}
+
+	if ok, err := rules[0].Match(s, role, p); err != nil || !ok {
+		t.Fatalf("role.clusterScope: %v, %v", ok, err)
+	}
+	role.ClusterScope = false
+	if ok, err := rules[0].Match(s, role, p); err != nil || ok {
+		t.Fatalf("role.clusterScope on a namespaced role: %v, %v", ok, err)
+	}
+	// dyn, который оказался строкой, — ошибка при вычислении, а не молчаливый false
+	if _, err := rules[1].Match(s, role, p); err == nil || !strings.Contains(err.Error(), "not-bool") {
+		t.Fatalf("string result: %v", err)
+	}
+}
+
+func TestParseRejectsNonBool(t *testing.T) {
+	_, err := Parse([]byte(`
+rules:
+  - id: size
+    expr: size(perm.resourceNames)
+`))
+	if err == nil || !strings.Contains(err.Error(), "must return bool") {
+		t.Fatalf("int expression: %v", err)
+	}
+}
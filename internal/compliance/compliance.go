@@ -0,0 +1,232 @@
+// Package compliance сопоставляет находки анализатора с контролями
+// CIS Kubernetes Benchmark (раздел 5.1, RBAC) и рекомендациями NSA/CISA Kubernetes Hardening Guide.
+package compliance
+
+import (
+	"sort"
+	"strings"
+
+	"rbac-analyzer/internal/rbac"
+)
+
+// Статусы контроля.
+const (
+	StatusPass = "pass"
+	StatusFail = "fail"
+)
+
+// Ссылки на NSA/CISA Kubernetes Hardening Guide (v1.2).
+const (
+	nsaLeastPrivilege = "NSA-CISA: Authentication and authorization — least privilege RBAC"
+	nsaSATokens       = "NSA-CISA: Kubernetes Pod security — service account tokens"
+	nsaAdminAccess    = "NSA-CISA: Authentication and authorization — restrict cluster-admin"
+)
+
+// Control — контроль бенчмарка.
+type Control struct {
+	ID      string   `json:"id"` // "CIS 5.1.1"
+	Title   string   `json:"title"`
+	NSA     []string `json:"nsa"`
+	RuleIDs []string `json:"ruleIds,omitempty"` // связанные правила движка опасностей (Finding.RuleID)
+}
+
+// Evidence — конкретное основание для fail.
+type Evidence struct {
+	Subject  string `json:"subject,omitempty"`
+	Role     string `json:"role,omitempty"`
+	Binding  string `json:"binding,omitempty"`
+	Workload string `json:"workload,omitempty"`
+	Detail   string `json:"detail"`
+}
+
+// ControlResult — результат одного контроля.
+type ControlResult struct {
+	Control
+	Status   string     `json:"status"`
+	Evidence []Evidence `json:"evidence"`
+}
+
+// Report — результаты по всем контролям (в порядке Controls).
+type Report struct {
+	Controls []ControlResult `json:"controls"`
+	Passed   int             `json:"passed"`
+	Failed   int             `json:"failed"`
+}
+
+// FailedIDs — идентификаторы проваленных контролей.
+func (r Report) FailedIDs() []string {
+	out := make([]string, 0, r.Failed)
+	for _, c := range r.Controls {
+		if c.Status == StatusFail {
+			out = append(out, c.ID)
+		}
+	}
+	return out
+}
+
+// ControlsVersion — версия набора контролей и логики их проверки.
+const ControlsVersion = "cis/v2"
+
+// Controls — поддерживаемые контроли CIS 5.1.x.
+var Controls = []Control{
+	{ID: "CIS 5.1.1", Title: "Ensure that the cluster-admin role is only used where required",
+		NSA: []string{nsaAdminAccess, nsaLeastPrivilege}},
+	{ID: "CIS 5.1.2", Title: "Minimize access to secrets",
+		NSA: []string{nsaLeastPrivilege}, RuleIDs: []string{"secrets-read"}},
+	{ID: "CIS 5.1.3", Title: "Minimize wildcard use in Roles and ClusterRoles",
+		NSA: []string{nsaLeastPrivilege}, RuleIDs: []string{"full-admin"}},
+	{ID: "CIS 5.1.4", Title: "Minimize access to create pods",
+		NSA: []string{nsaLeastPrivilege}, RuleIDs: []string{"workload-control"}},
+	{ID: "CIS 5.1.5", Title: "Ensure that default service accounts are not actively used",
+		NSA: []string{nsaSATokens, nsaLeastPrivilege}},
+	{ID: "CIS 5.1.6", Title: "Ensure that Service Account Tokens are only mounted where necessary",
+		NSA: []string{nsaSATokens}},
+	{ID: "CIS 5.1.7", Title: "Avoid use of system:masters group",
+		NSA: []string{nsaAdminAccess}},
+	{ID: "CIS 5.1.8", Title: "Limit use of the Bind, Impersonate and Escalate permissions in the Kubernetes cluster",
+		NSA: []string{nsaLeastPrivilege}, RuleIDs: []string{"rbac-escalate-verbs"}},
+}
+
+// Evaluate проверяет контроли по эффективным правам и (для 5.1.5/5.1.6) по рабочим нагрузкам.
+// Результат детерминирован: evidence отсортированы.
+//
+// 5.1.6 — эвристика: нужен ли нагрузке токен, по манифестам не понять, поэтому
+// проваливаются только нагрузки со смонтированным токеном ServiceAccount без прав RBAC.
+// Остальные смонтированные токены бенчмарк предлагает проверить вручную.
+func Evaluate(sp rbac.SubjectPermissions, workloads []rbac.Workload) Report {
+	ev := map[string][]Evidence{}
+	add := func(id string, e Evidence) { ev[id] = append(ev[id], e) }
+
+	for s, roles := range sp {
+		subj := s.String()
+		for _, r := range roles {
+			base := Evidence{Subject: subj, Role: r.RoleLabel(), Binding: r.BindingLabel()}
+
+			if r.SourceKind == "ClusterRole" && r.SourceName == "cluster-admin" &&
+				!(s.Kind == rbac.SubjectKindGroup && s.Name == "system:masters") {
+				e := base
+				e.Detail = "bound to cluster-admin"
+				add("CIS 5.1.1", e)
+			}
+			if hasFinding(r, "secrets-read") {
+				e := base
+				e.Detail = firstPermission(r, func(p rbac.Permission) bool {
+					return anyOf(p.Resource, "secrets", "*") && anyOf(p.Verb, "get", "list", "watch", "*")
+				})
+				add("CIS 5.1.2", e)
+			}
+			if d := firstPermission(r, func(p rbac.Permission) bool {
+				return p.Verb == "*" || p.Resource == "*" || p.APIGroup == "*"
+			}); d != "" {
+				e := base
+				e.Detail = "wildcard: " + d
+				add("CIS 5.1.3", e)
+			}
+			if d := firstPermission(r, func(p rbac.Permission) bool {
+				return anyOf(p.Resource, "pods", "*") && anyOf(p.Verb, "create", "*")
+			}); d != "" {
+				e := base
+				e.Detail = d
+				add("CIS 5.1.4", e)
+			}
+			if s.Kind == rbac.SubjectKindServiceAccount && s.Name == "default" {
+				e := base
+				e.Detail = "default service account has RBAC permissions"
+				add("CIS 5.1.5", e)
+			}
+			if s.Kind == rbac.SubjectKindGroup && s.Name == "system:masters" {
+				e := base
+				e.Detail = "binding to system:masters (bypasses RBAC, cannot be revoked)"
+				add("CIS 5.1.7", e)
+			}
+			if hasFinding(r, "rbac-escalate-verbs") {
+				e := base
+				e.Detail = firstPermission(r, func(p rbac.Permission) bool {
+					return anyOf(p.Verb, "bind", "escalate", "impersonate")
+				})
+				add("CIS 5.1.8", e)
+			}
+		}
+	}
+
+	for _, w := range workloads {
+		label := w.Kind + ":" + w.Namespace + "/" + w.Name
+		if w.ServiceAccount == "default" {
+			add("CIS 5.1.5", Evidence{
+				Workload: label,
+				Subject:  rbac.SubjectRef{Kind: rbac.SubjectKindServiceAccount, Name: "default", Namespace: w.Namespace}.String(),
+				Detail:   "workload runs as the default service account",
+			})
+		}
+		sa := rbac.SubjectRef{Kind: rbac.SubjectKindServiceAccount, Name: w.ServiceAccount, Namespace: w.Namespace}
+		if w.AutomountToken && len(sp[sa]) == 0 {
+			add("CIS 5.1.6", Evidence{
+				Workload: label,
+				Subject:  sa.String(),
+				Detail:   "heuristic: token is mounted but the service account has no RBAC permissions (set automountServiceAccountToken: false)",
+			})
+		}
+	}
+
+	rep := Report{Controls: make([]ControlResult, 0, len(Controls))}
+	for _, c := range Controls {
+		res := ControlResult{Control: c, Status: StatusPass, Evidence: sortEvidence(ev[c.ID])}
+		if len(res.Evidence) > 0 {
+			res.Status = StatusFail
+			rep.Failed++
+		} else {
+			rep.Passed++
+		}
+		rep.Controls = append(rep.Controls, res)
+	}
+	return rep
+}
+
+func hasFinding(r rbac.EffectiveRole, id string) bool {
+	for _, f := range r.Findings {
+		if f.RuleID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// firstPermission — каноничный ключ первого подходящего права ("" если нет).
+func firstPermission(r rbac.EffectiveRole, pred func(p rbac.Permission) bool) string {
+	for _, p := range r.Permissions {
+		if pred(p) {
+			return rbac.CanonicalPermissionKey(p.Namespace, p.Verb, p.APIGroup, p.Resource, p.ResourceNames)
+		}
+	}
+	return ""
+}
+
+func anyOf(v string, options ...string) bool {
+	v = strings.ToLower(v)
+	for _, o := range options {
+		if v == o {
+			return true
+		}
+	}
+	return false
+}
+
+func sortEvidence(in []Evidence) []Evidence {
+	if in == nil {
+		return []Evidence{}
+	}
+	sort.Slice(in, func(i, j int) bool {
+		a, b := in[i], in[j]
+		if a.Subject != b.Subject {
+			return a.Subject < b.Subject
+		}
+		if a.Workload != b.Workload {
+			return a.Workload < b.Workload
+		}
+		if a.Binding != b.Binding {
+			return a.Binding < b.Binding
+		}
+		return a.Role < b.Role
+	})
+	return in
+}
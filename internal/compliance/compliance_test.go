@@ -0,0 +1,95 @@
+package compliance
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"rbac-analyzer/internal/rbac"
+)
+
+func sa(ns, name string) rbac.SubjectRef {
+	return rbac.SubjectRef{Kind: rbac.SubjectKindServiceAccount, Namespace: ns, Name: name}
+}
+
+func clusterRole(name string, findings []string, perms ...rbac.Permission) rbac.EffectiveRole {
+	r := rbac.EffectiveRole{
+		SourceKind: "ClusterRole", SourceName: name, ClusterScope: true, Permissions: perms,
+		BoundVia: "ClusterRoleBinding", BindingName: name,
+	}
+	for _, id := range findings {
+		r.Findings = append(r.Findings, rbac.Finding{RuleID: id})
+	}
+	return r
+}
+
+func perm(verb, resource string) rbac.Permission {
+	return rbac.Permission{Verb: verb, Resource: resource, ClusterScope: true}
+}
+
+func TestEvaluate(t *testing.T) {
+	masters := rbac.SubjectRef{Kind: rbac.SubjectKindGroup, Name: "system:masters"}
+	for _, c := range []struct {
+		name       string
+		sp         rbac.SubjectPermissions
+		workloads  []rbac.Workload
+		wantFailed []string
+		detail     string // подстрока detail первого evidence первого проваленного контроля
+	}{
+		{name: "clean", sp: rbac.SubjectPermissions{
+			sa("prod", "app"): {clusterRole("view", nil, perm("get", "configmaps"))},
+		}, wantFailed: []string{}},
+		{name: "5.1.1 cluster-admin", sp: rbac.SubjectPermissions{
+			sa("prod", "app"): {clusterRole("cluster-admin", nil)},
+		}, wantFailed: []string{"CIS 5.1.1"}, detail: "cluster-admin"},
+		{name: "5.1.2 secrets", sp: rbac.SubjectPermissions{
+			sa("prod", "app"): {clusterRole("secret-reader", []string{"secrets-read"}, perm("list", "secrets"))},
+		}, wantFailed: []string{"CIS 5.1.2"}, detail: "secrets"},
+		{name: "5.1.3 wildcard", sp: rbac.SubjectPermissions{
+			sa("prod", "app"): {clusterRole("cm-admin", nil, perm("*", "configmaps"))},
+		}, wantFailed: []string{"CIS 5.1.3"}, detail: "wildcard:"},
+		{name: "5.1.4 create pods", sp: rbac.SubjectPermissions{
+			sa("prod", "app"): {clusterRole("pod-creator", nil, perm("create", "pods"))},
+		}, wantFailed: []string{"CIS 5.1.4"}, detail: "pods"},
+		{name: "5.1.5 default SA with permissions", sp: rbac.SubjectPermissions{
+			sa("prod", "default"): {clusterRole("view", nil, perm("get", "configmaps"))},
+		}, wantFailed: []string{"CIS 5.1.5"}, detail: "default service account"},
+		{name: "5.1.5 workload on default SA", workloads: []rbac.Workload{
+			{Kind: "Deployment", Namespace: "prod", Name: "web", ServiceAccount: "default"},
+		}, wantFailed: []string{"CIS 5.1.5"}, detail: "workload runs as"},
+		{name: "5.1.6 token mounted without permissions", workloads: []rbac.Workload{
+			{Kind: "Deployment", Namespace: "prod", Name: "web", ServiceAccount: "web", AutomountToken: true},
+		}, wantFailed: []string{"CIS 5.1.6"}, detail: "heuristic:"},
+		{name: "5.1.6 token mounted and used", sp: rbac.SubjectPermissions{
+			sa("prod", "web"): {clusterRole("view", nil, perm("get", "configmaps"))},
+		}, workloads: []rbac.Workload{
+			{Kind: "Deployment", Namespace: "prod", Name: "web", ServiceAccount: "web", AutomountToken: true},
+		}, wantFailed: []string{}},
+		{name: "5.1.6 token not mounted", workloads: []rbac.Workload{
+			{Kind: "Deployment", Namespace: "prod", Name: "web", ServiceAccount: "web"},
+		}, wantFailed: []string{}},
+		{name: "5.1.7 system:masters", sp: rbac.SubjectPermissions{
+			masters: {clusterRole("cluster-admin", nil)},
+		}, wantFailed: []string{"CIS 5.1.7"}, detail: "system:masters"},
+		{name: "5.1.8 bind", sp: rbac.SubjectPermissions{
+			sa("prod", "app"): {clusterRole("binder", []string{"rbac-escalate-verbs"}, perm("bind", "clusterroles"))},
+		}, wantFailed: []string{"CIS 5.1.8"}, detail: "bind"},
+	} {
+		rep := Evaluate(c.sp, c.workloads)
+		if got := rep.FailedIDs(); !reflect.DeepEqual(got, c.wantFailed) {
+			t.Errorf("%s: failed = %v, want %v", c.name, got, c.wantFailed)
+			continue
+		}
+		if len(rep.Controls) != len(Controls) || rep.Passed+rep.Failed != len(Controls) {
+			t.Errorf("%s: %d controls, passed %d, failed %d", c.name, len(rep.Controls), rep.Passed, rep.Failed)
+		}
+		for _, cr := range rep.Controls {
+			if cr.Status == StatusPass && len(cr.Evidence) != 0 {
+				t.Errorf("%s: %s passed with evidence %+v", c.name, cr.ID, cr.Evidence)
+			}
+			if cr.Status == StatusFail && !strings.Contains(cr.Evidence[0].Detail, c.detail) {
+				t.Errorf("%s: %s detail %q, want %q", c.name, cr.ID, cr.Evidence[0].Detail, c.detail)
+			}
+		}
+	}
+}
@@ -7,22 +7,59 @@ import (
 type Config struct {
 	Addr         string
 	DatabaseURL  string
-	JWTSecret    string
+	JWTSecret    string // JWT_SECRET или JWT_KEYS обязательны: секрета по умолчанию нет
+	JWTKeys      string // ротация ключей: "kid:secret,kid2:secret2", первый подписывает
 	BaseURL      string // для генерации ссылок
 	ContactEmail string // на сайт
 	ContactTG    string
 	ContactSite  string
+	RulesFile    string // CEL-правила опасности (необязательно)
+
+	// Почта: SMTP_ADDR (host:port) — отправка через SMTP; иначе MAIL_FILE — письма
+	// дописываются в файл; если не задано ни то, ни другое, письма печатаются в stderr.
+	SMTPAddr     string
+	SMTPUser     string
+	SMTPPassword string
+	MailFrom     string
+	MailFile     string
+
+	// TrustProxy — сервер за обратным прокси: адрес клиента берётся из X-Forwarded-For
+	// (для лимитов запросов и списка сессий). Без прокси включать нельзя — заголовок подделывается.
+	TrustProxy bool
+
+	// ReanalyzeOnStart — при старте пересчитать в фоне сканы, посчитанные другой версией движка.
+	ReanalyzeOnStart bool
+
+	// MigrateOnStart — применять миграции при старте; иначе сервер не стартует на устаревшей схеме.
+	MigrateOnStart bool
+
+	// DevMode — локальная разработка: SSO принимает http:// issuer и адреса во внутренней
+	// сети (например, mock-oidc на 127.0.0.1). В продакшене не включать.
+	DevMode bool
 }
 
 func Load() Config {
 	return Config{
 		Addr:         getenv("APP_ADDR", ":8080"),
 		DatabaseURL:  getenv("DATABASE_URL", "postgres://rbac:rbac@db:5432/rbac?sslmode=disable"),
-		JWTSecret:    getenv("JWT_SECRET", "dev_secret_change_me"),
+		JWTSecret:    getenv("JWT_SECRET", ""),
+		JWTKeys:      getenv("JWT_KEYS", ""),
 		BaseURL:      getenv("BASE_URL", "http://localhost:8080"),
 		ContactEmail: getenv("CONTACT_EMAIL", "sales@example.com"),
 		ContactTG:    getenv("CONTACT_TG", "@your_tg"),
 		ContactSite:  getenv("CONTACT_SITE", "https://example.com"),
+		RulesFile:    getenv("RULES_FILE", ""),
+
+		SMTPAddr:     getenv("SMTP_ADDR", ""),
+		SMTPUser:     getenv("SMTP_USER", ""),
+		SMTPPassword: getenv("SMTP_PASSWORD", ""),
+		MailFrom:     getenv("MAIL_FROM", "RBAC Analyzer <noreply@localhost>"),
+		MailFile:     getenv("MAIL_FILE", ""),
+
+		TrustProxy:       getenv("TRUST_PROXY", "") == "1",
+		ReanalyzeOnStart: getenv("REANALYZE_ON_START", "") == "1",
+		MigrateOnStart:   getenv("MIGRATE_ON_START", "") == "1",
+		DevMode:          getenv("DEV_MODE", "") == "1",
 	}
 }
 
@@ -0,0 +1,109 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+
+	"rbac-analyzer/internal/store"
+)
+
+// clusterAccess — гранты организации, из которых считаются роли пользователя на кластерах.
+//
+// Эффективная роль — максимум из:
+//   - admin, если пользователь owner/admin организации;
+//   - viewer, если кластер не restricted (его видят все участники);
+//   - гранты пользователю и командам, в которых он состоит.
+type clusterAccess struct {
+	orgRole string
+	userID  string
+	teams   map[string]string // id -> name, команды пользователя
+	grants  []store.ClusterGrant
+}
+
+func (s *Server) loadClusterAccess(ctx context.Context, m store.Membership, userID string) (*clusterAccess, error) {
+	a := &clusterAccess{orgRole: m.Role, userID: userID, teams: map[string]string{}}
+
+	// owner/admin организации видят всё — гранты не нужны
+	if store.RoleAtLeast(m.Role, store.RoleAdmin) {
+		return a, nil
+	}
+
+	teams, err := s.Store.ListTeams(ctx, m.Org.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range teams {
+		for _, id := range t.MemberIDs {
+			if id == userID {
+				a.teams[t.ID] = t.Name
+			}
+		}
+	}
+	if a.grants, err = s.Store.ListClusterGrants(ctx, m.Org.ID); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// role — эффективная роль на кластере ("" — нет доступа) и её источники.
+func (a *clusterAccess) role(c store.Cluster) (string, []string) {
+	if store.RoleAtLeast(a.orgRole, store.RoleAdmin) {
+		return store.ClusterAdmin, []string{"org:" + a.orgRole}
+	}
+
+	role := ""
+	var sources []string
+	raise := func(r, source string) {
+		if !store.ClusterRoleAtLeast(role, r) {
+			role = r
+		}
+		sources = append(sources, source)
+	}
+
+	if !c.Restricted {
+		raise(store.ClusterViewer, "org:"+a.orgRole)
+	}
+	for _, g := range a.grants {
+		if g.ClusterID != c.ID {
+			continue
+		}
+		switch {
+		case g.SubjectType == store.SubjectUser && g.SubjectID == a.userID:
+			raise(g.Role, "user:"+g.Role)
+		case g.SubjectType == store.SubjectTeam && a.teams[g.SubjectID] != "":
+			raise(g.Role, "team:"+a.teams[g.SubjectID]+":"+g.Role)
+		}
+	}
+	return role, sources
+}
+
+// orgCluster загружает кластер текущей организации и проверяет роль не ниже min.
+// Кластер, который пользователь не видит, для него не существует (404).
+// При ошибке ответ уже записан и возвращается false.
+func (s *Server) orgCluster(w http.ResponseWriter, r *http.Request, m store.Membership, clusterID, min string) (store.Cluster, bool) {
+	c, err := s.Store.GetCluster(r.Context(), clusterID)
+	if err != nil || c.OrgID != m.Org.ID {
+		if err != nil && !store.IsNotFound(err) {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return store.Cluster{}, false
+		}
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": "cluster not found"})
+		return store.Cluster{}, false
+	}
+
+	a, err := s.loadClusterAccess(r.Context(), m, GetUserID(r))
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return store.Cluster{}, false
+	}
+	role, _ := a.role(c)
+	if role == "" {
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": "cluster not found"})
+		return store.Cluster{}, false
+	}
+	if !store.ClusterRoleAtLeast(role, min) {
+		writeJSON(w, http.StatusForbidden, map[string]any{"error": "requires cluster role " + min})
+		return store.Cluster{}, false
+	}
+	return c, true
+}
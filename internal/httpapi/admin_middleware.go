@@ -1,10 +1,16 @@
 package httpapi
 
-import "net/http"
+import (
+	"net/http"
 
-func RequireAdmin(next http.Handler) http.Handler {
+	"rbac-analyzer/internal/store"
+)
+
+// RequireAdmin пускает только администраторов. Флаг берётся из базы, а не из токена:
+// снятие прав через ToggleAdmin действует сразу, а не по истечении токена.
+func RequireAdmin(users store.UserRepo, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		claims := GetClaims(r) // у тебя это НЕ *Claims, а struct
+		claims := GetClaims(r)
 
 		if claims.Sub == "" {
 			writeJSON(w, http.StatusUnauthorized, map[string]any{
@@ -13,7 +19,12 @@ func RequireAdmin(next http.Handler) http.Handler {
 			return
 		}
 
-		if !claims.Admin {
+		u, err := users.GetUser(r.Context(), claims.Sub)
+		if err != nil && !store.IsNotFound(err) {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		if err != nil || !u.IsAdmin {
 			writeJSON(w, http.StatusForbidden, map[string]any{
 				"error": "admin only",
 			})
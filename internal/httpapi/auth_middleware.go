@@ -4,8 +4,10 @@ import (
 	"context"
 	"net/http"
 	"strings"
+	"time"
 
 	"rbac-analyzer/internal/security"
+	"rbac-analyzer/internal/store"
 )
 
 type ctxKey string
@@ -16,7 +18,9 @@ const (
 	bearerPref        = "bearer "
 )
 
-func AuthMiddleware(jwtKey []byte, next http.Handler) http.Handler {
+// AuthMiddleware проверяет access token и то, что его сессия не отозвана (logout,
+// отзыв из списка сессий или администратором) — токен перестаёт работать сразу.
+func AuthMiddleware(keys *security.Keyring, sessions store.SessionRepo, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		auth := strings.TrimSpace(r.Header.Get("Authorization"))
 		if auth == "" {
@@ -34,11 +38,21 @@ func AuthMiddleware(jwtKey []byte, next http.Handler) http.Handler {
 			return
 		}
 
-		claims, err := security.VerifyJWT(jwtKey, auth)
-		if err != nil {
+		claims, err := keys.Verify(auth)
+		// токен второго шага входа (Purpose) доступа к API не даёт
+		if err != nil || claims.Sid == "" || claims.Purpose != "" {
 			writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "invalid token"})
 			return
 		}
+		ss, err := sessions.GetSession(r.Context(), claims.Sid)
+		if err != nil && !store.IsNotFound(err) {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		if err != nil || ss.UserID != claims.Sub || !ss.Active(time.Now()) {
+			writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "session revoked"})
+			return
+		}
 
 		ctx := context.WithValue(r.Context(), ctxUserID, claims.Sub)
 		ctx = context.WithValue(ctx, ctxClaims, claims)
@@ -0,0 +1,273 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"rbac-analyzer/internal/mail"
+	"rbac-analyzer/internal/security"
+	"rbac-analyzer/internal/store"
+)
+
+// Ссылки из писем одноразовые: новое письмо того же назначения отменяет прежнее.
+const (
+	emailVerifyTTL   = 48 * time.Hour
+	passwordResetTTL = time.Hour
+)
+
+// sendAccountMail выдаёт одноразовый токен и отправляет ссылку на /login?verify=… или ?reset=….
+func (s *Server) sendAccountMail(ctx context.Context, u store.User, purpose string) error {
+	token, hash, err := security.NewToken()
+	if err != nil {
+		return err
+	}
+	ttl, param, subject, text := emailVerifyTTL, "verify", "Confirm your email for RBAC Analyzer",
+		"Confirm your email address to finish setting up your account:"
+	if purpose == store.TokenResetPassword {
+		ttl, param, subject, text = passwordResetTTL, "reset", "Reset your RBAC Analyzer password",
+			"Someone (hopefully you) asked to reset the password for this account. Set a new password:"
+	}
+	expires := time.Now().UTC().Add(ttl)
+	if err := s.Store.CreateUserToken(ctx, store.UserToken{
+		UserID:    u.ID,
+		Purpose:   purpose,
+		TokenHash: hash,
+		ExpiresAt: expires,
+	}); err != nil {
+		return err
+	}
+
+	link := strings.TrimRight(s.Cfg.BaseURL, "/") + "/login?" + param + "=" + url.QueryEscape(token)
+	return s.Mail.Send(ctx, mail.Message{
+		To:      u.Email,
+		Subject: subject,
+		Body: fmt.Sprintf("%s\n\n%s\n\nThe link can be used once and expires on %s.\nIf you did not request this, ignore this email.\n",
+			text, link, expires.Format("2006-01-02 15:04 MST")),
+	})
+}
+
+// sendMailAsync выполняет send вне запроса: время ответа не должно зависеть от SMTP,
+// иначе по нему видно, есть ли аккаунт с таким email. Ошибки клиенту и так не сообщаются.
+func (s *Server) sendMailAsync(send func(ctx context.Context) error) {
+	s.mailJobs.Add(1)
+	go func() {
+		defer s.mailJobs.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		_ = send(ctx)
+	}()
+}
+
+// consumeAccountToken гасит токен из письма; при ошибке сам пишет ответ.
+func (s *Server) consumeAccountToken(w http.ResponseWriter, r *http.Request, purpose, token string) (store.UserToken, bool) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "token required"})
+		return store.UserToken{}, false
+	}
+	t, err := s.Store.ConsumeUserToken(r.Context(), purpose, security.HashToken(token))
+	if err != nil {
+		if store.IsNotFound(err) {
+			writeJSON(w, http.StatusGone, map[string]any{"error": "link is invalid or has expired"})
+			return store.UserToken{}, false
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return store.UserToken{}, false
+	}
+	return t, true
+}
+
+// POST /api/auth/verify-email {"token"} — подтверждение email по ссылке из письма (без входа).
+func (s *Server) handleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "bad json"})
+		return
+	}
+	if !s.allowIP(w, r, "link", limitLinkIP) {
+		return
+	}
+	t, ok := s.consumeAccountToken(w, r, store.TokenVerifyEmail, req.Token)
+	if !ok {
+		return
+	}
+	if err := s.Store.MarkEmailVerified(r.Context(), t.UserID); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+// POST /api/app/verify-email — отправить письмо для подтверждения ещё раз.
+func (s *Server) handleResendVerification(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	u, err := s.Store.GetUser(r.Context(), GetUserID(r))
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	if u.EmailVerified {
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true, "emailVerified": true})
+		return
+	}
+	if !s.allow(w, r, "mail:"+u.Email, limitMailTo) {
+		return
+	}
+	if err := s.sendAccountMail(r.Context(), u, store.TokenVerifyEmail); err != nil {
+		writeJSON(w, http.StatusBadGateway, map[string]any{"error": "send email: " + err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "emailSent": true})
+}
+
+// POST /api/auth/password/forgot {"email"} — письмо со ссылкой для сброса пароля.
+// Ответ всегда одинаковый, чтобы по нему нельзя было узнать, есть ли такой аккаунт.
+func (s *Server) handleForgotPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "bad json"})
+		return
+	}
+	req.Email = strings.TrimSpace(strings.ToLower(req.Email))
+	if req.Email == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "email required"})
+		return
+	}
+	// лимит на адрес получателя действует и для несуществующих аккаунтов
+	if !s.allowIP(w, r, "mail", limitMailIP) || !s.allow(w, r, "mail:"+req.Email, limitMailTo) {
+		return
+	}
+
+	u, err := s.Store.GetUserByEmail(r.Context(), req.Email)
+	switch {
+	case err == nil:
+		s.sendMailAsync(func(ctx context.Context) error {
+			return s.sendAccountMail(ctx, u, store.TokenResetPassword)
+		})
+	case !store.IsNotFound(err):
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+// POST /api/auth/password/reset {"token", "password"} — новый пароль по ссылке из письма.
+// Все сессии пользователя отзываются; ссылка из письма заодно подтверждает email.
+func (s *Server) handleResetPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "bad json"})
+		return
+	}
+	// длину проверяем до того, как гасить токен: иначе опечатка сожгла бы ссылку
+	if len(req.Password) < 8 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "password >= 8 required"})
+		return
+	}
+	if !s.allowIP(w, r, "link", limitLinkIP) {
+		return
+	}
+	t, ok := s.consumeAccountToken(w, r, store.TokenResetPassword, req.Token)
+	if !ok {
+		return
+	}
+
+	hash, err := security.HashPassword(req.Password)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "hash error"})
+		return
+	}
+	if err := s.Store.SetPassword(r.Context(), t.UserID, hash); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	_ = s.Store.MarkEmailVerified(r.Context(), t.UserID)
+	if _, err := s.Store.RevokeUserSessions(r.Context(), t.UserID, ""); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+// POST /api/app/password {"currentPassword", "newPassword"} — смена пароля.
+// Остальные сессии пользователя отзываются, текущая остаётся.
+func (s *Server) handleChangePassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		CurrentPassword string `json:"currentPassword"`
+		NewPassword     string `json:"newPassword"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "bad json"})
+		return
+	}
+	if len(req.NewPassword) < 8 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "new password >= 8 required"})
+		return
+	}
+
+	claims := GetClaims(r)
+	u, err := s.Store.GetUser(r.Context(), claims.Sub)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	// подбор пароля из чужой сессии блокирует и вход по паролю
+	account := "login:" + u.Email
+	if s.accountLocked(w, r, account) {
+		return
+	}
+	// 403, а не 401: клиент на 401 обновляет токен и повторяет запрос
+	if !security.CheckPassword(u.PasswordHash, req.CurrentPassword) {
+		s.authFailed(r.Context(), account)
+		writeJSON(w, http.StatusForbidden, map[string]any{"error": "current password is wrong"})
+		return
+	}
+	s.authSucceeded(r.Context(), account)
+
+	hash, err := security.HashPassword(req.NewPassword)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "hash error"})
+		return
+	}
+	if err := s.Store.SetPassword(r.Context(), u.ID, hash); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	n, err := s.Store.RevokeUserSessions(r.Context(), u.ID, claims.Sid)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "revokedSessions": n})
+}
@@ -0,0 +1,136 @@
+package httpapi
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// mailToken достаёт токен из ссылки /login?{param}=… в последнем письме на адрес to.
+func (e *testEnv) mailToken(to, param string) string {
+	e.t.Helper()
+	msg := e.mail.last()
+	if msg.To != to {
+		e.t.Fatalf("last mail to %q, want %q", msg.To, to)
+	}
+	for _, f := range strings.Fields(msg.Body) {
+		if u, err := url.Parse(f); err == nil && u.Path == "/login" && u.Query().Get(param) != "" {
+			return u.Query().Get(param)
+		}
+	}
+	e.t.Fatalf("no %s link in mail %q", param, msg.Body)
+	return ""
+}
+
+// verifyEmail подтверждает email по ссылке из только что отправленного письма.
+func (e *testEnv) verifyEmail(email string) {
+	e.t.Helper()
+	token := e.mailToken(email, "verify")
+	if code := e.doJSON(http.MethodPost, "/api/auth/verify-email", "", map[string]string{"token": token}, nil); code != http.StatusOK {
+		e.t.Fatalf("verify %s: status %d", email, code)
+	}
+}
+
+func TestEmailVerification(t *testing.T) {
+	e := newTestEnv(t)
+	owner := e.registerSession("owner@example.com").Token
+	orgID := e.me(owner, "").Org.ID
+	first := e.mailToken("owner@example.com", "verify")
+
+	var me struct{ EmailVerified bool }
+	e.doJSON(http.MethodGet, "/api/app/me", owner, nil, &me)
+	if me.EmailVerified {
+		t.Fatal("new account is verified before confirming email")
+	}
+	invite := map[string]string{"email": "bob@example.com"}
+	if code := e.doJSON(http.MethodPost, "/api/app/org/invitations?orgId="+orgID, owner, invite, nil); code != http.StatusForbidden {
+		t.Fatalf("invite from unverified email: status %d, want 403", code)
+	}
+
+	// повторное письмо отменяет ссылку из первого
+	if code := e.doJSON(http.MethodPost, "/api/app/verify-email", owner, nil, nil); code != http.StatusOK {
+		t.Fatalf("resend: status %d", code)
+	}
+	if code := e.doJSON(http.MethodPost, "/api/auth/verify-email", "", map[string]string{"token": first}, nil); code != http.StatusGone {
+		t.Fatalf("superseded link: status %d, want 410", code)
+	}
+	second := e.mailToken("owner@example.com", "verify")
+	if code := e.doJSON(http.MethodPost, "/api/auth/verify-email", "", map[string]string{"token": second}, nil); code != http.StatusOK {
+		t.Fatalf("verify: status %d", code)
+	}
+	if code := e.doJSON(http.MethodPost, "/api/auth/verify-email", "", map[string]string{"token": second}, nil); code != http.StatusGone {
+		t.Fatalf("link used twice: status %d, want 410", code)
+	}
+
+	e.doJSON(http.MethodGet, "/api/app/me", owner, nil, &me)
+	if !me.EmailVerified {
+		t.Fatal("email is not verified after following the link")
+	}
+	e.invite(owner, orgID, "bob@example.com", "member")
+
+	// токен приглашения видит и пригласивший, поэтому email он не подтверждает
+	bob, _ := e.join(owner, orgID, "bob@example.com")
+	var bobMe struct{ EmailVerified bool }
+	e.doJSON(http.MethodGet, "/api/app/me", bob, nil, &bobMe)
+	if bobMe.EmailVerified {
+		t.Fatal("account registered by invitation is verified without the email link")
+	}
+	e.verifyEmail("bob@example.com")
+}
+
+func TestPasswordResetAndChange(t *testing.T) {
+	e := newTestEnv(t)
+	laptop := e.register("owner@example.com")
+	phone, _ := e.login("owner@example.com", "password123")
+
+	// на неизвестный адрес ответ такой же, письмо не уходит
+	sent := e.mail.count()
+	if code := e.doJSON(http.MethodPost, "/api/auth/password/forgot", "", map[string]string{"email": "nobody@example.com"}, nil); code != http.StatusOK || e.mail.count() != sent {
+		t.Fatalf("forgot for unknown email: status %d, mails %d -> %d", code, sent, e.mail.count())
+	}
+
+	// смена пароля: текущая сессия остаётся, остальные отзываются
+	change := map[string]string{"currentPassword": "wrong-password", "newPassword": "changed-pass"}
+	if code := e.doJSON(http.MethodPost, "/api/app/password", laptop, change, nil); code != http.StatusForbidden {
+		t.Fatalf("change with wrong password: status %d, want 403", code)
+	}
+	change["currentPassword"] = "password123"
+	var changed struct{ RevokedSessions int }
+	if code := e.doJSON(http.MethodPost, "/api/app/password", laptop, change, &changed); code != http.StatusOK || changed.RevokedSessions != 1 {
+		t.Fatalf("change password: status %d, %+v", code, changed)
+	}
+	if code := e.doJSON(http.MethodGet, "/api/app/me", laptop, nil, nil); code != http.StatusOK {
+		t.Fatalf("current session after change: status %d", code)
+	}
+	if code := e.doJSON(http.MethodGet, "/api/app/me", phone, nil, nil); code != http.StatusUnauthorized {
+		t.Fatalf("other session after change: status %d, want 401", code)
+	}
+	if _, code := e.login("owner@example.com", "changed-pass"); code != http.StatusOK {
+		t.Fatalf("login with new password: status %d", code)
+	}
+
+	// сброс по ссылке из письма: одноразовый, отзывает все сессии
+	if code := e.doJSON(http.MethodPost, "/api/auth/password/forgot", "", map[string]string{"email": "Owner@Example.com"}, nil); code != http.StatusOK {
+		t.Fatalf("forgot: status %d", code)
+	}
+	token := e.mailToken("owner@example.com", "reset")
+	if code := e.doJSON(http.MethodPost, "/api/auth/password/reset", "", map[string]string{"token": token, "password": "short"}, nil); code != http.StatusBadRequest {
+		t.Fatalf("reset with short password: status %d, want 400", code)
+	}
+	if code := e.doJSON(http.MethodPost, "/api/auth/password/reset", "", map[string]string{"token": token, "password": "reset-pass-1"}, nil); code != http.StatusOK {
+		t.Fatalf("reset: status %d", code)
+	}
+	if code := e.doJSON(http.MethodPost, "/api/auth/password/reset", "", map[string]string{"token": token, "password": "reset-pass-2"}, nil); code != http.StatusGone {
+		t.Fatalf("reset link used twice: status %d, want 410", code)
+	}
+	if code := e.doJSON(http.MethodGet, "/api/app/me", laptop, nil, nil); code != http.StatusUnauthorized {
+		t.Fatalf("session after reset: status %d, want 401", code)
+	}
+	if _, code := e.login("owner@example.com", "changed-pass"); code != http.StatusUnauthorized {
+		t.Fatalf("old password after reset: status %d, want 401", code)
+	}
+	if _, code := e.login("owner@example.com", "reset-pass-1"); code != http.StatusOK {
+		t.Fatalf("login after reset: status %d", code)
+	}
+}
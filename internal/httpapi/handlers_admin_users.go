@@ -5,18 +5,31 @@ import (
 	"strings"
 )
 
-func (s *Server) handleAdminToggleUser(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		w.WriteHeader(http.StatusMethodNotAllowed)
-		return
-	}
-
-	// /api/admin/users/{id}/toggle-admin
+// handleAdminUser разбирает /api/admin/users/{id}/{action}.
+func (s *Server) handleAdminUser(w http.ResponseWriter, r *http.Request) {
 	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
 	if len(parts) != 5 {
 		http.Error(w, "bad path", http.StatusBadRequest)
 		return
 	}
+	switch parts[4] {
+	case "toggle-admin":
+		s.handleAdminToggleUser(w, r, parts[3])
+	case "sessions":
+		s.handleAdminUserSessions(w, r, parts[3])
+	case "mfa":
+		s.handleAdminUserMFA(w, r, parts[3])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// POST /api/admin/users/{id}/toggle-admin
+func (s *Server) handleAdminToggleUser(w http.ResponseWriter, r *http.Request, targetUserID string) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
 
 	adminID := GetUserID(r)
 	if adminID == "" {
@@ -24,8 +37,6 @@ func (s *Server) handleAdminToggleUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	targetUserID := parts[3]
-
 	// 🚫 запрещаем self-revoke
 	if adminID == targetUserID {
 		http.Error(w, "cannot revoke admin from yourself", http.StatusBadRequest)
@@ -2,38 +2,61 @@ package httpapi
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"strings"
 	"time"
 
-	"rbac-analyzer/internal/loader"
-	"rbac-analyzer/internal/rbac"
+	"rbac-analyzer/internal/audit"
+	"rbac-analyzer/internal/store"
 )
 
 func (s *Server) handleMe(w http.ResponseWriter, r *http.Request) {
 	userID := GetUserID(r)
-	org, err := s.Store.GetOwnerOrg(r.Context(), userID)
+	m, ok := s.resolveOrg(w, r)
+	if !ok {
+		return
+	}
+	orgs, err := s.Store.ListUserOrgs(r.Context(), userID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	sub, _ := s.Store.GetSubscription(r.Context(), m.Org.ID)
+	u, err := s.Store.GetUser(r.Context(), userID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	mfa, err := s.mfaEnabled(r.Context(), userID)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "org not found"})
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
 		return
 	}
-	sub, _ := s.Store.GetSubscription(r.Context(), org.ID)
 
 	writeJSON(w, http.StatusOK, map[string]any{
-		"userId": userID,
-		"org":    org,
-		"sub":    sub,
+		"userId":        userID,
+		"email":         u.Email,
+		"emailVerified": u.EmailVerified,
+		"mfaEnabled":    mfa,
+		"org":           m.Org,
+		"role":          m.Role,
+		"orgs":          orgs,
+		"sub":           sub,
+
+		// организация требует MFA, а она не подключена: остальное API вернёт 403
+		"mfaSetupRequired": m.Org.RequireMFA && !mfa,
 	})
 }
 
 func (s *Server) handleClusters(w http.ResponseWriter, r *http.Request) {
-	userID := GetUserID(r)
-	org, err := s.Store.GetOwnerOrg(r.Context(), userID)
-	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "org not found"})
+	m, ok := s.currentOrg(w, r)
+	if !ok {
 		return
 	}
+	org := m.Org
 
 	switch r.Method {
 
@@ -43,9 +66,27 @@ func (s *Server) handleClusters(w http.ResponseWriter, r *http.Request) {
 			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
 			return
 		}
-		writeJSON(w, http.StatusOK, map[string]any{"clusters": list})
+		a, err := s.loadClusterAccess(r.Context(), m, GetUserID(r))
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+
+		// только видимые пользователю кластеры и его роль на каждом
+		visible := []store.Cluster{}
+		roles := map[string]string{}
+		for _, c := range list {
+			if role, _ := a.role(c); role != "" {
+				visible = append(visible, c)
+				roles[c.ID] = role
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"clusters": visible, "roles": roles})
 
 	case http.MethodPost:
+		if !requireOrgRole(w, m, store.RoleAdmin) {
+			return
+		}
 		sub, _ := s.Store.GetSubscription(r.Context(), org.ID)
 		max, _ := s.Store.PlanMaxClusters(r.Context(), sub.PlanID)
 		cnt, _ := s.Store.CountClusters(r.Context(), org.ID)
@@ -57,8 +98,9 @@ func (s *Server) handleClusters(w http.ResponseWriter, r *http.Request) {
 		}
 
 		var req struct {
-			Name  string `json:"name"`
-			Notes string `json:"notes"`
+			Name       string `json:"name"`
+			Notes      string `json:"notes"`
+			Restricted bool   `json:"restricted"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "bad json"})
@@ -70,7 +112,7 @@ func (s *Server) handleClusters(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		c, err := s.Store.CreateCluster(r.Context(), org.ID, req.Name, req.Notes)
+		c, err := s.Store.CreateCluster(r.Context(), org.ID, req.Name, req.Notes, req.Restricted)
 		if err != nil {
 			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
 			return
@@ -83,12 +125,11 @@ func (s *Server) handleClusters(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleScans(w http.ResponseWriter, r *http.Request) {
-	userID := GetUserID(r)
-	org, err := s.Store.GetOwnerOrg(r.Context(), userID)
-	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "org not found"})
+	m, ok := s.currentOrg(w, r)
+	if !ok {
 		return
 	}
+	org := m.Org
 
 	switch r.Method {
 
@@ -98,6 +139,9 @@ func (s *Server) handleScans(w http.ResponseWriter, r *http.Request) {
 			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "clusterId required"})
 			return
 		}
+		if _, ok := s.orgCluster(w, r, m, clusterID, store.ClusterViewer); !ok {
+			return
+		}
 		list, err := s.Store.ListScans(r.Context(), org.ID, clusterID)
 		if err != nil {
 			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
@@ -106,7 +150,13 @@ func (s *Server) handleScans(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusOK, map[string]any{"scans": list})
 
 	case http.MethodPost:
+		r.Body = http.MaxBytesReader(w, r.Body, maxRBACBytes+maxAuditBytes+1<<20)
 		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeJSON(w, http.StatusRequestEntityTooLarge, map[string]any{"error": "upload too large"})
+				return
+			}
 			writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
 			return
 		}
@@ -116,42 +166,81 @@ func (s *Server) handleScans(w http.ResponseWriter, r *http.Request) {
 			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "clusterId required"})
 			return
 		}
+		// загрузка — uploader на кластере (owner/admin организации — всегда)
+		if _, ok := s.orgCluster(w, r, m, clusterID, store.ClusterUploader); !ok {
+			return
+		}
+		// квота и частота — только для тех, кто вправе загружать: иначе чужие запросы
+		// выбирали бы лимиты организации. Анализ дороже проверки, поэтому она до него.
+		quota, ok := s.allowScanUpload(w, r, org.ID)
+		if !ok {
+			return
+		}
 
-		file, _, err := r.FormFile("rbac")
+		file, fh, err := r.FormFile("rbac")
 		if err != nil {
 			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "rbac file required"})
 			return
 		}
 		defer file.Close()
+		if fh.Size > maxRBACBytes {
+			writeJSON(w, http.StatusRequestEntityTooLarge, map[string]any{"error": "rbac file too large"})
+			return
+		}
 
-		content, err := io.ReadAll(io.LimitReader(file, 64<<20))
+		content, err := io.ReadAll(file)
 		if err != nil {
 			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "read failed"})
 			return
 		}
 
-		data, err := loader.LoadFromBytes(content)
+		perms, sum, full, err := s.analyzeSnapshot(content)
 		if err != nil {
 			writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
 			return
 		}
 
-		perms := rbac.BuildSubjectPermissions(
-			data.Roles,
-			data.ClusterRoles,
-			data.RoleBindings,
-			data.ClusterRoleBindings,
-		)
-
-		sum := BuildSummary(perms)
-		full := BuildFullReport(perms)
+		// необязательный audit-лог (JSON lines, можно .gz): used/partial/unused по биндингам
+		// (файл разбирается потоком: multipart уже сбросил его на диск)
+		if auditFile, ah, err := r.FormFile("audit"); err == nil {
+			defer auditFile.Close()
+			if ah.Size > maxAuditBytes {
+				writeJSON(w, http.StatusRequestEntityTooLarge, map[string]any{"error": "audit log too large"})
+				return
+			}
+			usage, err := audit.ReadStream(auditFile, audit.Window{})
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]any{"error": "audit log: " + err.Error()})
+				return
+			}
+			ur := audit.BuildUsageReport(perms, usage, audit.Window{}, time.Now().UTC(), audit.DefaultStaleAfter)
+			full.Usage = &ur
+			sum["usage"] = ur.Counts
+		}
 
-		sc, err := s.Store.CreateScan(r.Context(), org.ID, clusterID, "upload")
+		// исходный YAML сохраняется, чтобы скан можно было пересчитать новой версией правил
+		snapshotSHA, err := s.Store.PutSnapshot(r.Context(), content)
 		if err != nil {
 			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
 			return
 		}
-		if err := s.Store.UpsertScanResult(r.Context(), sc.ID, sum, full); err != nil {
+
+		// параллельные загрузки могли выбрать квоту после проверки: слот занимается атомарно,
+		// вместе со снимком и результатом — скан без результата квоту не займёт
+		sc, err := s.Store.CreateScanWithinQuota(r.Context(), store.NewScan{
+			OrgID:          org.ID,
+			ClusterID:      clusterID,
+			Source:         "upload",
+			SnapshotSHA256: snapshotSHA,
+			Summary:        sum,
+			Full:           full,
+			EngineVersion:  s.EngineVersion,
+		}, quota.since, quota.max)
+		if errors.Is(err, store.ErrQuotaExceeded) {
+			quota.reject(w)
+			return
+		}
+		if err != nil {
 			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
 			return
 		}
@@ -166,12 +255,65 @@ func (s *Server) handleScans(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// Пределы загрузки скана: снимок RBAC и audit-лог (как загружен, gzip не распаковывается).
+// Всё тело запроса — их сумма плюс 1 МБ на поля и заголовки частей. Переменные — для тестов.
+var (
+	maxRBACBytes  int64 = 64 << 20
+	maxAuditBytes int64 = 256 << 20
+)
+
+// scanQuota — дневная квота сканов плана организации (сутки по UTC).
+type scanQuota struct {
+	plan  string
+	max   int
+	since time.Time // начало текущих суток
+}
+
+// reject пишет 429 с Retry-After до конца суток.
+func (q scanQuota) reject(w http.ResponseWriter) {
+	tooManyRequests(w, q.since.Add(24*time.Hour).Sub(time.Now()),
+		fmt.Sprintf("daily scan quota of the %s plan reached (%d per day, upgrade required)", q.plan, q.max))
+}
+
+// allowScanUpload проверяет дневную квоту сканов и частоту загрузок организации;
+// при отказе сам пишет 429 с Retry-After. Квота проверяется заранее, чтобы не тратить
+// анализ на заведомый отказ; окончательно слот занимает CreateScanWithinQuota.
+func (s *Server) allowScanUpload(w http.ResponseWriter, r *http.Request, orgID string) (scanQuota, bool) {
+	sub, err := s.Store.GetSubscription(r.Context(), orgID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return scanQuota{}, false
+	}
+	q := scanQuota{plan: sub.PlanID, since: time.Now().UTC().Truncate(24 * time.Hour)}
+	if q.max, err = s.Store.PlanMaxScansPerDay(r.Context(), sub.PlanID); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return scanQuota{}, false
+	}
+	cnt, err := s.Store.CountScansSince(r.Context(), orgID, q.since)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return scanQuota{}, false
+	}
+	if cnt >= q.max {
+		q.reject(w)
+		return scanQuota{}, false
+	}
+	return q, s.allow(w, r, "scans:"+orgID, limitScanOrg)
+}
+
 func (s *Server) handleScanReport(w http.ResponseWriter, r *http.Request) {
 	scanID := strings.TrimSpace(r.URL.Query().Get("scanId"))
 	if scanID == "" {
 		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "scanId required"})
 		return
 	}
+	m, ok := s.currentOrg(w, r)
+	if !ok {
+		return
+	}
+	if _, ok := s.orgScan(w, r, m, scanID); !ok {
+		return
+	}
 	sum, full, err := s.Store.GetScanReport(r.Context(), scanID)
 	if err != nil {
 		writeJSON(w, http.StatusNotFound, map[string]any{"error": "report not found"})
@@ -4,15 +4,19 @@ import (
 	"encoding/json"
 	"net/http"
 	"strings"
-	"time"
 
 	"rbac-analyzer/internal/security"
+	"rbac-analyzer/internal/store"
 )
 
 type registerReq struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 	OrgName  string `json:"orgName"`
+
+	// InviteToken — регистрация по приглашению: пользователь вступает в организацию
+	// из приглашения вместо создания своей.
+	InviteToken string `json:"inviteToken"`
 }
 
 type loginReq struct {
@@ -20,8 +24,14 @@ type loginReq struct {
 	Password string `json:"password"`
 }
 
+// authResp — access token (Bearer) и refresh token сессии.
 type authResp struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresIn    int    `json:"expiresIn"` // секунды жизни access token
+
+	// RecoveryCodesLeft — вход по коду восстановления: сколько кодов осталось.
+	RecoveryCodesLeft *int `json:"recoveryCodesLeft,omitempty"`
 }
 
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
@@ -41,6 +51,10 @@ func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !s.allowIP(w, r, "register", limitRegisterIP) {
+		return
+	}
+
 	req.Email = strings.TrimSpace(strings.ToLower(req.Email))
 	if req.Email == "" || len(req.Password) < 8 {
 		http.Error(w, "email required and password >= 8", http.StatusBadRequest)
@@ -50,6 +64,22 @@ func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
 		req.OrgName = "My Organization"
 	}
 
+	var inv store.Invitation
+	if req.InviteToken != "" {
+		var ok bool
+		if inv, ok = s.pendingInvitation(w, r, req.InviteToken); !ok {
+			return
+		}
+		if inv.Email != req.Email {
+			http.Error(w, "invitation was sent to another email", http.StatusForbidden)
+			return
+		}
+		if cfg, err := s.Store.GetOrgSSO(r.Context(), inv.OrgID); err == nil && cfg.Enforce {
+			http.Error(w, "sso required: sign in with SSO to join this organization", http.StatusForbidden)
+			return
+		}
+	}
+
 	hash, err := security.HashPassword(req.Password)
 	if err != nil {
 		http.Error(w, "hash error", http.StatusInternalServerError)
@@ -62,20 +92,25 @@ func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = s.Store.CreateOrgForOwner(r.Context(), u.ID, req.OrgName)
+	if inv.ID != "" {
+		_, err = s.Store.AcceptInvitation(r.Context(), inv.ID, u.ID)
+	} else {
+		_, err = s.Store.CreateOrgForOwner(r.Context(), u.ID, req.OrgName)
+	}
 	if err != nil {
 		http.Error(w, "create org failed: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	token, _ := security.SignJWT([]byte(s.Cfg.JWTSecret), security.Claims{
-		Sub:   u.ID,
-		Email: u.Email,
-		Admin: u.IsAdmin,
-		Exp:   time.Now().Add(7 * 24 * time.Hour).Unix(),
-	})
+	// приглашение адрес не подтверждает: ссылку с токеном видит и пригласивший (acceptUrl)
+	_ = s.sendAccountMail(r.Context(), u, store.TokenVerifyEmail)
 
-	writeJSON(w, http.StatusOK, authResp{Token: token})
+	resp, err := s.startSession(r.Context(), r, u)
+	if err != nil {
+		http.Error(w, "session error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
 }
 
 func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
@@ -96,22 +131,45 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// лимит на адрес клиента и блокировка аккаунта после неудачных попыток;
+	// неизвестный email блокируется так же, чтобы по ответу нельзя было узнать, есть ли аккаунт
+	account := "login:" + req.Email
+	if !s.allowIP(w, r, "login", limitLoginIP) || s.accountLocked(w, r, account) {
+		return
+	}
 	u, err := s.Store.GetUserByEmail(r.Context(), req.Email)
-	if err != nil {
+	if err != nil || !security.CheckPassword(u.PasswordHash, req.Password) {
+		s.authFailed(r.Context(), account)
 		http.Error(w, "invalid credentials", http.StatusUnauthorized)
 		return
 	}
-	if !security.CheckPassword(u.PasswordHash, req.Password) {
-		http.Error(w, "invalid credentials", http.StatusUnauthorized)
+	s.authSucceeded(r.Context(), account)
+	// организация с обязательным SSO: пароль верный, но входить нужно через IdP
+	orgID, err := s.ssoEnforcedOrg(r.Context(), u.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if orgID != "" {
+		http.Error(w, "sso required: sign in with SSO", http.StatusForbidden)
 		return
 	}
 
-	token, _ := security.SignJWT([]byte(s.Cfg.JWTSecret), security.Claims{
-		Sub:   u.ID,
-		Email: u.Email,
-		Admin: u.IsAdmin,
-		Exp:   time.Now().Add(7 * 24 * time.Hour).Unix(),
-	})
+	// включена MFA — сессии пока нет, нужен код (POST /api/auth/mfa)
+	challenge, err := s.mfaChallengeFor(r.Context(), u)
+	if err != nil {
+		http.Error(w, "mfa error", http.StatusInternalServerError)
+		return
+	}
+	if challenge != nil {
+		writeJSON(w, http.StatusOK, challenge)
+		return
+	}
 
-	writeJSON(w, http.StatusOK, authResp{Token: token})
+	resp, err := s.startSession(r.Context(), r, u)
+	if err != nil {
+		http.Error(w, "session error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
 }
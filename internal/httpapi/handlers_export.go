@@ -0,0 +1,104 @@
+package httpapi
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"rbac-analyzer/internal/output"
+	"rbac-analyzer/internal/rbac"
+)
+
+// GET /api/app/scans/{id}/export.html
+// GET /api/app/scans/{id}/export.csv?granularity=permission|role&bom=1
+//
+// Фильтры (как у CLI): dangerOnly=1, namespace=, subject=, kind=, role= (повторяемые).
+func (s *Server) handleScanExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 5 {
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": "not found"})
+		return
+	}
+	scanID, name := parts[3], parts[4]
+
+	perms, ok := s.ownedScanPerms(w, r, scanID)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	filters := filtersFromQuery(q)
+
+	var buf bytes.Buffer
+	var contentType string
+	var err error
+
+	switch name {
+	case "export.html":
+		contentType = "text/html; charset=utf-8"
+		err = output.PrintHTML(&buf, perms, filters, "RBAC Report — scan "+scanID)
+	case "export.csv":
+		granularity, gerr := output.ParseCSVGranularity(q.Get("granularity"))
+		if gerr != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": gerr.Error()})
+			return
+		}
+		contentType = "text/csv; charset=utf-8"
+		err = output.PrintCSV(&buf, perms, filters, output.CSVOptions{
+			Granularity: granularity,
+			BOM:         q.Get("bom") == "1",
+		})
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": "unknown export format"})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="rbac-report-`+scanID+"."+strings.TrimPrefix(name, "export.")+`"`)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(buf.Bytes())
+}
+
+// ownedScanPerms загружает эффективные права из отчёта скана текущей организации.
+// При ошибке ответ уже записан и возвращается false.
+func (s *Server) ownedScanPerms(w http.ResponseWriter, r *http.Request, scanID string) (rbac.SubjectPermissions, bool) {
+	m, ok := s.currentOrg(w, r)
+	if !ok {
+		return nil, false
+	}
+	if _, ok := s.orgScan(w, r, m, scanID); !ok {
+		return nil, false
+	}
+
+	_, full, err := s.Store.GetScanReport(r.Context(), scanID)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": "report not found"})
+		return nil, false
+	}
+	perms, err := subjectPermsFromReport(full)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return nil, false
+	}
+	return perms, true
+}
+
+func filtersFromQuery(q url.Values) output.Filters {
+	return output.Filters{
+		DangerOnly: q.Get("dangerOnly") == "1" || q.Get("dangerOnly") == "true",
+		Namespaces: q["namespace"],
+		Subjects:   q["subject"],
+		Kinds:      q["kind"],
+		Roles:      q["role"],
+	}
+}
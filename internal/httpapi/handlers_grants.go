@@ -0,0 +1,331 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"rbac-analyzer/internal/store"
+)
+
+// handleClusterItem разбирает /api/app/clusters/{id}[/grants[/{subjectType}/{subjectId}]]:
+//
+//	PATCH  /api/app/clusters/{id} {"restricted": true}
+//	GET    /api/app/clusters/{id}/grants
+//	PUT    /api/app/clusters/{id}/grants {"subjectType":"user"|"team","subjectId","role"}
+//	DELETE /api/app/clusters/{id}/grants/{subjectType}/{subjectId}
+//
+// Всё это — для admin кластера (owner/admin организации или грант admin).
+func (s *Server) handleClusterItem(w http.ResponseWriter, r *http.Request) {
+	m, ok := s.currentOrg(w, r)
+	if !ok {
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	// api/app/clusters/{id}[/grants[/{type}/{subjectId}]]
+	validLen := len(parts) == 4 || len(parts) == 5 || len(parts) == 7
+	if !validLen || len(parts) > 4 && parts[4] != "grants" {
+		http.NotFound(w, r)
+		return
+	}
+
+	c, ok := s.orgCluster(w, r, m, parts[3], store.ClusterAdmin)
+	if !ok {
+		return
+	}
+
+	switch {
+	case len(parts) == 4 && r.Method == http.MethodPatch:
+		var req struct {
+			Restricted *bool `json:"restricted"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Restricted == nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "restricted required"})
+			return
+		}
+		if err := s.Store.SetClusterRestricted(r.Context(), c.ID, *req.Restricted); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		c.Restricted = *req.Restricted
+		writeJSON(w, http.StatusOK, c)
+
+	case len(parts) == 5 && r.Method == http.MethodGet:
+		grants, err := s.Store.ListClusterGrants(r.Context(), m.Org.ID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		out := []store.ClusterGrant{}
+		for _, g := range grants {
+			if g.ClusterID == c.ID {
+				out = append(out, g)
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"cluster": c, "grants": out})
+
+	case len(parts) == 5 && r.Method == http.MethodPut:
+		var g store.ClusterGrant
+		if err := json.NewDecoder(r.Body).Decode(&g); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "bad json"})
+			return
+		}
+		if !store.ValidClusterRole(g.Role) {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "role must be viewer, uploader or admin"})
+			return
+		}
+		if !s.grantSubjectInOrg(w, r, m, g.SubjectType, g.SubjectID) {
+			return
+		}
+		g.ClusterID, g.CreatedBy = c.ID, GetUserID(r)
+		if err := s.Store.SetClusterGrant(r.Context(), g); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+
+	case len(parts) == 7 && r.Method == http.MethodDelete:
+		if err := s.Store.DeleteClusterGrant(r.Context(), c.ID, parts[5], parts[6]); err != nil {
+			if store.IsNotFound(err) {
+				writeJSON(w, http.StatusNotFound, map[string]any{"error": "grant not found"})
+				return
+			}
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// grantSubjectInOrg: получатель гранта — участник или команда этой организации.
+func (s *Server) grantSubjectInOrg(w http.ResponseWriter, r *http.Request, m store.Membership, subjectType, subjectID string) bool {
+	switch subjectType {
+	case store.SubjectUser:
+		if _, err := s.Store.GetMembership(r.Context(), m.Org.ID, subjectID); err != nil {
+			if store.IsNotFound(err) {
+				writeJSON(w, http.StatusBadRequest, map[string]any{"error": "user is not a member of this org"})
+				return false
+			}
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return false
+		}
+		return true
+
+	case store.SubjectTeam:
+		if _, ok := s.orgTeam(w, r, m, subjectID); !ok {
+			return false
+		}
+		return true
+
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "subjectType must be user or team"})
+		return false
+	}
+}
+
+// orgTeam находит команду организации. При ошибке ответ уже записан и возвращается false.
+func (s *Server) orgTeam(w http.ResponseWriter, r *http.Request, m store.Membership, teamID string) (store.Team, bool) {
+	teams, err := s.Store.ListTeams(r.Context(), m.Org.ID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return store.Team{}, false
+	}
+	for _, t := range teams {
+		if t.ID == teamID {
+			return t, true
+		}
+	}
+	writeJSON(w, http.StatusNotFound, map[string]any{"error": "team not found"})
+	return store.Team{}, false
+}
+
+// GET    /api/app/org/teams — команды организации
+// POST   /api/app/org/teams {"name"} — admin+
+// DELETE /api/app/org/teams/{id} — admin+, гранты команды удаляются
+// PUT    /api/app/org/teams/{id}/members/{userId} — admin+
+// DELETE /api/app/org/teams/{id}/members/{userId} — admin+
+func (s *Server) handleOrgTeams(w http.ResponseWriter, r *http.Request, m store.Membership, rest []string) {
+	if len(rest) == 0 && r.Method == http.MethodGet {
+		teams, err := s.Store.ListTeams(r.Context(), m.Org.ID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		if teams == nil {
+			teams = []store.Team{}
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"teams": teams})
+		return
+	}
+
+	if !requireOrgRole(w, m, store.RoleAdmin) {
+		return
+	}
+
+	switch {
+	case len(rest) == 0 && r.Method == http.MethodPost:
+		var req struct {
+			Name string `json:"name"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "bad json"})
+			return
+		}
+		req.Name = strings.TrimSpace(req.Name)
+		if req.Name == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "name required"})
+			return
+		}
+		teams, err := s.Store.ListTeams(r.Context(), m.Org.ID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		for _, t := range teams {
+			if strings.EqualFold(t.Name, req.Name) {
+				writeJSON(w, http.StatusConflict, map[string]any{"error": "team already exists"})
+				return
+			}
+		}
+		t, err := s.Store.CreateTeam(r.Context(), m.Org.ID, req.Name)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, t)
+
+	case len(rest) == 1 && r.Method == http.MethodDelete:
+		if err := s.Store.DeleteTeam(r.Context(), m.Org.ID, rest[0]); err != nil {
+			if store.IsNotFound(err) {
+				writeJSON(w, http.StatusNotFound, map[string]any{"error": "team not found"})
+				return
+			}
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+
+	case len(rest) == 3 && rest[1] == "members" && (r.Method == http.MethodPut || r.Method == http.MethodDelete):
+		t, ok := s.orgTeam(w, r, m, rest[0])
+		if !ok {
+			return
+		}
+		userID := rest[2]
+		var err error
+		if r.Method == http.MethodPut {
+			if !s.grantSubjectInOrg(w, r, m, store.SubjectUser, userID) {
+				return
+			}
+			err = s.Store.AddTeamMember(r.Context(), t.ID, userID)
+		} else {
+			err = s.Store.RemoveTeamMember(r.Context(), t.ID, userID)
+		}
+		if err != nil {
+			if store.IsNotFound(err) {
+				writeJSON(w, http.StatusNotFound, map[string]any{"error": "team member not found"})
+				return
+			}
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// effectiveGrant — итоговая роль участника на кластере и её источники.
+type effectiveGrant struct {
+	UserID      string   `json:"userId"`
+	Email       string   `json:"email"`
+	OrgRole     string   `json:"orgRole"`
+	ClusterID   string   `json:"clusterId"`
+	ClusterName string   `json:"clusterName"`
+	Role        string   `json:"role"`
+	Sources     []string `json:"sources"`
+}
+
+// GET /api/admin/orgs/{id}/grants — кто какие кластеры организации видит и почему
+// (для админки: участники × кластеры, команды и сами гранты).
+func (s *Server) handleAdminOrgGrants(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 5 || parts[4] != "grants" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	orgID := parts[3]
+
+	org, err := s.Store.GetOrg(r.Context(), orgID)
+	if err != nil {
+		if store.IsNotFound(err) {
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": "org not found"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	members, err := s.Store.ListMembers(r.Context(), orgID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	clusters, err := s.Store.ListClusters(r.Context(), orgID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	teams, err := s.Store.ListTeams(r.Context(), orgID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	grants, err := s.Store.ListClusterGrants(r.Context(), orgID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+
+	effective := []effectiveGrant{}
+	for _, mb := range members {
+		a := &clusterAccess{orgRole: mb.Role, userID: mb.UserID, teams: map[string]string{}, grants: grants}
+		for _, t := range teams {
+			for _, id := range t.MemberIDs {
+				if id == mb.UserID {
+					a.teams[t.ID] = t.Name
+				}
+			}
+		}
+		for _, c := range clusters {
+			role, sources := a.role(c)
+			if role == "" {
+				continue
+			}
+			effective = append(effective, effectiveGrant{
+				UserID:      mb.UserID,
+				Email:       mb.Email,
+				OrgRole:     mb.Role,
+				ClusterID:   c.ID,
+				ClusterName: c.Name,
+				Role:        role,
+				Sources:     sources,
+			})
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"org":       org,
+		"clusters":  clusters,
+		"teams":     teams,
+		"grants":    grants,
+		"effective": effective,
+	})
+}
@@ -0,0 +1,154 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+// join регистрирует email и принимает приглашение owner в организацию orgID.
+func (e *testEnv) join(owner, orgID, email string) (token, userID string) {
+	e.t.Helper()
+	inviteToken := e.invite(owner, orgID, email, "member")
+	var reg authResp
+	if code := e.doJSON(http.MethodPost, "/api/auth/register", "", map[string]string{
+		"email": email, "password": "password123", "inviteToken": inviteToken,
+	}, &reg); code != http.StatusOK {
+		e.t.Fatalf("register %s by invitation: status %d", email, code)
+	}
+	return reg.Token, e.userID(email)
+}
+
+func (e *testEnv) visibleClusters(token string) map[string]string {
+	e.t.Helper()
+	var list struct {
+		Clusters []struct{ ID, Name string }
+		Roles    map[string]string `json:"roles"`
+	}
+	if code := e.doJSON(http.MethodGet, "/api/app/clusters", token, nil, &list); code != http.StatusOK {
+		e.t.Fatalf("clusters: status %d", code)
+	}
+	out := map[string]string{}
+	for _, c := range list.Clusters {
+		out[c.Name] = list.Roles[c.ID]
+	}
+	return out
+}
+
+func TestClusterGrants(t *testing.T) {
+	e := newTestEnv(t)
+	owner := e.register("owner@example.com")
+	orgID := e.me(owner, "").Org.ID
+	if err := e.store.AdminSetOrgPlan(context.Background(), orgID, "pro"); err != nil {
+		t.Fatal(err)
+	}
+
+	var prod struct{ ID string }
+	if code := e.doJSON(http.MethodPost, "/api/app/clusters", owner,
+		map[string]any{"name": "prod", "restricted": true}, &prod); code != http.StatusOK {
+		t.Fatalf("create restricted cluster: status %d", code)
+	}
+	stagingID := e.createCluster(owner, "staging")
+	prodScan := e.upload(owner, prod.ID, testRBAC)
+	stagingScan := e.upload(owner, stagingID, testRBAC)
+
+	sre, sreID := e.join(owner, orgID, "sre@example.com")
+	contractor, contractorID := e.join(owner, orgID, "contractor@example.com")
+
+	// команда sre видит prod, подрядчик загружает только в staging
+	var team struct{ ID string }
+	if code := e.doJSON(http.MethodPost, "/api/app/org/teams", owner, map[string]string{"name": "sre"}, &team); code != http.StatusOK {
+		t.Fatalf("create team: status %d", code)
+	}
+	if code := e.doJSON(http.MethodPut, "/api/app/org/teams/"+team.ID+"/members/"+sreID, owner, nil, nil); code != http.StatusOK {
+		t.Fatalf("add team member: status %d", code)
+	}
+	for _, g := range []struct{ cluster, typ, id, role string }{
+		{prod.ID, "team", team.ID, "viewer"},
+		{stagingID, "user", contractorID, "uploader"},
+	} {
+		if code := e.doJSON(http.MethodPut, "/api/app/clusters/"+g.cluster+"/grants", owner,
+			map[string]string{"subjectType": g.typ, "subjectId": g.id, "role": g.role}, nil); code != http.StatusOK {
+			t.Fatalf("grant %s %s: status %d", g.typ, g.role, code)
+		}
+	}
+
+	if got := e.visibleClusters(contractor); len(got) != 1 || got["staging"] != "uploader" {
+		t.Fatalf("contractor clusters = %v, want only staging (uploader)", got)
+	}
+	if got := e.visibleClusters(sre); got["prod"] != "viewer" || got["staging"] != "viewer" {
+		t.Fatalf("sre clusters = %v", got)
+	}
+
+	// prod для подрядчика не существует: ни отчёт, ни diff, ни история, ни экспорт
+	for _, c := range []struct {
+		method, path string
+		body         any
+	}{
+		{http.MethodGet, "/api/app/scan/report?scanId=" + prodScan, nil},
+		{http.MethodPost, "/api/app/scans/diff", map[string]string{"baseId": stagingScan, "targetId": prodScan}},
+		{http.MethodGet, "/api/app/scans?clusterId=" + prod.ID, nil},
+		{http.MethodGet, "/api/app/scans/" + prodScan + "/export.json", nil},
+	} {
+		if code := e.doJSON(c.method, c.path, contractor, c.body, nil); code != http.StatusNotFound {
+			t.Errorf("contractor %s %s: status %d, want 404", c.method, c.path, code)
+		}
+	}
+	if code := e.doJSON(http.MethodGet, "/api/app/scan/report?scanId="+stagingScan, contractor, nil, nil); code != http.StatusOK {
+		t.Fatalf("contractor staging report: status %d", code)
+	}
+	e.upload(contractor, stagingID, testRBAC)
+
+	// viewer не загружает и не управляет грантами
+	if code := e.doJSON(http.MethodPost, "/api/app/scans/diff", sre,
+		map[string]string{"baseId": stagingScan, "targetId": prodScan}, nil); code != http.StatusOK {
+		t.Fatalf("sre diff: status %d", code)
+	}
+	if code := e.doJSON(http.MethodGet, "/api/app/clusters/"+prod.ID+"/grants", sre, nil, nil); code != http.StatusForbidden {
+		t.Fatalf("viewer lists grants: status %d, want 403", code)
+	}
+
+	// админка: эффективные гранты с источниками
+	_ = e.store.AdminSetUserAdmin(context.Background(), e.userID("owner@example.com"), true)
+	admin, _ := e.login("owner@example.com", "password123")
+	var eff struct {
+		Effective []struct {
+			Email       string   `json:"email"`
+			ClusterName string   `json:"clusterName"`
+			Role        string   `json:"role"`
+			Sources     []string `json:"sources"`
+		} `json:"effective"`
+	}
+	if code := e.doJSON(http.MethodGet, "/api/admin/orgs/"+orgID+"/grants", admin, nil, &eff); code != http.StatusOK {
+		t.Fatalf("admin grants: status %d", code)
+	}
+	found := false
+	for _, g := range eff.Effective {
+		if g.Email == "contractor@example.com" && g.ClusterName == "prod" {
+			t.Errorf("contractor has effective access to prod: %+v", g)
+		}
+		if g.Email == "sre@example.com" && g.ClusterName == "prod" {
+			found = len(g.Sources) == 1 && g.Sources[0] == "team:sre:viewer"
+		}
+	}
+	if !found {
+		t.Fatalf("sre prod access via team not listed: %+v", eff.Effective)
+	}
+
+	// без команды prod снова скрыт
+	if code := e.doJSON(http.MethodDelete, "/api/app/org/teams/"+team.ID+"/members/"+sreID, owner, nil, nil); code != http.StatusOK {
+		t.Fatalf("remove team member: status %d", code)
+	}
+	if got := e.visibleClusters(sre); len(got) != 1 {
+		t.Fatalf("sre clusters after leaving team = %v", got)
+	}
+}
+
+func (e *testEnv) userID(email string) string {
+	e.t.Helper()
+	u, err := e.store.GetUserByEmail(context.Background(), email)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return u.ID
+}
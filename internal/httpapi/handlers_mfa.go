@@ -0,0 +1,398 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"rbac-analyzer/internal/security"
+	"rbac-analyzer/internal/store"
+)
+
+// Второй шаг входа: после пароля (или SSO) пользователь с MFA получает не сессию,
+// а короткий mfaToken и обменивает его вместе с кодом на POST /api/auth/mfa.
+const (
+	mfaTokenTTL      = 5 * time.Minute
+	mfaIssuer        = "RBAC Analyzer"
+	mfaRecoveryCodes = 10
+	mfaRequiredMsg   = "mfa required: this organization requires two-factor authentication"
+	mfaInvalidCode   = "invalid code"
+)
+
+// mfaChallenge — ответ входа, когда нужен код второго фактора.
+type mfaChallenge struct {
+	MFARequired bool   `json:"mfaRequired"`
+	MFAToken    string `json:"mfaToken"`
+	ExpiresIn   int    `json:"expiresIn"`
+}
+
+// mfaEnabled: подключена ли у пользователя MFA (подтверждённая кодом).
+func (s *Server) mfaEnabled(ctx context.Context, userID string) (bool, error) {
+	m, err := s.Store.GetMFA(ctx, userID)
+	if store.IsNotFound(err) {
+		return false, nil
+	}
+	return m.Enabled(), err
+}
+
+// mfaChallengeFor возвращает mfaChallenge, если у пользователя включена MFA;
+// иначе nil — можно сразу начинать сессию.
+func (s *Server) mfaChallengeFor(ctx context.Context, u store.User) (*mfaChallenge, error) {
+	on, err := s.mfaEnabled(ctx, u.ID)
+	if err != nil || !on {
+		return nil, err
+	}
+	token, err := s.Keys.Sign(security.Claims{
+		Sub:     u.ID,
+		Email:   u.Email,
+		Exp:     time.Now().Add(mfaTokenTTL).Unix(),
+		Purpose: security.PurposeMFA,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &mfaChallenge{MFARequired: true, MFAToken: token, ExpiresIn: int(mfaTokenTTL.Seconds())}, nil
+}
+
+// checkSecondFactor принимает TOTP-код (6 цифр) или код восстановления.
+// Каждый код срабатывает один раз. recovery — использован код восстановления.
+func (s *Server) checkSecondFactor(ctx context.Context, m store.UserMFA, code string) (ok, recovery bool, err error) {
+	code = strings.TrimSpace(code)
+	if step, valid := security.CheckTOTP(m.Secret, code, time.Now()); valid {
+		ok, err = s.Store.UseMFAStep(ctx, m.UserID, step)
+		return ok, false, err
+	}
+	if len(code) < 10 {
+		return false, false, nil
+	}
+	ok, err = s.Store.UseRecoveryCode(ctx, m.UserID, security.HashRecoveryCode(code))
+	return ok, ok, err
+}
+
+// POST /api/auth/mfa {"mfaToken", "code"} — второй шаг входа; code — TOTP или код восстановления.
+func (s *Server) handleMFALogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		MFAToken string `json:"mfaToken"`
+		Code     string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "bad json"})
+		return
+	}
+
+	if !s.allowIP(w, r, "login", limitLoginIP) {
+		return
+	}
+	claims, err := s.Keys.Verify(req.MFAToken)
+	if err != nil || claims.Purpose != security.PurposeMFA {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "sign-in expired, start again"})
+		return
+	}
+	// новый mfaToken после пароля не сбрасывает блокировку: она привязана к пользователю
+	if s.accountLocked(w, r, "mfa:"+claims.Sub) {
+		return
+	}
+	m, err := s.Store.GetMFA(r.Context(), claims.Sub)
+	if err != nil || !m.Enabled() {
+		// MFA сбросили, пока пользователь вводил код
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "sign-in expired, start again"})
+		return
+	}
+	ok, recovery, err := s.checkSecondFactor(r.Context(), m, req.Code)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	if !ok {
+		s.authFailed(r.Context(), "mfa:"+claims.Sub)
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"error": mfaInvalidCode})
+		return
+	}
+	s.authSucceeded(r.Context(), "mfa:"+claims.Sub)
+
+	u, err := s.Store.GetUser(r.Context(), claims.Sub)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "sign-in expired, start again"})
+		return
+	}
+	resp, err := s.startSession(r.Context(), r, u)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "session error"})
+		return
+	}
+	if recovery {
+		left := len(m.RecoveryHashes) - 1
+		resp.RecoveryCodesLeft = &left
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleMFA — второй фактор текущего пользователя:
+//
+//	GET    /api/app/mfa                — состояние (enabled, recoveryCodesLeft, required)
+//	POST   /api/app/mfa/enroll         — новый секрет и otpauth:// ссылка (для QR-кода)
+//	POST   /api/app/mfa/enable         {"code"} — подтвердить кодом; ответ — коды восстановления
+//	POST   /api/app/mfa/recovery-codes {"code"} — выпустить новые коды восстановления
+//	DELETE /api/app/mfa                {"code"} — отключить
+func (s *Server) handleMFA(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	action := ""
+	if len(parts) == 4 {
+		action = parts[3]
+	} else if len(parts) != 3 {
+		http.NotFound(w, r)
+		return
+	}
+
+	claims := GetClaims(r)
+	m, err := s.Store.GetMFA(r.Context(), claims.Sub)
+	if err != nil && !store.IsNotFound(err) {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+
+	var req struct {
+		Code string `json:"code"`
+	}
+	if r.Method == http.MethodPost || r.Method == http.MethodDelete {
+		_ = json.NewDecoder(r.Body).Decode(&req) // у enroll тела нет
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		required, err := s.mfaRequiredBy(r.Context(), claims.Sub)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"enabled":           m.Enabled(),
+			"recoveryCodesLeft": len(m.RecoveryHashes),
+			"requiredBy":        required,
+		})
+
+	case action == "enroll" && r.Method == http.MethodPost:
+		if m.Enabled() {
+			writeJSON(w, http.StatusConflict, map[string]any{"error": "mfa already enabled"})
+			return
+		}
+		secret, err := security.NewTOTPSecret()
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		if err := s.Store.PutMFA(r.Context(), store.UserMFA{UserID: claims.Sub, Secret: secret}); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"secret":     secret,
+			"otpauthUrl": security.TOTPURI(mfaIssuer, claims.Email, secret),
+		})
+
+	case action == "enable" && r.Method == http.MethodPost:
+		if m.UserID == "" || m.Enabled() {
+			writeJSON(w, http.StatusConflict, map[string]any{"error": "start enrollment first"})
+			return
+		}
+		step, ok := security.CheckTOTP(m.Secret, req.Code, time.Now())
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": mfaInvalidCode})
+			return
+		}
+		codes, hashes, err := security.NewRecoveryCodes(mfaRecoveryCodes)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		now := time.Now().UTC()
+		m.EnabledAt, m.LastStep, m.RecoveryHashes = &now, step, hashes
+		if err := s.Store.PutMFA(r.Context(), m); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		// сессии на других устройствах открыты без второго фактора
+		n, err := s.Store.RevokeUserSessions(r.Context(), claims.Sub, claims.Sid)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true, "recoveryCodes": codes, "revokedSessions": n})
+
+	case action == "recovery-codes" && r.Method == http.MethodPost:
+		if !s.requireSecondFactor(w, r, m, req.Code) {
+			return
+		}
+		codes, hashes, err := security.NewRecoveryCodes(mfaRecoveryCodes)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		// шаг мог сдвинуться при проверке кода — берём свежую запись
+		if m, err = s.Store.GetMFA(r.Context(), claims.Sub); err == nil {
+			m.RecoveryHashes = hashes
+			err = s.Store.PutMFA(r.Context(), m)
+		}
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true, "recoveryCodes": codes})
+
+	case action == "" && r.Method == http.MethodDelete:
+		required, err := s.mfaRequiredBy(r.Context(), claims.Sub)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		if len(required) > 0 {
+			writeJSON(w, http.StatusForbidden, map[string]any{"error": "organization " + required[0] + " requires two-factor authentication"})
+			return
+		}
+		if !s.requireSecondFactor(w, r, m, req.Code) {
+			return
+		}
+		if err := s.Store.DeleteMFA(r.Context(), claims.Sub); err != nil && !store.IsNotFound(err) {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+
+	case action == "" || action == "enroll" || action == "enable" || action == "recovery-codes":
+		w.WriteHeader(http.StatusMethodNotAllowed)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// requireSecondFactor: изменения MFA подтверждаются текущим кодом; при ошибке ответ уже записан.
+func (s *Server) requireSecondFactor(w http.ResponseWriter, r *http.Request, m store.UserMFA, code string) bool {
+	if !m.Enabled() {
+		writeJSON(w, http.StatusConflict, map[string]any{"error": "mfa is not enabled"})
+		return false
+	}
+	// подбор кода из чужой сессии блокируется так же, как при входе
+	if s.accountLocked(w, r, "mfa:"+m.UserID) {
+		return false
+	}
+	ok, _, err := s.checkSecondFactor(r.Context(), m, code)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return false
+	}
+	if !ok {
+		s.authFailed(r.Context(), "mfa:"+m.UserID)
+		// 403, а не 401: клиент на 401 обновляет токен и повторяет запрос
+		writeJSON(w, http.StatusForbidden, map[string]any{"error": mfaInvalidCode})
+		return false
+	}
+	s.authSucceeded(r.Context(), "mfa:"+m.UserID)
+	return true
+}
+
+// mfaRequiredBy — названия организаций пользователя с политикой «обязательная MFA».
+func (s *Server) mfaRequiredBy(ctx context.Context, userID string) ([]string, error) {
+	orgs, err := s.Store.ListUserOrgs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	out := []string{}
+	for _, m := range orgs {
+		if m.Org.RequireMFA {
+			out = append(out, m.Org.Name)
+		}
+	}
+	return out, nil
+}
+
+// requireOrgMFA — политика организации: без MFA доступа к её данным нет.
+// При отказе ответ уже записан.
+func (s *Server) requireOrgMFA(w http.ResponseWriter, r *http.Request, m store.Membership) bool {
+	if !m.Org.RequireMFA {
+		return true
+	}
+	on, err := s.mfaEnabled(r.Context(), GetUserID(r))
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return false
+	}
+	if !on {
+		writeJSON(w, http.StatusForbidden, map[string]any{"error": mfaRequiredMsg, "mfaSetupRequired": true})
+		return false
+	}
+	return true
+}
+
+// PUT /api/app/org/mfa {"require": true} — политика организации (только owner).
+// Включить её может только владелец, у которого MFA уже подключена.
+func (s *Server) handleOrgMFA(w http.ResponseWriter, r *http.Request, m store.Membership) {
+	if r.Method != http.MethodPut {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !requireOrgRole(w, m, store.RoleOwner) {
+		return
+	}
+	var req struct {
+		Require bool `json:"require"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "bad json"})
+		return
+	}
+	if req.Require {
+		on, err := s.mfaEnabled(r.Context(), GetUserID(r))
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		if !on {
+			writeJSON(w, http.StatusConflict, map[string]any{"error": "enable two-factor authentication for your account first"})
+			return
+		}
+	}
+	if err := s.Store.SetOrgRequireMFA(r.Context(), m.Org.ID, req.Require); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+
+	// кто из участников потеряет доступ, пока не подключит MFA
+	members, err := s.Store.ListMembers(r.Context(), m.Org.ID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	without := []string{}
+	for _, mb := range members {
+		if on, err := s.mfaEnabled(r.Context(), mb.UserID); err == nil && !on {
+			without = append(without, mb.Email)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "requireMfa": req.Require, "membersWithoutMfa": without})
+}
+
+// DELETE /api/admin/users/{id}/mfa — сбросить MFA пользователя (потерян телефон и коды).
+func (s *Server) handleAdminUserMFA(w http.ResponseWriter, r *http.Request, userID string) {
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := s.Store.DeleteMFA(r.Context(), userID); err != nil {
+		if store.IsNotFound(err) {
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": "mfa is not set up for this user"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	_ = s.Store.AddAdminAudit(r.Context(), GetUserID(r), "reset_mfa", "user", userID,
+		map[string]any{"by_email": GetClaims(r).Email})
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
@@ -0,0 +1,152 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"rbac-analyzer/internal/security"
+)
+
+// enableMFA подключает TOTP пользователю token; возвращает секрет и коды восстановления.
+func (e *testEnv) enableMFA(token string) (string, []string) {
+	e.t.Helper()
+	var enroll struct {
+		Secret     string `json:"secret"`
+		OtpauthURL string `json:"otpauthUrl"`
+	}
+	if code := e.doJSON(http.MethodPost, "/api/app/mfa/enroll", token, nil, &enroll); code != http.StatusOK {
+		e.t.Fatalf("mfa enroll: status %d", code)
+	}
+	if !strings.HasPrefix(enroll.OtpauthURL, "otpauth://totp/") || !strings.Contains(enroll.OtpauthURL, "secret="+enroll.Secret) {
+		e.t.Fatalf("otpauth url = %q", enroll.OtpauthURL)
+	}
+	code, _ := security.TOTPCode(enroll.Secret, time.Now())
+	var enabled struct {
+		RecoveryCodes []string `json:"recoveryCodes"`
+	}
+	if status := e.doJSON(http.MethodPost, "/api/app/mfa/enable", token, map[string]string{"code": code}, &enabled); status != http.StatusOK || len(enabled.RecoveryCodes) != mfaRecoveryCodes {
+		e.t.Fatalf("mfa enable: status %d, %+v", status, enabled)
+	}
+	return enroll.Secret, enabled.RecoveryCodes
+}
+
+// mfaLogin — вход по паролю и коду; возвращает статус второго шага и ответ.
+func (e *testEnv) mfaLogin(email, code string) (authResp, int) {
+	e.t.Helper()
+	var challenge mfaChallenge
+	if status := e.doJSON(http.MethodPost, "/api/auth/login", "",
+		map[string]string{"email": email, "password": "password123"}, &challenge); status != http.StatusOK || !challenge.MFARequired {
+		e.t.Fatalf("login of %s: status %d, %+v, want MFA challenge", email, status, challenge)
+	}
+	var resp authResp
+	status := e.doJSON(http.MethodPost, "/api/auth/mfa", "", map[string]string{"mfaToken": challenge.MFAToken, "code": code}, &resp)
+	return resp, status
+}
+
+func TestMFAEnrollLoginAndRecovery(t *testing.T) {
+	e := newTestEnv(t)
+	owner := e.register("owner@example.com")
+	if code := e.doJSON(http.MethodPost, "/api/app/mfa/enable", owner, map[string]string{"code": "123456"}, nil); code != http.StatusConflict {
+		t.Fatalf("enable without enrollment: status %d, want 409", code)
+	}
+	secret, recovery := e.enableMFA(owner)
+
+	// после пароля сессии нет, а mfaToken не открывает API
+	var challenge mfaChallenge
+	e.doJSON(http.MethodPost, "/api/auth/login", "", map[string]string{"email": "owner@example.com", "password": "password123"}, &challenge)
+	if code := e.doJSON(http.MethodGet, "/api/app/me", challenge.MFAToken, nil, nil); code != http.StatusUnauthorized {
+		t.Fatalf("mfa token as access token: status %d, want 401", code)
+	}
+
+	// код шага, не более позднего, чем у подтверждения подключения, больше не принимается
+	used, _ := security.TOTPCode(secret, time.Now().Add(-30*time.Second))
+	if _, code := e.mfaLogin("owner@example.com", used); code != http.StatusUnauthorized {
+		t.Fatalf("replayed totp code: status %d, want 401", code)
+	}
+	next, _ := security.TOTPCode(secret, time.Now().Add(30*time.Second))
+	resp, code := e.mfaLogin("owner@example.com", next)
+	if code != http.StatusOK || resp.Token == "" || resp.RecoveryCodesLeft != nil {
+		t.Fatalf("totp login: status %d, %+v", code, resp)
+	}
+
+	// код восстановления — один раз, в любом регистре
+	resp, code = e.mfaLogin("owner@example.com", strings.ToUpper(recovery[0]))
+	if code != http.StatusOK || resp.RecoveryCodesLeft == nil || *resp.RecoveryCodesLeft != mfaRecoveryCodes-1 {
+		t.Fatalf("recovery login: status %d, %+v", code, resp)
+	}
+	if _, code := e.mfaLogin("owner@example.com", recovery[0]); code != http.StatusUnauthorized {
+		t.Fatalf("recovery code used twice: status %d, want 401", code)
+	}
+
+	if code := e.doJSON(http.MethodDelete, "/api/app/mfa", resp.Token, map[string]string{"code": "000000"}, nil); code != http.StatusForbidden {
+		t.Fatalf("disable with wrong code: status %d, want 403", code)
+	}
+	if code := e.doJSON(http.MethodDelete, "/api/app/mfa", resp.Token, map[string]string{"code": recovery[1]}, nil); code != http.StatusOK {
+		t.Fatalf("disable: status %d", code)
+	}
+	if token, code := e.login("owner@example.com", "password123"); code != http.StatusOK || token == "" {
+		t.Fatalf("login after disabling mfa: status %d", code)
+	}
+}
+
+func TestOrgRequireMFAAndAdminReset(t *testing.T) {
+	e := newTestEnv(t)
+	owner := e.register("owner@example.com")
+	orgID := e.me(owner, "").Org.ID
+	e.createCluster(owner, "prod")
+	bob := e.register("bob@example.com")
+	invite := e.invite(owner, orgID, "bob@example.com", "member")
+	e.doJSON(http.MethodPost, "/api/app/invitations/accept", bob, map[string]string{"token": invite}, nil)
+
+	policy := map[string]bool{"require": true}
+	if code := e.doJSON(http.MethodPut, "/api/app/org/mfa?orgId="+orgID, owner, policy, nil); code != http.StatusConflict {
+		t.Fatalf("require mfa without own mfa: status %d, want 409", code)
+	}
+	e.enableMFA(owner)
+	var set struct {
+		MembersWithoutMfa []string `json:"membersWithoutMfa"`
+	}
+	if code := e.doJSON(http.MethodPut, "/api/app/org/mfa?orgId="+orgID, owner, policy, &set); code != http.StatusOK || len(set.MembersWithoutMfa) != 1 || set.MembersWithoutMfa[0] != "bob@example.com" {
+		t.Fatalf("require mfa: status %d, %+v", code, set)
+	}
+
+	// без MFA данные организации закрыты, но /me подсказывает, что делать
+	if code := e.doJSON(http.MethodGet, "/api/app/clusters?orgId="+orgID, bob, nil, nil); code != http.StatusForbidden {
+		t.Fatalf("member without mfa: status %d, want 403", code)
+	}
+	var me struct{ MfaSetupRequired bool }
+	if code := e.doJSON(http.MethodGet, "/api/app/me?orgId="+orgID, bob, nil, &me); code != http.StatusOK || !me.MfaSetupRequired {
+		t.Fatalf("me of member without mfa: status %d, %+v", code, me)
+	}
+	_, recovery := e.enableMFA(bob)
+	if code := e.doJSON(http.MethodGet, "/api/app/clusters?orgId="+orgID, bob, nil, nil); code != http.StatusOK {
+		t.Fatalf("member with mfa: status %d", code)
+	}
+	if code := e.doJSON(http.MethodDelete, "/api/app/mfa", bob, map[string]string{"code": recovery[0]}, nil); code != http.StatusForbidden {
+		t.Fatalf("disable mfa required by org: status %d, want 403", code)
+	}
+
+	// администратор сбрасывает MFA (потерян телефон) — с записью в журнал
+	e.register("root@example.com")
+	_ = e.store.AdminSetUserAdmin(context.Background(), e.userID("root@example.com"), true)
+	root, _ := e.login("root@example.com", "password123")
+	if code := e.doJSON(http.MethodDelete, "/api/admin/users/"+e.userID("bob@example.com")+"/mfa", root, nil, nil); code != http.StatusOK {
+		t.Fatalf("admin reset mfa: status %d", code)
+	}
+	var audit struct {
+		Events []struct{ Action, TargetID string }
+	}
+	e.doJSON(http.MethodGet, "/api/admin/audit", root, nil, &audit)
+	if len(audit.Events) == 0 || audit.Events[0].Action != "reset_mfa" || audit.Events[0].TargetID != e.userID("bob@example.com") {
+		t.Fatalf("audit = %+v", audit.Events)
+	}
+	if token, code := e.login("bob@example.com", "password123"); code != http.StatusOK || token == "" {
+		t.Fatalf("login after mfa reset: status %d", code)
+	}
+	if code := e.doJSON(http.MethodGet, "/api/app/clusters?orgId="+orgID, bob, nil, nil); code != http.StatusForbidden {
+		t.Fatalf("member after mfa reset: status %d, want 403", code)
+	}
+}
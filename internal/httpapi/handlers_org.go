@@ -0,0 +1,457 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"rbac-analyzer/internal/mail"
+	"rbac-analyzer/internal/security"
+	"rbac-analyzer/internal/store"
+)
+
+// invitationTTL — срок действия приглашения в организацию.
+const invitationTTL = 7 * 24 * time.Hour
+
+// GET  /api/app/orgs — организации пользователя и роли в них
+// POST /api/app/orgs {"name"} — новая организация, пользователь становится владельцем
+func (s *Server) handleOrgs(w http.ResponseWriter, r *http.Request) {
+	userID := GetUserID(r)
+
+	switch r.Method {
+
+	case http.MethodGet:
+		orgs, err := s.Store.ListUserOrgs(r.Context(), userID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"orgs": orgs})
+
+	case http.MethodPost:
+		var req struct {
+			Name string `json:"name"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "bad json"})
+			return
+		}
+		req.Name = strings.TrimSpace(req.Name)
+		if req.Name == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "name required"})
+			return
+		}
+		org, err := s.Store.CreateOrgForOwner(r.Context(), userID, req.Name)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, store.Membership{Org: org, Role: store.RoleOwner})
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleOrg разбирает /api/app/org/{members,transfer,invitations,teams,sso,mfa}[/{id}] текущей организации.
+func (s *Server) handleOrg(w http.ResponseWriter, r *http.Request) {
+	m, ok := s.currentOrg(w, r)
+	if !ok {
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	// api/app/org/{section}[/{id}...]
+	if len(parts) < 4 {
+		http.NotFound(w, r)
+		return
+	}
+	if parts[3] == "teams" {
+		s.handleOrgTeams(w, r, m, parts[4:])
+		return
+	}
+	if parts[3] == "sso" && len(parts) == 4 {
+		s.handleOrgSSO(w, r, m)
+		return
+	}
+	if parts[3] == "mfa" && len(parts) == 4 {
+		s.handleOrgMFA(w, r, m)
+		return
+	}
+	if len(parts) > 5 {
+		http.NotFound(w, r)
+		return
+	}
+	id := ""
+	if len(parts) == 5 {
+		id = parts[4]
+	}
+
+	switch {
+	case parts[3] == "members" && id == "":
+		s.handleOrgMembers(w, r, m)
+	case parts[3] == "members":
+		s.handleOrgMember(w, r, m, id)
+	case parts[3] == "transfer" && id == "":
+		s.handleOrgTransfer(w, r, m)
+	case parts[3] == "invitations" && id == "":
+		s.handleOrgInvitations(w, r, m)
+	case parts[3] == "invitations":
+		s.handleOrgInvitationRevoke(w, r, m, id)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// GET /api/app/org/members
+func (s *Server) handleOrgMembers(w http.ResponseWriter, r *http.Request, m store.Membership) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	list, err := s.Store.ListMembers(r.Context(), m.Org.ID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"members": list})
+}
+
+// PATCH  /api/app/org/members/{userId} {"role":"admin"|"member"} — admin+
+// DELETE /api/app/org/members/{userId} — admin+ или сам участник (выход из организации)
+//
+// Владельца нельзя удалить или понизить: сначала передаётся владение (/api/app/org/transfer).
+func (s *Server) handleOrgMember(w http.ResponseWriter, r *http.Request, m store.Membership, userID string) {
+	self := userID == GetUserID(r)
+
+	target, err := s.Store.GetMembership(r.Context(), m.Org.ID, userID)
+	if err != nil {
+		if store.IsNotFound(err) {
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": "member not found"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+
+	switch r.Method {
+
+	case http.MethodPatch:
+		if !requireOrgRole(w, m, store.RoleAdmin) {
+			return
+		}
+		var req struct {
+			Role string `json:"role"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "bad json"})
+			return
+		}
+		if req.Role != store.RoleAdmin && req.Role != store.RoleMember {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "role must be admin or member"})
+			return
+		}
+		if target.Role == store.RoleOwner {
+			writeJSON(w, http.StatusConflict, map[string]any{"error": "owner role can only change via ownership transfer"})
+			return
+		}
+		if err := s.Store.SetMemberRole(r.Context(), m.Org.ID, userID, req.Role); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true, "userId": userID, "role": req.Role})
+
+	case http.MethodDelete:
+		if !self && !requireOrgRole(w, m, store.RoleAdmin) {
+			return
+		}
+		if target.Role == store.RoleOwner {
+			writeJSON(w, http.StatusConflict, map[string]any{"error": "transfer ownership before removing the owner"})
+			return
+		}
+		if err := s.Store.RemoveMember(r.Context(), m.Org.ID, userID); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// POST /api/app/org/transfer {"userId"} — только владелец; прежний владелец становится admin.
+func (s *Server) handleOrgTransfer(w http.ResponseWriter, r *http.Request, m store.Membership) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !requireOrgRole(w, m, store.RoleOwner) {
+		return
+	}
+
+	var req struct {
+		UserID string `json:"userId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "bad json"})
+		return
+	}
+	if req.UserID == "" || req.UserID == GetUserID(r) {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "userId of another member required"})
+		return
+	}
+
+	if err := s.Store.TransferOwnership(r.Context(), m.Org.ID, GetUserID(r), req.UserID); err != nil {
+		if store.IsNotFound(err) {
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": "member not found"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "ownerUserId": req.UserID})
+}
+
+// GET  /api/app/org/invitations — действующие приглашения (admin+)
+// POST /api/app/org/invitations {"email","role"} — пригласить (admin+)
+//
+// Приглашённому уходит письмо со ссылкой; она же возвращается в ответе (acceptUrl),
+// чтобы её можно было передать вручную. Принять приглашение может только пользователь
+// с тем же email.
+func (s *Server) handleOrgInvitations(w http.ResponseWriter, r *http.Request, m store.Membership) {
+	if !requireOrgRole(w, m, store.RoleAdmin) {
+		return
+	}
+
+	switch r.Method {
+
+	case http.MethodGet:
+		list, err := s.Store.ListInvitations(r.Context(), m.Org.ID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"invitations": list})
+
+	case http.MethodPost:
+		var req struct {
+			Email string `json:"email"`
+			Role  string `json:"role"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "bad json"})
+			return
+		}
+		req.Email = strings.TrimSpace(strings.ToLower(req.Email))
+		if req.Email == "" || !strings.Contains(req.Email, "@") {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "email required"})
+			return
+		}
+		if req.Role == "" {
+			req.Role = store.RoleMember
+		}
+		if req.Role != store.RoleAdmin && req.Role != store.RoleMember {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "role must be admin or member"})
+			return
+		}
+		// письма от имени неподтверждённого адреса не отправляем
+		inviter, err := s.Store.GetUser(r.Context(), GetUserID(r))
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		if !inviter.EmailVerified {
+			writeJSON(w, http.StatusForbidden, map[string]any{"error": "verify your email before inviting colleagues"})
+			return
+		}
+
+		members, err := s.Store.ListMembers(r.Context(), m.Org.ID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		for _, mb := range members {
+			if mb.Email == req.Email {
+				writeJSON(w, http.StatusConflict, map[string]any{"error": "already a member"})
+				return
+			}
+		}
+
+		token, hash, err := security.NewToken()
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		inv, err := s.Store.CreateInvitation(r.Context(), store.Invitation{
+			OrgID:     m.Org.ID,
+			Email:     req.Email,
+			Role:      req.Role,
+			TokenHash: hash,
+			InvitedBy: GetUserID(r),
+			ExpiresAt: time.Now().UTC().Add(invitationTTL),
+		})
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+
+		acceptURL := strings.TrimRight(s.Cfg.BaseURL, "/") + "/app?invite=" + url.QueryEscape(token)
+		mailErr := s.Mail.Send(r.Context(), mail.Message{
+			To:      inv.Email,
+			Subject: "Invitation to " + m.Org.Name + " on RBAC Analyzer",
+			Body: fmt.Sprintf("%s invited you to the organization %q as %s.\n\nAccept: %s\n\nThe link expires on %s.\n",
+				GetClaims(r).Email, m.Org.Name, inv.Role, acceptURL, inv.ExpiresAt.Format("2006-01-02 15:04 MST")),
+		})
+
+		writeJSON(w, http.StatusOK, map[string]any{
+			"invitation": inv,
+			"acceptUrl":  acceptURL,
+			"emailSent":  mailErr == nil,
+		})
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// DELETE /api/app/org/invitations/{id} — отозвать приглашение (admin+)
+func (s *Server) handleOrgInvitationRevoke(w http.ResponseWriter, r *http.Request, m store.Membership, id string) {
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !requireOrgRole(w, m, store.RoleAdmin) {
+		return
+	}
+	if err := s.Store.CloseInvitation(r.Context(), m.Org.ID, id, store.InvitationRevoked); err != nil {
+		if store.IsNotFound(err) {
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": "invitation not found"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+// pendingInvitation находит действующее приглашение по токену из ссылки.
+// При ошибке ответ уже записан и возвращается false.
+func (s *Server) pendingInvitation(w http.ResponseWriter, r *http.Request, token string) (store.Invitation, bool) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "token required"})
+		return store.Invitation{}, false
+	}
+	inv, err := s.Store.GetInvitationByTokenHash(r.Context(), security.HashToken(token))
+	if err != nil {
+		if store.IsNotFound(err) {
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": "invitation not found"})
+			return store.Invitation{}, false
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return store.Invitation{}, false
+	}
+	if inv.Status != store.InvitationPending {
+		writeJSON(w, http.StatusGone, map[string]any{"error": "invitation already " + inv.Status})
+		return store.Invitation{}, false
+	}
+	if !inv.ExpiresAt.After(time.Now()) {
+		writeJSON(w, http.StatusGone, map[string]any{"error": "invitation expired"})
+		return store.Invitation{}, false
+	}
+	return inv, true
+}
+
+// POST /api/app/invitations/accept {"token"} — принять приглашение текущим пользователем.
+func (s *Server) handleInvitationAccept(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "bad json"})
+		return
+	}
+
+	inv, ok := s.pendingInvitation(w, r, req.Token)
+	if !ok {
+		return
+	}
+	if !strings.EqualFold(inv.Email, GetClaims(r).Email) {
+		writeJSON(w, http.StatusForbidden, map[string]any{"error": "invitation was sent to another email"})
+		return
+	}
+
+	m, err := s.Store.AcceptInvitation(r.Context(), inv.ID, GetUserID(r))
+	if err != nil {
+		if store.IsNotFound(err) {
+			writeJSON(w, http.StatusGone, map[string]any{"error": "invitation is no longer valid"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, m)
+}
+
+// handleInvitations — публичная часть приглашений (по токену из ссылки, без входа):
+//
+//	GET  /api/invitations/info?token= — организация, email и роль (для страниц входа/регистрации)
+//	POST /api/invitations/decline {"token"} — отклонить
+func (s *Server) handleInvitations(w http.ResponseWriter, r *http.Request) {
+	switch strings.TrimPrefix(r.URL.Path, "/api/invitations/") {
+
+	case "info":
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		inv, ok := s.pendingInvitation(w, r, r.URL.Query().Get("token"))
+		if !ok {
+			return
+		}
+		org, err := s.Store.GetOrg(r.Context(), inv.OrgID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"orgName":   org.Name,
+			"email":     inv.Email,
+			"role":      inv.Role,
+			"expiresAt": inv.ExpiresAt,
+		})
+
+	case "decline":
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			Token string `json:"token"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "bad json"})
+			return
+		}
+		inv, ok := s.pendingInvitation(w, r, req.Token)
+		if !ok {
+			return
+		}
+		if err := s.Store.CloseInvitation(r.Context(), inv.OrgID, inv.ID, store.InvitationDeclined); err != nil && !store.IsNotFound(err) {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+
+	default:
+		http.NotFound(w, r)
+	}
+}
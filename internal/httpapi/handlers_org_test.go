@@ -0,0 +1,218 @@
+package httpapi
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// invite приглашает email в текущую организацию token и возвращает токен из письма.
+func (e *testEnv) invite(token, orgID, email, role string) string {
+	e.t.Helper()
+	var resp struct {
+		AcceptURL string `json:"acceptUrl"`
+		EmailSent bool   `json:"emailSent"`
+	}
+	code := e.doJSON(http.MethodPost, "/api/app/org/invitations?orgId="+orgID, token,
+		map[string]string{"email": email, "role": role}, &resp)
+	if code != http.StatusOK || !resp.EmailSent {
+		e.t.Fatalf("invite %s: status %d", email, code)
+	}
+	msg := e.mail.last()
+	if msg.To != email || !strings.Contains(msg.Body, resp.AcceptURL) {
+		e.t.Fatalf("invitation mail = %+v", msg)
+	}
+	u, err := url.Parse(resp.AcceptURL)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return u.Query().Get("invite")
+}
+
+type meResp struct {
+	Org struct {
+		ID   string
+		Name string
+	} `json:"org"`
+	Role string `json:"role"`
+	Orgs []struct {
+		Role string `json:"role"`
+	} `json:"orgs"`
+}
+
+func (e *testEnv) me(token, orgID string) meResp {
+	e.t.Helper()
+	var me meResp
+	if code := e.doJSON(http.MethodGet, "/api/app/me?orgId="+orgID, token, nil, &me); code != http.StatusOK {
+		e.t.Fatalf("me: status %d", code)
+	}
+	return me
+}
+
+func TestOrgInvitationAcceptAndRoles(t *testing.T) {
+	e := newTestEnv(t)
+	owner := e.register("owner@example.com")
+	orgID := e.me(owner, "").Org.ID
+	clusterID := e.createCluster(owner, "prod")
+	scanID := e.upload(owner, clusterID, testRBAC)
+
+	bob := e.register("bob@example.com")
+	inviteToken := e.invite(owner, orgID, "bob@example.com", "member")
+
+	// чужой пользователь не может принять приглашение, отправленное на другой email
+	eve := e.register("eve@example.com")
+	if code := e.doJSON(http.MethodPost, "/api/app/invitations/accept", eve,
+		map[string]string{"token": inviteToken}, nil); code != http.StatusForbidden {
+		t.Fatalf("accept by another email: status %d, want 403", code)
+	}
+	if code := e.doJSON(http.MethodGet, "/api/app/clusters?orgId="+orgID, eve, nil, nil); code != http.StatusForbidden {
+		t.Fatalf("non-member selects org: status %d, want 403", code)
+	}
+
+	if code := e.doJSON(http.MethodPost, "/api/app/invitations/accept", bob,
+		map[string]string{"token": inviteToken}, nil); code != http.StatusOK {
+		t.Fatalf("accept: status %d", code)
+	}
+	if code := e.doJSON(http.MethodPost, "/api/app/invitations/accept", bob,
+		map[string]string{"token": inviteToken}, nil); code != http.StatusGone {
+		t.Fatalf("accept twice: status %d, want 410", code)
+	}
+
+	// у bob две организации: своя (по умолчанию) и выбранная через orgId
+	me := e.me(bob, orgID)
+	if len(me.Orgs) != 2 || me.Role != "member" || me.Org.ID != orgID {
+		t.Fatalf("me = %+v", me)
+	}
+
+	// member видит отчёты, но не создаёт кластеры и не загружает сканы
+	if code := e.doJSON(http.MethodGet, "/api/app/scan/report?scanId="+scanID+"&orgId="+orgID, bob, nil, nil); code != http.StatusOK {
+		t.Fatalf("member reads report: status %d", code)
+	}
+	if code := e.doJSON(http.MethodGet, "/api/app/scan/report?scanId="+scanID, bob, nil, nil); code != http.StatusNotFound {
+		t.Fatalf("report outside selected org: status %d, want 404", code)
+	}
+	if code := e.doJSON(http.MethodPost, "/api/app/clusters?orgId="+orgID, bob,
+		map[string]string{"name": "stage"}, nil); code != http.StatusForbidden {
+		t.Fatalf("member creates cluster: status %d, want 403", code)
+	}
+	if code := e.doJSON(http.MethodPost, "/api/app/org/invitations?orgId="+orgID, bob,
+		map[string]string{"email": "x@example.com"}, nil); code != http.StatusForbidden {
+		t.Fatalf("member invites: status %d, want 403", code)
+	}
+
+	var members struct {
+		Members []struct {
+			UserID string `json:"userId"`
+			Email  string `json:"email"`
+			Role   string `json:"role"`
+		} `json:"members"`
+	}
+	e.doJSON(http.MethodGet, "/api/app/org/members?orgId="+orgID, bob, nil, &members)
+	if len(members.Members) != 2 || members.Members[1].Email != "bob@example.com" {
+		t.Fatalf("members = %+v", members.Members)
+	}
+	ownerID, bobID := members.Members[0].UserID, members.Members[1].UserID
+
+	// owner повышает bob до admin; владельца понизить нельзя
+	if code := e.doJSON(http.MethodPatch, "/api/app/org/members/"+bobID+"?orgId="+orgID, owner,
+		map[string]string{"role": "admin"}, nil); code != http.StatusOK {
+		t.Fatalf("promote: status %d", code)
+	}
+	if code := e.doJSON(http.MethodPatch, "/api/app/org/members/"+ownerID+"?orgId="+orgID, bob,
+		map[string]string{"role": "member"}, nil); code != http.StatusConflict {
+		t.Fatalf("demote owner: status %d, want 409", code)
+	}
+	if code := e.doJSON(http.MethodDelete, "/api/app/org/members/"+ownerID+"?orgId="+orgID, bob, nil, nil); code != http.StatusConflict {
+		t.Fatalf("remove owner: status %d, want 409", code)
+	}
+	// передать владение может только владелец
+	if code := e.doJSON(http.MethodPost, "/api/app/org/transfer?orgId="+orgID, bob,
+		map[string]string{"userId": bobID}, nil); code != http.StatusForbidden {
+		t.Fatalf("transfer by admin: status %d, want 403", code)
+	}
+
+	if code := e.doJSON(http.MethodPost, "/api/app/org/transfer?orgId="+orgID, owner,
+		map[string]string{"userId": bobID}, nil); code != http.StatusOK {
+		t.Fatalf("transfer: status %d", code)
+	}
+	if me := e.me(owner, orgID); me.Role != "admin" {
+		t.Fatalf("previous owner role = %q, want admin", me.Role)
+	}
+	if me := e.me(bob, orgID); me.Role != "owner" {
+		t.Fatalf("new owner role = %q, want owner", me.Role)
+	}
+
+	// прежний владелец выходит из организации сам
+	if code := e.doJSON(http.MethodDelete, "/api/app/org/members/"+ownerID+"?orgId="+orgID, owner, nil, nil); code != http.StatusOK {
+		t.Fatalf("leave org: status %d", code)
+	}
+	if code := e.doJSON(http.MethodGet, "/api/app/clusters?orgId="+orgID, owner, nil, nil); code != http.StatusForbidden {
+		t.Fatalf("after leaving: status %d, want 403", code)
+	}
+}
+
+func TestOrgInvitationRegisterDeclineRevoke(t *testing.T) {
+	e := newTestEnv(t)
+	owner := e.register("owner@example.com")
+	orgID := e.me(owner, "").Org.ID
+
+	// регистрация по приглашению — без собственной организации
+	inviteToken := e.invite(owner, orgID, "carol@example.com", "admin")
+	var info struct {
+		OrgName string `json:"orgName"`
+		Role    string `json:"role"`
+	}
+	if code := e.doJSON(http.MethodGet, "/api/invitations/info?token="+url.QueryEscape(inviteToken), "", nil, &info); code != http.StatusOK {
+		t.Fatalf("invitation info: status %d", code)
+	}
+	if info.OrgName != "My Organization" || info.Role != "admin" {
+		t.Fatalf("info = %+v", info)
+	}
+	if code := e.doJSON(http.MethodPost, "/api/auth/register", "", map[string]string{
+		"email": "mallory@example.com", "password": "password123", "inviteToken": inviteToken,
+	}, nil); code != http.StatusForbidden {
+		t.Fatalf("register with another email: status %d, want 403", code)
+	}
+	var reg authResp
+	if code := e.doJSON(http.MethodPost, "/api/auth/register", "", map[string]string{
+		"email": "carol@example.com", "password": "password123", "inviteToken": inviteToken,
+	}, &reg); code != http.StatusOK {
+		t.Fatalf("register with invitation: status %d", code)
+	}
+	me := e.me(reg.Token, "")
+	if len(me.Orgs) != 1 || me.Org.ID != orgID || me.Role != "admin" {
+		t.Fatalf("me = %+v", me)
+	}
+	// admin создаёт кластеры
+	e.createCluster(reg.Token, "prod")
+
+	// отклонённое и отозванное приглашения больше не принимаются
+	dave := e.register("dave@example.com")
+	declined := e.invite(owner, orgID, "dave@example.com", "member")
+	if code := e.doJSON(http.MethodPost, "/api/invitations/decline", "", map[string]string{"token": declined}, nil); code != http.StatusOK {
+		t.Fatalf("decline: status %d", code)
+	}
+	if code := e.doJSON(http.MethodPost, "/api/app/invitations/accept", dave,
+		map[string]string{"token": declined}, nil); code != http.StatusGone {
+		t.Fatalf("accept declined: status %d, want 410", code)
+	}
+
+	revoked := e.invite(owner, orgID, "dave@example.com", "member")
+	var list struct {
+		Invitations []struct {
+			ID string `json:"id"`
+		} `json:"invitations"`
+	}
+	e.doJSON(http.MethodGet, "/api/app/org/invitations?orgId="+orgID, owner, nil, &list)
+	if len(list.Invitations) != 1 {
+		t.Fatalf("pending invitations = %+v", list.Invitations)
+	}
+	if code := e.doJSON(http.MethodDelete, "/api/app/org/invitations/"+list.Invitations[0].ID+"?orgId="+orgID, owner, nil, nil); code != http.StatusOK {
+		t.Fatalf("revoke: status %d", code)
+	}
+	if code := e.doJSON(http.MethodPost, "/api/app/invitations/accept", dave,
+		map[string]string{"token": revoked}, nil); code != http.StatusGone {
+		t.Fatalf("accept revoked: status %d, want 410", code)
+	}
+}
@@ -0,0 +1,152 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+// send выполняет запрос и возвращает статус и заголовок Retry-After.
+func (e *testEnv) send(method, path, token, contentType string, body []byte) (int, string) {
+	e.t.Helper()
+	req, _ := http.NewRequest(method, e.srv.URL+path, bytes.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode, resp.Header.Get("Retry-After")
+}
+
+func (e *testEnv) tryLogin(email, password string) (int, string) {
+	e.t.Helper()
+	body, _ := json.Marshal(map[string]string{"email": email, "password": password})
+	return e.send(http.MethodPost, "/api/auth/login", "", "application/json", body)
+}
+
+func (e *testEnv) tryUpload(token, clusterID string) (int, string) {
+	e.t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	_ = mw.WriteField("clusterId", clusterID)
+	fw, _ := mw.CreateFormFile("rbac", "rbac.yaml")
+	_, _ = fw.Write([]byte(testRBAC))
+	_ = mw.Close()
+	return e.send(http.MethodPost, "/api/app/scans", token, mw.FormDataContentType(), buf.Bytes())
+}
+
+func TestLoginLockout(t *testing.T) {
+	e := newTestEnv(t)
+	e.register("alice@example.com")
+	e.register("bob@example.com")
+
+	for i := 0; i < lockoutAfter; i++ {
+		if code, _ := e.tryLogin("alice@example.com", "wrong-password"); code != http.StatusUnauthorized {
+			t.Fatalf("failed login %d: status %d, want 401", i+1, code)
+		}
+	}
+	// после lockoutAfter ошибок не помогает и верный пароль
+	code, retry := e.tryLogin("alice@example.com", "password123")
+	if secs, _ := strconv.Atoi(retry); code != http.StatusTooManyRequests || secs < 1 || secs > int(lockoutBase.Seconds()) {
+		t.Fatalf("locked account: status %d, Retry-After %q", code, retry)
+	}
+	// блокировка — только этого аккаунта
+	if token, code := e.login("bob@example.com", "password123"); code != http.StatusOK || token == "" {
+		t.Fatalf("other account: status %d", code)
+	}
+
+	// лимит на IP: перебор разных адресов упирается в limitLoginIP
+	for i := 0; ; i++ {
+		code, retry := e.tryLogin("guess"+strconv.Itoa(i)+"@example.com", "password123")
+		if code == http.StatusTooManyRequests {
+			if retry == "" {
+				t.Fatal("429 without Retry-After")
+			}
+			break
+		}
+		if i > limitLoginIP.Limit {
+			t.Fatalf("login attempt %d from one IP: status %d, want 429", i+1, code)
+		}
+	}
+}
+
+func TestForgotPasswordMailLimit(t *testing.T) {
+	e := newTestEnv(t)
+	e.register("alice@example.com")
+
+	body, _ := json.Marshal(map[string]string{"email": "alice@example.com"})
+	for i := 0; i < limitMailTo.Limit; i++ {
+		if code, _ := e.send(http.MethodPost, "/api/auth/password/forgot", "", "application/json", body); code != http.StatusOK {
+			t.Fatalf("forgot %d: status %d", i+1, code)
+		}
+	}
+	sent := e.mail.count()
+	if code, retry := e.send(http.MethodPost, "/api/auth/password/forgot", "", "application/json", body); code != http.StatusTooManyRequests || retry == "" {
+		t.Fatalf("forgot over limit: status %d, Retry-After %q", code, retry)
+	}
+	if e.mail.count() != sent {
+		t.Fatal("mail sent over the limit")
+	}
+}
+
+func TestScanUploadQuota(t *testing.T) {
+	e := newTestEnv(t)
+	owner := e.register("owner@example.com")
+	orgID := e.me(owner, "").Org.ID
+	clusterID := e.createCluster(owner, "prod")
+
+	// план free: дневная квота уже выбрана
+	ctx := context.Background()
+	max, _ := e.store.PlanMaxScansPerDay(ctx, "free")
+	for i := 0; i < max; i++ {
+		if _, err := e.store.CreateScan(ctx, orgID, clusterID, "upload"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	code, retry := e.tryUpload(owner, clusterID)
+	if secs, _ := strconv.Atoi(retry); code != http.StatusTooManyRequests || secs < 1 || secs > 24*60*60 {
+		t.Fatalf("upload over daily quota: status %d, Retry-After %q", code, retry)
+	}
+
+	// на pro квота больше, но частые загрузки всё равно ограничены
+	if err := e.store.AdminSetOrgPlan(ctx, orgID, "pro"); err != nil {
+		t.Fatal(err)
+	}
+	// без права загрузки лимиты организации не расходуются
+	viewer, _ := e.join(owner, orgID, "viewer@example.com")
+	for i := 0; i <= limitScanOrg.Limit; i++ {
+		if code, _ := e.tryUpload(viewer, clusterID); code != http.StatusForbidden {
+			t.Fatalf("upload %d by viewer: status %d, want 403", i+1, code)
+		}
+	}
+	for i := 0; i < limitScanOrg.Limit; i++ {
+		if code, _ := e.tryUpload(owner, clusterID); code != http.StatusOK {
+			t.Fatalf("upload %d on pro: status %d", i+1, code)
+		}
+	}
+	if code, retry := e.tryUpload(owner, clusterID); code != http.StatusTooManyRequests || retry == "" {
+		t.Fatalf("upload burst: status %d, Retry-After %q", code, retry)
+	}
+}
+
+func TestClientIPBehindProxy(t *testing.T) {
+	s := &Server{}
+	r, _ := http.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "10.0.0.2:41000"
+	r.Header.Set("X-Forwarded-For", "203.0.113.9, 198.51.100.7")
+	if ip := s.clientIP(r); ip != "10.0.0.2" {
+		t.Fatalf("without TRUST_PROXY: %s", ip)
+	}
+	s.Cfg.TrustProxy = true
+	if ip := s.clientIP(r); ip != "198.51.100.7" {
+		t.Fatalf("with TRUST_PROXY: %s, want the address added by the proxy", ip)
+	}
+}
@@ -20,6 +20,17 @@ func (s *Server) handleDiffScans(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// оба скана должны принадлежать текущей организации
+	m, ok := s.currentOrg(w, r)
+	if !ok {
+		return
+	}
+	for _, id := range []string{req.BaseID, req.TargetID} {
+		if _, ok := s.orgScan(w, r, m, id); !ok {
+			return
+		}
+	}
+
 	baseSum, baseFull, err := s.Store.GetScanReport(r.Context(), req.BaseID)
 	if err != nil {
 		if store.IsNotFound(err) {
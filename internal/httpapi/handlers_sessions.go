@@ -0,0 +1,229 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"rbac-analyzer/internal/security"
+	"rbac-analyzer/internal/store"
+)
+
+// Access token живёт недолго и не отзывается сам по себе: AuthMiddleware проверяет,
+// что его сессия ещё действует. Refresh token хранится только как sha256 и меняется
+// при каждом POST /api/auth/refresh.
+const (
+	accessTokenTTL = 15 * time.Minute
+	sessionTTL     = 30 * 24 * time.Hour // продлевается при каждом обновлении
+)
+
+// startSession создаёт сессию и выдаёт пару токенов после входа.
+func (s *Server) startSession(ctx context.Context, r *http.Request, u store.User) (authResp, error) {
+	refresh, hash, err := security.NewToken()
+	if err != nil {
+		return authResp{}, err
+	}
+	ss, err := s.Store.CreateSession(ctx, store.Session{
+		UserID:      u.ID,
+		RefreshHash: hash,
+		UserAgent:   truncate(r.UserAgent(), 200),
+		IP:          s.clientIP(r),
+		ExpiresAt:   time.Now().Add(sessionTTL),
+	})
+	if err != nil {
+		return authResp{}, err
+	}
+	return s.tokens(u, ss.ID, refresh)
+}
+
+func (s *Server) tokens(u store.User, sessionID, refresh string) (authResp, error) {
+	token, err := s.Keys.Sign(security.Claims{
+		Sub:   u.ID,
+		Email: u.Email,
+		Admin: u.IsAdmin,
+		Exp:   time.Now().Add(accessTokenTTL).Unix(),
+		Sid:   sessionID,
+	})
+	if err != nil {
+		return authResp{}, err
+	}
+	return authResp{Token: token, RefreshToken: refresh, ExpiresIn: int(accessTokenTTL.Seconds())}, nil
+}
+
+// POST /api/auth/refresh {"refreshToken"} — новая пара токенов; старый refresh token
+// больше не действует. Повторное предъявление старого токена отзывает всю сессию.
+func (s *Server) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "refreshToken required"})
+		return
+	}
+	if !s.allowIP(w, r, "refresh", limitRefreshIP) {
+		return
+	}
+
+	oldHash := security.HashToken(req.RefreshToken)
+	refresh, newHash, err := security.NewToken()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	ss, err := s.Store.RotateSession(r.Context(), oldHash, newHash, time.Now().Add(sessionTTL))
+	if store.IsNotFound(err) {
+		reused, err := s.Store.RevokeReusedSession(r.Context(), oldHash)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		if reused {
+			writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "refresh token reused, session revoked"})
+			return
+		}
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "invalid refresh token"})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+
+	// email и флаг admin — из базы, а не из прежнего токена
+	u, err := s.Store.GetUser(r.Context(), ss.UserID)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "invalid refresh token"})
+		return
+	}
+	resp, err := s.tokens(u, ss.ID, refresh)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// POST /api/auth/logout [{"all": true}] — отзывает текущую сессию (или все сессии пользователя).
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		All bool `json:"all"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&req) // тело необязательно
+
+	claims := GetClaims(r)
+	var err error
+	if req.All {
+		_, err = s.Store.RevokeUserSessions(r.Context(), claims.Sub, "")
+	} else {
+		err = s.Store.RevokeSession(r.Context(), claims.Sub, claims.Sid)
+	}
+	if err != nil && !store.IsNotFound(err) {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+// GET    /api/app/sessions — действующие сессии пользователя (current — текущая)
+// DELETE /api/app/sessions/{id} — выйти на другом устройстве
+func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
+	claims := GetClaims(r)
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	switch {
+	case len(parts) == 3 && r.Method == http.MethodGet:
+		list, err := s.Store.ListSessions(r.Context(), claims.Sub)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		if list == nil {
+			list = []store.Session{}
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"sessions": list, "current": claims.Sid})
+
+	case len(parts) == 4 && r.Method == http.MethodDelete:
+		if err := s.Store.RevokeSession(r.Context(), claims.Sub, parts[3]); err != nil {
+			if store.IsNotFound(err) {
+				writeJSON(w, http.StatusNotFound, map[string]any{"error": "session not found"})
+				return
+			}
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+
+	case len(parts) <= 4:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// GET    /api/admin/users/{id}/sessions — сессии пользователя
+// DELETE /api/admin/users/{id}/sessions — отозвать все (с записью в журнал)
+func (s *Server) handleAdminUserSessions(w http.ResponseWriter, r *http.Request, userID string) {
+	switch r.Method {
+	case http.MethodGet:
+		list, err := s.Store.ListSessions(r.Context(), userID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		if list == nil {
+			list = []store.Session{}
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"sessions": list})
+
+	case http.MethodDelete:
+		n, err := s.Store.RevokeUserSessions(r.Context(), userID, "")
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		_ = s.Store.AddAdminAudit(r.Context(), GetUserID(r), "revoke_sessions", "user", userID,
+			map[string]any{"by_email": GetClaims(r).Email, "revoked": n})
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true, "revoked": n})
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// clientIP — адрес клиента для сессий и лимитов. За обратным прокси (TRUST_PROXY=1)
+// берётся последний адрес из X-Forwarded-For: его дописал сам прокси, а начало
+// заголовка клиент может подделать.
+func (s *Server) clientIP(r *http.Request) string {
+	if s.Cfg.TrustProxy {
+		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+			parts := strings.Split(xff, ",")
+			if ip := strings.TrimSpace(parts[len(parts)-1]); ip != "" {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
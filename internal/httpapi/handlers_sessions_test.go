@@ -0,0 +1,145 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"rbac-analyzer/internal/config"
+)
+
+func (e *testEnv) registerSession(email string) authResp {
+	e.t.Helper()
+	var resp authResp
+	if code := e.doJSON(http.MethodPost, "/api/auth/register", "",
+		map[string]string{"email": email, "password": "password123"}, &resp); code != http.StatusOK {
+		e.t.Fatalf("register %s: status %d", email, code)
+	}
+	if resp.Token == "" || resp.RefreshToken == "" || resp.ExpiresIn != int(accessTokenTTL.Seconds()) {
+		e.t.Fatalf("register %s: %+v", email, resp)
+	}
+	return resp
+}
+
+func (e *testEnv) refresh(refreshToken string) (authResp, int) {
+	e.t.Helper()
+	var resp authResp
+	code := e.doJSON(http.MethodPost, "/api/auth/refresh", "", map[string]string{"refreshToken": refreshToken}, &resp)
+	return resp, code
+}
+
+func TestRefreshRotationAndReuse(t *testing.T) {
+	e := newTestEnv(t)
+	first := e.registerSession("owner@example.com")
+
+	second, code := e.refresh(first.RefreshToken)
+	if code != http.StatusOK || second.RefreshToken == first.RefreshToken {
+		t.Fatalf("refresh: status %d, %+v", code, second)
+	}
+	if code := e.doJSON(http.MethodGet, "/api/app/me", second.Token, nil, nil); code != http.StatusOK {
+		t.Fatalf("me with refreshed token: status %d", code)
+	}
+
+	// старый refresh token предъявлен повторно — сессия отзывается целиком
+	if _, code := e.refresh(first.RefreshToken); code != http.StatusUnauthorized {
+		t.Fatalf("reuse of rotated refresh token: status %d, want 401", code)
+	}
+	if _, code := e.refresh(second.RefreshToken); code != http.StatusUnauthorized {
+		t.Fatalf("refresh after reuse: status %d, want 401", code)
+	}
+	if code := e.doJSON(http.MethodGet, "/api/app/me", second.Token, nil, nil); code != http.StatusUnauthorized {
+		t.Fatalf("access token of revoked session: status %d, want 401", code)
+	}
+}
+
+func TestLogoutAndSessionRevocation(t *testing.T) {
+	e := newTestEnv(t)
+	laptop := e.registerSession("owner@example.com")
+	phone, _ := e.login("owner@example.com", "password123")
+
+	var list struct {
+		Sessions []struct{ ID string } `json:"sessions"`
+		Current  string                `json:"current"`
+	}
+	if code := e.doJSON(http.MethodGet, "/api/app/sessions", laptop.Token, nil, &list); code != http.StatusOK || len(list.Sessions) != 2 {
+		t.Fatalf("sessions: status %d, %+v", code, list)
+	}
+	other := list.Sessions[0].ID
+	if other == list.Current {
+		other = list.Sessions[1].ID
+	}
+	if code := e.doJSON(http.MethodDelete, "/api/app/sessions/"+other, laptop.Token, nil, nil); code != http.StatusOK {
+		t.Fatalf("revoke session: status %d", code)
+	}
+	if code := e.doJSON(http.MethodGet, "/api/app/me", phone, nil, nil); code != http.StatusUnauthorized {
+		t.Fatalf("revoked device: status %d, want 401", code)
+	}
+
+	if code := e.doJSON(http.MethodPost, "/api/auth/logout", laptop.Token, nil, nil); code != http.StatusOK {
+		t.Fatalf("logout: status %d", code)
+	}
+	if code := e.doJSON(http.MethodGet, "/api/app/me", laptop.Token, nil, nil); code != http.StatusUnauthorized {
+		t.Fatalf("after logout: status %d, want 401", code)
+	}
+	if _, code := e.refresh(laptop.RefreshToken); code != http.StatusUnauthorized {
+		t.Fatalf("refresh after logout: status %d, want 401", code)
+	}
+}
+
+func TestAdminDemotionAppliesImmediately(t *testing.T) {
+	e := newTestEnv(t)
+	e.register("root@example.com")
+	e.register("ops@example.com")
+	ctx := context.Background()
+	_ = e.store.AdminSetUserAdmin(ctx, e.userID("root@example.com"), true)
+	_ = e.store.AdminSetUserAdmin(ctx, e.userID("ops@example.com"), true)
+	root, _ := e.login("root@example.com", "password123")
+	ops, _ := e.login("ops@example.com", "password123")
+
+	if code := e.doJSON(http.MethodGet, "/api/admin/users", ops, nil, nil); code != http.StatusOK {
+		t.Fatalf("admin list: status %d", code)
+	}
+	if code := e.doJSON(http.MethodPost, "/api/admin/users/"+e.userID("ops@example.com")+"/toggle-admin", root, nil, nil); code != http.StatusNoContent {
+		t.Fatalf("toggle admin: status %d", code)
+	}
+	// в токене ops всё ещё admin: true, но права проверяются по базе
+	if code := e.doJSON(http.MethodGet, "/api/admin/users", ops, nil, nil); code != http.StatusForbidden {
+		t.Fatalf("demoted admin: status %d, want 403", code)
+	}
+
+	// администратор отзывает все сессии пользователя
+	var revoked struct{ Revoked int }
+	if code := e.doJSON(http.MethodDelete, "/api/admin/users/"+e.userID("ops@example.com")+"/sessions", root, nil, &revoked); code != http.StatusOK || revoked.Revoked != 2 {
+		t.Fatalf("admin revoke sessions: status %d, %+v", code, revoked)
+	}
+	if code := e.doJSON(http.MethodGet, "/api/app/me", ops, nil, nil); code != http.StatusUnauthorized {
+		t.Fatalf("after admin revoke: status %d, want 401", code)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	e := newTestEnv(t) // подписывает ключом JWT_SECRET (kid "default")
+	old := e.register("owner@example.com")
+
+	serve := func(cfg config.Config) *testEnv {
+		ts := httptest.NewServer(NewServer(cfg, e.store, http.NotFoundHandler()).Routes())
+		t.Cleanup(ts.Close)
+		return &testEnv{t: t, srv: ts, store: e.store}
+	}
+	// новый ключ подписывает, старый ещё принимается
+	rotated := serve(config.Config{JWTKeys: "2026-10:new-secret", JWTSecret: "test-secret"})
+	if code := rotated.doJSON(http.MethodGet, "/api/app/me", old, nil, nil); code != http.StatusOK {
+		t.Fatalf("old token after rotation: status %d", code)
+	}
+	fresh, _ := rotated.login("owner@example.com", "password123")
+
+	// старый ключ убран: его токены больше не принимаются, новые — да
+	retired := serve(config.Config{JWTKeys: "2026-10:new-secret"})
+	if code := retired.doJSON(http.MethodGet, "/api/app/me", old, nil, nil); code != http.StatusUnauthorized {
+		t.Fatalf("token of retired key: status %d, want 401", code)
+	}
+	if code := retired.doJSON(http.MethodGet, "/api/app/me", fresh, nil, nil); code != http.StatusOK {
+		t.Fatalf("token of new key: status %d", code)
+	}
+}
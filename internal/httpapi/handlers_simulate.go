@@ -0,0 +1,107 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"rbac-analyzer/internal/loader"
+	"rbac-analyzer/internal/simulate"
+)
+
+// handleScanItem разбирает /api/app/scans/{id}/{name}.
+func (s *Server) handleScanItem(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) == 5 && parts[4] == "simulate" {
+		s.handleScanSimulate(w, r, parts[3])
+		return
+	}
+	s.handleScanExport(w, r)
+}
+
+// POST /api/app/scans/{id}/simulate
+// Body: {"apply": "<multi-doc YAML>", "delete": ["ClusterRoleBinding/name", "RoleBinding/ns/name"]}
+//
+// Изменения применяются к сохранённому снимку скана; в ответе — diff эффективных прав
+// и смена статуса опасности.
+func (s *Server) handleScanSimulate(w http.ResponseWriter, r *http.Request, scanID string) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Apply  string   `json:"apply"`
+		Delete []string `json:"delete"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 32<<20)).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "bad json"})
+		return
+	}
+	if strings.TrimSpace(req.Apply) == "" && len(req.Delete) == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "apply or delete required"})
+		return
+	}
+
+	var ch simulate.Change
+	for _, d := range req.Delete {
+		ref, err := loader.ParseObjectRef(d)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+			return
+		}
+		ch.Delete = append(ch.Delete, ref)
+	}
+	if strings.TrimSpace(req.Apply) != "" {
+		data, err := loader.LoadFromBytes([]byte(req.Apply))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "bad yaml: " + err.Error()})
+			return
+		}
+		ch.Apply = data
+	}
+
+	base, ok := s.scanBase(w, r, scanID)
+	if !ok {
+		return
+	}
+
+	res, err := simulate.Run(base, ch, s.Rules)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// scanBase — исходное состояние скана: его снимок. Для старых сканов без снимка
+// состояние восстанавливается из отчёта — без ролей, на которые нет биндингов.
+func (s *Server) scanBase(w http.ResponseWriter, r *http.Request, scanID string) (*loader.Data, bool) {
+	m, ok := s.currentOrg(w, r)
+	if !ok {
+		return nil, false
+	}
+	sc, ok := s.orgScan(w, r, m, scanID)
+	if !ok {
+		return nil, false
+	}
+	if sc.SnapshotSHA256 == "" {
+		perms, ok := s.ownedScanPerms(w, r, scanID)
+		if !ok {
+			return nil, false
+		}
+		return loader.FromSubjectPermissions(perms), true
+	}
+
+	content, err := s.Store.GetSnapshot(r.Context(), sc.SnapshotSHA256)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return nil, false
+	}
+	data, err := loader.LoadFromBytes(content)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return nil, false
+	}
+	return data, true
+}
@@ -0,0 +1,532 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"rbac-analyzer/internal/oidc"
+	"rbac-analyzer/internal/security"
+	"rbac-analyzer/internal/store"
+)
+
+// Вход через SSO (OpenID Connect, authorization code + PKCE):
+//
+//	GET /api/auth/oidc/start?orgId=… | ?email=… — редирект на IdP организации
+//	    (по email организация находится по домену из настроек SSO)
+//	GET /api/auth/oidc/callback — обмен code, проверка ID token, вход
+//
+// Результат в обоих случаях — редирект на /login: #token=…&refreshToken=…&orgId=…,
+// #ssoLink=1&email=… (привязку к существующему аккаунту нужно подтвердить, см. handleSSOLink)
+// или #ssoError=….
+
+// ssoFail возвращает браузер на страницу входа с сообщением.
+func (s *Server) ssoFail(w http.ResponseWriter, r *http.Request, msg string) {
+	http.Redirect(w, r, "/login#ssoError="+url.QueryEscape(msg), http.StatusFound)
+}
+
+func (s *Server) handleSSOStart(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var (
+		cfg store.OrgSSO
+		err error
+	)
+	q := r.URL.Query()
+	if orgID := q.Get("orgId"); orgID != "" {
+		cfg, err = s.Store.GetOrgSSO(r.Context(), orgID)
+	} else {
+		email := strings.TrimSpace(strings.ToLower(q.Get("email")))
+		_, domain, found := strings.Cut(email, "@")
+		if !found || domain == "" {
+			s.ssoFail(w, r, "email or org required")
+			return
+		}
+		cfg, err = s.Store.FindOrgSSOByDomain(r.Context(), domain)
+	}
+	if err != nil {
+		if store.IsNotFound(err) {
+			s.ssoFail(w, r, "SSO is not configured for this organization")
+			return
+		}
+		s.ssoFail(w, r, err.Error())
+		return
+	}
+
+	p, err := s.sso.get(r.Context(), cfg.Issuer)
+	if err != nil {
+		s.ssoFail(w, r, "identity provider unavailable")
+		return
+	}
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		s.ssoFail(w, r, err.Error())
+		return
+	}
+	state, _ := oidc.RandomString(16)
+	nonce, _ := oidc.RandomString(16)
+
+	s.setSSOState(w, ssoState{
+		State:    state,
+		Nonce:    nonce,
+		Verifier: verifier,
+		OrgID:    cfg.OrgID,
+		Exp:      time.Now().Add(ssoStateTTL).Unix(),
+	})
+	http.Redirect(w, r, p.AuthCodeURL(s.ssoClient(cfg), state, nonce, challenge), http.StatusFound)
+}
+
+func (s *Server) ssoClient(cfg store.OrgSSO) oidc.Config {
+	return oidc.Config{ClientID: cfg.ClientID, ClientSecret: cfg.ClientSecret, RedirectURL: s.ssoRedirectURL()}
+}
+
+func (s *Server) handleSSOCallback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+
+	st, ok := s.ssoStateFrom(w, r)
+	if !ok || q.Get("state") == "" || q.Get("state") != st.State {
+		s.ssoFail(w, r, "SSO session expired, try again")
+		return
+	}
+	if e := q.Get("error"); e != "" {
+		s.ssoFail(w, r, "identity provider: "+e)
+		return
+	}
+
+	cfg, err := s.Store.GetOrgSSO(r.Context(), st.OrgID)
+	if err != nil {
+		s.ssoFail(w, r, "SSO is not configured for this organization")
+		return
+	}
+	p, err := s.sso.get(r.Context(), cfg.Issuer)
+	if err != nil {
+		s.ssoFail(w, r, "identity provider unavailable")
+		return
+	}
+	client := s.ssoClient(cfg)
+	// подробности ошибок IdP не показываются: в них бывают ответы внутренних адресов
+	raw, err := p.Exchange(r.Context(), client, q.Get("code"), st.Verifier)
+	if err != nil {
+		s.ssoFail(w, r, "identity provider rejected the sign-in")
+		return
+	}
+	claims, err := p.Verify(r.Context(), client, raw, st.Nonce)
+	if err != nil {
+		s.ssoFail(w, r, "invalid ID token from the identity provider")
+		return
+	}
+
+	u, link, msg, err := s.ssoLogin(r.Context(), cfg, claims)
+	if err != nil {
+		s.ssoFail(w, r, err.Error())
+		return
+	}
+	if msg != "" {
+		s.ssoFail(w, r, msg)
+		return
+	}
+	if link != nil {
+		s.setSSOLink(w, *link)
+		frag := url.Values{"ssoLink": {"1"}, "email": {strings.ToLower(claims.Email)}, "orgId": {cfg.OrgID}}
+		http.Redirect(w, r, "/login#"+frag.Encode(), http.StatusFound)
+		return
+	}
+
+	// MFA проверяется и после IdP: страница входа спросит код по mfaToken
+	challenge, err := s.mfaChallengeFor(r.Context(), u)
+	if err != nil {
+		s.ssoFail(w, r, err.Error())
+		return
+	}
+	if challenge != nil {
+		frag := url.Values{"mfaToken": {challenge.MFAToken}, "orgId": {cfg.OrgID}}
+		http.Redirect(w, r, "/login#"+frag.Encode(), http.StatusFound)
+		return
+	}
+
+	resp, err := s.startSession(r.Context(), r, u)
+	if err != nil {
+		s.ssoFail(w, r, err.Error())
+		return
+	}
+	frag := url.Values{"token": {resp.Token}, "refreshToken": {resp.RefreshToken}, "orgId": {cfg.OrgID}}
+	http.Redirect(w, r, "/login#"+frag.Encode(), http.StatusFound)
+}
+
+// ssoLogin находит или создаёт (JIT) пользователя по ID token и синхронизирует его
+// участие в организации по группам IdP. msg — причина отказа во входе.
+// link — email из ID token принадлежит существующему аккаунту: привязку подтверждает
+// сам пользователь (handleSSOLink), сессия не выдаётся.
+func (s *Server) ssoLogin(ctx context.Context, cfg store.OrgSSO, c oidc.Claims) (u store.User, link *ssoLink, msg string, err error) {
+	groupsClaim := cfg.GroupsClaim
+	if groupsClaim == "" {
+		groupsClaim = "groups"
+	}
+	groupRole := cfg.GroupRole(c.Groups(groupsClaim))
+
+	u, err = s.Store.GetUserByIdentity(ctx, c.Issuer, c.Subject)
+	if err != nil && !store.IsNotFound(err) {
+		return store.User{}, nil, "", err
+	}
+
+	if err != nil {
+		// первый вход этой учётной записи IdP
+		email := strings.TrimSpace(strings.ToLower(c.Email))
+		_, domain, _ := strings.Cut(email, "@")
+		switch {
+		case email == "" || domain == "":
+			return store.User{}, nil, "identity provider did not return an email", nil
+		case c.EmailVerified != nil && !*c.EmailVerified:
+			return store.User{}, nil, "email is not verified by the identity provider", nil
+		}
+
+		u, err = s.Store.GetUserByEmail(ctx, email)
+		switch {
+		case err == nil:
+			// существующий аккаунт сам не привязывается: IdP настраивает владелец организации
+			// и мог бы выдать себя за любого участника. Привязать можно только участника
+			// с адресом из доменов организации, и подтверждает это сам пользователь.
+			if !containsString(cfg.Domains, domain) {
+				return store.User{}, nil, "email domain is not allowed for this organization", nil
+			}
+			if _, err := s.Store.GetMembership(ctx, cfg.OrgID, u.ID); err != nil {
+				if store.IsNotFound(err) {
+					return store.User{}, nil, "an account with this email already exists; ask an org admin to invite it", nil
+				}
+				return store.User{}, nil, "", err
+			}
+			return store.User{}, &ssoLink{
+				UserID:  u.ID,
+				Issuer:  c.Issuer,
+				Subject: c.Subject,
+				OrgID:   cfg.OrgID,
+				Role:    groupRole,
+				Exp:     time.Now().Add(ssoStateTTL).Unix(),
+			}, "", nil
+		case store.IsNotFound(err):
+			if len(cfg.Domains) > 0 && !containsString(cfg.Domains, domain) {
+				return store.User{}, nil, "email domain is not allowed for this organization", nil
+			}
+			if groupRole == "" && cfg.DefaultRole == "" {
+				return store.User{}, nil, "your identity provider groups do not grant access to this organization", nil
+			}
+			// JIT: пароля нет, вход только через SSO; email подтверждает IdP организации
+			if u, err = s.Store.CreateUser(ctx, email, ""); err != nil {
+				return store.User{}, nil, "", err
+			}
+			if err := s.Store.MarkEmailVerified(ctx, u.ID); err != nil {
+				return store.User{}, nil, "", err
+			}
+			u.EmailVerified = true
+		default:
+			return store.User{}, nil, "", err
+		}
+		if err := s.Store.LinkIdentity(ctx, c.Issuer, c.Subject, u.ID); err != nil {
+			return store.User{}, nil, "", err
+		}
+	}
+
+	if msg, err := s.ssoSyncMember(ctx, cfg, u.ID, groupRole); msg != "" || err != nil {
+		return store.User{}, nil, msg, err
+	}
+	return u, nil, "", nil
+}
+
+// ssoSyncMember выставляет роль участника по группам IdP (groupRole) или добавляет
+// его с DefaultRole. msg — причина отказа во входе.
+func (s *Server) ssoSyncMember(ctx context.Context, cfg store.OrgSSO, userID, groupRole string) (msg string, err error) {
+	m, err := s.Store.GetMembership(ctx, cfg.OrgID, userID)
+	switch {
+	case err == nil && m.Role == store.RoleOwner:
+		// владельца группы IdP не понижают и не блокируют
+	case err == nil && groupRole != "":
+		if m.Role != groupRole {
+			err = s.Store.SetMemberRole(ctx, cfg.OrgID, userID, groupRole)
+		}
+	case err == nil && cfg.DefaultRole == "":
+		return "your identity provider groups do not grant access to this organization", nil
+	case store.IsNotFound(err):
+		role := groupRole
+		if role == "" {
+			role = cfg.DefaultRole
+		}
+		if role == "" {
+			return "your identity provider groups do not grant access to this organization", nil
+		}
+		err = s.Store.AddMember(ctx, cfg.OrgID, userID, role)
+	}
+	return "", err
+}
+
+// handleSSOLink подтверждает привязку учётной записи IdP к существующему аккаунту
+// (ожидающая привязка — в cookie после /callback):
+//
+//	POST /api/auth/oidc/link {"password"} — паролем аккаунта; ответ как у входа по паролю
+//	POST /api/app/sso/link — из сессии этого же аккаунта (например, у аккаунта нет пароля)
+func (s *Server) handleSSOLink(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	link, ok := s.ssoLinkFrom(r)
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "SSO sign-in expired, try again"})
+		return
+	}
+	u, err := s.Store.GetUser(r.Context(), link.UserID)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "SSO sign-in expired, try again"})
+		return
+	}
+
+	session := GetUserID(r) != ""
+	if session {
+		if GetUserID(r) != u.ID {
+			writeJSON(w, http.StatusForbidden, map[string]any{"error": "signed in as another account"})
+			return
+		}
+	} else {
+		var req struct {
+			Password string `json:"password"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "bad json"})
+			return
+		}
+		account := "login:" + u.Email
+		if !s.allowIP(w, r, "login", limitLoginIP) || s.accountLocked(w, r, account) {
+			return
+		}
+		if !security.CheckPassword(u.PasswordHash, req.Password) {
+			s.authFailed(r.Context(), account)
+			writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "invalid credentials"})
+			return
+		}
+		s.authSucceeded(r.Context(), account)
+	}
+
+	// настройки могли поменяться, пока пользователь подтверждал привязку
+	cfg, err := s.Store.GetOrgSSO(r.Context(), link.OrgID)
+	_, domain, _ := strings.Cut(u.Email, "@")
+	if err != nil || cfg.Issuer != link.Issuer || !containsString(cfg.Domains, domain) {
+		writeJSON(w, http.StatusConflict, map[string]any{"error": "SSO settings of the organization changed, try again"})
+		return
+	}
+	if err := s.Store.LinkIdentity(r.Context(), link.Issuer, link.Subject, u.ID); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	clearSSOLink(w)
+	msg, err := s.ssoSyncMember(r.Context(), cfg, u.ID, link.Role)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	if msg != "" {
+		writeJSON(w, http.StatusForbidden, map[string]any{"error": msg})
+		return
+	}
+	if session {
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true, "orgId": cfg.OrgID})
+		return
+	}
+
+	challenge, err := s.mfaChallengeFor(r.Context(), u)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "mfa error"})
+		return
+	}
+	if challenge != nil {
+		writeJSON(w, http.StatusOK, challenge)
+		return
+	}
+	resp, err := s.startSession(r.Context(), r, u)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "session error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// ssoEnforcedOrg — организация пользователя, где вход разрешён только через SSO ("" — нет такой).
+func (s *Server) ssoEnforcedOrg(ctx context.Context, userID string) (string, error) {
+	orgs, err := s.Store.ListUserOrgs(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	for _, m := range orgs {
+		cfg, err := s.Store.GetOrgSSO(ctx, m.Org.ID)
+		if err != nil {
+			if store.IsNotFound(err) {
+				continue
+			}
+			return "", err
+		}
+		if cfg.Enforce {
+			return m.Org.ID, nil
+		}
+	}
+	return "", nil
+}
+
+// ssoConfigResp — настройки SSO без client secret.
+type ssoConfigResp struct {
+	store.OrgSSO
+	HasSecret   bool   `json:"hasSecret"`
+	RedirectURL string `json:"redirectUrl"`
+}
+
+// GET    /api/app/org/sso — настройки IdP организации (admin+)
+// PUT    /api/app/org/sso — сохранить (owner); пустой clientSecret оставляет прежний
+// DELETE /api/app/org/sso — отключить SSO (owner)
+func (s *Server) handleOrgSSO(w http.ResponseWriter, r *http.Request, m store.Membership) {
+	if !requireOrgRole(w, m, store.RoleAdmin) {
+		return
+	}
+
+	cur, err := s.Store.GetOrgSSO(r.Context(), m.Org.ID)
+	configured := err == nil
+	if err != nil && !store.IsNotFound(err) {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if !configured {
+			writeJSON(w, http.StatusOK, map[string]any{"configured": false, "redirectUrl": s.ssoRedirectURL()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"configured": true,
+			"sso":        ssoConfigResp{OrgSSO: cur, HasSecret: cur.ClientSecret != "", RedirectURL: s.ssoRedirectURL()},
+		})
+
+	case http.MethodPut:
+		if !requireOrgRole(w, m, store.RoleOwner) {
+			return
+		}
+		var req struct {
+			store.OrgSSO
+			ClientSecret string `json:"clientSecret"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "bad json"})
+			return
+		}
+		cfg, ok := s.validSSOConfig(w, r, m, req.OrgSSO)
+		if !ok {
+			return
+		}
+		cfg.ClientSecret = req.ClientSecret
+		if cfg.ClientSecret == "" && configured {
+			cfg.ClientSecret = cur.ClientSecret
+		}
+		if err := s.Store.PutOrgSSO(r.Context(), cfg); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"configured": true,
+			"sso":        ssoConfigResp{OrgSSO: cfg, HasSecret: cfg.ClientSecret != "", RedirectURL: s.ssoRedirectURL()},
+		})
+
+	case http.MethodDelete:
+		if !requireOrgRole(w, m, store.RoleOwner) {
+			return
+		}
+		if !configured {
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": "sso not configured"})
+			return
+		}
+		if err := s.Store.DeleteOrgSSO(r.Context(), m.Org.ID); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// validSSOConfig нормализует и проверяет настройки; issuer проверяется запросом discovery.
+// Текст ошибки discovery не возвращается: иначе PUT читал бы ответы внутренних адресов.
+// При ошибке ответ уже записан и возвращается false.
+func (s *Server) validSSOConfig(w http.ResponseWriter, r *http.Request, m store.Membership, c store.OrgSSO) (store.OrgSSO, bool) {
+	c.OrgID = m.Org.ID
+	c.Issuer = strings.TrimRight(strings.TrimSpace(c.Issuer), "/")
+	c.ClientID = strings.TrimSpace(c.ClientID)
+	c.GroupsClaim = strings.TrimSpace(c.GroupsClaim)
+	if c.GroupsClaim == "" {
+		c.GroupsClaim = "groups"
+	}
+	// http:// (и адреса внутренней сети, см. newSSOClient) — только в DEV_MODE
+	if u, err := url.Parse(c.Issuer); err != nil || u.Host == "" || (u.Scheme != "https" && (u.Scheme != "http" || !s.Cfg.DevMode)) {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "issuer must be an https URL"})
+		return c, false
+	}
+	if c.ClientID == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "clientId required"})
+		return c, false
+	}
+
+	validRole := func(role string) bool { return role == store.RoleAdmin || role == store.RoleMember }
+	if c.DefaultRole != "" && !validRole(c.DefaultRole) {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "defaultRole must be admin, member or empty"})
+		return c, false
+	}
+	if c.GroupRoles == nil {
+		c.GroupRoles = map[string]string{}
+	}
+	for g, role := range c.GroupRoles {
+		if g == "" || !validRole(role) {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "groupRoles: group -> admin|member"})
+			return c, false
+		}
+	}
+
+	domains := []string{}
+	for _, d := range c.Domains {
+		d = strings.TrimPrefix(strings.TrimSpace(strings.ToLower(d)), "@")
+		if d == "" || containsString(domains, d) {
+			continue
+		}
+		other, err := s.Store.FindOrgSSOByDomain(r.Context(), d)
+		if err == nil && other.OrgID != c.OrgID {
+			writeJSON(w, http.StatusConflict, map[string]any{"error": "domain " + d + " is used by another organization"})
+			return c, false
+		}
+		if err != nil && !store.IsNotFound(err) {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return c, false
+		}
+		domains = append(domains, d)
+	}
+	c.Domains = domains
+
+	if _, err := s.sso.get(r.Context(), c.Issuer); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "cannot load OpenID configuration from the issuer"})
+		return c, false
+	}
+	return c, true
+}
+
+func containsString(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
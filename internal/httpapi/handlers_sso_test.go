@@ -0,0 +1,278 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"rbac-analyzer/internal/config"
+	"rbac-analyzer/internal/oidc/oidctest"
+)
+
+// ssoLogin проходит вход через SSO: /start -> IdP /authorize -> /callback.
+// Возвращает фрагмент итогового редиректа на /login (token, orgId или ssoError).
+func (e *testEnv) ssoLogin(idp *oidctest.Provider, user oidctest.User, startQuery string) url.Values {
+	e.t.Helper()
+	idp.SetUser(user)
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+	get := func(u string, cookies []*http.Cookie) *http.Response {
+		req, _ := http.NewRequest(http.MethodGet, u, nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			e.t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusFound {
+			e.t.Fatalf("GET %s: status %d, want 302", u, resp.StatusCode)
+		}
+		return resp
+	}
+	fragment := func(resp *http.Response) url.Values {
+		loc := resp.Header.Get("Location")
+		if !strings.HasPrefix(loc, "/login#") {
+			e.t.Fatalf("redirect to %q, want /login#…", loc)
+		}
+		v, _ := url.ParseQuery(strings.TrimPrefix(loc, "/login#"))
+		return v
+	}
+
+	start := get(e.srv.URL+"/api/auth/oidc/start?"+startQuery, nil)
+	authorize := start.Header.Get("Location")
+	if !strings.HasPrefix(authorize, idp.Issuer+"/authorize?") {
+		return fragment(start)
+	}
+
+	// IdP возвращает браузер на BASE_URL сервера — подставляем адрес тестового сервера
+	callback := get(authorize, nil).Header.Get("Location")
+	callback = strings.Replace(callback, "http://rbac.test", e.srv.URL, 1)
+	resp := get(callback, start.Cookies())
+	e.ssoCookies = resp.Cookies()
+	return fragment(resp)
+}
+
+// linkSSO подтверждает привязку после ssoLogin: паролем или, если token задан, из сессии.
+func (e *testEnv) linkSSO(token, password string, out any) int {
+	e.t.Helper()
+	path, body := "/api/auth/oidc/link", `{"password":`+strconv.Quote(password)+`}`
+	if token != "" {
+		path, body = "/api/app/sso/link", ""
+	}
+	req, _ := http.NewRequest(http.MethodPost, e.srv.URL+path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for _, c := range e.ssoCookies {
+		req.AddCookie(c)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		_ = json.NewDecoder(resp.Body).Decode(out)
+	}
+	return resp.StatusCode
+}
+
+func TestSSOLoginWithPKCEAndGroupMapping(t *testing.T) {
+	e := newTestEnv(t)
+	idp, idpSrv, err := oidctest.Start("rbac", "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(idpSrv.Close)
+
+	owner := e.register("owner@example.com")
+	orgID := e.me(owner, "").Org.ID
+	cfg := map[string]any{
+		"issuer":       idp.Issuer,
+		"clientId":     "rbac",
+		"clientSecret": "s3cret",
+		"domains":      []string{"Corp.Example"},
+		"groupRoles":   map[string]string{"rbac-admins": "admin", "rbac-users": "member"},
+	}
+	if code := e.doJSON(http.MethodPut, "/api/app/org/sso", owner, cfg, nil); code != http.StatusOK {
+		t.Fatalf("put sso: status %d", code)
+	}
+	var got struct {
+		SSO map[string]any `json:"sso"`
+	}
+	e.doJSON(http.MethodGet, "/api/app/org/sso", owner, nil, &got)
+	if got.SSO["hasSecret"] != true || got.SSO["clientSecret"] != nil || got.SSO["redirectUrl"] != "http://rbac.test/api/auth/oidc/callback" {
+		t.Fatalf("sso config = %v", got.SSO)
+	}
+
+	// JIT: новый пользователь по домену email, роль из группы
+	alice := oidctest.User{Subject: "alice-1", Email: "alice@corp.example", Groups: []string{"rbac-users"}}
+	res := e.ssoLogin(idp, alice, "email=alice@corp.example")
+	if res.Get("token") == "" || res.Get("orgId") != orgID {
+		t.Fatalf("sso login: %v", res)
+	}
+	if m := e.me(res.Get("token"), orgID); m.Role != "member" {
+		t.Fatalf("alice role = %q, want member", m.Role)
+	}
+	if _, code := e.login("alice@corp.example", ""); code != http.StatusUnauthorized {
+		t.Fatalf("JIT user password login: status %d, want 401", code)
+	}
+
+	// группы синхронизируются при каждом входе
+	alice.Groups = []string{"rbac-users", "rbac-admins"}
+	res = e.ssoLogin(idp, alice, "orgId="+orgID)
+	if m := e.me(res.Get("token"), orgID); m.Role != "admin" {
+		t.Fatalf("alice role after group change = %q, want admin", m.Role)
+	}
+
+	for _, c := range []struct {
+		name string
+		user oidctest.User
+		want string
+	}{
+		{"no mapped group", oidctest.User{Subject: "bob-1", Email: "bob@corp.example", Groups: []string{"sales"}}, "groups do not grant"},
+		{"foreign domain", oidctest.User{Subject: "eve-1", Email: "eve@evil.example", Groups: []string{"rbac-admins"}}, "domain is not allowed"},
+	} {
+		if res := e.ssoLogin(idp, c.user, "orgId="+orgID); !strings.Contains(res.Get("ssoError"), c.want) {
+			t.Errorf("%s: %v, want error %q", c.name, res, c.want)
+		}
+	}
+
+	// чужой аккаунт с паролем не привязывается к IdP организации
+	e.register("carol@corp.example")
+	carol := oidctest.User{Subject: "carol-1", Email: "carol@corp.example", Groups: []string{"rbac-admins"}}
+	if res := e.ssoLogin(idp, carol, "orgId="+orgID); !strings.Contains(res.Get("ssoError"), "already exists") {
+		t.Fatalf("foreign account takeover: %v", res)
+	}
+
+	// владелец организации управляет IdP и утверждает email участника с паролем:
+	// аккаунт не привязывается без подтверждения самого участника
+	dave := e.register("dave@corp.example")
+	e.doJSON(http.MethodPost, "/api/app/invitations/accept", dave,
+		map[string]string{"token": e.invite(owner, orgID, "dave@corp.example", "member")}, nil)
+	forged := oidctest.User{Subject: "attacker-1", Email: "dave@corp.example", Groups: []string{"rbac-admins"}}
+	res = e.ssoLogin(idp, forged, "orgId="+orgID)
+	if res.Get("token") != "" || res.Get("ssoLink") != "1" {
+		t.Fatalf("sso login as existing member: %v, want link confirmation", res)
+	}
+	if code := e.linkSSO("", "wrong-password", nil); code != http.StatusUnauthorized {
+		t.Fatalf("link with wrong password: status %d, want 401", code)
+	}
+	if code := e.linkSSO(owner, "", nil); code != http.StatusForbidden {
+		t.Fatalf("link from another account's session: status %d, want 403", code)
+	}
+	e.ssoCookies = nil
+	if code := e.linkSSO("", "password123", nil); code != http.StatusUnauthorized {
+		t.Fatalf("link without the sso cookie: status %d, want 401", code)
+	}
+	if m := e.me(dave, orgID); m.Role != "member" {
+		t.Fatalf("dave role after failed takeover = %q, want member", m.Role)
+	}
+
+	// подмена state
+	start, err := (&http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}).
+		Get(e.srv.URL + "/api/auth/oidc/callback?state=forged&code=x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start.Body.Close()
+	if loc := start.Header.Get("Location"); !strings.Contains(loc, "ssoError") {
+		t.Fatalf("forged state: redirect %q", loc)
+	}
+
+	// обязательный SSO: пароль владельца больше не подходит, вход через IdP
+	cfg["enforce"] = true
+	delete(cfg, "clientSecret") // прежний секрет сохраняется
+	if code := e.doJSON(http.MethodPut, "/api/app/org/sso", owner, cfg, nil); code != http.StatusOK {
+		t.Fatalf("enforce sso: status %d", code)
+	}
+	if _, code := e.login("owner@example.com", "password123"); code != http.StatusForbidden {
+		t.Fatalf("password login with enforced SSO: status %d, want 403", code)
+	}
+	ownerIdP := oidctest.User{Subject: "owner-1", Email: "owner@example.com"}
+	if res := e.ssoLogin(idp, ownerIdP, "orgId="+orgID); !strings.Contains(res.Get("ssoError"), "domain is not allowed") {
+		t.Fatalf("link outside org domains: %v", res)
+	}
+	cfg["domains"] = []string{"corp.example", "example.com"}
+	if code := e.doJSON(http.MethodPut, "/api/app/org/sso", owner, cfg, nil); code != http.StatusOK {
+		t.Fatalf("add domain: status %d", code)
+	}
+	if res := e.ssoLogin(idp, ownerIdP, "orgId="+orgID); res.Get("ssoLink") != "1" {
+		t.Fatalf("owner via sso: %v, want link confirmation", res)
+	}
+	var linked authResp
+	if code := e.linkSSO("", "password123", &linked); code != http.StatusOK || linked.Token == "" {
+		t.Fatalf("link with password: status %d", code)
+	}
+	res = e.ssoLogin(idp, ownerIdP, "orgId="+orgID)
+	if m := e.me(res.Get("token"), orgID); m.Role != "owner" {
+		t.Fatalf("owner via sso: %v, role %q", res, m.Role)
+	}
+
+	// настраивает SSO только owner
+	if code := e.doJSON(http.MethodPut, "/api/app/org/sso", res.Get("token"), cfg, nil); code != http.StatusOK {
+		t.Fatalf("owner put sso: status %d", code)
+	}
+	aliceToken := e.ssoLogin(idp, alice, "orgId="+orgID).Get("token")
+	if code := e.doJSON(http.MethodPut, "/api/app/org/sso?orgId="+orgID, aliceToken, cfg, nil); code != http.StatusForbidden {
+		t.Fatalf("admin put sso: status %d, want 403", code)
+	}
+}
+
+func TestSSOIssuerRestrictedOutsideDevMode(t *testing.T) {
+	e := newTestEnvConfig(t, config.Config{JWTSecret: "test-secret", BaseURL: "http://rbac.test"})
+	owner := e.register("owner@example.com")
+
+	// внутренний сервис с «секретом» в ответе
+	internal := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("internal-secret"))
+	}))
+	t.Cleanup(internal.Close)
+
+	for _, c := range []struct{ issuer, want string }{
+		{"http://idp.example", "https"},
+		{internal.URL, "cannot load OpenID configuration"},
+		{"https://169.254.169.254/latest", "cannot load OpenID configuration"},
+	} {
+		var resp struct {
+			Error string `json:"error"`
+		}
+		cfg := map[string]any{"issuer": c.issuer, "clientId": "rbac"}
+		code := e.doJSON(http.MethodPut, "/api/app/org/sso", owner, cfg, &resp)
+		if code != http.StatusBadRequest || !strings.Contains(resp.Error, c.want) || strings.Contains(resp.Error, "127.0.0.1") {
+			t.Errorf("issuer %s: status %d, error %q, want 400 %q", c.issuer, code, resp.Error, c.want)
+		}
+	}
+}
+
+func TestPublicIP(t *testing.T) {
+	for _, c := range []struct {
+		ip   string
+		want bool
+	}{
+		{"8.8.8.8", true},
+		{"2001:4860:4860::8888", true},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::1", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"::ffff:127.0.0.1", false},
+	} {
+		if got := publicIP(net.ParseIP(c.ip)); got != c.want {
+			t.Errorf("publicIP(%s) = %v, want %v", c.ip, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,532 @@
+package httpapi
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"rbac-analyzer/internal/config"
+	"rbac-analyzer/internal/mail"
+	"rbac-analyzer/internal/store/memstore"
+)
+
+const testRBAC = `
+apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: reader
+  namespace: dev
+rules:
+- apiGroups: [""]
+  resources: ["pods"]
+  verbs: ["get", "list"]
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: reader
+  namespace: dev
+subjects:
+- kind: User
+  name: alice
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: Role
+  name: reader
+`
+
+const testAdminBinding = `
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: everything
+rules:
+- apiGroups: ["*"]
+  resources: ["*"]
+  verbs: ["*"]
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: bob-admin
+subjects:
+- kind: User
+  name: bob
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: everything
+`
+
+type testEnv struct {
+	t     *testing.T
+	srv   *httptest.Server
+	store *memstore.Store
+	mail  *mailbox
+
+	ssoCookies []*http.Cookie // cookie последнего ответа /api/auth/oidc/callback
+}
+
+// mailbox — mail.Sender, запоминающий отправленные письма.
+// Чтение ждёт писем, которые сервер отправляет в фоне.
+type mailbox struct {
+	mu   sync.Mutex
+	sent []mail.Message
+	wait func()
+}
+
+func (m *mailbox) Send(ctx context.Context, msg mail.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func (m *mailbox) count() int {
+	m.wait()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.sent)
+}
+
+func (m *mailbox) last() mail.Message {
+	m.wait()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.sent) == 0 {
+		return mail.Message{}
+	}
+	return m.sent[len(m.sent)-1]
+}
+
+// newTestEnv — сервер в DEV_MODE: тестовый IdP слушает http на 127.0.0.1.
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	return newTestEnvConfig(t, config.Config{JWTSecret: "test-secret", BaseURL: "http://rbac.test", DevMode: true})
+}
+
+func newTestEnvConfig(t *testing.T, cfg config.Config) *testEnv {
+	t.Helper()
+	st := memstore.New()
+	mb := &mailbox{}
+	s := NewServer(cfg, st, http.NotFoundHandler())
+	s.Mail = mb
+	mb.wait = s.mailJobs.Wait
+	ts := httptest.NewServer(s.Routes())
+	t.Cleanup(ts.Close)
+	return &testEnv{t: t, srv: ts, store: st, mail: mb}
+}
+
+// do отправляет запрос и декодирует JSON-ответ в out (если out != nil).
+func (e *testEnv) do(method, path, token, contentType string, body io.Reader, out any) int {
+	e.t.Helper()
+	req, err := http.NewRequest(method, e.srv.URL+path, body)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		b, _ := io.ReadAll(resp.Body)
+		if err := json.Unmarshal(b, out); err != nil {
+			e.t.Fatalf("%s %s: decode %q: %v", method, path, b, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func (e *testEnv) doJSON(method, path, token string, in, out any) int {
+	e.t.Helper()
+	var body io.Reader
+	if in != nil {
+		b, _ := json.Marshal(in)
+		body = bytes.NewReader(b)
+	}
+	return e.do(method, path, token, "application/json", body, out)
+}
+
+func (e *testEnv) register(email string) string {
+	e.t.Helper()
+	var resp authResp
+	code := e.doJSON(http.MethodPost, "/api/auth/register", "",
+		map[string]string{"email": email, "password": "password123"}, &resp)
+	if code != http.StatusOK || resp.Token == "" {
+		e.t.Fatalf("register %s: status %d", email, code)
+	}
+	e.verifyEmail(email)
+	return resp.Token
+}
+
+func (e *testEnv) login(email, password string) (string, int) {
+	e.t.Helper()
+	body, _ := json.Marshal(map[string]string{"email": email, "password": password})
+	req, _ := http.NewRequest(http.MethodPost, e.srv.URL+"/api/auth/login", bytes.NewReader(body))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var ar authResp
+	_ = json.NewDecoder(resp.Body).Decode(&ar)
+	return ar.Token, resp.StatusCode
+}
+
+func (e *testEnv) createCluster(token, name string) string {
+	e.t.Helper()
+	var c struct{ ID string }
+	if code := e.doJSON(http.MethodPost, "/api/app/clusters", token, map[string]string{"name": name}, &c); code != http.StatusOK {
+		e.t.Fatalf("create cluster: status %d", code)
+	}
+	return c.ID
+}
+
+func (e *testEnv) upload(token, clusterID, yaml string) string {
+	e.t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	_ = mw.WriteField("clusterId", clusterID)
+	fw, _ := mw.CreateFormFile("rbac", "rbac.yaml")
+	_, _ = fw.Write([]byte(yaml))
+	_ = mw.Close()
+
+	var resp struct {
+		Scan    struct{ ID string }
+		Summary map[string]any
+	}
+	if code := e.do(http.MethodPost, "/api/app/scans", token, mw.FormDataContentType(), &buf, &resp); code != http.StatusOK {
+		e.t.Fatalf("upload: status %d", code)
+	}
+	if resp.Scan.ID == "" || resp.Summary["counts"] == nil {
+		e.t.Fatalf("upload: unexpected response %+v", resp)
+	}
+	return resp.Scan.ID
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	e := newTestEnv(t)
+	e.register("user@example.com")
+
+	if token, code := e.login("USER@example.com", "password123"); code != http.StatusOK || token == "" {
+		t.Fatalf("login: status %d", code)
+	}
+	if _, code := e.login("user@example.com", "wrong-password"); code != http.StatusUnauthorized {
+		t.Fatalf("login with wrong password: status %d, want 401", code)
+	}
+	if code := e.doJSON(http.MethodPost, "/api/auth/register", "",
+		map[string]string{"email": "user@example.com", "password": "password123"}, nil); code != http.StatusBadRequest {
+		t.Fatalf("duplicate register: status %d, want 400", code)
+	}
+}
+
+func TestAppRequiresToken(t *testing.T) {
+	e := newTestEnv(t)
+	if code := e.doJSON(http.MethodGet, "/api/app/me", "", nil, nil); code != http.StatusUnauthorized {
+		t.Fatalf("no token: status %d, want 401", code)
+	}
+	if code := e.doJSON(http.MethodGet, "/api/app/me", "garbage", nil, nil); code != http.StatusUnauthorized {
+		t.Fatalf("bad token: status %d, want 401", code)
+	}
+}
+
+func TestClusterPlanLimit(t *testing.T) {
+	e := newTestEnv(t)
+	token := e.register("owner@example.com")
+	e.createCluster(token, "prod")
+
+	// free-план — один кластер
+	if code := e.doJSON(http.MethodPost, "/api/app/clusters", token, map[string]string{"name": "stage"}, nil); code != http.StatusPaymentRequired {
+		t.Fatalf("second cluster on free plan: status %d, want 402", code)
+	}
+
+	var list struct{ Clusters []struct{ Name string } }
+	e.doJSON(http.MethodGet, "/api/app/clusters", token, nil, &list)
+	if len(list.Clusters) != 1 || list.Clusters[0].Name != "prod" {
+		t.Fatalf("clusters = %+v", list.Clusters)
+	}
+}
+
+func TestScanUploadReportAndExport(t *testing.T) {
+	e := newTestEnv(t)
+	token := e.register("owner@example.com")
+	clusterID := e.createCluster(token, "prod")
+	scanID := e.upload(token, clusterID, testRBAC)
+
+	var list struct{ Scans []struct{ ID string } }
+	e.doJSON(http.MethodGet, "/api/app/scans?clusterId="+clusterID, token, nil, &list)
+	if len(list.Scans) != 1 || list.Scans[0].ID != scanID {
+		t.Fatalf("scans = %+v", list.Scans)
+	}
+
+	var rep struct {
+		Report struct {
+			SchemaVersion string `json:"schemaVersion"`
+			Subjects      []struct {
+				Subject string `json:"subject"`
+			} `json:"subjects"`
+		} `json:"report"`
+	}
+	if code := e.doJSON(http.MethodGet, "/api/app/scan/report?scanId="+scanID, token, nil, &rep); code != http.StatusOK {
+		t.Fatalf("report: status %d", code)
+	}
+	if rep.Report.SchemaVersion == "" || len(rep.Report.Subjects) != 1 || rep.Report.Subjects[0].Subject != "User:alice" {
+		t.Fatalf("report = %+v", rep.Report)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, e.srv.URL+"/api/app/scans/"+scanID+"/export.csv", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/csv") {
+		t.Fatalf("export.csv: status %d, content-type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if !strings.Contains(string(body), "alice") {
+		t.Fatalf("export.csv has no subject row:\n%s", body)
+	}
+	if code := e.do(http.MethodGet, "/api/app/scans/"+scanID+"/export.csv?granularity=roles", token, "", nil, nil); code != http.StatusBadRequest {
+		t.Fatalf("export.csv with unknown granularity: status %d, want 400", code)
+	}
+}
+
+func TestScanUploadAuditLog(t *testing.T) {
+	e := newTestEnv(t)
+	token := e.register("owner@example.com")
+	clusterID := e.createCluster(token, "prod")
+
+	var log bytes.Buffer
+	gz := gzip.NewWriter(&log)
+	_, _ = gz.Write([]byte(`{"stage":"ResponseComplete","verb":"get","user":{"username":"alice"},"objectRef":{"resource":"pods","namespace":"dev"},"responseStatus":{"code":200},"requestReceivedTimestamp":"2024-05-01T10:00:00Z"}` + "\n"))
+	_ = gz.Close()
+
+	uploadAudit := func() (int, map[string]any) {
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		_ = mw.WriteField("clusterId", clusterID)
+		fw, _ := mw.CreateFormFile("rbac", "rbac.yaml")
+		_, _ = fw.Write([]byte(testRBAC))
+		fw, _ = mw.CreateFormFile("audit", "audit.log.gz")
+		_, _ = fw.Write(log.Bytes())
+		_ = mw.Close()
+		var resp struct{ Summary map[string]any }
+		code := e.do(http.MethodPost, "/api/app/scans", token, mw.FormDataContentType(), &buf, &resp)
+		return code, resp.Summary
+	}
+
+	if code, sum := uploadAudit(); code != http.StatusOK || sum["usage"] == nil {
+		t.Fatalf("upload with audit log: status %d, summary %v", code, sum)
+	}
+
+	// лог больше предела не обрезается молча
+	defer func(v int64) { maxAuditBytes = v }(maxAuditBytes)
+	maxAuditBytes = int64(log.Len() - 1)
+	if code, _ := uploadAudit(); code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("audit log over the limit: status %d, want 413", code)
+	}
+}
+
+func TestScanExportIsolatedBetweenOrgs(t *testing.T) {
+	e := newTestEnv(t)
+	owner := e.register("owner@example.com")
+	scanID := e.upload(owner, e.createCluster(owner, "prod"), testRBAC)
+
+	other := e.register("other@example.com")
+	if code := e.doJSON(http.MethodGet, "/api/app/scans/"+scanID+"/export.html", other, nil, nil); code != http.StatusNotFound {
+		t.Fatalf("export of another org's scan: status %d, want 404", code)
+	}
+	if code := e.doJSON(http.MethodPost, "/api/app/scans/"+scanID+"/simulate", other,
+		map[string]any{"delete": []string{"RoleBinding/dev/reader"}}, nil); code != http.StatusNotFound {
+		t.Fatalf("simulate on another org's scan: status %d, want 404", code)
+	}
+}
+
+func TestScanSimulate(t *testing.T) {
+	e := newTestEnv(t)
+	token := e.register("owner@example.com")
+	scanID := e.upload(token, e.createCluster(token, "prod"), testRBAC)
+
+	var res struct {
+		Applied  []string `json:"applied"`
+		Deleted  []string `json:"deleted"`
+		NotFound []string `json:"notFound"`
+		Diff     struct {
+			Summary struct {
+				DangerIncreased int `json:"dangerIncreased"`
+				PermsRemoved    int `json:"permsRemoved"`
+			} `json:"summary"`
+		} `json:"diff"`
+	}
+	code := e.doJSON(http.MethodPost, "/api/app/scans/"+scanID+"/simulate", token, map[string]any{
+		"apply":  testAdminBinding,
+		"delete": []string{"RoleBinding/dev/reader", "ClusterRoleBinding/missing"},
+	}, &res)
+	if code != http.StatusOK {
+		t.Fatalf("simulate: status %d", code)
+	}
+	if res.Diff.Summary.DangerIncreased != 1 {
+		t.Errorf("dangerIncreased = %d, want 1", res.Diff.Summary.DangerIncreased)
+	}
+	if res.Diff.Summary.PermsRemoved != 2 {
+		t.Errorf("permsRemoved = %d, want 2 (get/list pods)", res.Diff.Summary.PermsRemoved)
+	}
+	if len(res.Deleted) != 1 || len(res.NotFound) != 1 || res.NotFound[0] != "ClusterRoleBinding/missing" {
+		t.Errorf("deleted = %v, notFound = %v", res.Deleted, res.NotFound)
+	}
+
+	if code := e.doJSON(http.MethodPost, "/api/app/scans/"+scanID+"/simulate", token,
+		map[string]any{"delete": []string{"RoleBinding/no-namespace"}}, nil); code != http.StatusBadRequest {
+		t.Fatalf("bad object ref: status %d, want 400", code)
+	}
+}
+
+// Роль без биндингов в отчёт не попадает, но есть в снимке: what-if её видит.
+func TestScanSimulateUnboundRole(t *testing.T) {
+	e := newTestEnv(t)
+	token := e.register("owner@example.com")
+	scanID := e.upload(token, e.createCluster(token, "prod"), testRBAC+`---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: secret-reader
+  namespace: dev
+rules:
+- apiGroups: [""]
+  resources: ["secrets"]
+  verbs: ["get"]
+`)
+
+	var res struct {
+		Diff struct {
+			Summary struct {
+				PermsAdded      int `json:"permsAdded"`
+				DangerIncreased int `json:"dangerIncreased"`
+			} `json:"summary"`
+		} `json:"diff"`
+	}
+	code := e.doJSON(http.MethodPost, "/api/app/scans/"+scanID+"/simulate", token, map[string]any{
+		"apply": `
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: bob-secrets
+  namespace: dev
+subjects:
+- kind: User
+  name: bob
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: Role
+  name: secret-reader
+`,
+	}, &res)
+	if code != http.StatusOK {
+		t.Fatalf("simulate: status %d", code)
+	}
+	if res.Diff.Summary.PermsAdded != 1 || res.Diff.Summary.DangerIncreased != 1 {
+		t.Fatalf("binding to an existing unbound role: %+v", res.Diff.Summary)
+	}
+}
+
+func TestAdminEndpoints(t *testing.T) {
+	e := newTestEnv(t)
+	token := e.register("admin@example.com")
+	if code := e.doJSON(http.MethodGet, "/api/admin/users", token, nil, nil); code != http.StatusForbidden {
+		t.Fatalf("admin api as regular user: status %d, want 403", code)
+	}
+
+	u, err := e.store.GetUserByEmail(context.Background(), "admin@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.store.AdminSetUserAdmin(context.Background(), u.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	// флаг админа попадает в токен при входе
+	token, _ = e.login("admin@example.com", "password123")
+
+	var users struct {
+		Users []struct {
+			Email   string `json:"email"`
+			IsAdmin bool   `json:"isAdmin"`
+		} `json:"users"`
+	}
+	if code := e.doJSON(http.MethodGet, "/api/admin/users", token, nil, &users); code != http.StatusOK {
+		t.Fatalf("admin users: status %d", code)
+	}
+	if len(users.Users) != 1 || !users.Users[0].IsAdmin {
+		t.Fatalf("users = %+v", users.Users)
+	}
+}
+
+func TestAdminReanalyze(t *testing.T) {
+	e := newTestEnv(t)
+	owner := e.register("owner@example.com")
+	e.upload(owner, e.createCluster(owner, "prod"), testRBAC)
+
+	admin := e.register("admin@example.com")
+	u, _ := e.store.GetUserByEmail(context.Background(), "admin@example.com")
+	_ = e.store.AdminSetUserAdmin(context.Background(), u.ID, true)
+	admin, _ = e.login("admin@example.com", "password123")
+
+	// результат, посчитанный «старой» версией движка
+	list, _ := e.store.ListStaleScans(context.Background(), "old-engine", 0, 10)
+	if len(list) != 1 {
+		t.Fatalf("stale for another engine = %d, want 1", len(list))
+	}
+	sum, full, _ := e.store.GetScanReport(context.Background(), list[0].ScanID)
+	_ = e.store.UpsertScanResult(context.Background(), list[0].ScanID, sum, full, "old-engine")
+
+	var status struct {
+		Stale int `json:"stale"`
+	}
+	e.doJSON(http.MethodGet, "/api/admin/reanalyze", admin, nil, &status)
+	if status.Stale != 1 {
+		t.Fatalf("stale = %d, want 1", status.Stale)
+	}
+
+	if code := e.doJSON(http.MethodPost, "/api/admin/reanalyze", admin, nil, nil); code != http.StatusAccepted {
+		t.Fatalf("start reanalyze: status %d, want 202", code)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var st struct {
+			Stale int `json:"stale"`
+			Job   struct {
+				Running bool `json:"running"`
+				Done    int  `json:"done"`
+			} `json:"job"`
+		}
+		e.doJSON(http.MethodGet, "/api/admin/reanalyze", admin, nil, &st)
+		if !st.Job.Running {
+			if st.Stale != 0 || st.Job.Done != 1 {
+				t.Fatalf("after reanalyze: stale = %d, done = %d", st.Stale, st.Job.Done)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("reanalyze did not finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
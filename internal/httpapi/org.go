@@ -0,0 +1,99 @@
+package httpapi
+
+import (
+	"net/http"
+	"strings"
+
+	"rbac-analyzer/internal/store"
+)
+
+// orgHeader — выбор организации в запросе, если пользователь состоит в нескольких
+// (вместо заголовка можно передать ?orgId=). Без него — первая организация пользователя.
+const orgHeader = "X-Org-ID"
+
+// currentOrg возвращает организацию запроса и роль пользователя в ней.
+// Учитывает политику организации «обязательная MFA».
+// При ошибке ответ уже записан и возвращается false.
+func (s *Server) currentOrg(w http.ResponseWriter, r *http.Request) (store.Membership, bool) {
+	m, ok := s.resolveOrg(w, r)
+	if !ok || !s.requireOrgMFA(w, r, m) {
+		return store.Membership{}, false
+	}
+	return m, true
+}
+
+// resolveOrg — как currentOrg, но без проверки MFA (для /api/app/me: клиент должен
+// узнать, что пора подключить MFA).
+func (s *Server) resolveOrg(w http.ResponseWriter, r *http.Request) (store.Membership, bool) {
+	userID := GetUserID(r)
+	orgID := strings.TrimSpace(r.Header.Get(orgHeader))
+	if orgID == "" {
+		orgID = strings.TrimSpace(r.URL.Query().Get("orgId"))
+	}
+
+	if orgID != "" {
+		m, err := s.Store.GetMembership(r.Context(), orgID, userID)
+		if err != nil {
+			if store.IsNotFound(err) {
+				writeJSON(w, http.StatusForbidden, map[string]any{"error": "not a member of this org"})
+				return store.Membership{}, false
+			}
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return store.Membership{}, false
+		}
+		return m, true
+	}
+
+	orgs, err := s.Store.ListUserOrgs(r.Context(), userID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return store.Membership{}, false
+	}
+	if len(orgs) == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "org not found"})
+		return store.Membership{}, false
+	}
+	return orgs[0], true
+}
+
+// requireOrgRole: роль пользователя в организации не ниже min, иначе 403.
+func requireOrgRole(w http.ResponseWriter, m store.Membership, min string) bool {
+	if store.RoleAtLeast(m.Role, min) {
+		return true
+	}
+	writeJSON(w, http.StatusForbidden, map[string]any{"error": "requires org role " + min})
+	return false
+}
+
+// orgScan проверяет, что скан принадлежит организации и пользователь видит его кластер;
+// иначе — 404, как и для несуществующего скана.
+func (s *Server) orgScan(w http.ResponseWriter, r *http.Request, m store.Membership, scanID string) (store.Scan, bool) {
+	sc, err := s.Store.GetScan(r.Context(), scanID)
+	if err == nil && sc.OrgID != m.Org.ID {
+		err = store.ErrNotFound
+	}
+
+	var c store.Cluster
+	if err == nil {
+		c, err = s.Store.GetCluster(r.Context(), sc.ClusterID)
+	}
+	var a *clusterAccess
+	if err == nil {
+		a, err = s.loadClusterAccess(r.Context(), m, GetUserID(r))
+	}
+	if err == nil {
+		if role, _ := a.role(c); role == "" {
+			err = store.ErrNotFound
+		}
+	}
+
+	if err != nil {
+		if store.IsNotFound(err) {
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": "scan not found"})
+			return store.Scan{}, false
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return store.Scan{}, false
+	}
+	return sc, true
+}
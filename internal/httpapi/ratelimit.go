@@ -0,0 +1,169 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Ограничение частоты запросов: счётчики с фиксированным окном в RateStore.
+// По умолчанию счётчики живут в памяти процесса (MemoryRateStore). При нескольких
+// репликах нужно общее хранилище — например, RateStore поверх Redis (INCR + PEXPIRE),
+// иначе каждая реплика считает лимиты отдельно.
+type RateStore interface {
+	// Incr увеличивает счётчик key и возвращает новое значение и время до сброса.
+	// Окно window отсчитывается от первого увеличения.
+	Incr(ctx context.Context, key string, window time.Duration) (n int, reset time.Duration, err error)
+	// Get — значение счётчика и время до сброса; (0, 0), если счётчика нет.
+	Get(ctx context.Context, key string) (n int, reset time.Duration, err error)
+	Delete(ctx context.Context, key string) error
+}
+
+// rateLimit — не больше Limit запросов за Window на один ключ.
+type rateLimit struct {
+	Limit  int
+	Window time.Duration
+}
+
+var (
+	limitLoginIP    = rateLimit{Limit: 20, Window: time.Minute}  // вход: пароль и код MFA
+	limitRegisterIP = rateLimit{Limit: 10, Window: time.Hour}    // регистрация
+	limitMailIP     = rateLimit{Limit: 10, Window: time.Hour}    // запросы писем (сброс пароля)
+	limitMailTo     = rateLimit{Limit: 3, Window: time.Hour}     // письма на один адрес
+	limitLinkIP     = rateLimit{Limit: 30, Window: time.Hour}    // ссылки из писем
+	limitRefreshIP  = rateLimit{Limit: 120, Window: time.Minute} // обновление access token
+	limitScanOrg    = rateLimit{Limit: 10, Window: time.Minute}  // загрузки сканов организации
+)
+
+// Блокировка аккаунта: после lockoutAfter неудачных попыток вход закрыт на lockoutBase,
+// каждая следующая неудача удваивает паузу (не больше lockoutMax). Счётчик неудач
+// сбрасывается успешным входом или через lockoutWindow после первой неудачи.
+const (
+	lockoutAfter  = 5
+	lockoutBase   = time.Minute
+	lockoutMax    = 30 * time.Minute
+	lockoutWindow = time.Hour
+)
+
+// tooManyRequests — 429 с Retry-After в целых секундах (не меньше 1).
+func tooManyRequests(w http.ResponseWriter, retry time.Duration, msg string) {
+	secs := int((retry + time.Second - 1) / time.Second)
+	if secs < 1 {
+		secs = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(secs))
+	writeJSON(w, http.StatusTooManyRequests, map[string]any{"error": msg, "retryAfter": secs})
+}
+
+// allow учитывает запрос под ключом key; при превышении лимита сам пишет 429.
+// Если хранилище счётчиков недоступно, запрос пропускается: лимиты не должны закрывать вход.
+func (s *Server) allow(w http.ResponseWriter, r *http.Request, key string, l rateLimit) bool {
+	n, reset, err := s.RateStore.Incr(r.Context(), "rate:"+key, l.Window)
+	if err != nil || n <= l.Limit {
+		return true
+	}
+	tooManyRequests(w, reset, "too many requests, try again later")
+	return false
+}
+
+// allowIP — лимит группы эндпоинтов name на адрес клиента.
+func (s *Server) allowIP(w http.ResponseWriter, r *http.Request, name string, l rateLimit) bool {
+	return s.allow(w, r, name+":ip:"+s.clientIP(r), l)
+}
+
+// accountLocked: заблокирован ли account после неудачных попыток; если да, пишет 429.
+// account — "login:<email>" для пароля, "mfa:<user id>" для кодов второго фактора.
+func (s *Server) accountLocked(w http.ResponseWriter, r *http.Request, account string) bool {
+	_, reset, err := s.RateStore.Get(r.Context(), "lock:"+account)
+	if err != nil || reset <= 0 {
+		return false
+	}
+	tooManyRequests(w, reset, "too many failed attempts, try again later")
+	return true
+}
+
+// authFailed учитывает неудачную попытку и, начиная с lockoutAfter-й, блокирует account.
+// Пока аккаунт заблокирован, попытки не доходят до проверки, поэтому ключ блокировки
+// к этому моменту уже истёк и Incr задаёт ему новое окно.
+func (s *Server) authFailed(ctx context.Context, account string) {
+	n, _, err := s.RateStore.Incr(ctx, "fail:"+account, lockoutWindow)
+	if err != nil || n < lockoutAfter {
+		return
+	}
+	d := lockoutMax
+	if k := n - lockoutAfter; k < 16 && lockoutBase<<k < lockoutMax {
+		d = lockoutBase << k
+	}
+	_, _, _ = s.RateStore.Incr(ctx, "lock:"+account, d)
+}
+
+// authSucceeded сбрасывает счётчик неудачных попыток account.
+func (s *Server) authSucceeded(ctx context.Context, account string) {
+	_ = s.RateStore.Delete(ctx, "fail:"+account)
+}
+
+// MemoryRateStore — RateStore в памяти процесса; подходит для одной реплики.
+type MemoryRateStore struct {
+	mu      sync.Mutex
+	entries map[string]rateEntry
+	sweepAt time.Time
+}
+
+type rateEntry struct {
+	n       int
+	expires time.Time
+}
+
+func NewMemoryRateStore() *MemoryRateStore {
+	return &MemoryRateStore{entries: map[string]rateEntry{}}
+}
+
+func (m *MemoryRateStore) Incr(ctx context.Context, key string, window time.Duration) (int, time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.sweep(now)
+	e, ok := m.entries[key]
+	if !ok || !now.Before(e.expires) {
+		e = rateEntry{expires: now.Add(window)}
+	}
+	e.n++
+	m.entries[key] = e
+	return e.n, e.expires.Sub(now), nil
+}
+
+func (m *MemoryRateStore) Get(ctx context.Context, key string) (int, time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	e, ok := m.entries[key]
+	if !ok || !now.Before(e.expires) {
+		return 0, 0, nil
+	}
+	return e.n, e.expires.Sub(now), nil
+}
+
+func (m *MemoryRateStore) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.entries, key)
+	return nil
+}
+
+// sweep раз в минуту удаляет истёкшие счётчики (вызывается под mu).
+func (m *MemoryRateStore) sweep(now time.Time) {
+	if now.Before(m.sweepAt) {
+		return
+	}
+	m.sweepAt = now.Add(time.Minute)
+	for k, e := range m.entries {
+		if !now.Before(e.expires) {
+			delete(m.entries, k)
+		}
+	}
+}
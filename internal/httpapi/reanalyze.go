@@ -0,0 +1,175 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"rbac-analyzer/internal/audit"
+	"rbac-analyzer/internal/loader"
+	"rbac-analyzer/internal/rbac"
+	"rbac-analyzer/internal/report"
+	"rbac-analyzer/internal/store"
+)
+
+// analyzeSnapshot — анализ исходного YAML, общий для загрузки и пересчёта.
+func (s *Server) analyzeSnapshot(content []byte) (rbac.SubjectPermissions, map[string]any, report.Report, error) {
+	data, err := loader.LoadFromBytes(content)
+	if err != nil {
+		return nil, nil, report.Report{}, err
+	}
+
+	perms := rbac.BuildSubjectPermissions(
+		data.Roles,
+		data.ClusterRoles,
+		data.RoleBindings,
+		data.ClusterRoleBindings,
+	)
+	if err := rbac.ApplyCustomRules(perms, s.Rules); err != nil {
+		return nil, nil, report.Report{}, err
+	}
+
+	return perms, BuildSummary(perms, data.Workloads), BuildFullReport(perms, data), nil
+}
+
+// ReanalyzeStatus — состояние фонового пересчёта.
+type ReanalyzeStatus struct {
+	Running       bool       `json:"running"`
+	EngineVersion string     `json:"engineVersion"`
+	StartedAt     *time.Time `json:"startedAt,omitempty"`
+	FinishedAt    *time.Time `json:"finishedAt,omitempty"`
+	Done          int        `json:"done"`
+	Failed        int        `json:"failed"`
+	LastError     string     `json:"lastError,omitempty"`
+}
+
+type reanalyzeJob struct {
+	mu     sync.Mutex
+	status ReanalyzeStatus
+}
+
+// StartReanalyze запускает в фоне пересчёт всех сканов, чей результат посчитан
+// другой версией движка. Возвращает false, если пересчёт уже идёт.
+func (s *Server) StartReanalyze() bool {
+	j := &s.reanalyze
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.status.Running {
+		return false
+	}
+
+	now := time.Now().UTC()
+	j.status = ReanalyzeStatus{Running: true, EngineVersion: s.EngineVersion, StartedAt: &now}
+	go s.runReanalyze(context.Background())
+	return true
+}
+
+// ReanalyzeStatus — снимок состояния последнего (или текущего) пересчёта.
+func (s *Server) ReanalyzeStatus() ReanalyzeStatus {
+	s.reanalyze.mu.Lock()
+	defer s.reanalyze.mu.Unlock()
+	return s.reanalyze.status
+}
+
+func (s *Server) runReanalyze(ctx context.Context) {
+	j := &s.reanalyze
+	defer func() {
+		now := time.Now().UTC()
+		j.mu.Lock()
+		j.status.Running = false
+		j.status.FinishedAt = &now
+		j.mu.Unlock()
+	}()
+
+	// успешно пересчитанные выпадают из выборки, неудачные остаются в начале — их пропускаем
+	failed := 0
+	for {
+		batch, err := s.Store.ListStaleScans(ctx, s.EngineVersion, failed, 100)
+		if err != nil {
+			j.mu.Lock()
+			j.status.LastError = err.Error()
+			j.mu.Unlock()
+			return
+		}
+		if len(batch) == 0 {
+			return
+		}
+
+		for _, st := range batch {
+			err := s.reanalyzeScan(ctx, st)
+
+			j.mu.Lock()
+			if err != nil {
+				failed++
+				j.status.Failed++
+				j.status.LastError = "scan " + st.ScanID + ": " + err.Error()
+			} else {
+				j.status.Done++
+			}
+			j.mu.Unlock()
+		}
+	}
+}
+
+// reanalyzeScan пересчитывает один скан по его снимку.
+func (s *Server) reanalyzeScan(ctx context.Context, st store.StaleScan) error {
+	content, err := s.Store.GetSnapshot(ctx, st.SnapshotSHA256)
+	if err != nil {
+		return err
+	}
+	_, sum, full, err := s.analyzeSnapshot(content)
+	if err != nil {
+		return err
+	}
+
+	// audit-лог не хранится: отчёт об использовании прав переносится из прежнего результата
+	if _, old, err := s.Store.GetScanReport(ctx, st.ScanID); err == nil && old["usage"] != nil {
+		b, _ := json.Marshal(old["usage"])
+		var ur audit.UsageReport
+		if json.Unmarshal(b, &ur) == nil {
+			full.Usage = &ur
+			sum["usage"] = ur.Counts
+		}
+	}
+
+	return s.Store.UpsertScanResult(ctx, st.ScanID, sum, full, s.EngineVersion)
+}
+
+// GET  /api/admin/reanalyze — состояние пересчёта и число устаревших сканов
+// POST /api/admin/reanalyze — запустить пересчёт (202; 409, если уже идёт)
+func (s *Server) handleAdminReanalyze(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		stale, err := s.Store.CountStaleScans(r.Context(), s.EngineVersion)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"engineVersion": s.EngineVersion,
+			"stale":         stale,
+			"job":           s.ReanalyzeStatus(),
+		})
+
+	case http.MethodPost:
+		if !s.StartReanalyze() {
+			writeJSON(w, http.StatusConflict, map[string]any{"error": "reanalyze already running", "job": s.ReanalyzeStatus()})
+			return
+		}
+
+		_ = s.Store.AddAdminAudit(
+			r.Context(),
+			GetUserID(r),
+			"reanalyze_scans",
+			"engine",
+			s.EngineVersion,
+			map[string]any{"by_email": GetClaims(r).Email},
+		)
+		writeJSON(w, http.StatusAccepted, map[string]any{"job": s.ReanalyzeStatus()})
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -1,67 +1,64 @@
 package httpapi
 
-import "rbac-analyzer/internal/rbac"
+import (
+	"encoding/json"
+	"net/http"
+
+	"rbac-analyzer/internal/compliance"
+	"rbac-analyzer/internal/loader"
+	"rbac-analyzer/internal/rbac"
+	"rbac-analyzer/internal/report"
+)
 
 // ---- Summary helpers (MVP-коммерческий смысл) ----
 
-func BuildSummary(sp rbac.SubjectPermissions) map[string]any {
-	type counts struct {
-		Subjects    int `json:"subjects"`
-		Roles       int `json:"roles"`
-		Perms       int `json:"perms"`
-		DangerRoles int `json:"dangerRoles"`
-	}
-	c := counts{}
-	topDanger := make([]map[string]any, 0)
+func BuildSummary(sp rbac.SubjectPermissions, workloads []rbac.Workload) map[string]any {
+	sum := rbac.Summarize(sp)
+	cr := compliance.Evaluate(sp, workloads)
+	risk := rbac.ScoreRisk(sp, workloads)
 
-	for subj, roles := range sp {
-		c.Subjects++
-		dCount := 0
-		pCount := 0
-		for _, r := range roles {
-			c.Roles++
-			pCount += len(r.Permissions)
-			if r.Dangerous {
-				c.DangerRoles++
-				dCount++
-			}
-		}
-		c.Perms += pCount
-		if dCount > 0 {
-			topDanger = append(topDanger, map[string]any{
-				"subject":     subj.String(),
-				"dangerRoles": dCount,
-				"perms":       pCount,
-			})
-		}
+	return map[string]any{
+		"counts":    sum.Counts,
+		"riskScore": sum.RiskScore,
+		"topDanger": sum.TopDanger,
+		"compliance": map[string]any{
+			"passed":         cr.Passed,
+			"failed":         cr.Failed,
+			"failedControls": cr.FailedIDs(),
+		},
+		"risk": map[string]any{
+			"modelVersion": risk.ModelVersion,
+			"orgScore":     risk.OrgScore,
+			"topSubjects":  risk.Top(10),
+		},
 	}
+}
 
-	riskScore := 0.0
-	if c.Roles > 0 {
-		riskScore = float64(c.DangerRoles) / float64(c.Roles) * 10.0
-		if riskScore > 10 {
-			riskScore = 10
-		}
+// BuildFullReport — тот же версионированный конверт, что и у CLI (-output json),
+// вместе с результатами CIS/NSA (compliance).
+func BuildFullReport(sp rbac.SubjectPermissions, data *loader.Data) report.Report {
+	return report.Build(sp, report.Meta{InputSHA256: data.SHA256, Workloads: data.Workloads})
+}
+
+// subjectPermsFromReport восстанавливает SubjectPermissions из сохранённого full_report
+// (нужно для экспорта уже сохранённых сканов в другие форматы).
+// Старые отчёты без конверта ({"subjects":[{"subject","roles"}]}) читаются так же.
+func subjectPermsFromReport(full map[string]any) (rbac.SubjectPermissions, error) {
+	b, err := json.Marshal(full)
+	if err != nil {
+		return nil, err
 	}
 
-	return map[string]any{
-		"counts":    c,
-		"riskScore": riskScore,
-		"topDanger": topDanger,
+	var rep report.Report
+	if err := json.Unmarshal(b, &rep); err != nil {
+		return nil, err
 	}
+	return rep.SubjectPermissions(), nil
 }
 
-func BuildFullReport(sp rbac.SubjectPermissions) map[string]any {
-	type subjOut struct {
-		Subject string               `json:"subject"`
-		Roles   []rbac.EffectiveRole `json:"roles"`
-	}
-	out := make([]subjOut, 0, len(sp))
-	for sref, roles := range sp {
-		out = append(out, subjOut{
-			Subject: sref.String(),
-			Roles:   roles,
-		})
-	}
-	return map[string]any{"subjects": out}
+// GET /api/schema/report.v1.json
+func (s *Server) handleReportSchema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(report.Schema)
 }
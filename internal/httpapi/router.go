@@ -2,19 +2,69 @@ package httpapi
 
 import (
 	"net/http"
+	"os"
+	"sync"
 
 	"rbac-analyzer/internal/config"
+	"rbac-analyzer/internal/mail"
+	"rbac-analyzer/internal/rbac"
+	"rbac-analyzer/internal/report"
+	"rbac-analyzer/internal/security"
 	"rbac-analyzer/internal/store"
 )
 
 type Server struct {
 	Cfg   config.Config
-	Store *store.Store
-	Web   http.Handler // static web
+	Store store.Repository
+	Web   http.Handler      // static web
+	Mail  mail.Sender       // приглашения, подтверждение email, сброс пароля; см. newMailSender
+	Keys  *security.Keyring // подпись access token (JWT_KEYS / JWT_SECRET)
+
+	// RateStore — счётчики лимитов запросов и блокировок входа; по умолчанию в памяти
+	// процесса. Для нескольких реплик подставьте общее хранилище (см. ratelimit.go).
+	RateStore RateStore
+
+	Rules []rbac.CustomRule // пользовательские правила опасности (RULES_FILE)
+
+	// EngineVersion записывается в каждый результат; см. report.EngineVersion.
+	EngineVersion string
+
+	reanalyze reanalyzeJob
+	sso       ssoProviders
+	mailJobs  sync.WaitGroup // письма, отправляемые в фоне (sendMailAsync)
 }
 
-func NewServer(cfg config.Config, st *store.Store, web http.Handler) *Server {
-	return &Server{Cfg: cfg, Store: st, Web: web}
+// NewServer паникует при неверном JWT_KEYS: без ключей сервер не может работать.
+func NewServer(cfg config.Config, st store.Repository, web http.Handler) *Server {
+	keys, err := security.NewKeyring(cfg.JWTKeys, cfg.JWTSecret)
+	if err != nil {
+		panic(err)
+	}
+	s := &Server{
+		Cfg:           cfg,
+		Keys:          keys,
+		Store:         st,
+		Web:           web,
+		Mail:          newMailSender(cfg),
+		RateStore:     NewMemoryRateStore(),
+		EngineVersion: report.EngineVersion(""),
+	}
+	if !cfg.DevMode {
+		s.sso.client = newSSOClient()
+	}
+	return s
+}
+
+// newMailSender выбирает отправку писем по конфигурации: SMTP, файл или stderr.
+func newMailSender(cfg config.Config) mail.Sender {
+	switch {
+	case cfg.SMTPAddr != "":
+		return &mail.SMTPSender{Addr: cfg.SMTPAddr, Username: cfg.SMTPUser, Password: cfg.SMTPPassword, From: cfg.MailFrom}
+	case cfg.MailFile != "":
+		return &mail.FileSender{Path: cfg.MailFile, From: cfg.MailFrom}
+	default:
+		return &mail.LogSender{W: os.Stderr}
+	}
 }
 
 func (s *Server) Routes() http.Handler {
@@ -24,58 +74,78 @@ func (s *Server) Routes() http.Handler {
 	mux.HandleFunc("/api/health", s.handleHealth)
 	mux.HandleFunc("/api/auth/register", s.handleRegister)
 	mux.HandleFunc("/api/auth/login", s.handleLogin)
+	mux.HandleFunc("/api/auth/refresh", s.handleRefresh)
+	// второй шаг входа: код TOTP или код восстановления
+	mux.HandleFunc("/api/auth/mfa", s.handleMFALogin)
+	// ссылки из писем: подтверждение email и сброс пароля
+	mux.HandleFunc("/api/auth/verify-email", s.handleVerifyEmail)
+	mux.HandleFunc("/api/auth/password/forgot", s.handleForgotPassword)
+	mux.HandleFunc("/api/auth/password/reset", s.handleResetPassword)
+	// вход через IdP организации (OpenID Connect)
+	mux.HandleFunc("/api/auth/oidc/start", s.handleSSOStart)
+	mux.HandleFunc("/api/auth/oidc/callback", s.handleSSOCallback)
+	mux.HandleFunc("/api/auth/oidc/link", s.handleSSOLink)
+	mux.HandleFunc("/api/schema/report.v1.json", s.handleReportSchema)
+	// приглашение по токену из ссылки: info, decline
+	mux.HandleFunc("/api/invitations/", s.handleInvitations)
 
 	// Billing hooks (stub)
 	mux.HandleFunc("/api/billing/stripe/webhook", s.handleStripeWebhook)
 
-	// Auth middleware key
-	jwtKey := []byte(s.Cfg.JWTSecret)
+	// Auth middleware: access token + действующая сессия
+	auth := func(h http.HandlerFunc) http.Handler { return AuthMiddleware(s.Keys, s.Store, h) }
+	admin := func(h http.HandlerFunc) http.Handler { return auth(RequireAdmin(s.Store, h).ServeHTTP) }
+
+	mux.Handle("/api/auth/logout", auth(s.handleLogout))
 
 	// App API (auth required)
-	mux.Handle("/api/app/me", AuthMiddleware(jwtKey, http.HandlerFunc(s.handleMe)))
-	mux.Handle("/api/app/clusters", AuthMiddleware(jwtKey, http.HandlerFunc(s.handleClusters)))
-	mux.Handle("/api/app/scans", AuthMiddleware(jwtKey, http.HandlerFunc(s.handleScans)))
-	mux.Handle("/api/app/scans/diff", AuthMiddleware(jwtKey, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	mux.Handle("/api/app/me", auth(s.handleMe))
+	mux.Handle("/api/app/verify-email", auth(s.handleResendVerification))
+	mux.Handle("/api/app/password", auth(s.handleChangePassword))
+	// второй фактор: /api/app/mfa[/enroll|/enable|/recovery-codes]
+	mux.Handle("/api/app/mfa", auth(s.handleMFA))
+	mux.Handle("/api/app/mfa/", auth(s.handleMFA))
+	mux.Handle("/api/app/orgs", auth(s.handleOrgs))
+	// текущая организация (X-Org-ID): members, transfer, invitations, teams, sso, mfa
+	mux.Handle("/api/app/org/", auth(s.handleOrg))
+	mux.Handle("/api/app/invitations/accept", auth(s.handleInvitationAccept))
+	// привязка входа через IdP к текущему аккаунту (после #ssoLink на /login)
+	mux.Handle("/api/app/sso/link", auth(s.handleSSOLink))
+	mux.Handle("/api/app/clusters", auth(s.handleClusters))
+	// флаг restricted и гранты кластера: /api/app/clusters/{id}[/grants...]
+	mux.Handle("/api/app/clusters/", auth(s.handleClusterItem))
+	mux.Handle("/api/app/scans", auth(s.handleScans))
+	mux.Handle("/api/app/scans/diff", auth(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			w.WriteHeader(http.StatusMethodNotAllowed)
 			return
 		}
 		s.handleDiffScans(w, r)
-	})))
-	mux.Handle("/api/app/scan/report", AuthMiddleware(jwtKey, http.HandlerFunc(s.handleScanReport)))
+	}))
+	// сохранённый скан: /api/app/scans/{id}/export.html, /api/app/scans/{id}/simulate
+	mux.Handle("/api/app/scans/", auth(s.handleScanItem))
+	mux.Handle("/api/app/scan/report", auth(s.handleScanReport))
+	// свои сессии: список и выход на других устройствах
+	mux.Handle("/api/app/sessions", auth(s.handleSessions))
+	mux.Handle("/api/app/sessions/", auth(s.handleSessions))
 
 	// Admin API (auth + admin required)
 
 	// список пользователей
-	mux.Handle(
-		"/api/admin/users",
-		AuthMiddleware(
-			jwtKey,
-			RequireAdmin(http.HandlerFunc(s.handleAdminUsers)),
-		),
-	)
-
-	// toggle admin (POST /api/admin/users/{id}/toggle-admin)
-	mux.Handle(
-		"/api/admin/users/",
-		AuthMiddleware(
-			jwtKey,
-			RequireAdmin(http.HandlerFunc(s.handleAdminToggleUser)),
-		),
-	)
+	mux.Handle("/api/admin/users", admin(s.handleAdminUsers))
+
+	// POST /api/admin/users/{id}/toggle-admin, GET|DELETE /api/admin/users/{id}/sessions,
+	// DELETE /api/admin/users/{id}/mfa
+	mux.Handle("/api/admin/users/", admin(s.handleAdminUser))
 
 	// список организаций
-	mux.Handle(
-		"/api/admin/orgs",
-		AuthMiddleware(
-			jwtKey,
-			RequireAdmin(http.HandlerFunc(s.handleAdminOrgs)),
-		),
-	)
-	mux.Handle(
-		"/api/admin/audit",
-		AuthMiddleware(jwtKey, RequireAdmin(http.HandlerFunc(s.handleAdminAudit))),
-	)
+	mux.Handle("/api/admin/orgs", admin(s.handleAdminOrgs))
+	// эффективные гранты на кластеры (GET /api/admin/orgs/{id}/grants)
+	mux.Handle("/api/admin/orgs/", admin(s.handleAdminOrgGrants))
+	mux.Handle("/api/admin/audit", admin(s.handleAdminAudit))
+
+	// пересчёт сохранённых сканов текущей версией движка
+	mux.Handle("/api/admin/reanalyze", admin(s.handleAdminReanalyze))
 
 	// Static site last
 	mux.Handle("/", s.Web)
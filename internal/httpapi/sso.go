@@ -0,0 +1,203 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"rbac-analyzer/internal/oidc"
+)
+
+// ssoCookie хранит state, nonce и PKCE verifier между /start и /callback.
+// Cookie подписана ключом подписи токенов (Keyring.MAC), поэтому сервер между запросами
+// ничего не хранит.
+const (
+	ssoCookie     = "rbac_sso"
+	ssoLinkCookie = "rbac_sso_link"
+	ssoStateTTL   = 10 * time.Minute
+)
+
+type ssoState struct {
+	State    string `json:"s"`
+	Nonce    string `json:"n"`
+	Verifier string `json:"v"`
+	OrgID    string `json:"o"`
+	Exp      int64  `json:"e"`
+}
+
+// sealSSO кодирует v и подписывает; openSSO проверяет подпись и декодирует.
+func (s *Server) sealSSO(v any) string {
+	b, _ := json.Marshal(v)
+	payload := base64.RawURLEncoding.EncodeToString(b)
+	return payload + "." + s.Keys.MAC("sso", payload)
+}
+
+func (s *Server) openSSO(value string, v any) bool {
+	payload, mac, ok := strings.Cut(value, ".")
+	if !ok || !s.Keys.CheckMAC("sso", payload, mac) {
+		return false
+	}
+	b, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(b, v) == nil
+}
+
+func (s *Server) setSSOState(w http.ResponseWriter, st ssoState) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     ssoCookie,
+		Value:    s.sealSSO(st),
+		Path:     "/api/auth/oidc/",
+		MaxAge:   int(ssoStateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(s.Cfg.BaseURL, "https://"),
+		// Lax: cookie уходит при возврате с IdP (top-level GET)
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// ssoStateFrom читает и сразу удаляет cookie: state одноразовый.
+func (s *Server) ssoStateFrom(w http.ResponseWriter, r *http.Request) (ssoState, bool) {
+	http.SetCookie(w, &http.Cookie{Name: ssoCookie, Path: "/api/auth/oidc/", MaxAge: -1, HttpOnly: true})
+
+	c, err := r.Cookie(ssoCookie)
+	if err != nil {
+		return ssoState{}, false
+	}
+	var st ssoState
+	if !s.openSSO(c.Value, &st) || time.Now().Unix() > st.Exp {
+		return ssoState{}, false
+	}
+	return st, true
+}
+
+// ssoLink — учётная запись IdP, которую нужно привязать к существующему аккаунту.
+// Хранится в подписанной cookie браузера, прошедшего вход через IdP: подтвердить её
+// (паролем или из сессии аккаунта) можно только из этого браузера, а не по ссылке.
+type ssoLink struct {
+	UserID  string `json:"u"`
+	Issuer  string `json:"i"`
+	Subject string `json:"s"`
+	OrgID   string `json:"o"`
+	Role    string `json:"r"` // роль по группам IdP на момент входа
+	Exp     int64  `json:"e"`
+}
+
+func (s *Server) setSSOLink(w http.ResponseWriter, l ssoLink) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     ssoLinkCookie,
+		Value:    s.sealSSO(l),
+		Path:     "/api/",
+		MaxAge:   int(ssoStateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(s.Cfg.BaseURL, "https://"),
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+func (s *Server) ssoLinkFrom(r *http.Request) (ssoLink, bool) {
+	c, err := r.Cookie(ssoLinkCookie)
+	if err != nil {
+		return ssoLink{}, false
+	}
+	var l ssoLink
+	if !s.openSSO(c.Value, &l) || time.Now().Unix() > l.Exp {
+		return ssoLink{}, false
+	}
+	return l, true
+}
+
+func clearSSOLink(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{Name: ssoLinkCookie, Path: "/api/", MaxAge: -1, HttpOnly: true})
+}
+
+// ssoProviders — кэш discovery и JWKS по issuer (метаданные перечитываются раз в час).
+type ssoProviders struct {
+	mu   sync.Mutex
+	byIs map[string]cachedProvider
+
+	client *http.Client // запросы к IdP; nil — oidc по умолчанию (только DEV_MODE)
+}
+
+type cachedProvider struct {
+	p       *oidc.Provider
+	fetched time.Time
+}
+
+const ssoDiscoveryTTL = time.Hour
+
+func (c *ssoProviders) get(ctx context.Context, issuer string) (*oidc.Provider, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cp, ok := c.byIs[issuer]; ok && time.Since(cp.fetched) < ssoDiscoveryTTL {
+		return cp.p, nil
+	}
+	p, err := oidc.Discover(ctx, c.client, issuer)
+	if err != nil {
+		return nil, err
+	}
+	if c.byIs == nil {
+		c.byIs = map[string]cachedProvider{}
+	}
+	c.byIs[issuer] = cachedProvider{p: p, fetched: time.Now()}
+	return p, nil
+}
+
+// ssoRedirectURL — redirect_uri, который нужно зарегистрировать у IdP (один на все организации).
+func (s *Server) ssoRedirectURL() string {
+	return strings.TrimRight(s.Cfg.BaseURL, "/") + "/api/auth/oidc/callback"
+}
+
+// errSSOAddress — адрес IdP во внутренней сети.
+var errSSOAddress = errors.New("identity provider address is not public")
+
+// newSSOClient — клиент для запросов к IdP. Issuer задаёт администратор организации,
+// поэтому без ограничений сервер ходил бы по его указке во внутреннюю сеть (SSRF).
+// Разрешены только https и публичные адреса; адрес проверяется при соединении,
+// так что не помогают ни редиректы, ни DNS, отвечающий внутренним адресом.
+func newSSOClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+				return errSSOAddress
+			}
+			return nil
+		},
+	}
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.Proxy = nil
+	tr.DialContext = dialer.DialContext
+	return &http.Client{Timeout: 10 * time.Second, Transport: httpsOnly{tr}}
+}
+
+// httpsOnly отклоняет запросы не по https (в т.ч. endpoints из discovery и редиректы).
+type httpsOnly struct{ next http.RoundTripper }
+
+func (t httpsOnly) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != "https" {
+		return nil, errors.New("identity provider URL must be https")
+	}
+	return t.next.RoundTrip(req)
+}
+
+func publicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || cgnat.Contains(ip))
+}
+
+// cgnat — разделяемые адреса провайдеров (RFC 6598), в облаках бывают внутренними.
+var cgnat = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
@@ -2,6 +2,8 @@ package loader
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io/fs"
 	"os"
@@ -19,6 +21,10 @@ type Data struct {
 	ClusterRoles        []rbac.ClusterRole
 	RoleBindings        []rbac.RoleBinding
 	ClusterRoleBindings []rbac.ClusterRoleBinding
+	Workloads           []rbac.Workload
+
+	// SHA256 — хеш входных данных (для метаданных отчёта и дедупликации).
+	SHA256 string
 }
 
 // typeMeta нужен для определения kind
@@ -26,6 +32,32 @@ type typeMeta struct {
 	Kind string `yaml:"kind"`
 }
 
+// podSpecMeta — только то, что нужно от PodSpec
+type podSpecMeta struct {
+	ServiceAccountName string `yaml:"serviceAccountName"`
+	ServiceAccount     string `yaml:"serviceAccount"` // устаревший алиас
+
+	AutomountServiceAccountToken *bool `yaml:"automountServiceAccountToken"`
+}
+
+// workloadMeta покрывает Pod, контроллеры с template и CronJob
+type workloadMeta struct {
+	Metadata rbac.ObjectMeta `yaml:"metadata"`
+	Spec     struct {
+		podSpecMeta `yaml:",inline"`
+		Template    struct {
+			Spec podSpecMeta `yaml:"spec"`
+		} `yaml:"template"`
+		JobTemplate struct {
+			Spec struct {
+				Template struct {
+					Spec podSpecMeta `yaml:"spec"`
+				} `yaml:"template"`
+			} `yaml:"spec"`
+		} `yaml:"jobTemplate"`
+	} `yaml:"spec"`
+}
+
 // listMeta — поддержка kubectl get ... -o yaml (kind: List)
 type listMeta struct {
 	Items []map[string]interface{} `yaml:"items"`
@@ -34,7 +66,9 @@ type listMeta struct {
 // LoadFromDir рекурсивно читает YAML-файлы и извлекает RBAC-объекты.
 func LoadFromDir(root string) (*Data, error) {
 	data := &Data{}
+	h := sha256.New()
 
+	// WalkDir обходит файлы в лексическом порядке, поэтому хеш детерминирован
 	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
@@ -51,6 +85,12 @@ func LoadFromDir(root string) (*Data, error) {
 			return fmt.Errorf("read file %s: %w", path, err)
 		}
 
+		rel, _ := filepath.Rel(root, path)
+		h.Write([]byte(filepath.ToSlash(rel)))
+		h.Write([]byte{0})
+		h.Write(content)
+		h.Write([]byte{0})
+
 		docs := splitYAMLDocuments(content)
 		for _, doc := range docs {
 			if len(bytes.TrimSpace(doc)) == 0 {
@@ -64,6 +104,7 @@ func LoadFromDir(root string) (*Data, error) {
 	if err != nil {
 		return nil, err
 	}
+	data.SHA256 = hex.EncodeToString(h.Sum(nil))
 	return data, nil
 }
 
@@ -121,6 +162,45 @@ func parseSingleObject(doc []byte, data *Data) {
 		if err := yaml.Unmarshal(doc, &crb); err == nil {
 			data.ClusterRoleBindings = append(data.ClusterRoleBindings, crb)
 		}
+	case "Pod", "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "Job", "CronJob":
+		var wm workloadMeta
+		if err := yaml.Unmarshal(doc, &wm); err == nil {
+			data.Workloads = append(data.Workloads, workloadFromMeta(tm.Kind, wm))
+		}
+	}
+}
+
+// workloadFromMeta достаёт serviceAccountName (по умолчанию — "default").
+func workloadFromMeta(kind string, wm workloadMeta) rbac.Workload {
+	var ps podSpecMeta
+	switch kind {
+	case "Pod":
+		ps = wm.Spec.podSpecMeta
+	case "CronJob":
+		ps = wm.Spec.JobTemplate.Spec.Template.Spec
+	default:
+		ps = wm.Spec.Template.Spec
+	}
+
+	sa := ps.ServiceAccountName
+	if sa == "" {
+		sa = ps.ServiceAccount
+	}
+	if sa == "" {
+		sa = "default"
+	}
+
+	ns := wm.Metadata.Namespace
+	if ns == "" {
+		ns = "default"
+	}
+
+	return rbac.Workload{
+		Kind:           kind,
+		Name:           wm.Metadata.Name,
+		Namespace:      ns,
+		ServiceAccount: sa,
+		AutomountToken: ps.AutomountServiceAccountToken == nil || *ps.AutomountServiceAccountToken,
 	}
 }
 
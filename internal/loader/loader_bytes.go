@@ -2,6 +2,8 @@ package loader
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 
 	"gopkg.in/yaml.v3"
 )
@@ -9,7 +11,8 @@ import (
 // LoadFromBytes парсит YAML (включая kind: List и multi-doc) напрямую из памяти.
 // Это удобно для веба (upload файла).
 func LoadFromBytes(content []byte) (*Data, error) {
-	data := &Data{}
+	sum := sha256.Sum256(content)
+	data := &Data{SHA256: hex.EncodeToString(sum[:])}
 
 	docs := splitYAMLDocuments(content)
 	for _, doc := range docs {
@@ -0,0 +1,226 @@
+package loader
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"rbac-analyzer/internal/rbac"
+)
+
+// ObjectRef — ссылка на RBAC-объект: "ClusterRoleBinding/name" или "RoleBinding/ns/name".
+type ObjectRef struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+func (o ObjectRef) String() string {
+	if o.Namespace != "" {
+		return o.Kind + "/" + o.Namespace + "/" + o.Name
+	}
+	return o.Kind + "/" + o.Name
+}
+
+var kindAliases = map[string]string{
+	"role":               "Role",
+	"clusterrole":        "ClusterRole",
+	"rolebinding":        "RoleBinding",
+	"rb":                 "RoleBinding",
+	"clusterrolebinding": "ClusterRoleBinding",
+	"crb":                "ClusterRoleBinding",
+}
+
+// ParseObjectRef разбирает ссылку. Role/RoleBinding требуют namespace, кластерные — нет.
+func ParseObjectRef(s string) (ObjectRef, error) {
+	parts := strings.Split(strings.TrimSpace(s), "/")
+	if len(parts) < 2 {
+		return ObjectRef{}, fmt.Errorf("bad object ref %q: want Kind/name or Kind/namespace/name", s)
+	}
+	kind, ok := kindAliases[strings.ToLower(parts[0])]
+	if !ok {
+		return ObjectRef{}, fmt.Errorf("bad object ref %q: unknown kind %s", s, parts[0])
+	}
+
+	namespaced := kind == "Role" || kind == "RoleBinding"
+	switch {
+	case namespaced && len(parts) == 3:
+		return ObjectRef{Kind: kind, Namespace: parts[1], Name: parts[2]}, nil
+	case !namespaced && len(parts) == 2:
+		return ObjectRef{Kind: kind, Name: parts[1]}, nil
+	case namespaced:
+		return ObjectRef{}, fmt.Errorf("bad object ref %q: %s needs Kind/namespace/name", s, kind)
+	default:
+		return ObjectRef{}, fmt.Errorf("bad object ref %q: %s is cluster-scoped", s, kind)
+	}
+}
+
+// Clone — копия списков объектов (сами объекты не меняются, поэтому достаточно мелкой копии).
+func (d *Data) Clone() *Data {
+	return &Data{
+		Roles:               append([]rbac.Role{}, d.Roles...),
+		ClusterRoles:        append([]rbac.ClusterRole{}, d.ClusterRoles...),
+		RoleBindings:        append([]rbac.RoleBinding{}, d.RoleBindings...),
+		ClusterRoleBindings: append([]rbac.ClusterRoleBinding{}, d.ClusterRoleBindings...),
+		Workloads:           append([]rbac.Workload{}, d.Workloads...),
+		SHA256:              d.SHA256,
+	}
+}
+
+// Apply добавляет объекты patch (включая рабочие нагрузки); объект с тем же
+// kind/namespace/name заменяется.
+// Возвращает ссылки на применённые объекты.
+func (d *Data) Apply(patch *Data) []ObjectRef {
+	var applied []ObjectRef
+
+	for _, r := range patch.Roles {
+		ref := ObjectRef{Kind: "Role", Namespace: r.Metadata.Namespace, Name: r.Metadata.Name}
+		d.Roles = upsert(d.Roles, r, func(x rbac.Role) bool { return x.Metadata == r.Metadata })
+		applied = append(applied, ref)
+	}
+	for _, cr := range patch.ClusterRoles {
+		ref := ObjectRef{Kind: "ClusterRole", Name: cr.Metadata.Name}
+		d.ClusterRoles = upsert(d.ClusterRoles, cr, func(x rbac.ClusterRole) bool { return x.Metadata.Name == cr.Metadata.Name })
+		applied = append(applied, ref)
+	}
+	for _, rb := range patch.RoleBindings {
+		ref := ObjectRef{Kind: "RoleBinding", Namespace: rb.Metadata.Namespace, Name: rb.Metadata.Name}
+		d.RoleBindings = upsert(d.RoleBindings, rb, func(x rbac.RoleBinding) bool { return x.Metadata == rb.Metadata })
+		applied = append(applied, ref)
+	}
+	for _, crb := range patch.ClusterRoleBindings {
+		ref := ObjectRef{Kind: "ClusterRoleBinding", Name: crb.Metadata.Name}
+		d.ClusterRoleBindings = upsert(d.ClusterRoleBindings, crb, func(x rbac.ClusterRoleBinding) bool {
+			return x.Metadata.Name == crb.Metadata.Name
+		})
+		applied = append(applied, ref)
+	}
+	// рабочие нагрузки тоже заменяются: иначе повторно объявленный Deployment
+	// считался бы дважды (например, в оценке риска)
+	for _, w := range patch.Workloads {
+		d.Workloads = upsert(d.Workloads, w, func(x rbac.Workload) bool {
+			return x.Kind == w.Kind && x.Namespace == w.Namespace && x.Name == w.Name
+		})
+	}
+	return applied
+}
+
+// Delete удаляет объекты по ссылкам. Возвращает ссылки, которых не было в данных.
+func (d *Data) Delete(refs []ObjectRef) []ObjectRef {
+	var notFound []ObjectRef
+	for _, ref := range refs {
+		var n int
+		switch ref.Kind {
+		case "Role":
+			d.Roles, n = remove(d.Roles, func(x rbac.Role) bool {
+				return x.Metadata.Namespace == ref.Namespace && x.Metadata.Name == ref.Name
+			})
+		case "ClusterRole":
+			d.ClusterRoles, n = remove(d.ClusterRoles, func(x rbac.ClusterRole) bool { return x.Metadata.Name == ref.Name })
+		case "RoleBinding":
+			d.RoleBindings, n = remove(d.RoleBindings, func(x rbac.RoleBinding) bool {
+				return x.Metadata.Namespace == ref.Namespace && x.Metadata.Name == ref.Name
+			})
+		case "ClusterRoleBinding":
+			d.ClusterRoleBindings, n = remove(d.ClusterRoleBindings, func(x rbac.ClusterRoleBinding) bool {
+				return x.Metadata.Name == ref.Name
+			})
+		}
+		if n == 0 {
+			notFound = append(notFound, ref)
+		}
+	}
+	return notFound
+}
+
+func upsert[T any](items []T, v T, same func(T) bool) []T {
+	for i := range items {
+		if same(items[i]) {
+			items[i] = v
+			return items
+		}
+	}
+	return append(items, v)
+}
+
+func remove[T any](items []T, match func(T) bool) ([]T, int) {
+	out := items[:0]
+	n := 0
+	for _, x := range items {
+		if match(x) {
+			n++
+			continue
+		}
+		out = append(out, x)
+	}
+	return out, n
+}
+
+// FromSubjectPermissions восстанавливает роли и биндинги из эффективных прав
+// (например, из сохранённого отчёта, когда исходного YAML нет).
+// Правила ролей собираются заново через rbac.CompactRules. Роли без биндингов
+// и рабочие нагрузки в отчёт не попадают и потеряны.
+func FromSubjectPermissions(sp rbac.SubjectPermissions) *Data {
+	d := &Data{}
+
+	roles := map[string]bool{}
+	bindings := map[string]bool{}
+
+	refs := make([]rbac.SubjectRef, 0, len(sp))
+	for s := range sp {
+		refs = append(refs, s)
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].String() < refs[j].String() })
+
+	for _, s := range refs {
+		for _, r := range sp[s] {
+			if !roles[r.RoleLabel()] {
+				roles[r.RoleLabel()] = true
+				rules := rbac.CompactRules(r.Permissions)
+				if r.SourceKind == "Role" {
+					d.Roles = append(d.Roles, rbac.Role{
+						APIVersion: "rbac.authorization.k8s.io/v1", Kind: "Role",
+						Metadata: rbac.ObjectMeta{Name: r.SourceName, Namespace: r.SourceNamespace},
+						Rules:    rules,
+					})
+				} else {
+					d.ClusterRoles = append(d.ClusterRoles, rbac.ClusterRole{
+						APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRole",
+						Metadata: rbac.ObjectMeta{Name: r.SourceName},
+						Rules:    rules,
+					})
+				}
+			}
+
+			if bindings[r.BindingLabel()] {
+				continue
+			}
+			bindings[r.BindingLabel()] = true
+
+			subjects := make([]rbac.Subject, 0, len(r.BindingSubjects))
+			for _, bs := range r.BindingSubjects {
+				ref := rbac.ParseSubjectRef(bs)
+				subjects = append(subjects, rbac.Subject{Kind: string(ref.Kind), Name: ref.Name, Namespace: ref.Namespace})
+			}
+			if len(subjects) == 0 {
+				subjects = append(subjects, rbac.Subject{Kind: string(s.Kind), Name: s.Name, Namespace: s.Namespace})
+			}
+			roleRef := rbac.RoleRef{APIGroup: "rbac.authorization.k8s.io", Kind: r.SourceKind, Name: r.SourceName}
+
+			if r.BoundVia == "ClusterRoleBinding" {
+				d.ClusterRoleBindings = append(d.ClusterRoleBindings, rbac.ClusterRoleBinding{
+					APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRoleBinding",
+					Metadata: rbac.ObjectMeta{Name: r.BindingName},
+					Subjects: subjects, RoleRef: roleRef,
+				})
+			} else {
+				d.RoleBindings = append(d.RoleBindings, rbac.RoleBinding{
+					APIVersion: "rbac.authorization.k8s.io/v1", Kind: "RoleBinding",
+					Metadata: rbac.ObjectMeta{Name: r.BindingName, Namespace: r.BindingNS},
+					Subjects: subjects, RoleRef: roleRef,
+				})
+			}
+		}
+	}
+	return d
+}
@@ -0,0 +1,45 @@
+package loader
+
+import (
+	"reflect"
+	"testing"
+
+	"rbac-analyzer/internal/rbac"
+)
+
+func TestApplyUpserts(t *testing.T) {
+	base := &Data{
+		ClusterRoles: []rbac.ClusterRole{{Metadata: rbac.ObjectMeta{Name: "view"}}},
+		Workloads: []rbac.Workload{
+			{Kind: "Deployment", Name: "api", Namespace: "prod", ServiceAccount: "default"},
+			{Kind: "Deployment", Name: "api", Namespace: "dev", ServiceAccount: "default"},
+		},
+	}
+	patch := &Data{
+		ClusterRoles: []rbac.ClusterRole{{Metadata: rbac.ObjectMeta{Name: "view"}, Rules: []rbac.PolicyRule{{Verbs: []string{"get"}}}}},
+		Workloads: []rbac.Workload{
+			{Kind: "Deployment", Name: "api", Namespace: "prod", ServiceAccount: "api"},
+			{Kind: "StatefulSet", Name: "api", Namespace: "prod", ServiceAccount: "db"},
+		},
+	}
+
+	d := base.Clone()
+	applied := d.Apply(patch)
+	if want := []ObjectRef{{Kind: "ClusterRole", Name: "view"}}; !reflect.DeepEqual(applied, want) {
+		t.Fatalf("applied = %v, want %v", applied, want)
+	}
+	if len(d.ClusterRoles) != 1 || len(d.ClusterRoles[0].Rules) != 1 {
+		t.Fatalf("cluster roles = %+v", d.ClusterRoles)
+	}
+	want := []rbac.Workload{
+		{Kind: "Deployment", Name: "api", Namespace: "prod", ServiceAccount: "api"},
+		{Kind: "Deployment", Name: "api", Namespace: "dev", ServiceAccount: "default"},
+		{Kind: "StatefulSet", Name: "api", Namespace: "prod", ServiceAccount: "db"},
+	}
+	if !reflect.DeepEqual(d.Workloads, want) {
+		t.Fatalf("workloads = %+v, want %+v", d.Workloads, want)
+	}
+	if base.Workloads[0].ServiceAccount != "default" {
+		t.Fatal("Apply on a clone changed the original")
+	}
+}
@@ -0,0 +1,62 @@
+// Package mail — отправка писем сервера: приглашения, подтверждение email, сброс пароля.
+package mail
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Message — простое текстовое письмо.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender отправляет письма.
+type Sender interface {
+	Send(ctx context.Context, m Message) error
+}
+
+// LogSender не отправляет письма, а печатает их в W (для разработки и установок без почты).
+type LogSender struct {
+	mu sync.Mutex
+	W  io.Writer
+}
+
+func (l *LogSender) Send(ctx context.Context, m Message) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err := fmt.Fprintf(l.W, "mail to=%s subject=%q\n%s\n", m.To, m.Subject, m.Body)
+	return err
+}
+
+// FileSender дописывает письма в файл Path целиком, с заголовками (для локальной проверки:
+// ссылки из писем можно открыть, не настраивая SMTP).
+type FileSender struct {
+	mu   sync.Mutex
+	Path string
+	From string
+}
+
+func (f *FileSender) Send(ctx context.Context, m Message) error {
+	msg, err := compose(f.From, m)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	file, err := os.OpenFile(f.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(msg, "\r\n\r\n"...)); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,78 @@
+package migrate
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"rbac-analyzer/migrations"
+)
+
+func sqlFS(names ...string) fstest.MapFS {
+	fsys := fstest.MapFS{}
+	for _, n := range names {
+		fsys[n] = &fstest.MapFile{Data: []byte("-- " + n)}
+	}
+	return fsys
+}
+
+func TestLoadOrdered(t *testing.T) {
+	fsys := sqlFS(
+		"010_ten.up.sql", "010_ten.down.sql",
+		"002_two.up.sql", "002_two.down.sql",
+		"001_one.down.sql", "001_one.up.sql",
+	)
+	for v := 3; v <= 9; v++ {
+		name := fmt.Sprintf("%03d_step", v)
+		fsys[name+".up.sql"] = &fstest.MapFile{Data: []byte("up")}
+		fsys[name+".down.sql"] = &fstest.MapFile{Data: []byte("down")}
+	}
+	fsys["README.md"] = &fstest.MapFile{Data: []byte("not a migration")}
+
+	ms, err := Load(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 10 {
+		t.Fatalf("loaded %d migrations, want 10", len(ms))
+	}
+	for i, m := range ms {
+		if m.Version != i+1 {
+			t.Fatalf("migration %d has version %d", i, m.Version)
+		}
+	}
+	if last := ms[9]; last.Name != "ten" || last.Up != "-- 010_ten.up.sql" || last.Down != "-- 010_ten.down.sql" {
+		t.Fatalf("last migration %+v", last)
+	}
+}
+
+func TestLoadRejects(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		files []string
+		want  string
+	}{
+		{"missing down", []string{"001_init.up.sql"}, "both up and down"},
+		{"missing up", []string{"001_init.down.sql"}, "both up and down"},
+		{"conflicting names", []string{"001_init.up.sql", "001_setup.down.sql"}, "conflicting names"},
+		{"gap", []string{"001_init.up.sql", "001_init.down.sql", "003_more.up.sql", "003_more.down.sql"}, "missing version 002"},
+		{"not starting at 001", []string{"002_init.up.sql", "002_init.down.sql"}, "missing version 001"},
+		{"bad name", []string{"1-init.up.sql"}, "want NNN_name"},
+	} {
+		_, err := Load(sqlFS(c.files...))
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: err = %v, want %q", c.name, err, c.want)
+		}
+	}
+}
+
+func TestLoadEmbedded(t *testing.T) {
+	ms, err := Load(migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) == 0 {
+		t.Fatal("no embedded migrations")
+	}
+}
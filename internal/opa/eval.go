@@ -0,0 +1,151 @@
+package opa
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/open-policy-agent/opa/rego"
+
+	"rbac-analyzer/internal/rbac"
+)
+
+// DefaultQuery — что вычисляем в загруженных .rego файлах.
+// Политика должна объявить `package rbac` и множество `findings` с объектами
+//
+//	{"id": "...", "message": "...", "subject": "...", "role": "...", "binding": "...", "severity": "..."}
+//
+// role/binding необязательны: без них находка относится ко всем ролям субъекта.
+// severity — low, medium, high или critical (по умолчанию high).
+const DefaultQuery = "data.rbac.findings"
+
+// Finding — результат Rego-правила.
+type Finding struct {
+	ID      string `json:"id"`
+	Message string `json:"message"`
+	Subject string `json:"subject"`
+	Role    string `json:"role,omitempty"`
+	Binding string `json:"binding,omitempty"`
+
+	Severity string `json:"severity,omitempty"`
+}
+
+// Eval выполняет локальные .rego файлы (или каталоги) над input.
+func Eval(ctx context.Context, paths []string, query string, in Input) ([]Finding, error) {
+	if query == "" {
+		query = DefaultQuery
+	}
+
+	// rego работает с "чистым" JSON, поэтому прогоняем input через encoding/json
+	b, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+	var raw any
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, err
+	}
+
+	rs, err := rego.New(
+		rego.Query(query),
+		rego.Load(paths, nil),
+		rego.Input(raw),
+	).Eval(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("rego: %w", err)
+	}
+
+	var out []Finding
+	for _, r := range rs {
+		for _, expr := range r.Expressions {
+			items, ok := expr.Value.([]any)
+			if !ok {
+				return nil, fmt.Errorf("rego: %s must be a set or array of objects", query)
+			}
+			for _, it := range items {
+				b, err := json.Marshal(it)
+				if err != nil {
+					return nil, err
+				}
+				var f Finding
+				if err := json.Unmarshal(b, &f); err != nil {
+					return nil, fmt.Errorf("rego: bad finding %s: %w", b, err)
+				}
+				if f.Subject == "" || f.Message == "" {
+					return nil, fmt.Errorf("rego: finding %s: subject and message required", b)
+				}
+				f.Severity = strings.ToLower(strings.TrimSpace(f.Severity))
+				switch f.Severity {
+				case "":
+					f.Severity = rbac.SeverityHigh
+				case rbac.SeverityLow, rbac.SeverityMedium, rbac.SeverityHigh, rbac.SeverityCritical:
+				default:
+					return nil, fmt.Errorf("rego: finding %s: severity must be low, medium, high or critical", b)
+				}
+				out = append(out, f)
+			}
+		}
+	}
+	return out, nil
+}
+
+// Merge добавляет находки Rego к Findings соответствующих ролей (рядом с EvaluateDangerous).
+// Находки от medium и выше делают роль опасной и попадают в DangerReasons; low только
+// учитываются в Findings (и в оценке риска), опасной роль не становится.
+// Возвращает число находок, которые не удалось привязать ни к одной роли.
+func Merge(sp rbac.SubjectPermissions, findings []Finding) int {
+	unmatched := 0
+	for _, f := range findings {
+		sref := rbac.ParseSubjectRef(f.Subject)
+		roles, ok := sp[sref]
+		if !ok {
+			unmatched++
+			continue
+		}
+
+		reason := f.Message
+		if f.ID != "" {
+			reason = "[rego:" + f.ID + "] " + f.Message
+		}
+		finding := rbac.Finding{RuleID: "rego:" + f.ID, Severity: f.Severity, Message: f.Message}
+		if finding.Severity == "" {
+			finding.Severity = rbac.SeverityHigh
+		}
+		dangerous := finding.Severity != rbac.SeverityLow
+
+		hit := false
+		for i := range roles {
+			r := &roles[i]
+			if f.Role != "" && f.Role != r.RoleLabel() {
+				continue
+			}
+			if f.Binding != "" && f.Binding != r.BindingLabel() {
+				continue
+			}
+			hit = true
+			if hasFinding(r.Findings, finding) {
+				continue
+			}
+			r.Findings = append(r.Findings, finding)
+			if dangerous {
+				r.Dangerous = true
+				r.DangerReasons = append(r.DangerReasons, reason)
+			}
+		}
+		if !hit {
+			unmatched++
+		}
+	}
+	return unmatched
+}
+
+// hasFinding: та же находка (правило и сообщение) уже есть у роли.
+func hasFinding(fs []rbac.Finding, f rbac.Finding) bool {
+	for _, x := range fs {
+		if x.RuleID == f.RuleID && x.Message == f.Message {
+			return true
+		}
+	}
+	return false
+}
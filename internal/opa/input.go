@@ -0,0 +1,142 @@
+package opa
+
+import (
+	"sort"
+
+	"rbac-analyzer/internal/loader"
+	"rbac-analyzer/internal/rbac"
+)
+
+// InputVersion — версия формата input для Rego-политик.
+const InputVersion = "rbac-analyzer.opa-input/v1"
+
+// Input — нормализованная модель, которую получают Rego-политики как `input`.
+//
+//	{
+//	  "version":   "rbac-analyzer.opa-input/v1",
+//	  "subjects":  [{"subject": "ServiceAccount:ns/name", "kind", "name", "namespace",
+//	                 "roles": [EffectiveRole + "role", "binding"]}],
+//	  "roles":     [{"kind": "ClusterRole", "name", "namespace", "rules": [PolicyRule]}],
+//	  "bindings":  [{"kind": "RoleBinding", "name", "namespace", "roleRef", "subjects": ["User:alice"]}],
+//	  "workloads": [{"kind": "Deployment", "name", "namespace", "serviceAccount"}]
+//	}
+//
+// "role" и "binding" в ролях субъекта — ключи вида "ClusterRole:name" / "RoleBinding:ns/name",
+// их же Rego-правило возвращает в findings, чтобы результат привязался к нужной роли.
+type Input struct {
+	Version   string          `json:"version"`
+	Subjects  []InputSubject  `json:"subjects"`
+	Roles     []InputRole     `json:"roles"`
+	Bindings  []InputBinding  `json:"bindings"`
+	Workloads []rbac.Workload `json:"workloads"`
+}
+
+type InputSubject struct {
+	Subject   string           `json:"subject"`
+	Kind      rbac.SubjectKind `json:"kind"`
+	Name      string           `json:"name"`
+	Namespace string           `json:"namespace,omitempty"`
+	Roles     []InputRoleRef   `json:"roles"`
+}
+
+type InputRoleRef struct {
+	Role    string `json:"role"`
+	Binding string `json:"binding"`
+	rbac.EffectiveRole
+}
+
+type InputRole struct {
+	Kind      string            `json:"kind"`
+	Name      string            `json:"name"`
+	Namespace string            `json:"namespace,omitempty"`
+	Rules     []rbac.PolicyRule `json:"rules"`
+}
+
+type InputBinding struct {
+	Kind      string       `json:"kind"`
+	Name      string       `json:"name"`
+	Namespace string       `json:"namespace,omitempty"`
+	RoleRef   rbac.RoleRef `json:"roleRef"`
+	Subjects  []string     `json:"subjects"`
+}
+
+// BuildInput собирает input из загруженных манифестов и посчитанных прав.
+// Субъекты, роли и права упорядочены так же, как в отчёте; sp не меняется.
+func BuildInput(data *loader.Data, sp rbac.SubjectPermissions) Input {
+	sp = rbac.SortedCopy(sp)
+	refs := make([]rbac.SubjectRef, 0, len(sp))
+	for s := range sp {
+		refs = append(refs, s)
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].String() < refs[j].String() })
+
+	in := Input{
+		Version:   InputVersion,
+		Subjects:  make([]InputSubject, 0, len(refs)),
+		Roles:     make([]InputRole, 0, len(data.Roles)+len(data.ClusterRoles)),
+		Bindings:  make([]InputBinding, 0, len(data.RoleBindings)+len(data.ClusterRoleBindings)),
+		Workloads: append([]rbac.Workload{}, data.Workloads...),
+	}
+
+	for _, s := range refs {
+		is := InputSubject{
+			Subject:   s.String(),
+			Kind:      s.Kind,
+			Name:      s.Name,
+			Namespace: s.Namespace,
+			Roles:     make([]InputRoleRef, 0, len(sp[s])),
+		}
+		for _, r := range sp[s] {
+			is.Roles = append(is.Roles, InputRoleRef{Role: r.RoleLabel(), Binding: r.BindingLabel(), EffectiveRole: r})
+		}
+		in.Subjects = append(in.Subjects, is)
+	}
+
+	for _, r := range data.Roles {
+		in.Roles = append(in.Roles, InputRole{Kind: "Role", Name: r.Metadata.Name, Namespace: r.Metadata.Namespace, Rules: r.Rules})
+	}
+	for _, cr := range data.ClusterRoles {
+		in.Roles = append(in.Roles, InputRole{Kind: "ClusterRole", Name: cr.Metadata.Name, Rules: cr.Rules})
+	}
+	for _, rb := range data.RoleBindings {
+		in.Bindings = append(in.Bindings, InputBinding{
+			Kind:      "RoleBinding",
+			Name:      rb.Metadata.Name,
+			Namespace: rb.Metadata.Namespace,
+			RoleRef:   rb.RoleRef,
+			Subjects:  subjectStrings(rb.Subjects, rb.Metadata.Namespace),
+		})
+	}
+	for _, crb := range data.ClusterRoleBindings {
+		in.Bindings = append(in.Bindings, InputBinding{
+			Kind:     "ClusterRoleBinding",
+			Name:     crb.Metadata.Name,
+			RoleRef:  crb.RoleRef,
+			Subjects: subjectStrings(crb.Subjects, ""),
+		})
+	}
+
+	sort.SliceStable(in.Roles, func(i, j int) bool {
+		return inputKey(in.Roles[i].Kind, in.Roles[i].Namespace, in.Roles[i].Name) < inputKey(in.Roles[j].Kind, in.Roles[j].Namespace, in.Roles[j].Name)
+	})
+	sort.SliceStable(in.Bindings, func(i, j int) bool {
+		return inputKey(in.Bindings[i].Kind, in.Bindings[i].Namespace, in.Bindings[i].Name) < inputKey(in.Bindings[j].Kind, in.Bindings[j].Namespace, in.Bindings[j].Name)
+	})
+	sort.SliceStable(in.Workloads, func(i, j int) bool {
+		return inputKey(in.Workloads[i].Kind, in.Workloads[i].Namespace, in.Workloads[i].Name) < inputKey(in.Workloads[j].Kind, in.Workloads[j].Namespace, in.Workloads[j].Name)
+	})
+
+	return in
+}
+
+func subjectStrings(subjects []rbac.Subject, defaultNS string) []string {
+	out := make([]string, 0, len(subjects))
+	for _, s := range subjects {
+		out = append(out, rbac.SubjectRefFromSubject(s, defaultNS).String())
+	}
+	return out
+}
+
+func inputKey(kind, ns, name string) string {
+	return kind + "\x00" + ns + "\x00" + name
+}
@@ -0,0 +1,134 @@
+package opa
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"rbac-analyzer/internal/loader"
+	"rbac-analyzer/internal/rbac"
+)
+
+var (
+	alice = rbac.SubjectRef{Kind: rbac.SubjectKindUser, Name: "alice"}
+	ci    = rbac.SubjectRef{Kind: rbac.SubjectKindServiceAccount, Name: "deployer", Namespace: "ci"}
+)
+
+func testPerms() rbac.SubjectPermissions {
+	return rbac.SubjectPermissions{
+		ci: {
+			{SourceKind: "Role", SourceName: "deploy", SourceNamespace: "prod", BoundVia: "RoleBinding", BindingName: "ci", BindingNS: "prod",
+				Permissions: []rbac.Permission{
+					{Namespace: "prod", Verb: "patch", APIGroup: "apps", Resource: "deployments"},
+					{Namespace: "prod", Verb: "create", Resource: "pods"},
+				}},
+		},
+		alice: {
+			{SourceKind: "Role", SourceName: "reader", SourceNamespace: "dev", BoundVia: "RoleBinding", BindingName: "reader", BindingNS: "dev",
+				Permissions: []rbac.Permission{
+					{Namespace: "dev", Verb: "list", Resource: "pods"},
+					{Namespace: "dev", Verb: "get", Resource: "pods"},
+				}},
+			{SourceKind: "ClusterRole", SourceName: "view", ClusterScope: true, BoundVia: "ClusterRoleBinding", BindingName: "alice-view",
+				Permissions: []rbac.Permission{{Verb: "get", Resource: "nodes", ClusterScope: true}}},
+		},
+	}
+}
+
+func TestBuildInputSortedWithoutMutation(t *testing.T) {
+	sp := testPerms()
+	before := testPerms()
+
+	in := BuildInput(&loader.Data{}, sp)
+	if !reflect.DeepEqual(sp, before) {
+		t.Fatal("BuildInput changed its input")
+	}
+
+	var subjects []string
+	for _, s := range in.Subjects {
+		subjects = append(subjects, s.Subject)
+	}
+	if want := []string{ci.String(), alice.String()}; !reflect.DeepEqual(subjects, want) {
+		t.Fatalf("subjects = %v, want %v", subjects, want)
+	}
+	roles := in.Subjects[1].Roles
+	if len(roles) != 2 || roles[0].Role != "ClusterRole:view" || roles[1].Binding != "RoleBinding:dev/reader" {
+		t.Fatalf("alice roles = %+v", roles)
+	}
+	if p := roles[1].Permissions; p[0].Verb != "get" || p[1].Verb != "list" {
+		t.Fatalf("permissions not sorted: %+v", p)
+	}
+}
+
+func TestMergeSeverity(t *testing.T) {
+	for _, c := range []struct {
+		severity      string
+		wantDangerous bool
+	}{
+		{rbac.SeverityLow, false},
+		{rbac.SeverityMedium, true},
+		{"", true},
+		{rbac.SeverityCritical, true},
+	} {
+		sp := testPerms()
+		f := Finding{ID: "x", Message: "m", Subject: ci.String(), Severity: c.severity}
+		if n := Merge(sp, []Finding{f, f}); n != 0 {
+			t.Fatalf("%q: %d unmatched", c.severity, n)
+		}
+		r := sp[ci][0]
+		if r.Dangerous != c.wantDangerous || len(r.Findings) != 1 {
+			t.Errorf("%q: dangerous=%v findings=%+v, want dangerous=%v and one finding", c.severity, r.Dangerous, r.Findings, c.wantDangerous)
+		}
+		if got := len(r.DangerReasons) > 0; got != c.wantDangerous {
+			t.Errorf("%q: dangerReasons %v", c.severity, r.DangerReasons)
+		}
+	}
+
+	sp := testPerms()
+	unmatched := Merge(sp, []Finding{
+		{ID: "a", Message: "m", Subject: "User:nobody"},
+		{ID: "b", Message: "m", Subject: alice.String(), Role: "ClusterRole:view"},
+	})
+	if unmatched != 1 || !sp[alice][1].Dangerous || sp[alice][0].Dangerous {
+		t.Fatalf("unmatched = %d, alice roles = %+v", unmatched, sp[alice])
+	}
+}
+
+func TestEvalSeverity(t *testing.T) {
+	policy := func(severity string) string {
+		return `package rbac
+
+import rego.v1
+
+findings contains f if {
+	some s in input.subjects
+	s.kind == "ServiceAccount"
+	f := {"id": "sa", "message": "service account", "subject": s.subject, "severity": "` + severity + `"}
+}
+`
+	}
+	in := BuildInput(&loader.Data{}, testPerms())
+	dir := t.TempDir()
+	path := filepath.Join(dir, "p.rego")
+
+	if err := os.WriteFile(path, []byte(policy(" Low ")), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	fs, err := Eval(context.Background(), []string{path}, "", in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fs) != 1 || fs[0].Subject != ci.String() || fs[0].Severity != rbac.SeverityLow {
+		t.Fatalf("findings = %+v", fs)
+	}
+
+	if err := os.WriteFile(path, []byte(policy("info")), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Eval(context.Background(), []string{path}, "", in); err == nil || !strings.Contains(err.Error(), "severity") {
+		t.Fatalf("unknown severity: err = %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"rbac-analyzer/internal/compliance"
+	"rbac-analyzer/internal/rbac"
+)
+
+// PrintCompliance — отчёт по контролям CIS 5.1.x / NSA: pass/fail и evidence.
+func PrintCompliance(
+	w io.Writer,
+	subjectPerms rbac.SubjectPermissions,
+	workloads []rbac.Workload,
+	filters Filters,
+	title string,
+) error {
+	// Контроли оцениваются по всему кластеру, фильтры сужают только evidence.
+	rep := compliance.Evaluate(subjectPerms, workloads)
+	if !filters.IsZero() {
+		rep = filterCompliance(rep, Filter(subjectPerms, filters), filters)
+	}
+
+	if title = strings.TrimSpace(title); title != "" {
+		fmt.Fprintf(w, "%s\n%s\n\n", title, strings.Repeat("=", len([]rune(title))))
+	}
+
+	for _, c := range rep.Controls {
+		fmt.Fprintf(w, "[%s] %s %s\n", strings.ToUpper(c.Status), c.ID, c.Title)
+		for _, ref := range c.NSA {
+			fmt.Fprintf(w, "    %s\n", ref)
+		}
+		for _, e := range c.Evidence {
+			fmt.Fprintf(w, "    - %s\n", evidenceLine(e))
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "\n%d passed, %d failed\n", rep.Passed, rep.Failed)
+	return err
+}
+
+func evidenceLine(e compliance.Evidence) string {
+	var chain []string
+	for _, v := range []string{e.Workload, e.Subject, e.Binding, e.Role} {
+		if v != "" {
+			chain = append(chain, v)
+		}
+	}
+	if e.Detail == "" {
+		return strings.Join(chain, " -> ")
+	}
+	return strings.Join(chain, " -> ") + ": " + e.Detail
+}
+
+// filterCompliance оставляет evidence, относящиеся к отфильтрованным субъектам и ролям.
+// Статусы и счётчики не меняются: контроль провален в кластере, даже если его
+// evidence отсечены фильтром.
+func filterCompliance(rep compliance.Report, filtered rbac.SubjectPermissions, f Filters) compliance.Report {
+	controls := make([]compliance.ControlResult, 0, len(rep.Controls))
+	for _, c := range rep.Controls {
+		evidence := []compliance.Evidence{}
+		for _, e := range c.Evidence {
+			if keepEvidence(e, filtered, f) {
+				evidence = append(evidence, e)
+			}
+		}
+		c.Evidence = evidence
+		controls = append(controls, c)
+	}
+	rep.Controls = controls
+	return rep
+}
+
+func keepEvidence(e compliance.Evidence, filtered rbac.SubjectPermissions, f Filters) bool {
+	s := rbac.ParseSubjectRef(e.Subject)
+	if e.Role == "" {
+		// evidence по рабочей нагрузке: роли нет, проверяется только её SA
+		return len(f.Roles) == 0 && f.matchSubject(s) &&
+			(len(f.Namespaces) == 0 || rbac.MatchGlob(f.Namespaces, s.Namespace))
+	}
+	for _, r := range filtered[s] {
+		if r.RoleLabel() == e.Role && r.BindingLabel() == e.Binding {
+			return true
+		}
+	}
+	return false
+}
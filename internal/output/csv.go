@@ -0,0 +1,140 @@
+package output
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"rbac-analyzer/internal/rbac"
+)
+
+// Гранулярность CSV-выгрузки.
+const (
+	CSVPerPermission = "permission" // строка = субъект × право
+	CSVPerRole       = "role"       // строка = субъект × роль (права склеены через ";")
+)
+
+// CSVOptions — настройки CSV-выгрузки.
+type CSVOptions struct {
+	Granularity string // CSVPerPermission (по умолчанию) | CSVPerRole
+	BOM         bool   // UTF-8 BOM, чтобы Excel корректно открыл файл
+}
+
+// ParseCSVGranularity проверяет значение -csv-granularity / ?granularity=.
+// Пустое значение — CSVPerPermission.
+func ParseCSVGranularity(s string) (string, error) {
+	switch s {
+	case "":
+		return CSVPerPermission, nil
+	case CSVPerPermission, CSVPerRole:
+		return s, nil
+	default:
+		return "", fmt.Errorf("unknown CSV granularity %q (want permission or role)", s)
+	}
+}
+
+var csvHeader = []string{
+	"subjectKind", "subjectName", "subjectNamespace",
+	"role", "binding", "scope",
+	"verb", "resource", "apiGroup", "resourceNames",
+	"dangerous", "reasons",
+}
+
+// PrintCSV — плоская выгрузка для таблиц (Excel / Google Sheets).
+func PrintCSV(
+	w io.Writer,
+	subjectPerms rbac.SubjectPermissions,
+	filters Filters,
+	opts CSVOptions,
+) error {
+	granularity, err := ParseCSVGranularity(opts.Granularity)
+	if err != nil {
+		return err
+	}
+	filtered := Filter(subjectPerms, filters)
+
+	if opts.BOM {
+		if _, err := io.WriteString(w, "\ufeff"); err != nil {
+			return err
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, s := range sortedSubjects(filtered) {
+		for _, r := range filtered[s] {
+			base := []string{
+				string(s.Kind), s.Name, s.Namespace,
+				r.RoleLabel(), r.BindingLabel(), roleScope(r),
+			}
+			tail := []string{
+				strconv.FormatBool(r.Dangerous),
+				strings.Join(r.DangerReasons, "; "),
+			}
+
+			if granularity == CSVPerRole {
+				var verbs, resources, groups, names []string
+				for _, p := range r.Permissions {
+					verbs = append(verbs, p.Verb)
+					resources = append(resources, p.Resource)
+					groups = append(groups, p.APIGroup)
+					names = append(names, p.ResourceNames...)
+				}
+				row := append(append(base,
+					joinUnique(verbs), joinUnique(resources), joinUnique(groups), joinUnique(names),
+				), tail...)
+				if err := cw.Write(csvSafe(row)); err != nil {
+					return err
+				}
+				continue
+			}
+
+			for _, p := range r.Permissions {
+				row := append(append(append([]string{}, base...),
+					p.Verb, p.Resource, p.APIGroup, strings.Join(p.ResourceNames, ";"),
+				), tail...)
+				if err := cw.Write(csvSafe(row)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func roleScope(r rbac.EffectiveRole) string {
+	if r.ClusterScope {
+		return "cluster"
+	}
+	return "namespace"
+}
+
+func joinUnique(items []string) string {
+	seen := make(map[string]bool, len(items))
+	out := make([]string, 0, len(items))
+	for _, v := range items {
+		if seen[v] {
+			continue
+		}
+		seen[v] = true
+		out = append(out, v)
+	}
+	return strings.Join(out, ";")
+}
+
+// csvSafe защищает от formula injection: Excel исполняет ячейки, начинающиеся с = + - @.
+func csvSafe(row []string) []string {
+	for i, v := range row {
+		if v != "" && strings.ContainsRune("=+-@", rune(v[0])) {
+			row[i] = "'" + v
+		}
+	}
+	return row
+}
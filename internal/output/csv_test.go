@@ -0,0 +1,42 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"rbac-analyzer/internal/rbac"
+)
+
+func TestPrintCSVGranularity(t *testing.T) {
+	sp, _ := testPerms()
+	// по два права у каждой роли: в режиме role они склеиваются в одну строку
+	for s, roles := range sp {
+		for i := range roles {
+			roles[i].Permissions = append(roles[i].Permissions, rbac.Permission{Verb: "list", Resource: "pods"})
+		}
+		sp[s] = roles
+	}
+	for _, c := range []struct {
+		granularity string
+		rows        int
+	}{
+		{"", 5},
+		{CSVPerPermission, 5},
+		{CSVPerRole, 3},
+	} {
+		var buf bytes.Buffer
+		if err := PrintCSV(&buf, sp, Filters{}, CSVOptions{Granularity: c.granularity}); err != nil {
+			t.Fatalf("%q: %v", c.granularity, err)
+		}
+		if n := strings.Count(buf.String(), "\n"); n != c.rows {
+			t.Errorf("%q: %d lines, want %d:\n%s", c.granularity, n, c.rows, buf.String())
+		}
+	}
+
+	for _, g := range []string{"roles", "Permission", "subject"} {
+		if err := PrintCSV(&bytes.Buffer{}, sp, Filters{}, CSVOptions{Granularity: g}); err == nil {
+			t.Errorf("granularity %q accepted", g)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package output
+
+import (
+	"strings"
+
+	"rbac-analyzer/internal/rbac"
+)
+
+// Filters — общие фильтры для всех форматов вывода.
+// Внутри одного списка значения объединяются по ИЛИ, между списками — по И.
+// Все строковые значения поддерживают glob-шаблоны (`tenant-*`); "*" совпадает и с "/".
+type Filters struct {
+	DangerOnly bool
+
+	// Namespaces — namespace, в котором действует роль. Кластерные роли
+	// (ClusterRoleBinding) действуют везде и под фильтр всегда попадают.
+	Namespaces []string
+	Subjects   []string // "ServiceAccount:ns/name", "User:alice" или просто имя
+	Kinds      []string // User | Group | ServiceAccount
+	Roles      []string // имя роли или "ns/name"
+}
+
+// IsZero — фильтры не заданы.
+func (f Filters) IsZero() bool {
+	return !f.DangerOnly && len(f.Namespaces) == 0 && len(f.Subjects) == 0 &&
+		len(f.Kinds) == 0 && len(f.Roles) == 0
+}
+
+// Filter применяет фильтры ко всей карте (субъекты без подходящих ролей выбрасываются).
+func Filter(subjectPerms rbac.SubjectPermissions, f Filters) rbac.SubjectPermissions {
+	if f.IsZero() {
+		return subjectPerms
+	}
+
+	out := make(rbac.SubjectPermissions, len(subjectPerms))
+	for s, roles := range subjectPerms {
+		if !f.matchSubject(s) {
+			continue
+		}
+		if fl := filterRoles(roles, f); len(fl) > 0 {
+			out[s] = fl
+		}
+	}
+	return out
+}
+
+func filterRoles(roles []rbac.EffectiveRole, f Filters) []rbac.EffectiveRole {
+	if !f.DangerOnly && len(f.Namespaces) == 0 && len(f.Roles) == 0 {
+		return roles
+	}
+
+	var out []rbac.EffectiveRole
+	for _, r := range roles {
+		if f.DangerOnly && !r.Dangerous {
+			continue
+		}
+		if len(f.Namespaces) > 0 && !r.ClusterScope && !rbac.MatchGlob(f.Namespaces, roleNamespace(r)) {
+			continue
+		}
+		if len(f.Roles) > 0 &&
+			!rbac.MatchGlob(f.Roles, r.SourceName) &&
+			!rbac.MatchGlob(f.Roles, r.SourceNamespace+"/"+r.SourceName) {
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+func (f Filters) matchSubject(s rbac.SubjectRef) bool {
+	if len(f.Kinds) > 0 && !matchAnyFold(f.Kinds, string(s.Kind)) {
+		return false
+	}
+	if len(f.Subjects) > 0 && !rbac.MatchGlob(f.Subjects, s.String()) && !rbac.MatchGlob(f.Subjects, s.Name) {
+		return false
+	}
+	return true
+}
+
+// roleNamespace — namespace, в котором роль реально действует:
+// для RoleBinding это namespace биндинга (в т.ч. когда он ссылается на ClusterRole).
+func roleNamespace(r rbac.EffectiveRole) string {
+	if r.BindingNS != "" {
+		return r.BindingNS
+	}
+	return r.SourceNamespace
+}
+
+func matchAnyFold(patterns []string, v string) bool {
+	for _, p := range patterns {
+		if strings.EqualFold(strings.TrimSpace(p), v) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"rbac-analyzer/internal/compliance"
+	"rbac-analyzer/internal/rbac"
+	"rbac-analyzer/internal/report"
+)
+
+// testPerms: у app есть только безопасная роль, у admin — опасная.
+func testPerms() (rbac.SubjectPermissions, []rbac.Workload) {
+	app := rbac.SubjectRef{Kind: rbac.SubjectKindServiceAccount, Name: "app", Namespace: "prod"}
+	admin := rbac.SubjectRef{Kind: rbac.SubjectKindServiceAccount, Name: "admin", Namespace: "prod"}
+	sp := rbac.SubjectPermissions{
+		app: {{
+			SourceKind: "Role", SourceName: "reader", SourceNamespace: "prod",
+			BoundVia: "RoleBinding", BindingName: "app-reader", BindingNS: "prod",
+			Permissions: []rbac.Permission{{Namespace: "prod", Verb: "get", Resource: "configmaps"}},
+		}},
+		admin: {{
+			SourceKind: "ClusterRole", SourceName: "cluster-admin", ClusterScope: true,
+			BoundVia: "ClusterRoleBinding", BindingName: "admin",
+			Permissions: []rbac.Permission{{Verb: "*", APIGroup: "*", Resource: "*"}},
+			Dangerous:   true,
+		}},
+	}
+	workloads := []rbac.Workload{{Kind: "Deployment", Name: "app", Namespace: "prod", ServiceAccount: "app", AutomountToken: true}}
+	return sp, workloads
+}
+
+func controlStatus(rep *compliance.Report, id string) string {
+	for _, c := range rep.Controls {
+		if c.ID == id {
+			return c.Status
+		}
+	}
+	return ""
+}
+
+func TestComplianceIgnoresFilters(t *testing.T) {
+	sp, workloads := testPerms()
+	filters := Filters{DangerOnly: true}
+
+	var buf bytes.Buffer
+	if err := PrintCompliance(&buf, sp, workloads, filters, ""); err != nil {
+		t.Fatal(err)
+	}
+	// у app есть права, просто не опасные: CIS 5.1.6 не должен проваливаться
+	if out := buf.String(); !strings.Contains(out, "[PASS] CIS 5.1.6") || !strings.Contains(out, "[FAIL] CIS 5.1.1") {
+		t.Fatalf("compliance with -danger-only:\n%s", out)
+	}
+
+	buf.Reset()
+	if err := PrintJSON(&buf, sp, filters, report.Meta{Workloads: workloads}); err != nil {
+		t.Fatal(err)
+	}
+	var rep report.Report
+	if err := json.Unmarshal(buf.Bytes(), &rep); err != nil {
+		t.Fatal(err)
+	}
+	if st := controlStatus(rep.Compliance, "CIS 5.1.6"); st != compliance.StatusPass {
+		t.Fatalf("json CIS 5.1.6: %s", st)
+	}
+	if len(rep.Subjects) != 1 || len(rep.Risk.Subjects) != 1 || rep.Risk.Subjects[0].Subject != rep.Subjects[0].Subject {
+		t.Fatalf("json: subjects %d, risk subjects %+v", len(rep.Subjects), rep.Risk.Subjects)
+	}
+	full := rbac.ScoreRisk(sp, workloads)
+	if rep.Risk.OrgScore != full.OrgScore {
+		t.Fatalf("org score %.1f, want %.1f from unfiltered permissions", rep.Risk.OrgScore, full.OrgScore)
+	}
+}
+
+func TestComplianceEvidenceFiltered(t *testing.T) {
+	sp, workloads := testPerms()
+	rep := compliance.Evaluate(sp, workloads)
+	rep = filterCompliance(rep, Filter(sp, Filters{Subjects: []string{"app"}}), Filters{Subjects: []string{"app"}})
+
+	// контроль остаётся проваленным, но evidence про admin отсечены
+	for _, c := range rep.Controls {
+		if c.ID == "CIS 5.1.1" && (c.Status != compliance.StatusFail || len(c.Evidence) != 0) {
+			t.Fatalf("CIS 5.1.1: %s, evidence %+v", c.Status, c.Evidence)
+		}
+	}
+}
+
+func TestFilterGlobCrossesSlash(t *testing.T) {
+	sp, _ := testPerms()
+	for _, tc := range []struct {
+		f    Filters
+		want int
+	}{
+		{Filters{Subjects: []string{"ServiceAccount:prod*"}}, 2},
+		{Filters{Subjects: []string{"ServiceAccount:prod/a*"}}, 2},
+		{Filters{Subjects: []string{"ServiceAccount:dev-*"}}, 0},
+		{Filters{Roles: []string{"prod/*"}}, 1},
+		{Filters{Namespaces: []string{"pr*"}}, 2},
+	} {
+		if got := len(Filter(sp, tc.f)); got != tc.want {
+			t.Errorf("%+v: %d subjects, want %d", tc.f, got, tc.want)
+		}
+	}
+}
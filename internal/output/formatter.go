@@ -8,31 +8,24 @@ import (
 	"strings"
 
 	"rbac-analyzer/internal/rbac"
+	"rbac-analyzer/internal/report"
 )
 
 // PrintTable — человекочитаемый табличный вывод.
 func PrintTable(
 	w io.Writer,
 	subjectPerms rbac.SubjectPermissions,
-	dangerOnly bool,
-	namespaceFilter string,
+	filters Filters,
+	title string,
 ) error {
-	nsFilter := strings.TrimSpace(namespaceFilter)
+	filtered := Filter(subjectPerms, filters)
 
-	// Отсортируем субъектов для стабильного вывода
-	subjects := make([]rbac.SubjectRef, 0, len(subjectPerms))
-	for s := range subjectPerms {
-		subjects = append(subjects, s)
+	if title = strings.TrimSpace(title); title != "" {
+		fmt.Fprintf(w, "%s\n%s\n\n", title, strings.Repeat("=", len([]rune(title))))
 	}
-	sort.Slice(subjects, func(i, j int) bool {
-		return subjects[i].String() < subjects[j].String()
-	})
 
-	for _, s := range subjects {
-		roles := filterRoles(subjectPerms[s], dangerOnly, nsFilter)
-		if len(roles) == 0 {
-			continue
-		}
+	for _, s := range sortedSubjects(filtered) {
+		roles := filtered[s]
 
 		fmt.Fprintf(w, "=== %s ===\n", s.String())
 		for _, r := range roles {
@@ -98,32 +91,21 @@ func PrintTable(
 	return nil
 }
 
-// PrintJSON — JSON вывод для дальнейшей обработки.
+// PrintJSON — JSON вывод для дальнейшей обработки (версионированный конверт report.Report).
 func PrintJSON(
 	w io.Writer,
 	subjectPerms rbac.SubjectPermissions,
-	dangerOnly bool,
-	namespaceFilter string,
+	filters Filters,
+	meta report.Meta,
 ) error {
-	nsFilter := strings.TrimSpace(namespaceFilter)
-
-	// Строим структуру для сериализации
-	type outputStruct struct {
-		Subject string               `json:"subject"`
-		Roles   []rbac.EffectiveRole `json:"roles"`
-	}
-
-	var out []outputStruct
-
-	for s, roles := range subjectPerms {
-		fl := filterRoles(roles, dangerOnly, nsFilter)
-		if len(fl) == 0 {
-			continue
-		}
-		out = append(out, outputStruct{
-			Subject: s.String(),
-			Roles:   fl,
-		})
+	filtered := Filter(subjectPerms, filters)
+	meta.All = subjectPerms
+	out := report.Build(filtered, meta)
+	if !filters.IsZero() {
+		cr := filterCompliance(*out.Compliance, filtered, filters)
+		out.Compliance = &cr
+		risk := filterRisk(*out.Risk, filtered)
+		out.Risk = &risk
 	}
 
 	enc := json.NewEncoder(w)
@@ -131,27 +113,14 @@ func PrintJSON(
 	return enc.Encode(out)
 }
 
-func filterRoles(
-	roles []rbac.EffectiveRole,
-	dangerOnly bool,
-	nsFilter string,
-) []rbac.EffectiveRole {
-	if !dangerOnly && nsFilter == "" {
-		return roles
-	}
-
-	var out []rbac.EffectiveRole
-	for _, r := range roles {
-		if dangerOnly && !r.Dangerous {
-			continue
-		}
-		if nsFilter != "" {
-			// фильтруем по namespace, где действует роль
-			if r.SourceNamespace != nsFilter && !r.ClusterScope {
-				continue
-			}
-		}
-		out = append(out, r)
+// sortedSubjects возвращает субъектов в стабильном порядке.
+func sortedSubjects(subjectPerms rbac.SubjectPermissions) []rbac.SubjectRef {
+	subjects := make([]rbac.SubjectRef, 0, len(subjectPerms))
+	for s := range subjectPerms {
+		subjects = append(subjects, s)
 	}
-	return out
+	sort.Slice(subjects, func(i, j int) bool {
+		return subjects[i].String() < subjects[j].String()
+	})
+	return subjects
 }
@@ -0,0 +1,227 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"rbac-analyzer/internal/rbac"
+)
+
+type graphNode struct {
+	Seq       int
+	ID        string
+	Label     string
+	Kind      string // subject | binding | role
+	Namespace string
+}
+
+type graphEdge struct {
+	From, To  *graphNode
+	Dangerous bool
+}
+
+type graph struct {
+	nodes map[string]*graphNode
+	edges map[[2]int]*graphEdge
+}
+
+// buildGraph строит граф subject -> binding -> role.
+// Биндинги и роли — общие узлы, поэтому видно, кто делит один и тот же биндинг.
+// Фокус на одном субъекте/роли/namespace задаётся обычными Filters.
+func buildGraph(subjectPerms rbac.SubjectPermissions) *graph {
+	g := &graph{
+		nodes: map[string]*graphNode{},
+		edges: map[[2]int]*graphEdge{},
+	}
+
+	node := func(key, label, kind, ns string) *graphNode {
+		if n, ok := g.nodes[key]; ok {
+			return n
+		}
+		seq := len(g.nodes) + 1
+		n := &graphNode{Seq: seq, ID: fmt.Sprintf("n%d", seq), Label: label, Kind: kind, Namespace: ns}
+		g.nodes[key] = n
+		return n
+	}
+	edge := func(from, to *graphNode, dangerous bool) {
+		k := [2]int{from.Seq, to.Seq}
+		if e, ok := g.edges[k]; ok {
+			e.Dangerous = e.Dangerous || dangerous
+			return
+		}
+		g.edges[k] = &graphEdge{From: from, To: to, Dangerous: dangerous}
+	}
+
+	for _, s := range sortedSubjects(subjectPerms) {
+		for _, r := range subjectPerms[s] {
+			sn := node("s|"+s.String(), s.String(), "subject", s.Namespace)
+			bn := node("b|"+r.BindingLabel(), r.BindingLabel(), "binding", r.BindingNS)
+			rn := node("r|"+r.RoleLabel(), r.RoleLabel(), "role", r.SourceNamespace)
+
+			edge(sn, bn, r.Dangerous)
+			edge(bn, rn, r.Dangerous)
+		}
+	}
+
+	return g
+}
+
+// sortedNodes — узлы в порядке добавления (субъекты уже отсортированы, так что вывод стабилен).
+func (g *graph) sortedNodes() []*graphNode {
+	out := make([]*graphNode, 0, len(g.nodes))
+	for _, n := range g.nodes {
+		out = append(out, n)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Seq < out[j].Seq })
+	return out
+}
+
+func (g *graph) sortedEdges() []*graphEdge {
+	out := make([]*graphEdge, 0, len(g.edges))
+	for _, e := range g.edges {
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].From.Seq != out[j].From.Seq {
+			return out[i].From.Seq < out[j].From.Seq
+		}
+		return out[i].To.Seq < out[j].To.Seq
+	})
+	return out
+}
+
+// byNamespace группирует узлы по namespace ("" — кластерные объекты).
+func (g *graph) byNamespace() (map[string][]*graphNode, []string) {
+	groups := map[string][]*graphNode{}
+	for _, n := range g.sortedNodes() {
+		groups[n.Namespace] = append(groups[n.Namespace], n)
+	}
+	names := make([]string, 0, len(groups))
+	for ns := range groups {
+		if ns != "" {
+			names = append(names, ns)
+		}
+	}
+	sort.Strings(names)
+	return groups, names
+}
+
+// PrintDOT — граф для Graphviz (`dot -Tsvg`).
+func PrintDOT(
+	w io.Writer,
+	subjectPerms rbac.SubjectPermissions,
+	filters Filters,
+) error {
+	g := buildGraph(Filter(subjectPerms, filters))
+
+	var b strings.Builder
+	b.WriteString("digraph rbac {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [fontname=\"Helvetica\", fontsize=10];\n")
+	b.WriteString("  edge [color=\"#7b8794\"];\n\n")
+
+	writeNode := func(indent string, n *graphNode) {
+		shape, fill := "box", "#e3f2fd"
+		switch n.Kind {
+		case "subject":
+			shape, fill = "ellipse", "#fff8e1"
+		case "binding":
+			shape, fill = "box", "#f0f4f8"
+		case "role":
+			shape, fill = "component", "#e8f5e9"
+		}
+		fmt.Fprintf(&b, "%s%s [label=%s, shape=%s, style=filled, fillcolor=%q];\n",
+			indent, n.ID, dotQuote(n.Label), shape, fill)
+	}
+
+	groups, names := g.byNamespace()
+	for _, n := range groups[""] {
+		writeNode("  ", n)
+	}
+	for i, ns := range names {
+		fmt.Fprintf(&b, "\n  subgraph cluster_%d {\n", i)
+		fmt.Fprintf(&b, "    label=%s;\n", dotQuote("namespace: "+ns))
+		b.WriteString("    style=dashed;\n")
+		for _, n := range groups[ns] {
+			writeNode("    ", n)
+		}
+		b.WriteString("  }\n")
+	}
+
+	b.WriteString("\n")
+	for _, e := range g.sortedEdges() {
+		if e.Dangerous {
+			fmt.Fprintf(&b, "  %s -> %s [color=\"#c62828\", penwidth=2];\n", e.From.ID, e.To.ID)
+		} else {
+			fmt.Fprintf(&b, "  %s -> %s;\n", e.From.ID, e.To.ID)
+		}
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// PrintMermaid — тот же граф в синтаксисе Mermaid (рендерится прямо в GitHub/GitLab markdown).
+func PrintMermaid(
+	w io.Writer,
+	subjectPerms rbac.SubjectPermissions,
+	filters Filters,
+) error {
+	g := buildGraph(Filter(subjectPerms, filters))
+
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	b.WriteString("  classDef subject fill:#fff8e1,stroke:#b7791f\n")
+	b.WriteString("  classDef binding fill:#f0f4f8,stroke:#7b8794\n")
+	b.WriteString("  classDef role fill:#e8f5e9,stroke:#2f855a\n")
+
+	writeNode := func(indent string, n *graphNode) {
+		label := mermaidQuote(n.Label)
+		switch n.Kind {
+		case "subject":
+			fmt.Fprintf(&b, "%s%s([%s]):::subject\n", indent, n.ID, label)
+		case "role":
+			fmt.Fprintf(&b, "%s%s[[%s]]:::role\n", indent, n.ID, label)
+		default:
+			fmt.Fprintf(&b, "%s%s[%s]:::binding\n", indent, n.ID, label)
+		}
+	}
+
+	groups, names := g.byNamespace()
+	for _, n := range groups[""] {
+		writeNode("  ", n)
+	}
+	for i, ns := range names {
+		fmt.Fprintf(&b, "  subgraph ns%d[%s]\n", i, mermaidQuote("namespace: "+ns))
+		for _, n := range groups[ns] {
+			writeNode("    ", n)
+		}
+		b.WriteString("  end\n")
+	}
+
+	// linkStyle ссылается на порядковый номер ребра
+	var danger []string
+	for i, e := range g.sortedEdges() {
+		fmt.Fprintf(&b, "  %s --> %s\n", e.From.ID, e.To.ID)
+		if e.Dangerous {
+			danger = append(danger, fmt.Sprint(i))
+		}
+	}
+	if len(danger) > 0 {
+		fmt.Fprintf(&b, "  linkStyle %s stroke:#c62828,stroke-width:2px\n", strings.Join(danger, ","))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func mermaidQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
@@ -0,0 +1,115 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"rbac-analyzer/internal/rbac"
+)
+
+const goldenDOT = `digraph rbac {
+  rankdir=LR;
+  node [fontname="Helvetica", fontsize=10];
+  edge [color="#7b8794"];
+
+  n2 [label="ClusterRoleBinding:admin", shape=box, style=filled, fillcolor="#f0f4f8"];
+  n3 [label="ClusterRole:cluster-admin", shape=component, style=filled, fillcolor="#e8f5e9"];
+
+  subgraph cluster_0 {
+    label="namespace: prod";
+    style=dashed;
+    n1 [label="ServiceAccount:prod/admin", shape=ellipse, style=filled, fillcolor="#fff8e1"];
+    n4 [label="ServiceAccount:prod/app", shape=ellipse, style=filled, fillcolor="#fff8e1"];
+    n5 [label="RoleBinding:prod/app-reader", shape=box, style=filled, fillcolor="#f0f4f8"];
+    n6 [label="Role:prod/reader", shape=component, style=filled, fillcolor="#e8f5e9"];
+  }
+
+  n1 -> n2 [color="#c62828", penwidth=2];
+  n2 -> n3 [color="#c62828", penwidth=2];
+  n4 -> n5;
+  n5 -> n6;
+}
+`
+
+const goldenMermaid = `flowchart LR
+  classDef subject fill:#fff8e1,stroke:#b7791f
+  classDef binding fill:#f0f4f8,stroke:#7b8794
+  classDef role fill:#e8f5e9,stroke:#2f855a
+  n2["ClusterRoleBinding:admin"]:::binding
+  n3[["ClusterRole:cluster-admin"]]:::role
+  subgraph ns0["namespace: prod"]
+    n1(["ServiceAccount:prod/admin"]):::subject
+    n4(["ServiceAccount:prod/app"]):::subject
+    n5["RoleBinding:prod/app-reader"]:::binding
+    n6[["Role:prod/reader"]]:::role
+  end
+  n1 --> n2
+  n2 --> n3
+  n4 --> n5
+  n5 --> n6
+  linkStyle 0,1 stroke:#c62828,stroke-width:2px
+`
+
+func TestGraphGolden(t *testing.T) {
+	sp, _ := testPerms()
+	for _, c := range []struct {
+		name  string
+		print func(*bytes.Buffer) error
+		want  string
+	}{
+		{"dot", func(b *bytes.Buffer) error { return PrintDOT(b, sp, Filters{}) }, goldenDOT},
+		{"mermaid", func(b *bytes.Buffer) error { return PrintMermaid(b, sp, Filters{}) }, goldenMermaid},
+	} {
+		var buf bytes.Buffer
+		if err := c.print(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != c.want {
+			t.Errorf("%s output:\n%s\nwant:\n%s", c.name, buf.String(), c.want)
+		}
+	}
+}
+
+// Имена с ":", "/" и кавычками всегда в кавычках, идентификаторы узлов — только nN.
+func TestGraphQuoting(t *testing.T) {
+	sp := rbac.SubjectPermissions{
+		{Kind: rbac.SubjectKindGroup, Name: `oidc:team "a"/ops`}: {{
+			SourceKind: "ClusterRole", SourceName: "system:aggregate-to-view", ClusterScope: true,
+			BoundVia: "ClusterRoleBinding", BindingName: `x"y`,
+		}},
+	}
+
+	var dot, mermaid bytes.Buffer
+	if err := PrintDOT(&dot, sp, Filters{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := PrintMermaid(&mermaid, sp, Filters{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		`n1 [label="Group:oidc:team \"a\"/ops",`,
+		`n2 [label="ClusterRoleBinding:x\"y",`,
+		`n3 [label="ClusterRole:system:aggregate-to-view",`,
+	} {
+		if !strings.Contains(dot.String(), want) {
+			t.Errorf("dot: %q not found in\n%s", want, dot.String())
+		}
+	}
+	for _, want := range []string{
+		`n1(["Group:oidc:team #quot;a#quot;/ops"]):::subject`,
+		`n2["ClusterRoleBinding:x#quot;y"]:::binding`,
+		`n3[["ClusterRole:system:aggregate-to-view"]]:::role`,
+	} {
+		if !strings.Contains(mermaid.String(), want) {
+			t.Errorf("mermaid: %q not found in\n%s", want, mermaid.String())
+		}
+	}
+	// в Mermaid кавычка внутри метки закрыла бы её
+	for _, line := range strings.Split(mermaid.String(), "\n") {
+		if strings.Count(line, `"`)%2 != 0 {
+			t.Errorf("mermaid: unbalanced quotes in %q", line)
+		}
+	}
+}
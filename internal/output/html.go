@@ -0,0 +1,214 @@
+package output
+
+import (
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"rbac-analyzer/internal/rbac"
+)
+
+// PrintHTML — самодостаточный HTML-отчёт (один файл, без внешних ресурсов).
+// Подходит для отправки аудиторам: открывается офлайн и нормально печатается.
+func PrintHTML(
+	w io.Writer,
+	subjectPerms rbac.SubjectPermissions,
+	filters Filters,
+	title string,
+) error {
+	filtered := Filter(subjectPerms, filters)
+
+	type htmlSubject struct {
+		Name      string
+		Dangerous bool
+		Roles     []rbac.EffectiveRole
+	}
+
+	subjects := make([]htmlSubject, 0, len(filtered))
+	for _, s := range sortedSubjects(filtered) {
+		hs := htmlSubject{Name: s.String(), Roles: filtered[s]}
+		for _, r := range hs.Roles {
+			if r.Dangerous {
+				hs.Dangerous = true
+				break
+			}
+		}
+		subjects = append(subjects, hs)
+	}
+
+	if strings.TrimSpace(title) == "" {
+		title = "RBAC Analysis Report"
+	}
+
+	return htmlReportTmpl.Execute(w, map[string]any{
+		"Title":       title,
+		"GeneratedAt": time.Now().UTC().Format(time.RFC3339),
+		"Summary":     rbac.Summarize(filtered),
+		"Subjects":    subjects,
+		"DangerOnly":  filters.DangerOnly,
+		"Namespace":   strings.Join(filters.Namespaces, ", "),
+	})
+}
+
+var htmlReportTmpl = template.Must(template.New("report").Funcs(template.FuncMap{
+	"scope": roleScope,
+	"dash": func(s string) string {
+		if s == "" {
+			return "-"
+		}
+		return s
+	},
+	"permNS": func(p rbac.Permission) string {
+		if p.ClusterScope {
+			return "*"
+		}
+		if p.Namespace == "" {
+			return "-"
+		}
+		return p.Namespace
+	},
+	"join": strings.Join,
+}).Parse(htmlReportSrc))
+
+const htmlReportSrc = `<!doctype html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width,initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+  body { font-family: ui-sans-serif, system-ui, -apple-system, Segoe UI, Roboto, sans-serif; margin: 24px; color: #1f2933; background: #fff; }
+  h1 { font-size: 22px; margin: 0 0 4px; }
+  h2 { font-size: 16px; margin: 0; display: inline; }
+  .meta { color: #616e7c; font-size: 12px; margin-bottom: 16px; }
+  .cards { display: flex; flex-wrap: wrap; gap: 10px; margin-bottom: 18px; }
+  .card { border: 1px solid #d9e2ec; border-radius: 8px; padding: 10px 14px; min-width: 120px; }
+  .card b { display: block; font-size: 20px; }
+  .card span { color: #616e7c; font-size: 12px; }
+  .card.risk b { color: #c62828; }
+  table { border-collapse: collapse; width: 100%; margin: 8px 0 4px; font-size: 13px; }
+  th, td { border: 1px solid #d9e2ec; padding: 4px 8px; text-align: left; vertical-align: top; }
+  th { background: #f0f4f8; cursor: pointer; user-select: none; white-space: nowrap; }
+  th.asc::after { content: " \25B2"; font-size: 10px; }
+  th.desc::after { content: " \25BC"; font-size: 10px; }
+  .subject { border: 1px solid #d9e2ec; border-radius: 8px; padding: 8px 12px; margin-bottom: 10px; }
+  .subject.danger { border-left: 4px solid #c62828; }
+  .badge { display: inline-block; padding: 0 6px; border-radius: 4px; font-size: 11px; font-weight: 700; background: #c62828; color: #fff; }
+  .reasons { margin: 2px 0; padding-left: 18px; color: #c62828; }
+  .perms { margin: 2px 0; padding-left: 18px; font-family: ui-monospace, Menlo, Consolas, monospace; font-size: 12px; }
+  summary { cursor: pointer; }
+  .toolbar { margin-bottom: 12px; }
+  .toolbar button { margin-right: 6px; }
+  @media print {
+    body { margin: 0; }
+    .toolbar { display: none; }
+    th { cursor: default; }
+    th.asc::after, th.desc::after { content: ""; }
+    .subject { break-inside: avoid; }
+    details > summary { list-style: none; }
+  }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<div class="meta">
+  Generated {{.GeneratedAt}}{{if .Namespace}} &middot; namespace: {{.Namespace}}{{end}}{{if .DangerOnly}} &middot; dangerous only{{end}}
+</div>
+
+<div class="cards">
+  <div class="card"><b>{{.Summary.Counts.Subjects}}</b><span>Subjects</span></div>
+  <div class="card"><b>{{.Summary.Counts.Roles}}</b><span>Role bindings</span></div>
+  <div class="card"><b>{{.Summary.Counts.Perms}}</b><span>Permissions</span></div>
+  <div class="card"><b>{{.Summary.Counts.DangerRoles}}</b><span>Dangerous roles</span></div>
+  <div class="card risk"><b>{{printf "%.1f" .Summary.RiskScore}} / 10</b><span>Risk score</span></div>
+</div>
+
+<div class="toolbar">
+  <button type="button" onclick="toggleAll(true)">Expand all</button>
+  <button type="button" onclick="toggleAll(false)">Collapse all</button>
+</div>
+
+{{range .Subjects}}
+<div class="subject{{if .Dangerous}} danger{{end}}">
+  <h2>{{.Name}}</h2>{{if .Dangerous}} <span class="badge">DANGEROUS</span>{{end}}
+  <table class="sortable">
+    <thead>
+      <tr><th>Role</th><th>Source</th><th>Scope</th><th>Bound via</th><th>Dangerous</th><th>Permissions</th></tr>
+    </thead>
+    <tbody>
+    {{range .Roles}}
+      <tr>
+        <td>{{dash .SourceNamespace}}/{{.SourceName}}</td>
+        <td>{{.SourceKind}}</td>
+        <td>{{scope .}}</td>
+        <td>{{.BoundVia}}/{{.BindingName}}</td>
+        <td>{{if .Dangerous}}yes{{else}}no{{end}}</td>
+        <td data-sort="{{len .Permissions}}">
+          {{if .DangerReasons}}
+          <ul class="reasons">{{range .DangerReasons}}<li>{{.}}</li>{{end}}</ul>
+          {{end}}
+          <details>
+            <summary>{{len .Permissions}} permission(s)</summary>
+            <ul class="perms">
+            {{range .Permissions}}<li>ns={{permNS .}} verb={{.Verb}} resource={{.Resource}} apiGroup={{.APIGroup}}{{if .ResourceNames}} names=[{{join .ResourceNames ","}}]{{end}}</li>
+            {{end}}
+            </ul>
+          </details>
+        </td>
+      </tr>
+    {{end}}
+    </tbody>
+  </table>
+</div>
+{{else}}
+<p>No subjects match the selected filters.</p>
+{{end}}
+
+<script>
+function toggleAll(open) {
+  document.querySelectorAll("details").forEach(function (d) { d.open = open; });
+}
+
+// перед печатью раскрываем все списки прав, после — возвращаем как было
+var printOpened = [];
+window.addEventListener("beforeprint", function () {
+  document.querySelectorAll("details:not([open])").forEach(function (d) {
+    d.open = true;
+    printOpened.push(d);
+  });
+});
+window.addEventListener("afterprint", function () {
+  printOpened.forEach(function (d) { d.open = false; });
+  printOpened = [];
+});
+
+function cellValue(row, idx) {
+  var td = row.children[idx];
+  return td.getAttribute("data-sort") || td.textContent.trim();
+}
+
+document.querySelectorAll("table.sortable th").forEach(function (th) {
+  th.addEventListener("click", function () {
+    var table = th.closest("table");
+    var tbody = table.querySelector("tbody");
+    var idx = Array.prototype.indexOf.call(th.parentNode.children, th);
+    var asc = !th.classList.contains("asc");
+
+    table.querySelectorAll("th").forEach(function (h) { h.classList.remove("asc", "desc"); });
+    th.classList.add(asc ? "asc" : "desc");
+
+    var rows = Array.prototype.slice.call(tbody.rows);
+    rows.sort(function (a, b) {
+      var x = cellValue(a, idx), y = cellValue(b, idx);
+      var nx = parseFloat(x), ny = parseFloat(y);
+      var cmp = (!isNaN(nx) && !isNaN(ny)) ? nx - ny : x.localeCompare(y);
+      return asc ? cmp : -cmp;
+    });
+    rows.forEach(function (r) { tbody.appendChild(r); });
+  });
+});
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,48 @@
+package output
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+
+	"rbac-analyzer/internal/rbac"
+)
+
+func TestPrintHTMLSelfContained(t *testing.T) {
+	sp, _ := testPerms()
+	evil := rbac.SubjectRef{Kind: rbac.SubjectKindUser, Name: `<script>alert("x")</script>`}
+	sp[evil] = []rbac.EffectiveRole{{
+		SourceKind: "ClusterRole", SourceName: `<img src=x onerror=alert(1)>`, ClusterScope: true,
+		BoundVia: "ClusterRoleBinding", BindingName: "b&b",
+		Permissions:   []rbac.Permission{{Verb: "get", Resource: "pods"}},
+		Dangerous:     true,
+		DangerReasons: []string{"<b>reason</b>"},
+	}}
+
+	var buf bytes.Buffer
+	if err := PrintHTML(&buf, sp, Filters{}, `Report <"prod">`); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	// без внешних ресурсов: ни подключаемых скриптов и стилей, ни картинок и шрифтов по URL
+	external := regexp.MustCompile(`(?i)<script[^>]+src\s*=|<link[^>]+href\s*=|<img[^>]+src\s*=|url\(|@import|https?://`)
+	if m := external.FindString(out); m != "" {
+		t.Fatalf("external resource %q in the HTML report", m)
+	}
+	if n := strings.Count(out, "<script"); n != 1 {
+		t.Fatalf("%d <script> elements, want only the inline one", n)
+	}
+
+	for _, raw := range []string{`<script>alert`, `<img src=x`, `<b>reason</b>`, `Report <"prod">`, "b&b"} {
+		if strings.Contains(out, raw) {
+			t.Errorf("%q is not escaped", raw)
+		}
+	}
+	for _, escaped := range []string{"&lt;script&gt;alert", "&lt;img src=x onerror=alert(1)&gt;", "&lt;b&gt;reason&lt;/b&gt;", "b&amp;b"} {
+		if !strings.Contains(out, escaped) {
+			t.Errorf("escaped %q not found", escaped)
+		}
+	}
+}
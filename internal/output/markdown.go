@@ -0,0 +1,267 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"rbac-analyzer/internal/rbac"
+)
+
+// GitHubCommentLimit — максимальная длина комментария к PR на GitHub.
+const GitHubCommentLimit = 65536
+
+// maxDiffTableRows — сколько строк сводной таблицы diff показываем до свёртки.
+const maxDiffTableRows = 50
+
+// MarkdownOptions — настройки markdown-вывода.
+type MarkdownOptions struct {
+	// MaxBytes ограничивает размер отчёта целиком, вместе с пометкой об усечении (0 = без ограничений).
+	// Блоки субъектов, не влезающие в лимит, отбрасываются целиком, в конце пишется пометка.
+	MaxBytes int
+}
+
+// PrintMarkdown — компактный отчёт для комментариев в PR: сводка, затем опасные субъекты,
+// затем остальные; права каждого субъекта свёрнуты в <details>.
+func PrintMarkdown(
+	w io.Writer,
+	subjectPerms rbac.SubjectPermissions,
+	filters Filters,
+	title string,
+	opts MarkdownOptions,
+) error {
+	filtered := Filter(subjectPerms, filters)
+
+	// опасные субъекты первыми, внутри группы — по имени
+	subjects := sortedSubjects(filtered)
+	sort.SliceStable(subjects, func(i, j int) bool {
+		return hasDangerous(filtered[subjects[i]]) && !hasDangerous(filtered[subjects[j]])
+	})
+
+	if strings.TrimSpace(title) == "" {
+		title = "RBAC Analysis Report"
+	}
+	sum := rbac.Summarize(filtered)
+
+	var head strings.Builder
+	fmt.Fprintf(&head, "## %s\n\n", title)
+	head.WriteString("| Subjects | Roles | Permissions | Dangerous roles | Risk score |\n")
+	head.WriteString("|---:|---:|---:|---:|---:|\n")
+	fmt.Fprintf(&head, "| %d | %d | %d | %d | %.1f / 10 |\n\n",
+		sum.Counts.Subjects, sum.Counts.Roles, sum.Counts.Perms, sum.Counts.DangerRoles, sum.RiskScore)
+
+	blocks := make([]string, 0, len(subjects))
+	for _, s := range subjects {
+		blocks = append(blocks, markdownSubject(s, filtered[s]))
+	}
+
+	return writeBounded(w, head.String(), blocks, "subject(s)", opts.MaxBytes)
+}
+
+// PrintMarkdownDiff — комментарий вида "этот PR выдаёт X новых прав Y субъектам".
+func PrintMarkdownDiff(w io.Writer, diff rbac.DiffResult, title string, opts MarkdownOptions) error {
+	if strings.TrimSpace(title) == "" {
+		title = "RBAC changes"
+	}
+
+	granted := 0
+	for _, sd := range diff.Subjects {
+		if len(sd.Added) > 0 {
+			granted++
+		}
+	}
+
+	var head strings.Builder
+	fmt.Fprintf(&head, "## %s\n\n", title)
+	if diff.Summary.SubjectsChanged == 0 {
+		head.WriteString("No RBAC changes.\n")
+		return writeBounded(w, head.String(), nil, "subject(s)", opts.MaxBytes)
+	}
+	fmt.Fprintf(&head, "This change grants **%d** new permission(s) to **%d** subject(s) and removes **%d** permission(s).\n\n",
+		diff.Summary.PermsAdded, granted, diff.Summary.PermsRemoved)
+	if diff.Summary.DangerIncreased > 0 {
+		fmt.Fprintf(&head, "> :warning: **%d** subject(s) become dangerous.\n\n", diff.Summary.DangerIncreased)
+	}
+
+	head.WriteString("| Subject | Added | Removed | Danger |\n")
+	head.WriteString("|---|---:|---:|---|\n")
+	for i, sd := range diff.Subjects {
+		if i == maxDiffTableRows {
+			fmt.Fprintf(&head, "| _… and %d more_ | | | |\n", len(diff.Subjects)-i)
+			break
+		}
+		fmt.Fprintf(&head, "| `%s` | %d | %d | %s |\n",
+			mdCell(sd.SubjectKey), len(sd.Added), len(sd.Removed), dangerTransition(sd))
+	}
+	head.WriteString("\n")
+
+	// сначала субъекты, ставшие опасными
+	subjects := append([]rbac.SubjectDiff{}, diff.Subjects...)
+	sort.SliceStable(subjects, func(i, j int) bool {
+		return subjects[i].TargetDangerous && !subjects[i].BaseDangerous &&
+			!(subjects[j].TargetDangerous && !subjects[j].BaseDangerous)
+	})
+
+	blocks := make([]string, 0, len(subjects))
+	for _, sd := range subjects {
+		var b strings.Builder
+		fmt.Fprintf(&b, "<details>\n<summary><code>%s</code> (+%d / -%d)</summary>\n\n```diff\n",
+			htmlEscape(sd.SubjectKey), len(sd.Added), len(sd.Removed))
+		for _, p := range sd.Added {
+			fmt.Fprintf(&b, "+ %s\n", p)
+		}
+		for _, p := range sd.Removed {
+			fmt.Fprintf(&b, "- %s\n", p)
+		}
+		b.WriteString("```\n")
+		if len(sd.TargetReasons) > 0 {
+			b.WriteString("\nDanger reasons:\n")
+			for _, r := range sd.TargetReasons {
+				fmt.Fprintf(&b, "- %s\n", r)
+			}
+		}
+		b.WriteString("\n</details>\n\n")
+		blocks = append(blocks, b.String())
+	}
+
+	return writeBounded(w, head.String(), blocks, "subject(s)", opts.MaxBytes)
+}
+
+func markdownSubject(s rbac.SubjectRef, roles []rbac.EffectiveRole) string {
+	var b strings.Builder
+
+	mark := ""
+	if hasDangerous(roles) {
+		mark = " :warning:"
+	}
+	fmt.Fprintf(&b, "<details>\n<summary><code>%s</code>%s — %d role(s)</summary>\n\n",
+		htmlEscape(s.String()), mark, len(roles))
+
+	b.WriteString("| Role | Bound via | Scope | Dangerous |\n")
+	b.WriteString("|---|---|---|---|\n")
+	for _, r := range roles {
+		danger := ""
+		if r.Dangerous {
+			danger = "**yes**: " + mdCell(strings.Join(r.DangerReasons, "; "))
+		}
+		fmt.Fprintf(&b, "| `%s` | `%s` | %s | %s |\n",
+			mdCell(r.RoleLabel()), mdCell(r.BindingLabel()), roleScope(r), danger)
+	}
+
+	b.WriteString("\n```\n")
+	for _, r := range roles {
+		for _, p := range r.Permissions {
+			fmt.Fprintln(&b, rbac.CanonicalPermissionKey(p.Namespace, p.Verb, p.APIGroup, p.Resource, p.ResourceNames))
+		}
+	}
+	b.WriteString("```\n\n</details>\n\n")
+
+	return b.String()
+}
+
+// writeBounded пишет head и столько блоков, сколько влезает в maxBytes (0 — без ограничения).
+// В лимит входит всё: заголовок, блоки и пометка об усечении. Не влезающий заголовок
+// (длинный title, большая таблица diff) обрезается по строкам, блоки тогда не пишутся.
+func writeBounded(w io.Writer, head string, blocks []string, noun string, maxBytes int) error {
+	total := len(head)
+	for _, blk := range blocks {
+		total += len(blk)
+	}
+	if maxBytes <= 0 || total <= maxBytes {
+		if _, err := io.WriteString(w, head); err != nil {
+			return err
+		}
+		for _, blk := range blocks {
+			if _, err := io.WriteString(w, blk); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	notice := func(omitted int) string {
+		if omitted == 0 {
+			return fmt.Sprintf("_Report truncated to fit the %d byte limit._\n", maxBytes)
+		}
+		return fmt.Sprintf("_Report truncated: %d more %s omitted to fit the %d byte limit._\n", omitted, noun, maxBytes)
+	}
+	// место под самую длинную пометку
+	budget := maxBytes - max(len(notice(0)), len(notice(len(blocks))))
+
+	var b strings.Builder
+	written := 0
+	if len(head) > budget {
+		b.WriteString(cutLines(head, budget))
+	} else {
+		b.WriteString(head)
+		for _, blk := range blocks {
+			if b.Len()+len(blk) > budget {
+				break
+			}
+			b.WriteString(blk)
+			written++
+		}
+	}
+	b.WriteString(notice(len(blocks) - written))
+
+	// лимит меньше самой пометки
+	_, err := io.WriteString(w, cutUTF8(b.String(), maxBytes))
+	return err
+}
+
+// cutLines — начало s не длиннее n байт, по границе строки (если она есть).
+func cutLines(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	if len(s) <= n {
+		return s
+	}
+	if i := strings.LastIndexByte(s[:n], '\n'); i >= 0 {
+		return s[:i+1]
+	}
+	return cutUTF8(s, n)
+}
+
+// cutUTF8 — начало s не длиннее n байт, не разрезая символы UTF-8.
+func cutUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+func hasDangerous(roles []rbac.EffectiveRole) bool {
+	for _, r := range roles {
+		if r.Dangerous {
+			return true
+		}
+	}
+	return false
+}
+
+func dangerTransition(sd rbac.SubjectDiff) string {
+	switch {
+	case !sd.BaseDangerous && sd.TargetDangerous:
+		return ":warning: becomes dangerous"
+	case sd.BaseDangerous && !sd.TargetDangerous:
+		return "no longer dangerous"
+	case sd.TargetDangerous:
+		return "dangerous"
+	default:
+		return ""
+	}
+}
+
+func mdCell(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
+
+func htmlEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
@@ -0,0 +1,62 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"rbac-analyzer/internal/rbac"
+)
+
+func TestPrintMarkdownMaxBytes(t *testing.T) {
+	sp, _ := testPerms()
+	var full bytes.Buffer
+	if err := PrintMarkdown(&full, sp, Filters{}, "Отчёт", MarkdownOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, limit := range []int{1, 20, 100, 300, full.Len() - 1, full.Len(), full.Len() + 100} {
+		var buf bytes.Buffer
+		if err := PrintMarkdown(&buf, sp, Filters{}, "Отчёт", MarkdownOptions{MaxBytes: limit}); err != nil {
+			t.Fatal(err)
+		}
+		out := buf.String()
+		if len(out) > limit || !utf8.ValidString(out) {
+			t.Errorf("limit %d: %d bytes, valid UTF-8 %v", limit, len(out), utf8.ValidString(out))
+		}
+		// пометка влезает, начиная примерно со 100 байт
+		if truncated := limit < full.Len(); limit >= 100 && truncated != strings.Contains(out, "truncated") {
+			t.Errorf("limit %d: truncated %v, output:\n%s", limit, truncated, out)
+		}
+		if limit >= full.Len() && out != full.String() {
+			t.Errorf("limit %d: output differs from the unbounded report", limit)
+		}
+	}
+}
+
+func TestPrintMarkdownDiffMaxBytes(t *testing.T) {
+	// таблица diff сама по себе больше лимита
+	var diff rbac.DiffResult
+	for i := 0; i < 200; i++ {
+		diff.Subjects = append(diff.Subjects, rbac.SubjectDiff{
+			SubjectKey: fmt.Sprintf("ServiceAccount:tenant-%03d/%s", i, strings.Repeat("x", 40)),
+			Added:      []string{"get secrets"},
+		})
+	}
+	diff.Summary = rbac.DiffSummary{SubjectsChanged: len(diff.Subjects), PermsAdded: len(diff.Subjects)}
+
+	const limit = 2000
+	var buf bytes.Buffer
+	if err := PrintMarkdownDiff(&buf, diff, strings.Repeat("long title ", 20), MarkdownOptions{MaxBytes: limit}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if len(out) > limit || !strings.HasSuffix(out, "byte limit._\n") {
+		t.Fatalf("%d bytes, want <= %d and a truncation note:\n%s", len(out), limit, out)
+	}
+	if !strings.Contains(out, "200 more subject(s) omitted") {
+		t.Fatalf("note does not count omitted subjects:\n%s", out[len(out)-200:])
+	}
+}
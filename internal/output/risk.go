@@ -0,0 +1,59 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"rbac-analyzer/internal/rbac"
+)
+
+// PrintRisk — ранжированный список субъектов по оценке риска с разложением по факторам.
+// top <= 0 — все субъекты.
+func PrintRisk(
+	w io.Writer,
+	subjectPerms rbac.SubjectPermissions,
+	workloads []rbac.Workload,
+	filters Filters,
+	title string,
+	top int,
+) error {
+	// Оценка организации считается по всем субъектам, фильтры сужают только список.
+	rep := rbac.ScoreRisk(subjectPerms, workloads)
+	if !filters.IsZero() {
+		rep = filterRisk(rep, Filter(subjectPerms, filters))
+	}
+
+	if title = strings.TrimSpace(title); title != "" {
+		fmt.Fprintf(w, "%s\n%s\n\n", title, strings.Repeat("=", len([]rune(title))))
+	}
+	fmt.Fprintf(w, "Org score: %.1f / 100 (model %s)\n\n", rep.OrgScore, rep.ModelVersion)
+
+	subjects := rep.Subjects
+	if top > 0 {
+		subjects = rep.Top(top)
+	}
+	for i, sr := range subjects {
+		fmt.Fprintf(w, "%3d. %5.1f  %s\n", i+1, sr.Score, sr.Subject)
+		for _, f := range sr.Factors {
+			if f.Name == "subjectType" {
+				fmt.Fprintf(w, "           x%.2f %-11s %s\n", f.Points, f.Name, f.Detail)
+				continue
+			}
+			fmt.Fprintf(w, "          +%5.1f %-11s %s\n", f.Points, f.Name, f.Detail)
+		}
+	}
+	return nil
+}
+
+// filterRisk оставляет субъектов, прошедших фильтры; порядок и OrgScore сохраняются.
+func filterRisk(rep rbac.RiskReport, filtered rbac.SubjectPermissions) rbac.RiskReport {
+	subjects := make([]rbac.SubjectRisk, 0, len(filtered))
+	for _, sr := range rep.Subjects {
+		if _, ok := filtered[rbac.ParseSubjectRef(sr.Subject)]; ok {
+			subjects = append(subjects, sr)
+		}
+	}
+	rep.Subjects = subjects
+	return rep
+}
@@ -0,0 +1,168 @@
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"rbac-analyzer/internal/rbac"
+)
+
+// Violation — нарушение правила вместе с цепочкой subject -> binding -> role.
+type Violation struct {
+	RuleID      string   `json:"ruleId"`
+	Level       string   `json:"level"`
+	Description string   `json:"description,omitempty"`
+	Message     string   `json:"message"`
+	Subject     string   `json:"subject,omitempty"`
+	Chain       []string `json:"chain,omitempty"`
+	Permission  string   `json:"permission,omitempty"`
+	Roles       []string `json:"roles,omitempty"` // для агрегатных правил (max)
+}
+
+// Result — итог проверки.
+type Result struct {
+	Violations []Violation `json:"violations"`
+	Denied     int         `json:"denied"`
+	Warned     int         `json:"warned"`
+}
+
+// Evaluate проверяет SubjectPermissions на соответствие политике.
+// Порядок нарушений стабилен: по правилам, затем по субъектам.
+func Evaluate(p *Policy, sp rbac.SubjectPermissions) Result {
+	subjects := make([]rbac.SubjectRef, 0, len(sp))
+	for s := range sp {
+		subjects = append(subjects, s)
+	}
+	sort.Slice(subjects, func(i, j int) bool { return subjects[i].String() < subjects[j].String() })
+
+	res := Result{Violations: make([]Violation, 0)}
+
+	for _, rule := range p.Rules {
+		var found []Violation
+		distinctRoles := map[string]bool{}
+
+		for _, s := range subjects {
+			for _, r := range sp[s] {
+				ok, perm := rule.Match.matches(s, r)
+				if !ok {
+					continue
+				}
+				if rule.Except != nil {
+					if ex, _ := rule.Except.matches(s, r); ex {
+						continue
+					}
+				}
+
+				distinctRoles[r.RoleLabel()] = true
+				found = append(found, Violation{
+					RuleID:      rule.ID,
+					Level:       rule.Level,
+					Description: rule.Description,
+					Message:     violationMessage(rule, r),
+					Subject:     s.String(),
+					Chain:       []string{s.String(), r.BindingLabel(), r.RoleLabel()},
+					Permission:  perm,
+				})
+			}
+		}
+
+		if rule.Max != nil {
+			if len(distinctRoles) <= *rule.Max {
+				continue
+			}
+			roles := make([]string, 0, len(distinctRoles))
+			for k := range distinctRoles {
+				roles = append(roles, k)
+			}
+			sort.Strings(roles)
+			found = []Violation{{
+				RuleID:      rule.ID,
+				Level:       rule.Level,
+				Description: rule.Description,
+				Message:     fmt.Sprintf("%d matching roles, max %d", len(roles), *rule.Max),
+				Roles:       roles,
+			}}
+		}
+
+		for _, v := range found {
+			if v.Level == LevelDeny {
+				res.Denied++
+			} else {
+				res.Warned++
+			}
+		}
+		res.Violations = append(res.Violations, found...)
+	}
+
+	return res
+}
+
+func violationMessage(rule Rule, r rbac.EffectiveRole) string {
+	msg := rule.Description
+	if msg == "" {
+		msg = "matches rule " + rule.ID
+	}
+	if r.Dangerous && len(r.DangerReasons) > 0 {
+		msg += " (" + strings.Join(r.DangerReasons, "; ") + ")"
+	}
+	return msg
+}
+
+// matches проверяет пару субъект/роль. Второе значение — первое подходящее право
+// (если в Match есть условия на права).
+func (m Match) matches(s rbac.SubjectRef, r rbac.EffectiveRole) (bool, string) {
+	if len(m.Kinds) > 0 && !matchFold(m.Kinds, string(s.Kind)) {
+		return false, ""
+	}
+	if len(m.Subjects) > 0 && !rbac.MatchGlob(m.Subjects, s.String()) && !rbac.MatchGlob(m.Subjects, s.Name) {
+		return false, ""
+	}
+	if len(m.SubjectNamespaces) > 0 && (s.Namespace == "" || !rbac.MatchGlob(m.SubjectNamespaces, s.Namespace)) {
+		return false, ""
+	}
+	if len(m.Roles) > 0 && !rbac.MatchGlob(m.Roles, r.SourceName) && !rbac.MatchGlob(m.Roles, r.SourceNamespace+"/"+r.SourceName) {
+		return false, ""
+	}
+	if m.BoundVia != "" && !strings.EqualFold(m.BoundVia, r.BoundVia) {
+		return false, ""
+	}
+	if (m.Scope == "cluster" && !r.ClusterScope) || (m.Scope == "namespace" && r.ClusterScope) {
+		return false, ""
+	}
+	if m.Dangerous != nil && *m.Dangerous != r.Dangerous {
+		return false, ""
+	}
+
+	if len(m.Verbs) == 0 && len(m.Resources) == 0 && len(m.APIGroups) == 0 {
+		return true, ""
+	}
+	for _, p := range r.Permissions {
+		if covers(m.Verbs, p.Verb) && covers(m.Resources, p.Resource) && covers(m.APIGroups, p.APIGroup) {
+			return true, rbac.CanonicalPermissionKey(p.Namespace, p.Verb, p.APIGroup, p.Resource, p.ResourceNames)
+		}
+	}
+	return false, ""
+}
+
+// covers: значение из роли подходит под список из политики ("*" в роли покрывает всё).
+// Регистр не важен ни в политике, ни в роли: `Secrets` и `secrets` — одно и то же.
+func covers(want []string, have string) bool {
+	if len(want) == 0 || have == "*" {
+		return true
+	}
+	patterns := make([]string, len(want))
+	for i, p := range want {
+		patterns[i] = strings.ToLower(p)
+	}
+	return rbac.MatchGlob(patterns, strings.ToLower(have))
+}
+
+func matchFold(patterns []string, v string) bool {
+	for _, p := range patterns {
+		if strings.EqualFold(strings.TrimSpace(p), v) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package policy
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Уровни правил.
+const (
+	LevelDeny = "deny"
+	LevelWarn = "warn"
+)
+
+// Policy — набор правил из policy.yaml.
+//
+//	rules:
+//	  - id: cluster-secrets-read
+//	    description: only platform admins may read secrets cluster-wide
+//	    level: deny
+//	    match:
+//	      scope: cluster
+//	      verbs: [get, list, watch]
+//	      resources: [secrets]
+//	    except:
+//	      subjects: ["Group:platform-admins"]
+//	  - id: tenant-sa-no-crb
+//	    level: deny
+//	    match:
+//	      kinds: [ServiceAccount]
+//	      subjectNamespaces: ["tenant-*"]
+//	      boundVia: ClusterRoleBinding
+//	  - id: max-dangerous-roles
+//	    level: warn
+//	    match:
+//	      dangerous: true
+//	    max: 20
+type Policy struct {
+	Rules []Rule `yaml:"rules" json:"rules"`
+}
+
+// Rule — одно правило. Нарушение = роль субъекта, подходящая под Match и не подходящая под Except.
+// Если задан Max, правило агрегатное: нарушение, когда различных подходящих ролей больше Max.
+type Rule struct {
+	ID          string `yaml:"id" json:"id"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+	Level       string `yaml:"level" json:"level"` // deny | warn
+	Match       Match  `yaml:"match" json:"match"`
+	Except      *Match `yaml:"except,omitempty" json:"except,omitempty"`
+	Max         *int   `yaml:"max,omitempty" json:"max,omitempty"`
+}
+
+// Match — условия (внутри списка — ИЛИ, между полями — И). Строки поддерживают glob
+// (rbac.MatchGlob, как и фильтры CLI: "*" совпадает и с "/").
+// Пустое поле — без ограничения.
+type Match struct {
+	Subjects          []string `yaml:"subjects,omitempty" json:"subjects,omitempty"` // "Group:x", "ServiceAccount:ns/name" или имя
+	Kinds             []string `yaml:"kinds,omitempty" json:"kinds,omitempty"`       // User | Group | ServiceAccount
+	SubjectNamespaces []string `yaml:"subjectNamespaces,omitempty" json:"subjectNamespaces,omitempty"`
+
+	Roles     []string `yaml:"roles,omitempty" json:"roles,omitempty"` // имя или ns/name
+	BoundVia  string   `yaml:"boundVia,omitempty" json:"boundVia,omitempty"`
+	Scope     string   `yaml:"scope,omitempty" json:"scope,omitempty"` // cluster | namespace
+	Dangerous *bool    `yaml:"dangerous,omitempty" json:"dangerous,omitempty"`
+
+	// Условия на права: роль подходит, если хотя бы одно её право подходит под все три списка.
+	// "*" в правиле роли покрывает любое значение.
+	Verbs     []string `yaml:"verbs,omitempty" json:"verbs,omitempty"`
+	Resources []string `yaml:"resources,omitempty" json:"resources,omitempty"`
+	APIGroups []string `yaml:"apiGroups,omitempty" json:"apiGroups,omitempty"`
+}
+
+// LoadFile читает и валидирует policy.yaml.
+func LoadFile(path string) (*Policy, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(b)
+}
+
+// Parse разбирает и валидирует политику.
+func Parse(b []byte) (*Policy, error) {
+	var p Policy
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&p); err != nil {
+		return nil, fmt.Errorf("parse policy: %w", err)
+	}
+
+	seen := map[string]bool{}
+	for i := range p.Rules {
+		r := &p.Rules[i]
+		if r.ID == "" {
+			return nil, fmt.Errorf("rule #%d: id required", i+1)
+		}
+		if seen[r.ID] {
+			return nil, fmt.Errorf("rule %s: duplicate id", r.ID)
+		}
+		seen[r.ID] = true
+
+		r.Level = strings.ToLower(strings.TrimSpace(r.Level))
+		if r.Level == "" {
+			r.Level = LevelDeny
+		}
+		if r.Level != LevelDeny && r.Level != LevelWarn {
+			return nil, fmt.Errorf("rule %s: level must be deny or warn", r.ID)
+		}
+		if r.Max != nil && *r.Max < 0 {
+			return nil, fmt.Errorf("rule %s: max must be >= 0", r.ID)
+		}
+		if s := r.Match.Scope; s != "" && s != "cluster" && s != "namespace" {
+			return nil, fmt.Errorf("rule %s: scope must be cluster or namespace", r.ID)
+		}
+	}
+	return &p, nil
+}
@@ -0,0 +1,111 @@
+package policy
+
+import (
+	"testing"
+
+	"rbac-analyzer/internal/rbac"
+)
+
+func testPerms() rbac.SubjectPermissions {
+	alice := rbac.SubjectRef{Kind: rbac.SubjectKindUser, Name: "alice"}
+	ci := rbac.SubjectRef{Kind: rbac.SubjectKindServiceAccount, Name: "deployer", Namespace: "tenant-a"}
+	return rbac.SubjectPermissions{
+		alice: {
+			{SourceKind: "ClusterRole", SourceName: "secret-reader", ClusterScope: true, BoundVia: "ClusterRoleBinding", BindingName: "alice",
+				Permissions: []rbac.Permission{{Verb: "Get", Resource: "Secrets", ClusterScope: true}}},
+		},
+		ci: {
+			{SourceKind: "ClusterRole", SourceName: "view", ClusterScope: true, BoundVia: "ClusterRoleBinding", BindingName: "ci-view",
+				Permissions: []rbac.Permission{{Verb: "list", Resource: "pods", ClusterScope: true}}},
+		},
+	}
+}
+
+func mustParse(t *testing.T, src string) *Policy {
+	t.Helper()
+	p, err := Parse([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestCoversIgnoresCase(t *testing.T) {
+	for _, c := range []struct {
+		want []string
+		have string
+		ok   bool
+	}{
+		{[]string{"Secrets"}, "secrets", true},
+		{[]string{"secrets"}, "Secrets", true},
+		{[]string{"SECRET*"}, "secrets", true},
+		{[]string{"configmaps"}, "Secrets", false},
+		{nil, "anything", true},
+		{[]string{"secrets"}, "*", true},
+	} {
+		if got := covers(c.want, c.have); got != c.ok {
+			t.Errorf("covers(%v, %q) = %v, want %v", c.want, c.have, got, c.ok)
+		}
+	}
+}
+
+func TestEvaluateLevels(t *testing.T) {
+	p := mustParse(t, `
+rules:
+  - id: cluster-secrets-read
+    level: deny
+    match:
+      scope: cluster
+      verbs: [GET, list]
+      resources: [Secrets]
+  - id: tenant-sa-no-crb
+    level: warn
+    match:
+      kinds: [serviceaccount]
+      subjectNamespaces: ["tenant-*"]
+      boundVia: clusterrolebinding
+  - id: max-cluster-roles
+    level: warn
+    match:
+      scope: cluster
+    max: 1
+`)
+	res := Evaluate(p, testPerms())
+	if res.Denied != 1 || res.Warned != 2 {
+		t.Fatalf("denied %d, warned %d: %+v", res.Denied, res.Warned, res.Violations)
+	}
+	v := res.Violations[0]
+	if v.RuleID != "cluster-secrets-read" || v.Subject != "User:alice" || v.Permission == "" {
+		t.Fatalf("first violation = %+v", v)
+	}
+	if agg := res.Violations[2]; agg.RuleID != "max-cluster-roles" || len(agg.Roles) != 2 {
+		t.Fatalf("aggregate violation = %+v", agg)
+	}
+
+	// except снимает нарушение
+	p.Rules[0].Except = &Match{Subjects: []string{"alice"}}
+	if res := Evaluate(p, testPerms()); res.Denied != 0 {
+		t.Fatalf("except: denied %d", res.Denied)
+	}
+}
+
+// Шаблоны политики и фильтров CLI совпадают: "*" переходит через "/".
+func TestSubjectGlobCrossesSlash(t *testing.T) {
+	p := mustParse(t, `
+rules:
+  - id: tenant-sa
+    match:
+      subjects: ["ServiceAccount:tenant-*"]
+  - id: tenant-roles
+    level: warn
+    match:
+      roles: ["*/view"]
+`)
+	res := Evaluate(p, testPerms())
+	if res.Denied != 1 || res.Violations[0].Subject != "ServiceAccount:tenant-a/deployer" {
+		t.Fatalf("subject glob: %+v", res.Violations)
+	}
+	if res.Warned != 1 || res.Violations[1].Subject != "ServiceAccount:tenant-a/deployer" {
+		t.Fatalf("role glob: %+v", res.Violations)
+	}
+}
@@ -18,7 +18,7 @@ func BuildSubjectPermissions(
 		allSubjects := collectSubjectsStrings(rb.Subjects, sourceNamespace)
 
 		for _, subj := range rb.Subjects {
-			sref := subjectRefFromSubject(subj, sourceNamespace)
+			sref := SubjectRefFromSubject(subj, sourceNamespace)
 			if sref.Name == "" {
 				continue
 			}
@@ -44,7 +44,7 @@ func BuildSubjectPermissions(
 	for _, crb := range clusterRoleBindings {
 		allSubjects := collectSubjectsStrings(crb.Subjects, "")
 		for _, subj := range crb.Subjects {
-			sref := subjectRefFromSubject(subj, "")
+			sref := SubjectRefFromSubject(subj, "")
 			if sref.Name == "" {
 				continue
 			}
@@ -85,7 +85,7 @@ func indexClusterRoles(clusterRoles []ClusterRole) map[string]*ClusterRole {
 func collectSubjectsStrings(subjects []Subject, defaultNS string) []string {
 	out := make([]string, 0, len(subjects))
 	for _, s := range subjects {
-		ref := subjectRefFromSubject(s, defaultNS)
+		ref := SubjectRefFromSubject(s, defaultNS)
 		out = append(out, ref.String())
 	}
 	return out
@@ -93,7 +93,7 @@ func collectSubjectsStrings(subjects []Subject, defaultNS string) []string {
 
 func buildEffectiveRoleFromRole(role *Role, rb RoleBinding, allSubjects []string) EffectiveRole {
 	perms := flattenRules(role.Rules, role.Metadata.Namespace, false)
-	dangerous, reasons := EvaluateDangerous(role.Rules)
+	findings := EvaluateFindings(role.Rules)
 
 	return EffectiveRole{
 		SourceKind:      "Role",
@@ -101,8 +101,9 @@ func buildEffectiveRoleFromRole(role *Role, rb RoleBinding, allSubjects []string
 		SourceNamespace: role.Metadata.Namespace,
 		ClusterScope:    false,
 		Permissions:     perms,
-		Dangerous:       dangerous,
-		DangerReasons:   reasons,
+		Dangerous:       len(findings) > 0,
+		DangerReasons:   findingMessages(findings),
+		Findings:        findings,
 		BoundVia:        "RoleBinding",
 		BindingName:     rb.Metadata.Name,
 		BindingNS:       rb.Metadata.Namespace,
@@ -121,9 +122,6 @@ func buildEffectiveRoleFromClusterRole(
 	allSubjects []string,
 	clusterScope bool,
 ) EffectiveRole {
-	perms := flattenRules(cr.Rules, "", clusterScope)
-	dangerous, reasons := EvaluateDangerous(cr.Rules)
-
 	var boundVia, bindingName, bindingNS string
 
 	switch b := binding.(type) {
@@ -139,14 +137,19 @@ func buildEffectiveRoleFromClusterRole(
 		boundVia = "UnknownBinding"
 	}
 
+	// ClusterRole через RoleBinding действует только в namespace биндинга
+	perms := flattenRules(cr.Rules, bindingNS, clusterScope)
+	findings := EvaluateFindings(cr.Rules)
+
 	return EffectiveRole{
 		SourceKind:      "ClusterRole",
 		SourceName:      cr.Metadata.Name,
 		SourceNamespace: "",
 		ClusterScope:    clusterScope,
 		Permissions:     perms,
-		Dangerous:       dangerous,
-		DangerReasons:   reasons,
+		Dangerous:       len(findings) > 0,
+		DangerReasons:   findingMessages(findings),
+		Findings:        findings,
 		BoundVia:        boundVia,
 		BindingName:     bindingName,
 		BindingNS:       bindingNS,
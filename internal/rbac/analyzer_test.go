@@ -0,0 +1,49 @@
+package rbac
+
+import "testing"
+
+var readServices = PolicyRule{APIGroups: []string{""}, Resources: []string{"services"}, Verbs: []string{"get", "list"}}
+
+func TestBuildSubjectPermissions(t *testing.T) {
+	roles := []Role{role("dev", "reader", readServices)}
+	clusterRoles := []ClusterRole{clusterRole("admin", all)}
+	bindings := []RoleBinding{
+		roleBinding("dev", "app-reader", "reader"),
+		roleBinding("dev", "missing", "no-such-role"),
+		{
+			Metadata: ObjectMeta{Name: "admin-in-dev", Namespace: "dev"},
+			Subjects: []Subject{{Kind: "User", Name: "alice"}},
+			RoleRef:  RoleRef{Kind: "ClusterRole", Name: "admin"},
+		},
+	}
+	clusterBindings := []ClusterRoleBinding{{
+		Metadata: ObjectMeta{Name: "root"},
+		Subjects: []Subject{{Kind: "Group", Name: "ops"}, {Kind: "User"}},
+		RoleRef:  RoleRef{Kind: "ClusterRole", Name: "admin"},
+	}}
+
+	sp := BuildSubjectPermissions(roles, clusterRoles, bindings, clusterBindings)
+	if len(sp) != 3 {
+		t.Fatalf("subjects = %v, want app, alice and ops", sp)
+	}
+
+	app := sp[SubjectRef{Kind: SubjectKindServiceAccount, Name: "app", Namespace: "dev"}]
+	if len(app) != 1 || app[0].Dangerous || app[0].ClusterScope || len(app[0].Permissions) != 2 {
+		t.Fatalf("app roles = %+v", app)
+	}
+	for _, p := range app[0].Permissions {
+		if p.Namespace != "dev" {
+			t.Errorf("app permission %+v outside dev", p)
+		}
+	}
+
+	// ClusterRole через RoleBinding действует только в namespace биндинга
+	alice := sp[SubjectRef{Kind: SubjectKindUser, Name: "alice"}]
+	if len(alice) != 1 || alice[0].ClusterScope || alice[0].Permissions[0].Namespace != "dev" || !alice[0].Dangerous {
+		t.Fatalf("alice roles = %+v", alice)
+	}
+	ops := sp[SubjectRef{Kind: SubjectKindGroup, Name: "ops"}]
+	if len(ops) != 1 || !ops[0].ClusterScope || ops[0].BoundVia != "ClusterRoleBinding" {
+		t.Fatalf("ops roles = %+v", ops)
+	}
+}
@@ -0,0 +1,349 @@
+package rbac
+
+import (
+	"sort"
+	"strings"
+)
+
+// DuplicateGroup — роли с одинаковым набором прав.
+type DuplicateGroup struct {
+	Roles       []string `json:"roles"` // RoleLabel
+	Permissions int      `json:"permissions"`
+}
+
+// SubsetPair — права роли целиком покрываются другой ролью (с учётом "*").
+type SubsetPair struct {
+	Role     string `json:"role"`
+	Superset string `json:"superset"`
+}
+
+// Suggestion — заменить копии Role в разных namespace одной ClusterRole и RoleBinding'ами на неё.
+type Suggestion struct {
+	ClusterRole    ClusterRole   `json:"clusterRole"`
+	ReuseExisting  bool          `json:"reuseExisting"` // ClusterRole с такими правами уже есть
+	Replaces       []string      `json:"replaces"`      // Role, которые можно удалить
+	RoleBindings   []RoleBinding `json:"roleBindings"`  // новые биндинги (roleRef -> ClusterRole)
+	RemoveBindings []string      `json:"removeBindings"`
+}
+
+// ConsolidationReport — результат анализа дубликатов.
+type ConsolidationReport struct {
+	Duplicates  []DuplicateGroup `json:"duplicates"`
+	Subsets     []SubsetPair     `json:"subsets"`
+	Suggestions []Suggestion     `json:"suggestions"`
+}
+
+// ConsolidateOptions — параметры анализа.
+type ConsolidateOptions struct {
+	MinCopies     int  // с какого числа копий Role предлагать общую ClusterRole (по умолчанию 2)
+	IncludeSystem bool // учитывать роли system:* (их поддерживает сам API server)
+}
+
+type normRole struct {
+	kind, name, namespace string
+	label                 string
+	perms                 []Permission
+	sig                   string
+}
+
+// Consolidate нормализует правила ролей (flattenRules + CanonicalPermissionKey без namespace),
+// находит одинаковые роли, роли-подмножества и предлагает общие ClusterRole
+// для Role, скопированных в несколько namespace.
+func Consolidate(
+	roles []Role,
+	clusterRoles []ClusterRole,
+	roleBindings []RoleBinding,
+	opts ConsolidateOptions,
+) ConsolidationReport {
+	if opts.MinCopies < 2 {
+		opts.MinCopies = 2
+	}
+	skip := func(name string) bool {
+		return !opts.IncludeSystem && strings.HasPrefix(name, "system:")
+	}
+
+	var all []normRole
+	for _, r := range roles {
+		if skip(r.Metadata.Name) {
+			continue
+		}
+		all = append(all, normalizeRole("Role", r.Metadata.Name, r.Metadata.Namespace, r.Rules))
+	}
+	for _, cr := range clusterRoles {
+		if skip(cr.Metadata.Name) {
+			continue
+		}
+		all = append(all, normalizeRole("ClusterRole", cr.Metadata.Name, "", cr.Rules))
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].label < all[j].label })
+
+	rep := ConsolidationReport{
+		Duplicates:  []DuplicateGroup{},
+		Subsets:     []SubsetPair{},
+		Suggestions: []Suggestion{},
+	}
+
+	// одинаковые наборы прав
+	bySig := map[string][]normRole{}
+	var sigs []string
+	for _, r := range all {
+		if len(r.perms) == 0 {
+			continue
+		}
+		if _, ok := bySig[r.sig]; !ok {
+			sigs = append(sigs, r.sig)
+		}
+		bySig[r.sig] = append(bySig[r.sig], r)
+	}
+	for _, sig := range sigs {
+		group := bySig[sig]
+		if len(group) < 2 {
+			continue
+		}
+		labels := make([]string, 0, len(group))
+		for _, r := range group {
+			labels = append(labels, r.label)
+		}
+		rep.Duplicates = append(rep.Duplicates, DuplicateGroup{Roles: labels, Permissions: len(group[0].perms)})
+
+		if s, ok := suggestShared(group, clusterRoles, roleBindings, opts.MinCopies); ok {
+			rep.Suggestions = append(rep.Suggestions, s)
+		}
+	}
+
+	// подмножества: сравниваем по одному представителю каждого набора
+	for i, a := range sigs {
+		for j, b := range sigs {
+			if i == j {
+				continue
+			}
+			ra, rb := bySig[a][0], bySig[b][0]
+			if permsCoveredBy(ra.perms, rb.perms) && !permsCoveredBy(rb.perms, ra.perms) {
+				for _, r := range bySig[a] {
+					rep.Subsets = append(rep.Subsets, SubsetPair{Role: r.label, Superset: rb.label})
+				}
+			}
+		}
+	}
+	sort.Slice(rep.Subsets, func(i, j int) bool {
+		if rep.Subsets[i].Role != rep.Subsets[j].Role {
+			return rep.Subsets[i].Role < rep.Subsets[j].Role
+		}
+		return rep.Subsets[i].Superset < rep.Subsets[j].Superset
+	})
+
+	return rep
+}
+
+func normalizeRole(kind, name, ns string, rules []PolicyRule) normRole {
+	raw := flattenRules(rules, "", false)
+
+	seen := map[string]bool{}
+	var perms []Permission
+	var keys []string
+	for _, p := range raw {
+		p.Verb = NormalizeVerb(p.Verb)
+		p.Resource = strings.ToLower(strings.TrimSpace(p.Resource))
+		p.APIGroup = strings.TrimSpace(p.APIGroup)
+		if p.Resource == "" {
+			continue // nonResourceURLs
+		}
+		k := CanonicalPermissionKey("", p.Verb, p.APIGroup, p.Resource, p.ResourceNames)
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		perms = append(perms, p)
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	r := normRole{kind: kind, name: name, namespace: ns, perms: perms, sig: strings.Join(keys, "\n")}
+	r.label = EffectiveRole{SourceKind: kind, SourceName: name, SourceNamespace: ns}.RoleLabel()
+	return r
+}
+
+// permsCoveredBy — каждое право a покрывается каким-либо правом b.
+func permsCoveredBy(a, b []Permission) bool {
+	for _, pa := range a {
+		ok := false
+		for _, pb := range b {
+			if permCovers(pb, pa) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func permCovers(have, want Permission) bool {
+	if have.Verb != "*" && have.Verb != want.Verb {
+		return false
+	}
+	if have.APIGroup != "*" && have.APIGroup != want.APIGroup {
+		return false
+	}
+	if have.Resource != "*" && have.Resource != want.Resource {
+		return false
+	}
+	if len(have.ResourceNames) == 0 {
+		return true
+	}
+	if len(want.ResourceNames) == 0 {
+		return false
+	}
+	names := map[string]bool{}
+	for _, n := range have.ResourceNames {
+		names[n] = true
+	}
+	for _, n := range want.ResourceNames {
+		if !names[n] {
+			return false
+		}
+	}
+	return true
+}
+
+// suggestShared — общая ClusterRole для одинаковых Role из разных namespace.
+func suggestShared(group []normRole, clusterRoles []ClusterRole, roleBindings []RoleBinding, minCopies int) (Suggestion, bool) {
+	var copies []normRole
+	namespaces := map[string]bool{}
+	var existing *normRole
+	for i, r := range group {
+		switch r.kind {
+		case "Role":
+			copies = append(copies, r)
+			namespaces[r.namespace] = true
+		case "ClusterRole":
+			if existing == nil {
+				existing = &group[i]
+			}
+		}
+	}
+	if len(copies) < minCopies || len(namespaces) < 2 {
+		return Suggestion{}, false
+	}
+
+	s := Suggestion{RoleBindings: []RoleBinding{}, RemoveBindings: []string{}}
+	if existing != nil {
+		s.ReuseExisting = true
+		s.ClusterRole = ClusterRole{
+			APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRole",
+			Metadata: ObjectMeta{Name: existing.name},
+		}
+	} else {
+		s.ClusterRole = ClusterRole{
+			APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRole",
+			Metadata: ObjectMeta{Name: sharedRoleName(copies, clusterRoles)},
+			Rules:    CompactRules(copies[0].perms),
+		}
+	}
+
+	replaced := map[string]bool{}
+	for _, r := range copies {
+		s.Replaces = append(s.Replaces, r.label)
+		replaced[roleKey(r.namespace, r.name)] = true
+	}
+
+	for _, rb := range roleBindings {
+		if rb.RoleRef.Kind != "Role" || !replaced[roleKey(rb.Metadata.Namespace, rb.RoleRef.Name)] {
+			continue
+		}
+		s.RemoveBindings = append(s.RemoveBindings, "RoleBinding:"+rb.Metadata.Namespace+"/"+rb.Metadata.Name)
+		s.RoleBindings = append(s.RoleBindings, RoleBinding{
+			APIVersion: "rbac.authorization.k8s.io/v1",
+			Kind:       "RoleBinding",
+			Metadata:   ObjectMeta{Name: rb.Metadata.Name, Namespace: rb.Metadata.Namespace},
+			Subjects:   rb.Subjects,
+			RoleRef:    RoleRef{APIGroup: "rbac.authorization.k8s.io", Kind: "ClusterRole", Name: s.ClusterRole.Metadata.Name},
+		})
+	}
+	sort.Strings(s.RemoveBindings)
+	return s, true
+}
+
+// sharedRoleName: общее имя копий, если оно одно и не занято ClusterRole; иначе "<имя>-shared".
+func sharedRoleName(copies []normRole, clusterRoles []ClusterRole) string {
+	name := copies[0].name
+	same := true
+	for _, r := range copies[1:] {
+		if r.name != name {
+			same = false
+			break
+		}
+	}
+	taken := false
+	for _, cr := range clusterRoles {
+		if cr.Metadata.Name == name {
+			taken = true
+			break
+		}
+	}
+	if same && !taken {
+		return name
+	}
+	return name + "-shared"
+}
+
+// CompactRules собирает права обратно в PolicyRule: ресурсы одной apiGroup
+// с одинаковыми глаголами и resourceNames объединяются. Namespace не учитывается.
+func CompactRules(perms []Permission) []PolicyRule {
+	type groupKey struct{ apiGroup, names, resource string }
+	verbs := map[groupKey]map[string]bool{}
+	for _, p := range perms {
+		names := append([]string{}, p.ResourceNames...)
+		sort.Strings(names)
+		k := groupKey{p.APIGroup, strings.Join(names, ","), p.Resource}
+		if verbs[k] == nil {
+			verbs[k] = map[string]bool{}
+		}
+		verbs[k][p.Verb] = true
+	}
+
+	type ruleKey struct{ apiGroup, names, verbs string }
+	resources := map[ruleKey][]string{}
+	for k, vs := range verbs {
+		list := make([]string, 0, len(vs))
+		for v := range vs {
+			list = append(list, v)
+		}
+		sort.Strings(list)
+		rk := ruleKey{k.apiGroup, k.names, strings.Join(list, ",")}
+		resources[rk] = append(resources[rk], k.resource)
+	}
+
+	keys := make([]ruleKey, 0, len(resources))
+	for k := range resources {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.apiGroup != b.apiGroup {
+			return a.apiGroup < b.apiGroup
+		}
+		if a.verbs != b.verbs {
+			return a.verbs < b.verbs
+		}
+		return a.names < b.names
+	})
+
+	out := make([]PolicyRule, 0, len(keys))
+	for _, k := range keys {
+		res := resources[k]
+		sort.Strings(res)
+		rule := PolicyRule{
+			APIGroups: []string{k.apiGroup},
+			Resources: res,
+			Verbs:     strings.Split(k.verbs, ","),
+		}
+		if k.names != "" {
+			rule.ResourceNames = strings.Split(k.names, ",")
+		}
+		out = append(out, rule)
+	}
+	return out
+}
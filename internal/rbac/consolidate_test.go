@@ -0,0 +1,251 @@
+package rbac
+
+import (
+	"reflect"
+	"testing"
+)
+
+func role(ns, name string, rules ...PolicyRule) Role {
+	return Role{Kind: "Role", Metadata: ObjectMeta{Name: name, Namespace: ns}, Rules: rules}
+}
+
+func clusterRole(name string, rules ...PolicyRule) ClusterRole {
+	return ClusterRole{Kind: "ClusterRole", Metadata: ObjectMeta{Name: name}, Rules: rules}
+}
+
+func roleBinding(ns, name, roleName string) RoleBinding {
+	return RoleBinding{
+		Kind:     "RoleBinding",
+		Metadata: ObjectMeta{Name: name, Namespace: ns},
+		Subjects: []Subject{{Kind: "ServiceAccount", Name: "app", Namespace: ns}},
+		RoleRef:  RoleRef{APIGroup: "rbac.authorization.k8s.io", Kind: "Role", Name: roleName},
+	}
+}
+
+var (
+	readPods = PolicyRule{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get", "list"}}
+	getPods  = PolicyRule{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get"}}
+	all      = PolicyRule{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"*"}}
+)
+
+func TestConsolidate(t *testing.T) {
+	for _, c := range []struct {
+		name         string
+		roles        []Role
+		clusterRoles []ClusterRole
+		bindings     []RoleBinding
+		opts         ConsolidateOptions
+
+		duplicates  [][]string
+		subsets     []SubsetPair
+		suggestions []string // имя ClusterRole каждой подсказки
+		reuse       bool
+	}{
+		{
+			name:        "copies in two namespaces",
+			roles:       []Role{role("a", "reader", readPods), role("b", "reader", readPods)},
+			duplicates:  [][]string{{"Role:a/reader", "Role:b/reader"}},
+			suggestions: []string{"reader"},
+		},
+		{
+			name:        "verbs differ only in case",
+			roles:       []Role{role("a", "reader", readPods), role("b", "reader", PolicyRule{Resources: []string{"Pods"}, Verbs: []string{"LIST", " get"}})},
+			duplicates:  [][]string{{"Role:a/reader", "Role:b/reader"}},
+			suggestions: []string{"reader"},
+		},
+		{
+			name:         "existing ClusterRole reused",
+			roles:        []Role{role("a", "reader", readPods), role("b", "pod-reader", readPods)},
+			clusterRoles: []ClusterRole{clusterRole("view-pods", readPods)},
+			duplicates:   [][]string{{"ClusterRole:view-pods", "Role:a/reader", "Role:b/pod-reader"}},
+			suggestions:  []string{"view-pods"},
+			reuse:        true,
+		},
+		{
+			name:         "name taken by another ClusterRole",
+			roles:        []Role{role("a", "reader", readPods), role("b", "reader", readPods)},
+			clusterRoles: []ClusterRole{clusterRole("reader", getPods)},
+			duplicates:   [][]string{{"Role:a/reader", "Role:b/reader"}},
+			subsets:      []SubsetPair{{Role: "ClusterRole:reader", Superset: "Role:a/reader"}},
+			suggestions:  []string{"reader-shared"},
+		},
+		{
+			name:       "copies in one namespace",
+			roles:      []Role{role("a", "reader", readPods), role("a", "reader2", readPods)},
+			duplicates: [][]string{{"Role:a/reader", "Role:a/reader2"}},
+		},
+		{
+			name:       "fewer copies than MinCopies",
+			roles:      []Role{role("a", "reader", readPods), role("b", "reader", readPods)},
+			opts:       ConsolidateOptions{MinCopies: 3},
+			duplicates: [][]string{{"Role:a/reader", "Role:b/reader"}},
+		},
+		{
+			name:         "system roles skipped",
+			clusterRoles: []ClusterRole{clusterRole("system:a", readPods), clusterRole("system:b", readPods)},
+		},
+		{
+			name:         "system roles included",
+			clusterRoles: []ClusterRole{clusterRole("system:a", readPods), clusterRole("system:b", readPods)},
+			opts:         ConsolidateOptions{IncludeSystem: true},
+			duplicates:   [][]string{{"ClusterRole:system:a", "ClusterRole:system:b"}},
+		},
+		{
+			name:         "subsets with wildcards",
+			roles:        []Role{role("a", "getter", getPods), role("a", "reader", readPods)},
+			clusterRoles: []ClusterRole{clusterRole("admin", all)},
+			subsets: []SubsetPair{
+				{Role: "Role:a/getter", Superset: "ClusterRole:admin"},
+				{Role: "Role:a/getter", Superset: "Role:a/reader"},
+				{Role: "Role:a/reader", Superset: "ClusterRole:admin"},
+			},
+		},
+		{
+			name:  "roles without resource permissions",
+			roles: []Role{role("a", "empty"), role("b", "empty")},
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			rep := Consolidate(c.roles, c.clusterRoles, c.bindings, c.opts)
+
+			var dups [][]string
+			for _, d := range rep.Duplicates {
+				dups = append(dups, d.Roles)
+			}
+			if !reflect.DeepEqual(dups, c.duplicates) {
+				t.Errorf("duplicates = %v, want %v", dups, c.duplicates)
+			}
+			if len(rep.Subsets) > 0 || len(c.subsets) > 0 {
+				if !reflect.DeepEqual(rep.Subsets, c.subsets) {
+					t.Errorf("subsets = %v, want %v", rep.Subsets, c.subsets)
+				}
+			}
+			var names []string
+			for _, s := range rep.Suggestions {
+				names = append(names, s.ClusterRole.Metadata.Name)
+				if s.ReuseExisting != c.reuse {
+					t.Errorf("%s: reuseExisting = %v, want %v", s.ClusterRole.Metadata.Name, s.ReuseExisting, c.reuse)
+				}
+			}
+			if !reflect.DeepEqual(names, c.suggestions) {
+				t.Errorf("suggestions = %v, want %v", names, c.suggestions)
+			}
+		})
+	}
+}
+
+func TestConsolidateRebindsCopies(t *testing.T) {
+	roles := []Role{role("a", "reader", readPods), role("b", "reader", readPods)}
+	bindings := []RoleBinding{
+		roleBinding("b", "app-reader", "reader"),
+		roleBinding("a", "app-reader", "reader"),
+		roleBinding("a", "other", "other-role"),
+	}
+	rep := Consolidate(roles, nil, bindings, ConsolidateOptions{})
+	if len(rep.Suggestions) != 1 {
+		t.Fatalf("suggestions = %+v", rep.Suggestions)
+	}
+	s := rep.Suggestions[0]
+
+	if want := []PolicyRule{readPods}; !reflect.DeepEqual(s.ClusterRole.Rules, want) {
+		t.Errorf("rules = %+v, want %+v", s.ClusterRole.Rules, want)
+	}
+	if want := []string{"Role:a/reader", "Role:b/reader"}; !reflect.DeepEqual(s.Replaces, want) {
+		t.Errorf("replaces = %v, want %v", s.Replaces, want)
+	}
+	if want := []string{"RoleBinding:a/app-reader", "RoleBinding:b/app-reader"}; !reflect.DeepEqual(s.RemoveBindings, want) {
+		t.Errorf("removeBindings = %v, want %v", s.RemoveBindings, want)
+	}
+	if len(s.RoleBindings) != 2 {
+		t.Fatalf("roleBindings = %+v", s.RoleBindings)
+	}
+	for _, rb := range s.RoleBindings {
+		if rb.RoleRef.Kind != "ClusterRole" || rb.RoleRef.Name != "reader" || len(rb.Subjects) != 1 {
+			t.Errorf("roleBinding %s/%s = %+v", rb.Metadata.Namespace, rb.Metadata.Name, rb)
+		}
+	}
+}
+
+func TestPermCovers(t *testing.T) {
+	p := func(verb, group, resource string, names ...string) Permission {
+		return Permission{Verb: verb, APIGroup: group, Resource: resource, ResourceNames: names}
+	}
+	for _, c := range []struct {
+		name       string
+		have, want Permission
+		covers     bool
+	}{
+		{"same", p("get", "", "pods"), p("get", "", "pods"), true},
+		{"verb wildcard", p("*", "", "pods"), p("delete", "", "pods"), true},
+		{"group wildcard", p("get", "*", "deployments"), p("get", "apps", "deployments"), true},
+		{"resource wildcard", p("get", "", "*"), p("get", "", "pods/log"), true},
+		{"other verb", p("get", "", "pods"), p("list", "", "pods"), false},
+		{"other group", p("get", "", "deployments"), p("get", "apps", "deployments"), false},
+		{"other resource", p("get", "", "pods"), p("get", "", "pods/log"), false},
+		{"wildcard only in want", p("get", "", "pods"), p("*", "", "pods"), false},
+		{"unrestricted covers names", p("get", "", "secrets"), p("get", "", "secrets", "a"), true},
+		{"names do not cover unrestricted", p("get", "", "secrets", "a"), p("get", "", "secrets"), false},
+		{"names superset", p("get", "", "secrets", "a", "b"), p("get", "", "secrets", "b"), true},
+		{"names not a superset", p("get", "", "secrets", "a"), p("get", "", "secrets", "a", "b"), false},
+		{"wildcards with names", p("*", "*", "*", "a"), p("get", "", "secrets", "a"), true},
+	} {
+		if got := permCovers(c.have, c.want); got != c.covers {
+			t.Errorf("%s: permCovers = %v, want %v", c.name, got, c.covers)
+		}
+	}
+}
+
+func TestCompactRules(t *testing.T) {
+	p := func(verb, group, resource string, names ...string) Permission {
+		return Permission{Verb: verb, APIGroup: group, Resource: resource, ResourceNames: names}
+	}
+	for _, c := range []struct {
+		name  string
+		perms []Permission
+		want  []PolicyRule
+	}{
+		{
+			name:  "empty",
+			perms: nil,
+			want:  []PolicyRule{},
+		},
+		{
+			name:  "resources with the same verbs merge",
+			perms: []Permission{p("list", "", "services"), p("get", "", "pods"), p("list", "", "pods"), p("get", "", "services")},
+			want:  []PolicyRule{{APIGroups: []string{""}, Resources: []string{"pods", "services"}, Verbs: []string{"get", "list"}}},
+		},
+		{
+			name:  "different verbs stay apart",
+			perms: []Permission{p("get", "", "pods"), p("list", "", "pods"), p("get", "", "services")},
+			want: []PolicyRule{
+				{APIGroups: []string{""}, Resources: []string{"services"}, Verbs: []string{"get"}},
+				{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get", "list"}},
+			},
+		},
+		{
+			name:  "api groups stay apart and sorted",
+			perms: []Permission{p("get", "apps", "deployments"), p("get", "", "pods")},
+			want: []PolicyRule{
+				{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get"}},
+				{APIGroups: []string{"apps"}, Resources: []string{"deployments"}, Verbs: []string{"get"}},
+			},
+		},
+		{
+			name:  "resourceNames kept",
+			perms: []Permission{p("get", "", "secrets", "b", "a"), p("get", "", "configmaps", "a", "b"), p("get", "", "pods")},
+			want: []PolicyRule{
+				{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get"}},
+				{APIGroups: []string{""}, Resources: []string{"configmaps", "secrets"}, Verbs: []string{"get"}, ResourceNames: []string{"a", "b"}},
+			},
+		},
+		{
+			name:  "duplicates collapse",
+			perms: []Permission{p("get", "", "pods"), p("get", "", "pods")},
+			want:  []PolicyRule{{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get"}}},
+		},
+	} {
+		if got := CompactRules(c.perms); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: CompactRules = %+v, want %+v", c.name, got, c.want)
+		}
+	}
+}
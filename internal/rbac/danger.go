@@ -2,51 +2,165 @@ package rbac
 
 import "strings"
 
+// Уровни серьёзности находок.
+const (
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+)
+
+// Finding — находка движка опасностей: встроенная эвристика или пользовательское правило.
+type Finding struct {
+	RuleID   string `json:"ruleId"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// builtinRule — встроенная эвристика над одним PolicyRule.
+type builtinRule struct {
+	id       string
+	severity string
+	message  string
+	match    func(verbs, resources map[string]struct{}, rule PolicyRule) bool
+}
+
+// RulesVersion — версия набора встроенных правил. Меняется при любом изменении builtinRules,
+// чтобы сохранённые сканы, посчитанные старыми правилами, можно было найти и пересчитать.
+const RulesVersion = "rules/v2"
+
+var builtinRules = []builtinRule{
+	// 1. Полные права "*" на все "*"
+	{"full-admin", SeverityCritical, "Full admin: verbs=* and resources=*",
+		func(_, _ map[string]struct{}, rule PolicyRule) bool {
+			return containsStar(rule.Verbs) && containsStar(rule.Resources)
+		}},
+	// 2. Управление ролями/биндингами => потенциальный privilege escalation
+	{"rbac-modify", SeverityCritical, "Can modify RBAC objects (potential privilege escalation)",
+		func(verbs, resources map[string]struct{}, _ PolicyRule) bool {
+			return hasAny(resources, "roles", "clusterroles", "rolebindings", "clusterrolebindings") &&
+				hasAny(verbs, "create", "update", "patch", "delete", "*")
+		}},
+	// 2a. bind/escalate/impersonate — обход ограничений RBAC без прав на изменение ролей
+	{"rbac-escalate-verbs", SeverityCritical, "Can bind/escalate/impersonate (privilege escalation)",
+		func(verbs, _ map[string]struct{}, _ PolicyRule) bool {
+			return hasAny(verbs, "bind", "escalate", "impersonate")
+		}},
+	// 3. Работа с secrets
+	{"secrets-read", SeverityHigh, "Can read Secrets (sensitive data exposure)",
+		func(verbs, resources map[string]struct{}, _ PolicyRule) bool {
+			return hasAny(resources, "secrets", "*") &&
+				hasAny(verbs, "get", "list", "watch", "*")
+		}},
+	// 4. Exec/attach в поды => удалённое выполнение кода
+	{"pods-exec", SeverityHigh, "Can exec/attach into pods (remote code execution)",
+		func(verbs, resources map[string]struct{}, _ PolicyRule) bool {
+			return hasAny(resources, "pods/exec", "pods/attach", "pods", "*") &&
+				hasAny(verbs, "create", "update", "patch", "delete", "get", "*")
+		}},
+	// 5. Управление Pod/Deployment => возможность разворачивать произвольный код
+	{"workload-control", SeverityHigh, "Can control workload objects (deploy arbitrary code)",
+		func(verbs, resources map[string]struct{}, _ PolicyRule) bool {
+			return hasAny(resources, "pods", "deployments", "statefulsets", "daemonsets", "*") &&
+				hasAny(verbs, "create", "update", "patch", "delete", "*")
+		}},
+	// 6. Доступ к ConfigMap (утечка конфигурации)
+	{"configmaps-read", SeverityMedium, "Can read ConfigMaps (configuration/secret leakage)",
+		func(verbs, resources map[string]struct{}, _ PolicyRule) bool {
+			return hasAny(resources, "configmaps", "*") &&
+				hasAny(verbs, "get", "list", "watch", "*")
+		}},
+}
+
 // EvaluateDangerous определяет, является ли набор правил "опасным".
 func EvaluateDangerous(rules []PolicyRule) (bool, []string) {
-	var reasons []string
+	reasons := findingMessages(EvaluateFindings(rules))
+	return len(reasons) > 0, reasons
+}
+
+func findingMessages(findings []Finding) []string {
+	var out []string
+	for _, f := range findings {
+		out = append(out, f.Message)
+	}
+	return out
+}
+
+// EvaluateFindings прогоняет встроенные эвристики; каждая находка встречается один раз.
+func EvaluateFindings(rules []PolicyRule) []Finding {
+	var out []Finding
+	seen := map[string]bool{}
 
 	for _, rule := range rules {
 		verbs := toLowerSet(rule.Verbs)
 		resources := toLowerSet(rule.Resources)
 
-		// 1. Полные права "*" на все "*"
-		if containsStar(rule.Verbs) && containsStar(rule.Resources) {
-			reasons = append(reasons, "Full admin: verbs=* and resources=*")
+		for _, b := range builtinRules {
+			if seen[b.id] || !b.match(verbs, resources, rule) {
+				continue
+			}
+			seen[b.id] = true
+			out = append(out, Finding{RuleID: b.id, Severity: b.severity, Message: b.message})
 		}
+	}
+	return out
+}
 
-		// 2. Управление ролями/биндингами => потенциальный privilege escalation
-		if hasAny(resources, "roles", "clusterroles", "rolebindings", "clusterrolebindings") &&
-			hasAny(verbs, "create", "update", "patch", "delete", "*") {
-			reasons = append(reasons, "Can modify RBAC objects (potential privilege escalation)")
-		}
+// CustomRule — пользовательское правило (например, CEL-выражение из файла правил).
+// Match вызывается для каждого права роли; достаточно одного совпадения.
+type CustomRule struct {
+	ID       string
+	Severity string
+	Message  string
+	Match    func(s SubjectRef, r EffectiveRole, p Permission) (bool, error)
+}
 
-		// 3. Работа с secrets
-		if hasAny(resources, "secrets", "*") &&
-			hasAny(verbs, "get", "list", "watch", "*") {
-			reasons = append(reasons, "Can read Secrets (sensitive data exposure)")
+// ApplyCustomRules дополняет находки ролей пользовательскими правилами:
+// совпавшая роль становится опасной, сообщение добавляется в DangerReasons.
+func ApplyCustomRules(sp SubjectPermissions, rules []CustomRule) error {
+	if len(rules) == 0 {
+		return nil
+	}
+	for s, roles := range sp {
+		for i := range roles {
+			r := &roles[i]
+			for _, cr := range rules {
+				hit, err := customRuleHits(cr, s, *r)
+				if err != nil {
+					return err
+				}
+				if !hit || r.hasFinding(cr.ID) {
+					continue
+				}
+				r.Dangerous = true
+				r.Findings = append(r.Findings, Finding{RuleID: cr.ID, Severity: cr.Severity, Message: cr.Message})
+				r.DangerReasons = append(r.DangerReasons, cr.Message)
+			}
 		}
+	}
+	return nil
+}
 
-		// 4. Exec/attach в поды => удалённое выполнение кода
-		if hasAny(resources, "pods/exec", "pods/attach", "pods", "*") &&
-			hasAny(verbs, "create", "update", "patch", "delete", "get", "*") {
-			reasons = append(reasons, "Can exec/attach into pods (remote code execution)")
+func customRuleHits(cr CustomRule, s SubjectRef, r EffectiveRole) (bool, error) {
+	for _, p := range r.Permissions {
+		ok, err := cr.Match(s, r, p)
+		if err != nil {
+			return false, err
 		}
-
-		// 5. Управление Pod/Deployment => возможность разворачивать произвольный код
-		if hasAny(resources, "pods", "deployments", "statefulsets", "daemonsets", "*") &&
-			hasAny(verbs, "create", "update", "patch", "delete", "*") {
-			reasons = append(reasons, "Can control workload objects (deploy arbitrary code)")
+		if ok {
+			return true, nil
 		}
+	}
+	return false, nil
+}
 
-		// 6. Доступ к ConfigMap (утечка конфигурации)
-		if hasAny(resources, "configmaps", "*") &&
-			hasAny(verbs, "get", "list", "watch", "*") {
-			reasons = append(reasons, "Can read ConfigMaps (configuration/secret leakage)")
+func (r EffectiveRole) hasFinding(id string) bool {
+	for _, f := range r.Findings {
+		if f.RuleID == id {
+			return true
 		}
 	}
-
-	return len(reasons) > 0, uniqueStrings(reasons)
+	return false
 }
 
 func containsStar(items []string) bool {
@@ -74,16 +188,3 @@ func hasAny(set map[string]struct{}, keys ...string) bool {
 	}
 	return false
 }
-
-func uniqueStrings(in []string) []string {
-	m := make(map[string]struct{}, len(in))
-	var out []string
-	for _, v := range in {
-		if _, ok := m[v]; ok {
-			continue
-		}
-		m[v] = struct{}{}
-		out = append(out, v)
-	}
-	return out
-}
@@ -0,0 +1,34 @@
+package rbac
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEvaluateFindings(t *testing.T) {
+	ids := func(rules ...PolicyRule) []string {
+		var out []string
+		for _, f := range EvaluateFindings(rules) {
+			out = append(out, f.RuleID)
+		}
+		return out
+	}
+	for _, c := range []struct {
+		name  string
+		rules []PolicyRule
+		want  []string
+	}{
+		{"read services", []PolicyRule{readServices}, nil},
+		{"full admin", []PolicyRule{all}, []string{"full-admin", "secrets-read", "pods-exec", "workload-control", "configmaps-read"}},
+		{"rbac verbs", []PolicyRule{{APIGroups: []string{"rbac.authorization.k8s.io"}, Resources: []string{"clusterroles"}, Verbs: []string{"bind"}}}, []string{"rbac-escalate-verbs"}},
+		{"case-insensitive", []PolicyRule{{Resources: []string{"ConfigMaps"}, Verbs: []string{"LIST"}}}, []string{"configmaps-read"}},
+		{"deduplicated", []PolicyRule{
+			{Resources: []string{"configmaps"}, Verbs: []string{"get"}},
+			{Resources: []string{"configmaps"}, Verbs: []string{"list"}},
+		}, []string{"configmaps-read"}},
+	} {
+		if got := ids(c.rules...); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: findings = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package rbac
+
+import (
+	"path"
+	"strings"
+)
+
+// MatchGlob — подходит ли v хотя бы под один шаблон. Синтаксис path.Match, но "*" и "?"
+// совпадают и с "/": `ServiceAccount:tenant-*` означает и `ServiceAccount:tenant-a/deployer`.
+// Один матчер для фильтров вывода и политик, чтобы шаблон везде значил одно и то же.
+func MatchGlob(patterns []string, v string) bool {
+	for _, p := range patterns {
+		p = strings.TrimSpace(p)
+		if p == v {
+			return true
+		}
+		if ok, err := path.Match(globSlash(p), globSlash(v)); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// globSlash заменяет "/" на символ, которого нет в именах Kubernetes: в path.Match
+// "*" не переходит через "/".
+func globSlash(s string) string {
+	return strings.ReplaceAll(s, "/", "\x00")
+}
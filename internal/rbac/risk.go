@@ -0,0 +1,237 @@
+package rbac
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// RiskModelVersion — версия модели оценки риска. Меняется при изменении весов и подсчёта,
+// чтобы orgScore разных версий не сравнивали между собой.
+// v2: роль, выданная несколькими биндингами, учитывается один раз.
+const RiskModelVersion = "risk/v2"
+
+// Веса модели (баллы 0..100 до множителя субъекта).
+const (
+	riskMaxExtraFindings = 10 // за дополнительные находки сверх самой серьёзной
+	riskClusterScope     = 15 // опасная роль действует во всём кластере
+	riskMaxNamespaces    = 10 // 2 балла за namespace с опасными правами
+	riskMaxWildcards     = 10 // 2 балла за право с "*"
+	riskWorkloadUsed     = 10 // SA используется подами: компрометация пода = эти права
+)
+
+var severityWeight = map[string]float64{
+	SeverityCritical: 40,
+	SeverityHigh:     25,
+	SeverityMedium:   10,
+	SeverityLow:      5,
+}
+
+// RiskFactor — вклад одного фактора в оценку (для объяснения).
+type RiskFactor struct {
+	Name   string  `json:"name"`   // severity | scope | namespaces | wildcards | workloads | subjectType
+	Points float64 `json:"points"` // аддитивный вклад; для subjectType — множитель
+	Detail string  `json:"detail"`
+}
+
+// SubjectRisk — оценка риска субъекта (0..100) с разложением по факторам.
+type SubjectRisk struct {
+	Subject string       `json:"subject"`
+	Score   float64      `json:"score"`
+	Factors []RiskFactor `json:"factors"`
+}
+
+// RiskReport — ранжированный список субъектов и общий балл.
+type RiskReport struct {
+	ModelVersion string        `json:"modelVersion"`
+	OrgScore     float64       `json:"orgScore"`
+	Subjects     []SubjectRisk `json:"subjects"`
+}
+
+// Top — первые n субъектов из ранжированного списка.
+func (r RiskReport) Top(n int) []SubjectRisk {
+	if n >= len(r.Subjects) {
+		return r.Subjects
+	}
+	return r.Subjects[:n]
+}
+
+// ScoreRisk оценивает каждого субъекта:
+//
+//	score = min(100, (severity + scope + namespaces + wildcards + workloads) × subjectType)
+//
+// orgScore = 0.5 × max + 0.5 × среднее top-10 (недостающие места — нули). Он не зависит
+// от размера кластера, поэтому сравним между сканами одной версии модели.
+func ScoreRisk(sp SubjectPermissions, workloads []Workload) RiskReport {
+	used := map[SubjectRef]int{}
+	for _, w := range workloads {
+		used[SubjectRef{Kind: SubjectKindServiceAccount, Name: w.ServiceAccount, Namespace: w.Namespace}]++
+	}
+
+	out := RiskReport{ModelVersion: RiskModelVersion, Subjects: make([]SubjectRisk, 0, len(sp))}
+	for s, roles := range sp {
+		out.Subjects = append(out.Subjects, scoreSubject(s, roles, used[s]))
+	}
+
+	sort.Slice(out.Subjects, func(i, j int) bool {
+		a, b := out.Subjects[i], out.Subjects[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return a.Subject < b.Subject
+	})
+
+	if len(out.Subjects) > 0 {
+		var sum float64
+		for _, sr := range out.Top(10) {
+			sum += sr.Score
+		}
+		out.OrgScore = round1(0.5*out.Subjects[0].Score + 0.5*sum/10)
+	}
+	return out
+}
+
+func scoreSubject(s SubjectRef, roles []EffectiveRole, workloads int) SubjectRisk {
+	var factors []RiskFactor
+	var total float64
+
+	// severity: самая серьёзная находка + по 2 балла за каждую следующую.
+	// Находка считается один раз на роль: ClusterRole, выданная двумя биндингами,
+	// не удваивает число находок.
+	var findings []Finding
+	seen := map[string]bool{}
+	for _, r := range roles {
+		for _, f := range roleFindings(r) {
+			k := r.RoleLabel() + "\x00" + f.RuleID + "\x00" + f.Message
+			if !seen[k] {
+				seen[k] = true
+				findings = append(findings, f)
+			}
+		}
+	}
+	if len(findings) > 0 {
+		top := findings[0]
+		for _, f := range findings[1:] {
+			if severityWeight[f.Severity] > severityWeight[top.Severity] {
+				top = f
+			}
+		}
+		pts := severityWeight[top.Severity] + math.Min(float64(2*(len(findings)-1)), riskMaxExtraFindings)
+		total += pts
+		factors = append(factors, RiskFactor{
+			Name:   "severity",
+			Points: pts,
+			Detail: fmt.Sprintf("%d finding(s), worst %s: %s", len(findings), top.Severity, top.Message),
+		})
+	}
+
+	// scope: опасная роль на весь кластер
+	for _, r := range roles {
+		if r.Dangerous && r.ClusterScope {
+			total += riskClusterScope
+			factors = append(factors, RiskFactor{
+				Name:   "scope",
+				Points: riskClusterScope,
+				Detail: "dangerous cluster-wide role " + r.RoleLabel(),
+			})
+			break
+		}
+	}
+
+	// breadth: namespaces с опасными правами и права с "*"
+	nsSet := map[string]bool{}
+	wildcards := 0
+	countedRoles := map[string]bool{}
+	for _, r := range roles {
+		if r.Dangerous && !r.ClusterScope {
+			if ns := r.BindingNS; ns != "" {
+				nsSet[ns] = true
+			} else if r.SourceNamespace != "" {
+				nsSet[r.SourceNamespace] = true
+			}
+		}
+		if countedRoles[r.RoleLabel()] {
+			continue
+		}
+		countedRoles[r.RoleLabel()] = true
+		for _, p := range r.Permissions {
+			if p.Verb == "*" || p.Resource == "*" || p.APIGroup == "*" {
+				wildcards++
+			}
+		}
+	}
+	if n := len(nsSet); n > 0 {
+		pts := math.Min(float64(2*n), riskMaxNamespaces)
+		total += pts
+		factors = append(factors, RiskFactor{
+			Name:   "namespaces",
+			Points: pts,
+			Detail: fmt.Sprintf("dangerous permissions in %d namespace(s)", n),
+		})
+	}
+	if wildcards > 0 {
+		pts := math.Min(float64(2*wildcards), riskMaxWildcards)
+		total += pts
+		factors = append(factors, RiskFactor{
+			Name:   "wildcards",
+			Points: pts,
+			Detail: fmt.Sprintf("%d permission(s) with *", wildcards),
+		})
+	}
+
+	// workloads: токен SA доступен в подах
+	if workloads > 0 {
+		total += riskWorkloadUsed
+		factors = append(factors, RiskFactor{
+			Name:   "workloads",
+			Points: riskWorkloadUsed,
+			Detail: fmt.Sprintf("used by %d workload(s)", workloads),
+		})
+	}
+
+	// subjectType: насколько широк круг людей/подов за субъектом
+	mult, why := subjectTypeMultiplier(s)
+	factors = append(factors, RiskFactor{Name: "subjectType", Points: mult, Detail: why})
+
+	return SubjectRisk{
+		Subject: s.String(),
+		Score:   round1(math.Min(total*mult, 100)),
+		Factors: factors,
+	}
+}
+
+// roleFindings — находки роли; для отчётов без findings (старые сканы) опасная роль считается high.
+func roleFindings(r EffectiveRole) []Finding {
+	if len(r.Findings) > 0 {
+		return r.Findings
+	}
+	if !r.Dangerous {
+		return nil
+	}
+	out := make([]Finding, 0, len(r.DangerReasons))
+	for _, reason := range r.DangerReasons {
+		out = append(out, Finding{Severity: SeverityHigh, Message: reason})
+	}
+	if len(out) == 0 {
+		out = append(out, Finding{Severity: SeverityHigh, Message: "dangerous role"})
+	}
+	return out
+}
+
+func subjectTypeMultiplier(s SubjectRef) (float64, string) {
+	switch {
+	case s.Kind == SubjectKindGroup && (s.Name == "system:authenticated" || s.Name == "system:unauthenticated"):
+		return 2.0, "group " + s.Name + " covers every caller"
+	case s.Kind == SubjectKindGroup && strings.HasPrefix(s.Name, "system:serviceaccounts"):
+		return 1.75, "group " + s.Name + " covers all service accounts in scope"
+	case s.Kind == SubjectKindGroup:
+		return 1.5, "group: permissions shared by all members"
+	default:
+		return 1.0, "single " + string(s.Kind)
+	}
+}
+
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
@@ -0,0 +1,48 @@
+package rbac
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestScoreRiskRoleBoundTwice(t *testing.T) {
+	admin := clusterRole("admin", all)
+	bind := func(name, role string) ClusterRoleBinding {
+		return ClusterRoleBinding{
+			Metadata: ObjectMeta{Name: name},
+			Subjects: []Subject{{Kind: "User", Name: "alice"}},
+			RoleRef:  RoleRef{Kind: "ClusterRole", Name: role},
+		}
+	}
+	severity := func(r RiskReport) RiskFactor {
+		for _, f := range r.Subjects[0].Factors {
+			if f.Name == "severity" {
+				return f
+			}
+		}
+		t.Fatalf("no severity factor: %+v", r.Subjects[0])
+		return RiskFactor{}
+	}
+
+	once := ScoreRisk(BuildSubjectPermissions(nil, []ClusterRole{admin}, nil,
+		[]ClusterRoleBinding{bind("a", "admin")}), nil)
+	twice := ScoreRisk(BuildSubjectPermissions(nil, []ClusterRole{admin}, nil,
+		[]ClusterRoleBinding{bind("a", "admin"), bind("b", "admin")}), nil)
+
+	if twice.Subjects[0].Score != once.Subjects[0].Score {
+		t.Fatalf("score with two bindings %v, with one %v", twice.Subjects[0].Score, once.Subjects[0].Score)
+	}
+	n := len(EvaluateFindings(admin.Rules))
+	if d := severity(twice).Detail; !strings.HasPrefix(d, fmt.Sprintf("%d finding(s)", n)) {
+		t.Fatalf("severity detail = %q, want %d finding(s)", d, n)
+	}
+
+	// та же находка в другой роли — отдельная
+	secrets := clusterRole("secrets", PolicyRule{Resources: []string{"secrets"}, Verbs: []string{"get"}})
+	two := ScoreRisk(BuildSubjectPermissions(nil, []ClusterRole{admin, secrets}, nil,
+		[]ClusterRoleBinding{bind("a", "admin"), bind("b", "secrets")}), nil)
+	if d := severity(two).Detail; !strings.HasPrefix(d, fmt.Sprintf("%d finding(s)", n+1)) {
+		t.Fatalf("two roles: severity detail = %q, want %d finding(s)", d, n+1)
+	}
+}
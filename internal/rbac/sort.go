@@ -0,0 +1,49 @@
+package rbac
+
+import "sort"
+
+// SortSubjectPermissions упорядочивает роли каждого субъекта и права внутри ролей,
+// чтобы сериализация была побайтово стабильной между запусками.
+func SortSubjectPermissions(sp SubjectPermissions) {
+	for _, roles := range sp {
+		for i := range roles {
+			SortPermissions(roles[i].Permissions)
+		}
+		sort.SliceStable(roles, func(i, j int) bool {
+			return effectiveRoleKey(roles[i]) < effectiveRoleKey(roles[j])
+		})
+	}
+}
+
+// SortedCopy — упорядоченная копия sp: роли и права копируются, исходная карта не меняется.
+func SortedCopy(sp SubjectPermissions) SubjectPermissions {
+	out := make(SubjectPermissions, len(sp))
+	for s, roles := range sp {
+		cp := make([]EffectiveRole, len(roles))
+		for i, r := range roles {
+			if r.Permissions != nil {
+				r.Permissions = append(make([]Permission, 0, len(r.Permissions)), r.Permissions...)
+			}
+			cp[i] = r
+		}
+		out[s] = cp
+	}
+	SortSubjectPermissions(out)
+	return out
+}
+
+// SortPermissions сортирует права по каноническому ключу.
+func SortPermissions(perms []Permission) {
+	sort.SliceStable(perms, func(i, j int) bool {
+		return permissionSortKey(perms[i]) < permissionSortKey(perms[j])
+	})
+}
+
+func permissionSortKey(p Permission) string {
+	return CanonicalPermissionKey(p.Namespace, p.Verb, p.APIGroup, p.Resource, p.ResourceNames)
+}
+
+func effectiveRoleKey(r EffectiveRole) string {
+	return r.SourceKind + "\x00" + r.SourceNamespace + "\x00" + r.SourceName + "\x00" +
+		r.BoundVia + "\x00" + r.BindingNS + "\x00" + r.BindingName
+}
@@ -0,0 +1,70 @@
+package rbac
+
+import "sort"
+
+// SummaryCounts — агрегаты по отчёту.
+type SummaryCounts struct {
+	Subjects    int `json:"subjects"`
+	Roles       int `json:"roles"`
+	Perms       int `json:"perms"`
+	DangerRoles int `json:"dangerRoles"`
+}
+
+// DangerSubject — субъект, у которого есть хотя бы одна опасная роль.
+type DangerSubject struct {
+	Subject     string `json:"subject"`
+	DangerRoles int    `json:"dangerRoles"`
+	Perms       int    `json:"perms"`
+}
+
+// Summary — краткая сводка по SubjectPermissions (общая для CLI и сервера).
+type Summary struct {
+	Counts    SummaryCounts   `json:"counts"`
+	RiskScore float64         `json:"riskScore"`
+	TopDanger []DangerSubject `json:"topDanger"`
+}
+
+// Summarize считает агрегаты и riskScore (доля опасных ролей, 0..10).
+func Summarize(sp SubjectPermissions) Summary {
+	out := Summary{TopDanger: make([]DangerSubject, 0)}
+
+	for subj, roles := range sp {
+		out.Counts.Subjects++
+		dCount := 0
+		pCount := 0
+		for _, r := range roles {
+			out.Counts.Roles++
+			pCount += len(r.Permissions)
+			if r.Dangerous {
+				out.Counts.DangerRoles++
+				dCount++
+			}
+		}
+		out.Counts.Perms += pCount
+		if dCount > 0 {
+			out.TopDanger = append(out.TopDanger, DangerSubject{
+				Subject:     subj.String(),
+				DangerRoles: dCount,
+				Perms:       pCount,
+			})
+		}
+	}
+
+	// самые опасные сверху, при равенстве — по имени (стабильный порядок)
+	sort.Slice(out.TopDanger, func(i, j int) bool {
+		a, b := out.TopDanger[i], out.TopDanger[j]
+		if a.DangerRoles != b.DangerRoles {
+			return a.DangerRoles > b.DangerRoles
+		}
+		return a.Subject < b.Subject
+	})
+
+	if out.Counts.Roles > 0 {
+		out.RiskScore = float64(out.Counts.DangerRoles) / float64(out.Counts.Roles) * 10.0
+		if out.RiskScore > 10 {
+			out.RiskScore = 10
+		}
+	}
+
+	return out
+}
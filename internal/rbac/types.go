@@ -61,6 +61,15 @@ type ClusterRoleBinding struct {
 	RoleRef    RoleRef    `yaml:"roleRef" json:"roleRef"`
 }
 
+// Workload — под или контроллер подов и ServiceAccount, под которым он работает.
+type Workload struct {
+	Kind           string `json:"kind"`
+	Name           string `json:"name"`
+	Namespace      string `json:"namespace"`
+	ServiceAccount string `json:"serviceAccount"`
+	AutomountToken bool   `json:"automountToken"` // automountServiceAccountToken в PodSpec не выключен
+}
+
 // ===== Наши аналитические типы =====
 
 type SubjectKind string
@@ -102,12 +111,29 @@ type EffectiveRole struct {
 	ClusterScope    bool         `json:"clusterScope"`
 	Permissions     []Permission `json:"permissions"`
 
-	Dangerous       bool     `json:"dangerous"`
-	DangerReasons   []string `json:"dangerReasons,omitempty"`
-	BoundVia        string   `json:"boundVia"`                  // RoleBinding / ClusterRoleBinding
-	BindingName     string   `json:"bindingName"`               // имя биндинга
-	BindingNS       string   `json:"bindingNS"`                 // namespace биндинга
-	BindingSubjects []string `json:"bindingSubjects,omitempty"` // список всех subj в биндинге (для контекста)
+	Dangerous       bool      `json:"dangerous"`
+	DangerReasons   []string  `json:"dangerReasons,omitempty"`
+	Findings        []Finding `json:"findings,omitempty"`        // структурированные DangerReasons: id, severity
+	BoundVia        string    `json:"boundVia"`                  // RoleBinding / ClusterRoleBinding
+	BindingName     string    `json:"bindingName"`               // имя биндинга
+	BindingNS       string    `json:"bindingNS"`                 // namespace биндинга
+	BindingSubjects []string  `json:"bindingSubjects,omitempty"` // список всех subj в биндинге (для контекста)
+}
+
+// RoleLabel — "ClusterRole:name" или "Role:ns/name".
+func (r EffectiveRole) RoleLabel() string {
+	if r.SourceNamespace != "" {
+		return r.SourceKind + ":" + r.SourceNamespace + "/" + r.SourceName
+	}
+	return r.SourceKind + ":" + r.SourceName
+}
+
+// BindingLabel — "ClusterRoleBinding:name" или "RoleBinding:ns/name".
+func (r EffectiveRole) BindingLabel() string {
+	if r.BindingNS != "" {
+		return r.BoundVia + ":" + r.BindingNS + "/" + r.BindingName
+	}
+	return r.BoundVia + ":" + r.BindingName
 }
 
 // SubjectPermissions — итоговая структура:
@@ -120,7 +146,7 @@ func NormalizeVerb(v string) string {
 	return strings.TrimSpace(strings.ToLower(v))
 }
 
-func subjectRefFromSubject(s Subject, defaultNamespace string) SubjectRef {
+func SubjectRefFromSubject(s Subject, defaultNamespace string) SubjectRef {
 	ns := s.Namespace
 	if ns == "" && s.Kind == "ServiceAccount" {
 		ns = defaultNamespace
@@ -138,3 +164,23 @@ func subjectRefFromSubject(s Subject, defaultNamespace string) SubjectRef {
 		return SubjectRef{Kind: SubjectKindUser, Name: fmt.Sprintf("%s:%s", s.Kind, s.Name)}
 	}
 }
+
+// ParseSubjectRef — обратная операция к SubjectRef.String().
+func ParseSubjectRef(s string) SubjectRef {
+	kind, rest, ok := strings.Cut(s, ":")
+	if !ok {
+		return SubjectRef{Kind: SubjectKindUser, Name: s}
+	}
+
+	switch SubjectKind(kind) {
+	case SubjectKindServiceAccount:
+		if ns, name, ok := strings.Cut(rest, "/"); ok {
+			return SubjectRef{Kind: SubjectKindServiceAccount, Name: name, Namespace: ns}
+		}
+		return SubjectRef{Kind: SubjectKindServiceAccount, Name: rest}
+	case SubjectKindGroup:
+		return SubjectRef{Kind: SubjectKindGroup, Name: rest}
+	default:
+		return SubjectRef{Kind: SubjectKindUser, Name: rest}
+	}
+}
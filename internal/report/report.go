@@ -0,0 +1,145 @@
+package report
+
+import (
+	_ "embed"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"rbac-analyzer/internal/audit"
+	"rbac-analyzer/internal/compliance"
+	"rbac-analyzer/internal/rbac"
+	"rbac-analyzer/internal/version"
+)
+
+// SchemaVersion — версия формата отчёта. Меняется только при несовместимых изменениях;
+// новые необязательные поля добавляются без смены версии.
+const SchemaVersion = "rbac-analyzer.report/v1"
+
+// Schema — JSON Schema отчёта (report.schema.json).
+//
+//go:embed report.schema.json
+var Schema []byte
+
+// Tool — чем сгенерирован отчёт.
+type Tool struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// Subject — субъект и его роли. Поля subject/roles совпадают со старым форматом full_report.
+type Subject struct {
+	Subject   string               `json:"subject"`
+	Kind      rbac.SubjectKind     `json:"kind"`
+	Name      string               `json:"name"`
+	Namespace string               `json:"namespace,omitempty"`
+	Roles     []rbac.EffectiveRole `json:"roles"`
+}
+
+// Report — версионированный конверт отчёта, общий для CLI и сервера.
+type Report struct {
+	SchemaVersion string       `json:"schemaVersion"`
+	Tool          Tool         `json:"tool"`
+	GeneratedAt   time.Time    `json:"generatedAt"`
+	InputSHA256   string       `json:"inputSha256,omitempty"`
+	Title         string       `json:"title,omitempty"`
+	Summary       rbac.Summary `json:"summary"`
+	Subjects      []Subject    `json:"subjects"`
+
+	Compliance *compliance.Report `json:"compliance,omitempty"`
+	Risk       *rbac.RiskReport   `json:"risk,omitempty"`
+	Usage      *audit.UsageReport `json:"usage,omitempty"` // только если передан audit-лог
+}
+
+// Meta — метаданные, которые не выводятся из самих прав.
+type Meta struct {
+	Title       string
+	InputSHA256 string
+	GeneratedAt time.Time // zero = Now()
+
+	// Workloads — какие SA реально используются подами (CIS 5.1.5/5.1.6, оценка риска).
+	Workloads []rbac.Workload
+
+	// All — полный набор прав, по которому считаются compliance и риск, когда в отчёт
+	// попадает только отфильтрованная часть (nil = те же права, что и в отчёте).
+	// Иначе контроли вроде CIS 5.1.6 видят субъекты без прав там, где их отсёк фильтр.
+	All rbac.SubjectPermissions
+}
+
+// Build собирает отчёт: субъекты, роли и права отсортированы стабильно.
+// sp не меняется: сортируется копия.
+func Build(sp rbac.SubjectPermissions, meta Meta) Report {
+	sp = rbac.SortedCopy(sp)
+
+	generatedAt := meta.GeneratedAt
+	if generatedAt.IsZero() {
+		generatedAt = Now()
+	}
+
+	out := Report{
+		SchemaVersion: SchemaVersion,
+		Tool:          Tool{Name: "rbac-analyzer", Version: version.Version},
+		GeneratedAt:   generatedAt.UTC(),
+		InputSHA256:   meta.InputSHA256,
+		Title:         meta.Title,
+		Summary:       rbac.Summarize(sp),
+		Subjects:      make([]Subject, 0, len(sp)),
+	}
+	all := meta.All
+	if all == nil {
+		all = sp
+	}
+	cr := compliance.Evaluate(all, meta.Workloads)
+	out.Compliance = &cr
+	risk := rbac.ScoreRisk(all, meta.Workloads)
+	out.Risk = &risk
+
+	refs := make([]rbac.SubjectRef, 0, len(sp))
+	for s := range sp {
+		refs = append(refs, s)
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].String() < refs[j].String() })
+
+	for _, s := range refs {
+		out.Subjects = append(out.Subjects, Subject{
+			Subject:   s.String(),
+			Kind:      s.Kind,
+			Name:      s.Name,
+			Namespace: s.Namespace,
+			Roles:     sp[s],
+		})
+	}
+	return out
+}
+
+// SubjectPermissions — обратное преобразование (для повторного рендера сохранённых отчётов).
+func (r Report) SubjectPermissions() rbac.SubjectPermissions {
+	sp := make(rbac.SubjectPermissions, len(r.Subjects))
+	for _, s := range r.Subjects {
+		sref := rbac.ParseSubjectRef(s.Subject)
+		sp[sref] = append(sp[sref], s.Roles...)
+	}
+	return sp
+}
+
+// EngineVersion — версия движка анализа: сборка, встроенные правила, CIS/NSA и модель риска.
+// rulesDigest — хеш пользовательских правил (пусто, если их нет). Результаты с другой
+// версией считаются устаревшими и пересчитываются по сохранённому снимку.
+func EngineVersion(rulesDigest string) string {
+	v := version.Version + "+" + rbac.RulesVersion + "+" + compliance.ControlsVersion + "+" + rbac.RiskModelVersion
+	if rulesDigest != "" {
+		v += "+custom:" + rulesDigest
+	}
+	return v
+}
+
+// Now — текущее время с учётом SOURCE_DATE_EPOCH (воспроизводимые сборки / snapshot-тесты).
+func Now() time.Time {
+	if v := os.Getenv("SOURCE_DATE_EPOCH"); v != "" {
+		if sec, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return time.Unix(sec, 0).UTC()
+		}
+	}
+	return time.Now().UTC()
+}
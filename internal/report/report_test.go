@@ -0,0 +1,80 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/rand"
+	"testing"
+	"time"
+
+	"rbac-analyzer/internal/rbac"
+)
+
+func testPerms() rbac.SubjectPermissions {
+	alice := rbac.SubjectRef{Kind: rbac.SubjectKindUser, Name: "alice"}
+	ci := rbac.SubjectRef{Kind: rbac.SubjectKindServiceAccount, Name: "deployer", Namespace: "ci"}
+	return rbac.SubjectPermissions{
+		alice: {
+			{SourceKind: "Role", SourceName: "reader", SourceNamespace: "dev", BoundVia: "RoleBinding", BindingName: "reader", BindingNS: "dev",
+				Permissions: []rbac.Permission{
+					{Namespace: "dev", Verb: "list", Resource: "pods"},
+					{Namespace: "dev", Verb: "get", Resource: "pods"},
+					{Namespace: "dev", Verb: "get", Resource: "configmaps"},
+				}},
+			{SourceKind: "ClusterRole", SourceName: "view", ClusterScope: true, BoundVia: "ClusterRoleBinding", BindingName: "alice-view",
+				Permissions: []rbac.Permission{{Verb: "get", Resource: "nodes", ClusterScope: true}}},
+		},
+		ci: {
+			{SourceKind: "Role", SourceName: "deploy", SourceNamespace: "prod", BoundVia: "RoleBinding", BindingName: "ci", BindingNS: "prod",
+				Permissions: []rbac.Permission{
+					{Namespace: "prod", Verb: "patch", APIGroup: "apps", Resource: "deployments"},
+					{Namespace: "prod", Verb: "create", Resource: "pods"},
+				}},
+		},
+	}
+}
+
+// shuffled — те же права в случайном порядке ролей и прав.
+func shuffled(sp rbac.SubjectPermissions, rnd *rand.Rand) rbac.SubjectPermissions {
+	out := rbac.SubjectPermissions{}
+	for s, roles := range sp {
+		cp := append([]rbac.EffectiveRole(nil), roles...)
+		for i := range cp {
+			cp[i].Permissions = append([]rbac.Permission(nil), cp[i].Permissions...)
+			rnd.Shuffle(len(cp[i].Permissions), func(a, b int) {
+				cp[i].Permissions[a], cp[i].Permissions[b] = cp[i].Permissions[b], cp[i].Permissions[a]
+			})
+		}
+		rnd.Shuffle(len(cp), func(a, b int) { cp[a], cp[b] = cp[b], cp[a] })
+		out[s] = cp
+	}
+	return out
+}
+
+func TestBuildDeterministic(t *testing.T) {
+	meta := Meta{Title: "t", GeneratedAt: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)}
+	render := func(sp rbac.SubjectPermissions) []byte {
+		b, err := json.Marshal(Build(sp, meta))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	want := render(testPerms())
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 20; i++ {
+		if got := render(shuffled(testPerms(), rnd)); !bytes.Equal(got, want) {
+			t.Fatalf("run %d: report differs for shuffled input\n%s\nwant\n%s", i, got, want)
+		}
+	}
+}
+
+func TestBuildKeepsInput(t *testing.T) {
+	sp := testPerms()
+	alice := rbac.SubjectRef{Kind: rbac.SubjectKindUser, Name: "alice"}
+	Build(sp, Meta{})
+	if roles := sp[alice]; roles[0].SourceName != "reader" || roles[0].Permissions[0].Verb != "list" {
+		t.Fatalf("input reordered: %+v", roles)
+	}
+}
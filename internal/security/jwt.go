@@ -15,8 +15,19 @@ type Claims struct {
 	Email string `json:"email"`
 	Admin bool   `json:"admin"`
 	Exp   int64  `json:"exp"`
+
+	// Sid — сессия (refresh token), к которой относится access token; см. Keyring.
+	Sid string `json:"sid,omitempty"`
+	Iat int64  `json:"iat,omitempty"`
+
+	// Purpose — токен промежуточного шага (PurposeMFA), а не доступа к API.
+	// AuthMiddleware принимает только токены без Purpose.
+	Purpose string `json:"pur,omitempty"`
 }
 
+// PurposeMFA — пароль (или SSO) проверен, осталось ввести код второго фактора.
+const PurposeMFA = "mfa"
+
 func SignJWT(secret []byte, c Claims) (string, error) {
 	header := map[string]string{"alg": "HS256", "typ": "JWT"}
 	hb, _ := json.Marshal(header)
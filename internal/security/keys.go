@@ -0,0 +1,117 @@
+package security
+
+import (
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Keyring — ключи HS256 с идентификаторами (kid). Новые токены подписываются текущим
+// ключом, проверяются любым из набора: при ротации новый ключ ставится первым, а старый
+// остаётся в наборе, пока не истекут подписанные им токены.
+type Keyring struct {
+	signKID string
+	keys    map[string][]byte
+}
+
+// DefaultKID — kid ключа из JWT_SECRET.
+const DefaultKID = "default"
+
+// NewKeyring собирает набор из спецификации "kid:secret,kid2:secret2" (первый подписывает).
+// secret (JWT_SECRET) добавляется с kid DefaultKID и подписывает, если спецификация пуста.
+// Пустой secret в набор не попадает: известный всем ключ позволил бы подделать любой токен.
+func NewKeyring(spec, secret string) (*Keyring, error) {
+	k := &Keyring{keys: map[string][]byte{}}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		kid, key, ok := strings.Cut(item, ":")
+		if !ok || kid == "" || key == "" {
+			return nil, fmt.Errorf("jwt keys: want kid:secret, got %q", item)
+		}
+		if _, dup := k.keys[kid]; dup {
+			return nil, fmt.Errorf("jwt keys: duplicate kid %q", kid)
+		}
+		k.keys[kid] = []byte(key)
+		if k.signKID == "" {
+			k.signKID = kid
+		}
+	}
+	if secret != "" {
+		if _, ok := k.keys[DefaultKID]; !ok {
+			k.keys[DefaultKID] = []byte(secret)
+		}
+		if k.signKID == "" {
+			k.signKID = DefaultKID
+		}
+	}
+	if k.signKID == "" {
+		return nil, errors.New("jwt keys: no signing key, set JWT_SECRET or JWT_KEYS")
+	}
+	return k, nil
+}
+
+// MAC подписывает произвольные данные (не JWT) текущим ключом. label разделяет назначения:
+// подпись одного вида нельзя выдать за другую. Результат — "kid~подпись".
+func (k *Keyring) MAC(label, payload string) string {
+	return k.signKID + "~" + signHS256(k.keys[k.signKID], label+"\x00"+payload)
+}
+
+// CheckMAC проверяет подпись MAC ключом из её kid (подходят и ключи на ротации).
+func (k *Keyring) CheckMAC(label, payload, mac string) bool {
+	i := strings.LastIndex(mac, "~")
+	if i < 0 {
+		return false
+	}
+	key, ok := k.keys[mac[:i]]
+	if !ok {
+		return false
+	}
+	return hmac.Equal([]byte(mac[i+1:]), []byte(signHS256(key, label+"\x00"+payload)))
+}
+
+// SigningKID — kid, которым подписываются новые токены.
+func (k *Keyring) SigningKID() string { return k.signKID }
+
+func (k *Keyring) Sign(c Claims) (string, error) {
+	if c.Iat == 0 {
+		c.Iat = time.Now().Unix()
+	}
+	hb, _ := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT", "kid": k.signKID})
+	cb, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	msg := base64.RawURLEncoding.EncodeToString(hb) + "." + base64.RawURLEncoding.EncodeToString(cb)
+	return msg + "." + signHS256(k.keys[k.signKID], msg), nil
+}
+
+// Verify проверяет подпись ключом из заголовка kid и срок действия.
+func (k *Keyring) Verify(token string) (Claims, error) {
+	hdr, _, ok := strings.Cut(token, ".")
+	if !ok {
+		return Claims{}, errors.New("invalid token format")
+	}
+	b, err := base64.RawURLEncoding.DecodeString(hdr)
+	if err != nil {
+		return Claims{}, errors.New("bad header encoding")
+	}
+	var h struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := json.Unmarshal(b, &h); err != nil || h.Alg != "HS256" {
+		return Claims{}, errors.New("bad header")
+	}
+	key, ok := k.keys[h.Kid]
+	if !ok {
+		return Claims{}, errors.New("unknown key id")
+	}
+	return VerifyJWT(key, token)
+}
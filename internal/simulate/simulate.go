@@ -0,0 +1,72 @@
+// Package simulate — режим "что если": применить к снимку RBAC новые/изменённые
+// манифесты и удаления, пересчитать эффективные права и сравнить с текущими.
+package simulate
+
+import (
+	"rbac-analyzer/internal/loader"
+	"rbac-analyzer/internal/rbac"
+)
+
+// Change — набор изменений.
+type Change struct {
+	Apply  *loader.Data // новые или изменённые объекты (может быть nil)
+	Delete []loader.ObjectRef
+}
+
+// Result — итог симуляции.
+type Result struct {
+	Applied  []string        `json:"applied"`
+	Deleted  []string        `json:"deleted"`
+	NotFound []string        `json:"notFound"` // удаления, которых нет в снимке
+	Diff     rbac.DiffResult `json:"diff"`
+
+	Base   rbac.SubjectPermissions `json:"-"`
+	Target rbac.SubjectPermissions `json:"-"`
+}
+
+// DangerIncreased — есть субъекты, которые станут опасными.
+func (r Result) DangerIncreased() bool {
+	return r.Diff.Summary.DangerIncreased > 0
+}
+
+// Run применяет изменения к копии base. Сам base не меняется.
+// Пользовательские правила (rules) применяются к обоим состояниям одинаково.
+func Run(base *loader.Data, ch Change, rules []rbac.CustomRule) (Result, error) {
+	target := base.Clone()
+
+	res := Result{Applied: []string{}, Deleted: []string{}, NotFound: []string{}}
+	if ch.Apply != nil {
+		for _, ref := range target.Apply(ch.Apply) {
+			res.Applied = append(res.Applied, ref.String())
+		}
+	}
+
+	missing := map[loader.ObjectRef]bool{}
+	for _, ref := range target.Delete(ch.Delete) {
+		missing[ref] = true
+		res.NotFound = append(res.NotFound, ref.String())
+	}
+	for _, ref := range ch.Delete {
+		if !missing[ref] {
+			res.Deleted = append(res.Deleted, ref.String())
+		}
+	}
+
+	var err error
+	if res.Base, err = analyze(base, rules); err != nil {
+		return res, err
+	}
+	if res.Target, err = analyze(target, rules); err != nil {
+		return res, err
+	}
+	res.Diff = rbac.DiffSubjectPermissions(res.Base, res.Target)
+	return res, nil
+}
+
+func analyze(d *loader.Data, rules []rbac.CustomRule) (rbac.SubjectPermissions, error) {
+	sp := rbac.BuildSubjectPermissions(d.Roles, d.ClusterRoles, d.RoleBindings, d.ClusterRoleBindings)
+	if err := rbac.ApplyCustomRules(sp, rules); err != nil {
+		return nil, err
+	}
+	return sp, nil
+}
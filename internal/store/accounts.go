@@ -0,0 +1,80 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// Назначение одноразового токена из письма.
+const (
+	TokenVerifyEmail   = "verify_email"
+	TokenResetPassword = "reset_password"
+)
+
+// UserToken — одноразовый токен из письма (подтверждение email, сброс пароля).
+type UserToken struct {
+	ID        string
+	UserID    string
+	Purpose   string
+	TokenHash string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+// CreateUserToken сохраняет новый токен; прежние неиспользованные токены
+// того же назначения перестают действовать (действует только последнее письмо).
+func (s *Store) CreateUserToken(ctx context.Context, t UserToken) error {
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx,
+		`UPDATE user_tokens SET used_at=now()
+		 WHERE user_id=$1 AND purpose=$2 AND used_at IS NULL`,
+		t.UserID, t.Purpose,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO user_tokens(user_id, purpose, token_hash, expires_at) VALUES($1,$2,$3,$4)`,
+		t.UserID, t.Purpose, t.TokenHash, t.ExpiresAt,
+	); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ConsumeUserToken помечает токен использованным и возвращает его.
+// ErrNotFound — токена нет, он уже использован или истёк.
+func (s *Store) ConsumeUserToken(ctx context.Context, purpose, tokenHash string) (UserToken, error) {
+	var t UserToken
+	err := s.DB.QueryRow(ctx,
+		`UPDATE user_tokens SET used_at=now()
+		 WHERE token_hash=$1 AND purpose=$2 AND used_at IS NULL AND expires_at > now()
+		 RETURNING id, user_id, purpose, token_hash, created_at, expires_at, used_at`,
+		tokenHash, purpose,
+	).Scan(&t.ID, &t.UserID, &t.Purpose, &t.TokenHash, &t.CreatedAt, &t.ExpiresAt, &t.UsedAt)
+	if IsNotFound(err) {
+		return UserToken{}, ErrNotFound
+	}
+	return t, err
+}
+
+func (s *Store) SetPassword(ctx context.Context, userID, passwordHash string) error {
+	tag, err := s.DB.Exec(ctx, `UPDATE users SET password_hash=$2 WHERE id=$1`, userID, passwordHash)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *Store) MarkEmailVerified(ctx context.Context, userID string) error {
+	_, err := s.DB.Exec(ctx,
+		`UPDATE users SET email_verified_at=now() WHERE id=$1 AND email_verified_at IS NULL`,
+		userID,
+	)
+	return err
+}
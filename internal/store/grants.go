@@ -0,0 +1,178 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// Роли на кластере (cluster_grants.role), по возрастанию прав.
+const (
+	ClusterViewer   = "viewer"   // кластер, сканы, отчёты, diff, экспорт
+	ClusterUploader = "uploader" // + загрузка сканов
+	ClusterAdmin    = "admin"    // + гранты кластера и флаг restricted
+)
+
+var clusterRoleRank = map[string]int{ClusterViewer: 1, ClusterUploader: 2, ClusterAdmin: 3}
+
+// ClusterRoleAtLeast — role на кластере даёт не меньше прав, чем min.
+func ClusterRoleAtLeast(role, min string) bool {
+	return clusterRoleRank[role] >= clusterRoleRank[min] && clusterRoleRank[role] > 0
+}
+
+// ValidClusterRole — viewer, uploader или admin.
+func ValidClusterRole(role string) bool {
+	return clusterRoleRank[role] > 0
+}
+
+// Кому выдан грант.
+const (
+	SubjectUser = "user"
+	SubjectTeam = "team"
+)
+
+// Team — команда внутри организации (получатель грантов).
+type Team struct {
+	ID        string    `json:"id"`
+	OrgID     string    `json:"orgId"`
+	Name      string    `json:"name"`
+	MemberIDs []string  `json:"memberIds"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ClusterGrant — роль пользователя или команды на кластере.
+type ClusterGrant struct {
+	ClusterID   string    `json:"clusterId"`
+	SubjectType string    `json:"subjectType"`
+	SubjectID   string    `json:"subjectId"`
+	Role        string    `json:"role"`
+	CreatedBy   string    `json:"createdBy,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+func (s *Store) ListTeams(ctx context.Context, orgID string) ([]Team, error) {
+	rows, err := s.DB.Query(ctx,
+		`SELECT t.id, t.org_id, t.name, t.created_at,
+		        COALESCE(array_agg(tm.user_id::text ORDER BY tm.created_at) FILTER (WHERE tm.user_id IS NOT NULL), '{}')
+		 FROM teams t
+		 LEFT JOIN team_members tm ON tm.team_id=t.id
+		 WHERE t.org_id=$1
+		 GROUP BY t.id
+		 ORDER BY t.name ASC`,
+		orgID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Team
+	for rows.Next() {
+		var t Team
+		if err := rows.Scan(&t.ID, &t.OrgID, &t.Name, &t.CreatedAt, &t.MemberIDs); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) CreateTeam(ctx context.Context, orgID, name string) (Team, error) {
+	t := Team{OrgID: orgID, Name: name, MemberIDs: []string{}}
+	err := s.DB.QueryRow(ctx,
+		`INSERT INTO teams(org_id, name) VALUES($1,$2) RETURNING id, created_at`,
+		orgID, name,
+	).Scan(&t.ID, &t.CreatedAt)
+	return t, err
+}
+
+// DeleteTeam удаляет команду вместе с её грантами.
+func (s *Store) DeleteTeam(ctx context.Context, orgID, teamID string) error {
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `DELETE FROM teams WHERE org_id=$1 AND id=$2`, orgID, teamID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM cluster_grants WHERE subject_type='team' AND subject_id=$1`,
+		teamID,
+	); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *Store) AddTeamMember(ctx context.Context, teamID, userID string) error {
+	_, err := s.DB.Exec(ctx,
+		`INSERT INTO team_members(team_id, user_id) VALUES($1,$2)
+		 ON CONFLICT (team_id, user_id) DO NOTHING`,
+		teamID, userID,
+	)
+	return err
+}
+
+func (s *Store) RemoveTeamMember(ctx context.Context, teamID, userID string) error {
+	tag, err := s.DB.Exec(ctx,
+		`DELETE FROM team_members WHERE team_id=$1 AND user_id=$2`,
+		teamID, userID,
+	)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+// ListClusterGrants — все гранты на кластеры организации.
+func (s *Store) ListClusterGrants(ctx context.Context, orgID string) ([]ClusterGrant, error) {
+	rows, err := s.DB.Query(ctx,
+		`SELECT g.cluster_id, g.subject_type, g.subject_id, g.role, COALESCE(g.created_by::text, ''), g.created_at
+		 FROM cluster_grants g
+		 JOIN clusters c ON c.id=g.cluster_id
+		 WHERE c.org_id=$1
+		 ORDER BY g.created_at ASC`,
+		orgID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []ClusterGrant
+	for rows.Next() {
+		var g ClusterGrant
+		if err := rows.Scan(&g.ClusterID, &g.SubjectType, &g.SubjectID, &g.Role, &g.CreatedBy, &g.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, g)
+	}
+	return out, rows.Err()
+}
+
+// SetClusterGrant выдаёт грант или меняет роль существующего.
+func (s *Store) SetClusterGrant(ctx context.Context, g ClusterGrant) error {
+	_, err := s.DB.Exec(ctx,
+		`INSERT INTO cluster_grants(cluster_id, subject_type, subject_id, role, created_by)
+		 VALUES($1,$2,$3,$4,NULLIF($5,'')::uuid)
+		 ON CONFLICT (cluster_id, subject_type, subject_id) DO UPDATE SET role=EXCLUDED.role`,
+		g.ClusterID, g.SubjectType, g.SubjectID, g.Role, g.CreatedBy,
+	)
+	return err
+}
+
+func (s *Store) DeleteClusterGrant(ctx context.Context, clusterID, subjectType, subjectID string) error {
+	tag, err := s.DB.Exec(ctx,
+		`DELETE FROM cluster_grants WHERE cluster_id=$1 AND subject_type=$2 AND subject_id=$3`,
+		clusterID, subjectType, subjectID,
+	)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return err
+}
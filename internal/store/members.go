@@ -0,0 +1,317 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// Роли в организации (org_members.role), по возрастанию прав.
+const (
+	RoleMember = "member"
+	RoleAdmin  = "admin"
+	RoleOwner  = "owner"
+)
+
+var roleRank = map[string]int{RoleMember: 1, RoleAdmin: 2, RoleOwner: 3}
+
+// RoleAtLeast — role даёт не меньше прав, чем min.
+func RoleAtLeast(role, min string) bool {
+	return roleRank[role] >= roleRank[min] && roleRank[role] > 0
+}
+
+// Membership — организация пользователя и его роль в ней.
+type Membership struct {
+	Org  Org    `json:"org"`
+	Role string `json:"role"`
+}
+
+// Member — участник организации.
+type Member struct {
+	UserID   string    `json:"userId"`
+	Email    string    `json:"email"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joinedAt"`
+}
+
+// Статусы приглашения.
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationDeclined = "declined"
+	InvitationRevoked  = "revoked"
+)
+
+// Invitation — приглашение в организацию; сам токен не хранится, только его sha256.
+type Invitation struct {
+	ID          string     `json:"id"`
+	OrgID       string     `json:"orgId"`
+	Email       string     `json:"email"`
+	Role        string     `json:"role"`
+	TokenHash   string     `json:"-"`
+	InvitedBy   string     `json:"invitedBy"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	ExpiresAt   time.Time  `json:"expiresAt"`
+	RespondedAt *time.Time `json:"respondedAt,omitempty"`
+}
+
+// ListUserOrgs — организации пользователя в порядке вступления (первая — по умолчанию).
+func (s *Store) ListUserOrgs(ctx context.Context, userID string) ([]Membership, error) {
+	rows, err := s.DB.Query(ctx,
+		`SELECT o.id, o.name, o.require_mfa, m.role
+		 FROM orgs o
+		 JOIN org_members m ON m.org_id=o.id
+		 WHERE m.user_id=$1
+		 ORDER BY m.created_at ASC, o.created_at ASC`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Membership
+	for rows.Next() {
+		var m Membership
+		if err := rows.Scan(&m.Org.ID, &m.Org.Name, &m.Org.RequireMFA, &m.Role); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) GetMembership(ctx context.Context, orgID, userID string) (Membership, error) {
+	var m Membership
+	err := s.DB.QueryRow(ctx,
+		`SELECT o.id, o.name, o.require_mfa, m.role
+		 FROM orgs o
+		 JOIN org_members m ON m.org_id=o.id
+		 WHERE o.id=$1 AND m.user_id=$2`,
+		orgID, userID,
+	).Scan(&m.Org.ID, &m.Org.Name, &m.Org.RequireMFA, &m.Role)
+	return m, err
+}
+
+func (s *Store) ListMembers(ctx context.Context, orgID string) ([]Member, error) {
+	rows, err := s.DB.Query(ctx,
+		`SELECT u.id, u.email, m.role, m.created_at
+		 FROM org_members m
+		 JOIN users u ON u.id=m.user_id
+		 WHERE m.org_id=$1
+		 ORDER BY m.created_at ASC`,
+		orgID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Member
+	for rows.Next() {
+		var m Member
+		if err := rows.Scan(&m.UserID, &m.Email, &m.Role, &m.JoinedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) SetMemberRole(ctx context.Context, orgID, userID, role string) error {
+	tag, err := s.DB.Exec(ctx,
+		`UPDATE org_members SET role=$3 WHERE org_id=$1 AND user_id=$2`,
+		orgID, userID, role,
+	)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+// AddMember добавляет пользователя в организацию; роль уже состоящего участника не меняется.
+func (s *Store) AddMember(ctx context.Context, orgID, userID, role string) error {
+	_, err := s.DB.Exec(ctx,
+		`INSERT INTO org_members(org_id, user_id, role) VALUES($1,$2,$3)
+		 ON CONFLICT (org_id, user_id) DO NOTHING`,
+		orgID, userID, role,
+	)
+	return err
+}
+
+// RemoveMember удаляет участника вместе с его командами и грантами в этой организации.
+func (s *Store) RemoveMember(ctx context.Context, orgID, userID string) error {
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx,
+		`DELETE FROM org_members WHERE org_id=$1 AND user_id=$2`,
+		orgID, userID,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM team_members
+		 WHERE user_id=$2 AND team_id IN (SELECT id FROM teams WHERE org_id=$1)`,
+		orgID, userID,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM cluster_grants
+		 WHERE subject_type='user' AND subject_id=$2
+		   AND cluster_id IN (SELECT id FROM clusters WHERE org_id=$1)`,
+		orgID, userID,
+	); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// TransferOwnership: новый владелец — участник toUserID, прежний становится admin.
+func (s *Store) TransferOwnership(ctx context.Context, orgID, fromUserID, toUserID string) error {
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx,
+		`UPDATE org_members SET role='owner' WHERE org_id=$1 AND user_id=$2`,
+		orgID, toUserID,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	if _, err := tx.Exec(ctx,
+		`UPDATE org_members SET role='admin' WHERE org_id=$1 AND user_id=$2`,
+		orgID, fromUserID,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx,
+		`UPDATE orgs SET owner_user_id=$2 WHERE id=$1`,
+		orgID, toUserID,
+	); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *Store) CreateInvitation(ctx context.Context, inv Invitation) (Invitation, error) {
+	err := s.DB.QueryRow(ctx,
+		`INSERT INTO org_invitations(org_id, email, role, token_hash, invited_by, expires_at)
+		 VALUES($1,$2,$3,$4,$5,$6)
+		 RETURNING id, status, created_at`,
+		inv.OrgID, inv.Email, inv.Role, inv.TokenHash, inv.InvitedBy, inv.ExpiresAt,
+	).Scan(&inv.ID, &inv.Status, &inv.CreatedAt)
+	return inv, err
+}
+
+const invitationColumns = `id, org_id, email, role, token_hash, invited_by, status, created_at, expires_at, responded_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanInvitation(row rowScanner) (Invitation, error) {
+	var inv Invitation
+	err := row.Scan(&inv.ID, &inv.OrgID, &inv.Email, &inv.Role, &inv.TokenHash, &inv.InvitedBy,
+		&inv.Status, &inv.CreatedAt, &inv.ExpiresAt, &inv.RespondedAt)
+	return inv, err
+}
+
+// ListInvitations — действующие (pending и не истёкшие) приглашения организации.
+func (s *Store) ListInvitations(ctx context.Context, orgID string) ([]Invitation, error) {
+	rows, err := s.DB.Query(ctx,
+		`SELECT `+invitationColumns+`
+		 FROM org_invitations
+		 WHERE org_id=$1 AND status='pending' AND expires_at > now()
+		 ORDER BY created_at DESC`,
+		orgID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Invitation
+	for rows.Next() {
+		inv, err := scanInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, inv)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) GetInvitationByTokenHash(ctx context.Context, tokenHash string) (Invitation, error) {
+	return scanInvitation(s.DB.QueryRow(ctx,
+		`SELECT `+invitationColumns+` FROM org_invitations WHERE token_hash=$1`,
+		tokenHash,
+	))
+}
+
+// AcceptInvitation добавляет userID в организацию с ролью из приглашения.
+// ErrNotFound — приглашение уже не pending или истекло.
+func (s *Store) AcceptInvitation(ctx context.Context, invitationID, userID string) (Membership, error) {
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return Membership{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	var m Membership
+	err = tx.QueryRow(ctx,
+		`UPDATE org_invitations SET status='accepted', responded_at=now()
+		 WHERE id=$1 AND status='pending' AND expires_at > now()
+		 RETURNING org_id, role`,
+		invitationID,
+	).Scan(&m.Org.ID, &m.Role)
+	if IsNotFound(err) {
+		return Membership{}, ErrNotFound
+	}
+	if err != nil {
+		return Membership{}, err
+	}
+
+	// уже участник — роль не понижаем
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO org_members(org_id, user_id, role) VALUES($1,$2,$3)
+		 ON CONFLICT (org_id, user_id) DO NOTHING`,
+		m.Org.ID, userID, m.Role,
+	); err != nil {
+		return Membership{}, err
+	}
+	if err := tx.QueryRow(ctx,
+		`SELECT o.name, o.require_mfa, m.role FROM orgs o JOIN org_members m ON m.org_id=o.id WHERE o.id=$1 AND m.user_id=$2`,
+		m.Org.ID, userID,
+	).Scan(&m.Org.Name, &m.Org.RequireMFA, &m.Role); err != nil {
+		return Membership{}, err
+	}
+	return m, tx.Commit(ctx)
+}
+
+// CloseInvitation переводит pending-приглашение организации в status (declined/revoked).
+func (s *Store) CloseInvitation(ctx context.Context, orgID, invitationID, status string) error {
+	tag, err := s.DB.Exec(ctx,
+		`UPDATE org_invitations SET status=$3, responded_at=now()
+		 WHERE org_id=$1 AND id=$2 AND status='pending'`,
+		orgID, invitationID, status,
+	)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,69 @@
+package memstore
+
+import (
+	"context"
+
+	"rbac-analyzer/internal/store"
+)
+
+// ---- пароль и токены из писем ----
+
+func (s *Store) CreateUserToken(ctx context.Context, t store.UserToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := now()
+	for i := range s.st.UserTokens {
+		old := &s.st.UserTokens[i]
+		if old.UserID == t.UserID && old.Purpose == t.Purpose && old.UsedAt == nil {
+			old.UsedAt = &n
+		}
+	}
+	t.ID = newID()
+	t.CreatedAt = n
+	t.UsedAt = nil
+	s.st.UserTokens = append(s.st.UserTokens, t)
+	return s.save()
+}
+
+func (s *Store) ConsumeUserToken(ctx context.Context, purpose, tokenHash string) (store.UserToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := now()
+	for i := range s.st.UserTokens {
+		t := &s.st.UserTokens[i]
+		if t.TokenHash != tokenHash || t.Purpose != purpose || t.UsedAt != nil || !n.Before(t.ExpiresAt) {
+			continue
+		}
+		t.UsedAt = &n
+		return *t, s.save()
+	}
+	return store.UserToken{}, store.ErrNotFound
+}
+
+func (s *Store) SetPassword(ctx context.Context, userID, passwordHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.st.Users {
+		if s.st.Users[i].ID == userID {
+			s.st.Users[i].PasswordHash = passwordHash
+			return s.save()
+		}
+	}
+	return store.ErrNotFound
+}
+
+func (s *Store) MarkEmailVerified(ctx context.Context, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.st.Users {
+		if s.st.Users[i].ID == userID {
+			s.st.Users[i].EmailVerified = true
+			return s.save()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"rbac-analyzer/internal/store"
+)
+
+// ---- teams & cluster grants ----
+
+func (s *Store) clusterInOrg(clusterID, orgID string) bool {
+	for _, c := range s.st.Clusters {
+		if c.ID == clusterID {
+			return c.OrgID == orgID
+		}
+	}
+	return false
+}
+
+func without(ids []string, id string) []string {
+	out := ids[:0]
+	for _, v := range ids {
+		if v != id {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// filterGrants удаляет гранты, для которых drop возвращает true.
+func filterGrants(list []store.ClusterGrant, drop func(store.ClusterGrant) bool) []store.ClusterGrant {
+	out := list[:0]
+	for _, g := range list {
+		if !drop(g) {
+			out = append(out, g)
+		}
+	}
+	return out
+}
+
+func (s *Store) ListTeams(ctx context.Context, orgID string) ([]store.Team, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []store.Team
+	for _, t := range s.st.Teams {
+		if t.OrgID == orgID {
+			t.MemberIDs = append([]string{}, t.MemberIDs...)
+			out = append(out, t)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+func (s *Store) CreateTeam(ctx context.Context, orgID, name string) (store.Team, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, t := range s.st.Teams {
+		if t.OrgID == orgID && t.Name == name {
+			return store.Team{}, fmt.Errorf("team %s already exists", name)
+		}
+	}
+	t := store.Team{ID: newID(), OrgID: orgID, Name: name, MemberIDs: []string{}, CreatedAt: now()}
+	s.st.Teams = append(s.st.Teams, t)
+	return t, s.save()
+}
+
+func (s *Store) DeleteTeam(ctx context.Context, orgID, teamID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, t := range s.st.Teams {
+		if t.ID == teamID && t.OrgID == orgID {
+			s.st.Teams = append(s.st.Teams[:i], s.st.Teams[i+1:]...)
+			s.st.Grants = filterGrants(s.st.Grants, func(g store.ClusterGrant) bool {
+				return g.SubjectType == store.SubjectTeam && g.SubjectID == teamID
+			})
+			return s.save()
+		}
+	}
+	return store.ErrNotFound
+}
+
+func (s *Store) AddTeamMember(ctx context.Context, teamID, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.st.Teams {
+		t := &s.st.Teams[i]
+		if t.ID != teamID {
+			continue
+		}
+		for _, id := range t.MemberIDs {
+			if id == userID {
+				return nil
+			}
+		}
+		t.MemberIDs = append(t.MemberIDs, userID)
+		return s.save()
+	}
+	return store.ErrNotFound
+}
+
+func (s *Store) RemoveTeamMember(ctx context.Context, teamID, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.st.Teams {
+		t := &s.st.Teams[i]
+		if t.ID != teamID {
+			continue
+		}
+		n := len(t.MemberIDs)
+		t.MemberIDs = without(t.MemberIDs, userID)
+		if len(t.MemberIDs) == n {
+			return store.ErrNotFound
+		}
+		return s.save()
+	}
+	return store.ErrNotFound
+}
+
+func (s *Store) ListClusterGrants(ctx context.Context, orgID string) ([]store.ClusterGrant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []store.ClusterGrant
+	for _, g := range s.st.Grants {
+		if s.clusterInOrg(g.ClusterID, orgID) {
+			out = append(out, g)
+		}
+	}
+	return out, nil
+}
+
+func (s *Store) SetClusterGrant(ctx context.Context, g store.ClusterGrant) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.st.Grants {
+		cur := &s.st.Grants[i]
+		if cur.ClusterID == g.ClusterID && cur.SubjectType == g.SubjectType && cur.SubjectID == g.SubjectID {
+			cur.Role = g.Role
+			return s.save()
+		}
+	}
+	g.CreatedAt = now()
+	s.st.Grants = append(s.st.Grants, g)
+	return s.save()
+}
+
+func (s *Store) DeleteClusterGrant(ctx context.Context, clusterID, subjectType, subjectID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := len(s.st.Grants)
+	s.st.Grants = filterGrants(s.st.Grants, func(g store.ClusterGrant) bool {
+		return g.ClusterID == clusterID && g.SubjectType == subjectType && g.SubjectID == subjectID
+	})
+	if len(s.st.Grants) == n {
+		return store.ErrNotFound
+	}
+	return s.save()
+}
@@ -0,0 +1,229 @@
+package memstore
+
+import (
+	"context"
+
+	"rbac-analyzer/internal/store"
+)
+
+// ---- members ----
+
+func (s *Store) orgByID(orgID string) (org, bool) {
+	for _, o := range s.st.Orgs {
+		if o.ID == orgID {
+			return o, true
+		}
+	}
+	return org{}, false
+}
+
+func (s *Store) memberIndex(orgID, userID string) int {
+	for i, m := range s.st.Members {
+		if m.OrgID == orgID && m.UserID == userID {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *Store) ListUserOrgs(ctx context.Context, userID string) ([]store.Membership, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Members упорядочены по времени вступления
+	var out []store.Membership
+	for _, m := range s.st.Members {
+		if m.UserID != userID {
+			continue
+		}
+		if o, ok := s.orgByID(m.OrgID); ok {
+			out = append(out, store.Membership{Org: o.Org, Role: m.Role})
+		}
+	}
+	return out, nil
+}
+
+func (s *Store) GetMembership(ctx context.Context, orgID, userID string) (store.Membership, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o, ok := s.orgByID(orgID)
+	i := s.memberIndex(orgID, userID)
+	if !ok || i < 0 {
+		return store.Membership{}, store.ErrNotFound
+	}
+	return store.Membership{Org: o.Org, Role: s.st.Members[i].Role}, nil
+}
+
+func (s *Store) ListMembers(ctx context.Context, orgID string) ([]store.Member, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []store.Member
+	for _, m := range s.st.Members {
+		if m.OrgID != orgID {
+			continue
+		}
+		row := store.Member{UserID: m.UserID, Role: m.Role, JoinedAt: m.CreatedAt}
+		for _, u := range s.st.Users {
+			if u.ID == m.UserID {
+				row.Email = u.Email
+			}
+		}
+		out = append(out, row)
+	}
+	return out, nil
+}
+
+func (s *Store) AddMember(ctx context.Context, orgID, userID, role string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.memberIndex(orgID, userID) >= 0 {
+		return nil
+	}
+	s.st.Members = append(s.st.Members, member{OrgID: orgID, UserID: userID, Role: role, CreatedAt: now()})
+	return s.save()
+}
+
+func (s *Store) SetMemberRole(ctx context.Context, orgID, userID, role string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.memberIndex(orgID, userID)
+	if i < 0 {
+		return store.ErrNotFound
+	}
+	s.st.Members[i].Role = role
+	return s.save()
+}
+
+func (s *Store) RemoveMember(ctx context.Context, orgID, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.memberIndex(orgID, userID)
+	if i < 0 {
+		return store.ErrNotFound
+	}
+	s.st.Members = append(s.st.Members[:i], s.st.Members[i+1:]...)
+
+	// команды и гранты участника в этой организации
+	for j := range s.st.Teams {
+		if s.st.Teams[j].OrgID == orgID {
+			s.st.Teams[j].MemberIDs = without(s.st.Teams[j].MemberIDs, userID)
+		}
+	}
+	s.st.Grants = filterGrants(s.st.Grants, func(g store.ClusterGrant) bool {
+		return g.SubjectType == store.SubjectUser && g.SubjectID == userID && s.clusterInOrg(g.ClusterID, orgID)
+	})
+	return s.save()
+}
+
+func (s *Store) TransferOwnership(ctx context.Context, orgID, fromUserID, toUserID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	to := s.memberIndex(orgID, toUserID)
+	if to < 0 {
+		return store.ErrNotFound
+	}
+	s.st.Members[to].Role = store.RoleOwner
+	if from := s.memberIndex(orgID, fromUserID); from >= 0 {
+		s.st.Members[from].Role = store.RoleAdmin
+	}
+	for i := range s.st.Orgs {
+		if s.st.Orgs[i].ID == orgID {
+			s.st.Orgs[i].OwnerUserID = toUserID
+		}
+	}
+	return s.save()
+}
+
+// ---- invitations ----
+
+func (s *Store) CreateInvitation(ctx context.Context, inv store.Invitation) (store.Invitation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	inv.ID = newID()
+	inv.Status = store.InvitationPending
+	inv.CreatedAt = now()
+	s.st.Invitations = append(s.st.Invitations, inv)
+	return inv, s.save()
+}
+
+func (s *Store) ListInvitations(ctx context.Context, orgID string) ([]store.Invitation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := now()
+	var out []store.Invitation
+	for i := len(s.st.Invitations) - 1; i >= 0; i-- {
+		inv := s.st.Invitations[i]
+		if inv.OrgID == orgID && inv.Status == store.InvitationPending && inv.ExpiresAt.After(t) {
+			out = append(out, inv)
+		}
+	}
+	return out, nil
+}
+
+func (s *Store) GetInvitationByTokenHash(ctx context.Context, tokenHash string) (store.Invitation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, inv := range s.st.Invitations {
+		if inv.TokenHash == tokenHash {
+			return inv, nil
+		}
+	}
+	return store.Invitation{}, store.ErrNotFound
+}
+
+func (s *Store) AcceptInvitation(ctx context.Context, invitationID, userID string) (store.Membership, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := now()
+	for i := range s.st.Invitations {
+		inv := &s.st.Invitations[i]
+		if inv.ID != invitationID {
+			continue
+		}
+		if inv.Status != store.InvitationPending || !inv.ExpiresAt.After(t) {
+			break
+		}
+		o, ok := s.orgByID(inv.OrgID)
+		if !ok {
+			break
+		}
+		inv.Status = store.InvitationAccepted
+		inv.RespondedAt = &t
+
+		// уже участник — роль не понижаем
+		m := store.Membership{Org: o.Org, Role: inv.Role}
+		if j := s.memberIndex(o.ID, userID); j >= 0 {
+			m.Role = s.st.Members[j].Role
+		} else {
+			s.st.Members = append(s.st.Members, member{OrgID: o.ID, UserID: userID, Role: inv.Role, CreatedAt: t})
+		}
+		return m, s.save()
+	}
+	return store.Membership{}, store.ErrNotFound
+}
+
+func (s *Store) CloseInvitation(ctx context.Context, orgID, invitationID, status string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.st.Invitations {
+		inv := &s.st.Invitations[i]
+		if inv.ID == invitationID && inv.OrgID == orgID && inv.Status == store.InvitationPending {
+			t := now()
+			inv.Status = status
+			inv.RespondedAt = &t
+			return s.save()
+		}
+	}
+	return store.ErrNotFound
+}
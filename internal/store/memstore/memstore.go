@@ -0,0 +1,781 @@
+// Package memstore — хранилище сервера в памяти (store.Repository) без PostgreSQL.
+// Нужно для тестов обработчиков и небольших установок на одном узле: с путём к файлу
+// состояние после каждого изменения сохраняется в JSON и читается при старте.
+package memstore
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"rbac-analyzer/internal/store"
+)
+
+type plan struct {
+	ID             string
+	Name           string
+	MaxClusters    int
+	MaxScansPerDay int
+}
+
+// defaultPlans — планы как в миграциях 002 и 012.
+func defaultPlans() []plan {
+	return []plan{
+		{ID: "free", Name: "Free", MaxClusters: 1, MaxScansPerDay: 20},
+		{ID: "pro", Name: "Pro", MaxClusters: 50, MaxScansPerDay: 1000},
+		{ID: "enterprise", Name: "Enterprise", MaxClusters: 1000000, MaxScansPerDay: 1000000},
+	}
+}
+
+type org struct {
+	store.Org
+	OwnerUserID string
+	CreatedAt   time.Time
+}
+
+type member struct {
+	OrgID     string
+	UserID    string
+	Role      string
+	CreatedAt time.Time
+}
+
+type identity struct {
+	Issuer  string
+	Subject string
+	UserID  string
+}
+
+type scan struct {
+	store.Scan
+}
+
+type result struct {
+	Summary       json.RawMessage
+	Full          json.RawMessage
+	EngineVersion string
+	AnalyzedAt    time.Time
+}
+
+// state — всё содержимое хранилища; сохраняется в файл целиком.
+// Слайсы хранят порядок вставки (он же порядок created_at).
+type state struct {
+	Users         []store.User
+	Sessions      []store.Session
+	UserTokens    []store.UserToken
+	MFA           []store.UserMFA
+	Orgs          []org
+	Members       []member
+	Invitations   []store.Invitation
+	Teams         []store.Team // с MemberIDs
+	Grants        []store.ClusterGrant
+	SSO           []store.OrgSSO
+	Identities    []identity
+	Plans         []plan
+	Subscriptions map[string]store.Subscription // org_id ->
+	Clusters      []store.Cluster
+	Scans         []scan
+	Results       map[string]result // scan_id ->
+	Snapshots     map[string][]byte // sha256 -> gzip
+	Audit         []store.AdminAuditEvent
+}
+
+// fileState — state в файле. Поля доменных структур с тегом json:"-" (хэши refresh token
+// и приглашений, client secret SSO) скрыты от API, но в файл попадать обязаны, поэтому
+// эти списки пишутся своими записями.
+type fileState struct {
+	state
+	Sessions    []sessionRecord
+	Invitations []invitationRecord
+	SSO         []ssoRecord
+}
+
+type sessionRecord struct {
+	store.Session
+	RefreshHash     string
+	PrevRefreshHash string
+}
+
+type invitationRecord struct {
+	store.Invitation
+	TokenHash string
+}
+
+type ssoRecord struct {
+	store.OrgSSO
+	ClientSecret string
+}
+
+func toFile(st state) fileState {
+	f := fileState{state: st}
+	for _, ss := range st.Sessions {
+		f.Sessions = append(f.Sessions, sessionRecord{Session: ss, RefreshHash: ss.RefreshHash, PrevRefreshHash: ss.PrevRefreshHash})
+	}
+	for _, inv := range st.Invitations {
+		f.Invitations = append(f.Invitations, invitationRecord{Invitation: inv, TokenHash: inv.TokenHash})
+	}
+	for _, c := range st.SSO {
+		f.SSO = append(f.SSO, ssoRecord{OrgSSO: c, ClientSecret: c.ClientSecret})
+	}
+	return f
+}
+
+func (f fileState) toState() state {
+	st := f.state
+	st.Sessions, st.Invitations, st.SSO = nil, nil, nil
+	for _, r := range f.Sessions {
+		ss := r.Session
+		ss.RefreshHash, ss.PrevRefreshHash = r.RefreshHash, r.PrevRefreshHash
+		st.Sessions = append(st.Sessions, ss)
+	}
+	for _, r := range f.Invitations {
+		inv := r.Invitation
+		inv.TokenHash = r.TokenHash
+		st.Invitations = append(st.Invitations, inv)
+	}
+	for _, r := range f.SSO {
+		c := r.OrgSSO
+		c.ClientSecret = r.ClientSecret
+		st.SSO = append(st.SSO, c)
+	}
+	return st
+}
+
+// Store — реализация store.Repository в памяти.
+type Store struct {
+	mu   sync.Mutex
+	path string // "" — без сохранения
+	st   state
+}
+
+var _ store.Repository = (*Store)(nil)
+
+// New — пустое хранилище в памяти (планы — defaultPlans).
+func New() *Store {
+	return &Store{st: state{
+		Plans:         defaultPlans(),
+		Subscriptions: map[string]store.Subscription{},
+		Results:       map[string]result{},
+		Snapshots:     map[string][]byte{},
+	}}
+}
+
+// Open — хранилище, сохраняемое в JSON-файл path. Если файла нет, он появится при первом изменении.
+func Open(path string) (*Store, error) {
+	s := New()
+	s.path = path
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	f := fileState{state: s.st}
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	s.st = f.toState()
+	if s.st.Subscriptions == nil {
+		s.st.Subscriptions = map[string]store.Subscription{}
+	}
+	if s.st.Results == nil {
+		s.st.Results = map[string]result{}
+	}
+	if s.st.Snapshots == nil {
+		s.st.Snapshots = map[string][]byte{}
+	}
+	// файл записан до появления квоты сканов — берём её из планов по умолчанию
+	for i, p := range s.st.Plans {
+		if p.MaxScansPerDay != 0 {
+			continue
+		}
+		for _, d := range defaultPlans() {
+			if d.ID == p.ID {
+				s.st.Plans[i].MaxScansPerDay = d.MaxScansPerDay
+			}
+		}
+	}
+	return s, nil
+}
+
+// save пишет состояние во временный файл и переименовывает его (вызывается под mu).
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+	b, err := json.Marshal(toFile(s.st))
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".rbac-state-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+func newID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40 // UUID v4
+	b[8] = (b[8] & 0x3f) | 0x80
+	h := hex.EncodeToString(b[:])
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
+
+func now() time.Time {
+	return time.Now().UTC()
+}
+
+// ---- users ----
+
+func (s *Store) CreateUser(ctx context.Context, email, passwordHash string) (store.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.st.Users {
+		if u.Email == email {
+			return store.User{}, fmt.Errorf("user %s already exists", email)
+		}
+	}
+	u := store.User{ID: newID(), Email: email, PasswordHash: passwordHash, CreatedAt: now()}
+	s.st.Users = append(s.st.Users, u)
+	return u, s.save()
+}
+
+func (s *Store) GetUserByEmail(ctx context.Context, email string) (store.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.st.Users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return store.User{}, store.ErrNotFound
+}
+
+func (s *Store) GetUser(ctx context.Context, userID string) (store.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.st.Users {
+		if u.ID == userID {
+			return u, nil
+		}
+	}
+	return store.User{}, store.ErrNotFound
+}
+
+func (s *Store) ToggleAdmin(ctx context.Context, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.st.Users {
+		if s.st.Users[i].ID == userID {
+			s.st.Users[i].IsAdmin = !s.st.Users[i].IsAdmin
+			return s.save()
+		}
+	}
+	return nil
+}
+
+func (s *Store) AdminListUsers(ctx context.Context, limit int) ([]store.AdminUserRow, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 200
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]store.AdminUserRow, 0, len(s.st.Users))
+	for i := len(s.st.Users) - 1; i >= 0 && len(out) < limit; i-- {
+		u := s.st.Users[i]
+		out = append(out, store.AdminUserRow{ID: u.ID, Email: u.Email, IsAdmin: u.IsAdmin, CreatedAt: u.CreatedAt})
+	}
+	return out, nil
+}
+
+func (s *Store) AdminSetUserAdmin(ctx context.Context, userID string, isAdmin bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.st.Users {
+		if s.st.Users[i].ID == userID {
+			s.st.Users[i].IsAdmin = isAdmin
+			return s.save()
+		}
+	}
+	return nil
+}
+
+// ---- orgs ----
+
+func (s *Store) CreateOrgForOwner(ctx context.Context, ownerUserID, orgName string) (store.Org, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o := org{Org: store.Org{ID: newID(), Name: orgName}, OwnerUserID: ownerUserID, CreatedAt: now()}
+	s.st.Orgs = append(s.st.Orgs, o)
+	s.st.Members = append(s.st.Members, member{OrgID: o.ID, UserID: ownerUserID, Role: store.RoleOwner, CreatedAt: o.CreatedAt})
+	// по умолчанию подписка free
+	s.st.Subscriptions[o.ID] = store.Subscription{OrgID: o.ID, PlanID: "free", Status: "active"}
+	return o.Org, s.save()
+}
+
+func (s *Store) GetOrg(ctx context.Context, orgID string) (store.Org, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o, ok := s.orgByID(orgID)
+	if !ok {
+		return store.Org{}, store.ErrNotFound
+	}
+	return o.Org, nil
+}
+
+func (s *Store) GetSubscription(ctx context.Context, orgID string) (store.Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub, ok := s.st.Subscriptions[orgID]
+	if !ok {
+		return store.Subscription{}, store.ErrNotFound
+	}
+	return sub, nil
+}
+
+func (s *Store) PlanMaxClusters(ctx context.Context, planID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, p := range s.st.Plans {
+		if p.ID == planID {
+			return p.MaxClusters, nil
+		}
+	}
+	return 0, store.ErrNotFound
+}
+
+func (s *Store) AdminListOrgs(ctx context.Context, limit int) ([]store.AdminOrgRow, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 200
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]store.AdminOrgRow, 0, len(s.st.Orgs))
+	for i := len(s.st.Orgs) - 1; i >= 0 && len(out) < limit; i-- {
+		o := s.st.Orgs[i]
+		row := store.AdminOrgRow{ID: o.ID, Name: o.Name, OwnerUserID: o.OwnerUserID, CreatedAt: o.CreatedAt}
+		for _, u := range s.st.Users {
+			if u.ID == o.OwnerUserID {
+				row.OwnerEmail = u.Email
+			}
+		}
+		sub := s.st.Subscriptions[o.ID]
+		row.PlanID, row.Status = sub.PlanID, sub.Status
+		for _, p := range s.st.Plans {
+			if p.ID == sub.PlanID {
+				row.MaxClusters = p.MaxClusters
+			}
+		}
+		for _, c := range s.st.Clusters {
+			if c.OrgID == o.ID {
+				row.ClustersCnt++
+			}
+		}
+		out = append(out, row)
+	}
+	return out, nil
+}
+
+func (s *Store) AdminSetOrgPlan(ctx context.Context, orgID string, planID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub, ok := s.st.Subscriptions[orgID]
+	if !ok {
+		return nil
+	}
+	sub.PlanID = planID
+	s.st.Subscriptions[orgID] = sub
+	return s.save()
+}
+
+func (s *Store) PlanMaxScansPerDay(ctx context.Context, planID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, p := range s.st.Plans {
+		if p.ID == planID {
+			return p.MaxScansPerDay, nil
+		}
+	}
+	return 0, store.ErrNotFound
+}
+
+// ---- clusters ----
+
+func (s *Store) CountClusters(ctx context.Context, orgID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for _, c := range s.st.Clusters {
+		if c.OrgID == orgID {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *Store) CreateCluster(ctx context.Context, orgID, name, notes string, restricted bool) (store.Cluster, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := store.Cluster{ID: newID(), OrgID: orgID, Name: name, Notes: notes, Restricted: restricted, CreatedAt: now()}
+	s.st.Clusters = append(s.st.Clusters, c)
+	return c, s.save()
+}
+
+func (s *Store) GetCluster(ctx context.Context, clusterID string) (store.Cluster, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, c := range s.st.Clusters {
+		if c.ID == clusterID {
+			return c, nil
+		}
+	}
+	return store.Cluster{}, store.ErrNotFound
+}
+
+func (s *Store) SetClusterRestricted(ctx context.Context, clusterID string, restricted bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.st.Clusters {
+		if s.st.Clusters[i].ID == clusterID {
+			s.st.Clusters[i].Restricted = restricted
+			return s.save()
+		}
+	}
+	return store.ErrNotFound
+}
+
+func (s *Store) ListClusters(ctx context.Context, orgID string) ([]store.Cluster, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []store.Cluster
+	for i := len(s.st.Clusters) - 1; i >= 0; i-- {
+		if s.st.Clusters[i].OrgID == orgID {
+			out = append(out, s.st.Clusters[i])
+		}
+	}
+	return out, nil
+}
+
+// ---- scans ----
+
+func (s *Store) CreateScan(ctx context.Context, orgID, clusterID, source string) (store.Scan, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sc, err := s.createScan(orgID, clusterID, source)
+	if err != nil {
+		return store.Scan{}, err
+	}
+	return sc.Scan, s.save()
+}
+
+func (s *Store) CreateScanWithinQuota(ctx context.Context, ns store.NewScan, since time.Time, max int) (store.Scan, error) {
+	sumB, _ := json.Marshal(ns.Summary)
+	fullB, _ := json.Marshal(ns.Full)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.countScansSince(ns.OrgID, since) >= max {
+		return store.Scan{}, store.ErrQuotaExceeded
+	}
+	if _, ok := s.st.Snapshots[ns.SnapshotSHA256]; ns.SnapshotSHA256 != "" && !ok {
+		// в PostgreSQL это нарушение внешнего ключа
+		return store.Scan{}, fmt.Errorf("snapshot %s not found", ns.SnapshotSHA256)
+	}
+	sc, err := s.createScan(ns.OrgID, ns.ClusterID, ns.Source)
+	if err != nil {
+		return store.Scan{}, err
+	}
+	sc.SnapshotSHA256 = ns.SnapshotSHA256
+	s.st.Results[sc.ID] = result{Summary: sumB, Full: fullB, EngineVersion: ns.EngineVersion, AnalyzedAt: now()}
+	return sc.Scan, s.save()
+}
+
+// createScan добавляет скан в состояние, не сохраняя его (вызывается под mu).
+func (s *Store) createScan(orgID, clusterID, source string) (*scan, error) {
+	found := false
+	for _, c := range s.st.Clusters {
+		if c.ID == clusterID && c.OrgID == orgID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		// в PostgreSQL это нарушение внешнего ключа
+		return nil, fmt.Errorf("cluster %s not found", clusterID)
+	}
+
+	sc := store.Scan{ID: newID(), OrgID: orgID, ClusterID: clusterID, CreatedAt: now(), Source: source}
+	s.st.Scans = append(s.st.Scans, scan{Scan: sc})
+	return &s.st.Scans[len(s.st.Scans)-1], nil
+}
+
+func (s *Store) CountScansSince(ctx context.Context, orgID string, since time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.countScansSince(orgID, since), nil
+}
+
+// countScansSince вызывается под mu.
+func (s *Store) countScansSince(orgID string, since time.Time) int {
+	n := 0
+	for _, sc := range s.st.Scans {
+		if sc.OrgID == orgID && !sc.CreatedAt.Before(since) {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *Store) findScan(scanID string) *scan {
+	for i := range s.st.Scans {
+		if s.st.Scans[i].ID == scanID {
+			return &s.st.Scans[i]
+		}
+	}
+	return nil
+}
+
+func (s *Store) GetScan(ctx context.Context, scanID string) (store.Scan, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sc := s.findScan(scanID)
+	if sc == nil {
+		return store.Scan{}, store.ErrNotFound
+	}
+	return sc.Scan, nil
+}
+
+func (s *Store) ListScans(ctx context.Context, orgID, clusterID string) ([]store.Scan, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []store.Scan
+	for i := len(s.st.Scans) - 1; i >= 0 && len(out) < 50; i-- {
+		sc := s.st.Scans[i]
+		if sc.OrgID == orgID && sc.ClusterID == clusterID {
+			out = append(out, sc.Scan)
+		}
+	}
+	return out, nil
+}
+
+func (s *Store) UpsertScanResult(ctx context.Context, scanID string, summary any, full any, engineVersion string) error {
+	sumB, _ := json.Marshal(summary)
+	fullB, _ := json.Marshal(full)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.findScan(scanID) == nil {
+		return fmt.Errorf("scan %s not found", scanID)
+	}
+	s.st.Results[scanID] = result{Summary: sumB, Full: fullB, EngineVersion: engineVersion, AnalyzedAt: now()}
+	return s.save()
+}
+
+func (s *Store) GetScanReport(ctx context.Context, scanID string) (map[string]any, map[string]any, error) {
+	s.mu.Lock()
+	res, ok := s.st.Results[scanID]
+	s.mu.Unlock()
+	if !ok {
+		return nil, nil, store.ErrNotFound
+	}
+
+	var sum map[string]any
+	var full map[string]any
+	_ = json.Unmarshal(res.Summary, &sum)
+	_ = json.Unmarshal(res.Full, &full)
+	return sum, full, nil
+}
+
+// ---- snapshots ----
+
+func (s *Store) PutSnapshot(ctx context.Context, content []byte) (string, error) {
+	sum := sha256.Sum256(content)
+	sha := hex.EncodeToString(sum[:])
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.st.Snapshots[sha]; ok {
+		return sha, nil
+	}
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(content); err != nil {
+		return "", err
+	}
+	if err := gz.Close(); err != nil {
+		return "", err
+	}
+	s.st.Snapshots[sha] = buf.Bytes()
+	return sha, s.save()
+}
+
+func (s *Store) GetSnapshot(ctx context.Context, sha string) ([]byte, error) {
+	s.mu.Lock()
+	b, ok := s.st.Snapshots[sha]
+	s.mu.Unlock()
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	return io.ReadAll(gz)
+}
+
+func (s *Store) SetScanSnapshot(ctx context.Context, scanID, sha string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sc := s.findScan(scanID)
+	if sc == nil {
+		return nil
+	}
+	sc.SnapshotSHA256 = sha
+	return s.save()
+}
+
+// stale — сканы со снимком и устаревшим результатом, от старых к новым (вызывается под mu).
+func (s *Store) stale(engineVersion string) []store.StaleScan {
+	var out []store.StaleScan
+	for _, sc := range s.st.Scans {
+		if sc.SnapshotSHA256 == "" {
+			continue
+		}
+		ver := s.st.Results[sc.ID].EngineVersion
+		if ver != engineVersion {
+			out = append(out, store.StaleScan{ScanID: sc.ID, SnapshotSHA256: sc.SnapshotSHA256, EngineVersion: ver})
+		}
+	}
+	return out
+}
+
+func (s *Store) ListStaleScans(ctx context.Context, engineVersion string, offset, limit int) ([]store.StaleScan, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all := s.stale(engineVersion)
+	if offset >= len(all) {
+		return nil, nil
+	}
+	all = all[offset:]
+	if len(all) > limit {
+		all = all[:limit]
+	}
+	return all, nil
+}
+
+func (s *Store) CountStaleScans(ctx context.Context, engineVersion string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.stale(engineVersion)), nil
+}
+
+// ---- admin audit ----
+
+func (s *Store) AddAdminAudit(ctx context.Context, adminUserID, action, targetType, targetID string, meta map[string]any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.st.Audit = append(s.st.Audit, store.AdminAuditEvent{
+		ID:          newID(),
+		AdminUserID: adminUserID,
+		Action:      action,
+		TargetType:  targetType,
+		TargetID:    targetID,
+		Meta:        meta,
+		CreatedAt:   now(),
+	})
+	return s.save()
+}
+
+func (s *Store) ListAdminAudit(ctx context.Context, limit int) ([]store.AdminAuditEvent, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]store.AdminAuditEvent, 0, len(s.st.Audit))
+	for i := len(s.st.Audit) - 1; i >= 0 && len(out) < limit; i-- {
+		out = append(out, s.st.Audit[i])
+	}
+	return out, nil
+}
+
+// IsURL — адрес встроенного хранилища: "memory://" (только память)
+// или "file:///path/state.json" (с сохранением в файл). "sqlite://" тоже относится
+// к встроенному хранилищу, но не поддерживается: OpenURL вернёт ошибку.
+func IsURL(databaseURL string) bool {
+	return strings.HasPrefix(databaseURL, "memory://") || strings.HasPrefix(databaseURL, "file://") ||
+		strings.HasPrefix(databaseURL, "sqlite://")
+}
+
+// OpenURL открывает хранилище по адресу из IsURL.
+func OpenURL(databaseURL string) (*Store, error) {
+	if strings.HasPrefix(databaseURL, "memory://") {
+		return New(), nil
+	}
+	if strings.HasPrefix(databaseURL, "sqlite://") {
+		return nil, fmt.Errorf("sqlite:// is not supported, use file:///path/state.json for a single-node store")
+	}
+	path := strings.TrimPrefix(databaseURL, "file://")
+	if path == "" {
+		return nil, fmt.Errorf("file:// database URL needs a path")
+	}
+	return Open(path)
+}
@@ -0,0 +1,155 @@
+package memstore
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"rbac-analyzer/internal/store"
+)
+
+// Поля с json:"-" (хэши токенов, client secret) должны переживать перезапуск файлового хранилища.
+func TestReopenKeepsSecrets(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "state.json")
+	st, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := st.CreateUser(ctx, "owner@example.com", "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := st.CreateOrgForOwner(ctx, u.ID, "Acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.CreateSession(ctx, store.Session{UserID: u.ID, RefreshHash: "refresh-1", ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.CreateInvitation(ctx, store.Invitation{OrgID: o.ID, Email: "bob@example.com", Role: store.RoleMember,
+		TokenHash: "invite-1", InvitedBy: u.ID, ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.PutOrgSSO(ctx, store.OrgSSO{OrgID: o.ID, Issuer: "https://idp.example", ClientID: "rbac", ClientSecret: "s3cret"}); err != nil {
+		t.Fatal(err)
+	}
+
+	st, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.RotateSession(ctx, "refresh-1", "refresh-2", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("rotate session after reopen: %v", err)
+	}
+	if reused, err := st.RevokeReusedSession(ctx, "refresh-1"); err != nil || !reused {
+		t.Fatalf("previous refresh hash after reopen: reused=%v, err=%v", reused, err)
+	}
+	if inv, err := st.GetInvitationByTokenHash(ctx, "invite-1"); err != nil || inv.Email != "bob@example.com" {
+		t.Fatalf("invitation after reopen: %+v, %v", inv, err)
+	}
+	if c, err := st.GetOrgSSO(ctx, o.ID); err != nil || c.ClientSecret != "s3cret" {
+		t.Fatalf("sso after reopen: secret %q, %v", c.ClientSecret, err)
+	}
+}
+
+// Параллельные загрузки не превышают дневную квоту.
+func TestCreateScanWithinQuota(t *testing.T) {
+	ctx := context.Background()
+	st := New()
+	u, err := st.CreateUser(ctx, "owner@example.com", "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := st.CreateOrgForOwner(ctx, u.ID, "Acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := st.CreateCluster(ctx, o.ID, "prod", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const max = 5
+	since := time.Now().UTC().Truncate(24 * time.Hour)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	created, rejected := 0, 0
+	for i := 0; i < 4*max; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := st.CreateScanWithinQuota(ctx, store.NewScan{OrgID: o.ID, ClusterID: c.ID, Source: "upload"}, since, max)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				created++
+			case errors.Is(err, store.ErrQuotaExceeded):
+				rejected++
+			default:
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if created != max || rejected != 3*max {
+		t.Fatalf("created %d, rejected %d, want %d and %d", created, rejected, max, 3*max)
+	}
+}
+
+// Скан создаётся вместе со снимком и результатом; при ошибке не остаётся ничего.
+func TestCreateScanWithinQuotaAtomic(t *testing.T) {
+	ctx := context.Background()
+	st := New()
+	u, _ := st.CreateUser(ctx, "owner@example.com", "hash")
+	o, _ := st.CreateOrgForOwner(ctx, u.ID, "Acme")
+	c, _ := st.CreateCluster(ctx, o.ID, "prod", "", false)
+	since := time.Now().UTC().Truncate(24 * time.Hour)
+
+	sha, err := st.PutSnapshot(ctx, []byte("kind: Role"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns := store.NewScan{OrgID: o.ID, ClusterID: c.ID, Source: "upload", SnapshotSHA256: sha,
+		Summary: map[string]int{"subjects": 1}, Full: map[string]string{"k": "v"}, EngineVersion: "e1"}
+	sc, err := st.CreateScanWithinQuota(ctx, ns, since, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := st.GetScan(ctx, sc.ID); got.SnapshotSHA256 != sha {
+		t.Fatalf("scan snapshot = %q", got.SnapshotSHA256)
+	}
+	if sum, full, err := st.GetScanReport(ctx, sc.ID); err != nil || sum["subjects"] != float64(1) || full["k"] != "v" {
+		t.Fatalf("report = %v %v, %v", sum, full, err)
+	}
+
+	ns.SnapshotSHA256 = "missing"
+	if _, err := st.CreateScanWithinQuota(ctx, ns, since, 2); err == nil {
+		t.Fatal("scan with a missing snapshot created")
+	}
+	if n, _ := st.CountScansSince(ctx, o.ID, since); n != 1 {
+		t.Fatalf("failed upload counted against the quota: %d scans", n)
+	}
+}
+
+func TestOpenURL(t *testing.T) {
+	for _, u := range []string{"memory://", "file://" + filepath.Join(t.TempDir(), "state.json")} {
+		if !IsURL(u) {
+			t.Fatalf("%s: not an embedded store URL", u)
+		}
+		if _, err := OpenURL(u); err != nil {
+			t.Fatalf("%s: %v", u, err)
+		}
+	}
+	if !IsURL("sqlite:///var/lib/rbac.db") {
+		t.Fatal("sqlite:// must not fall through to PostgreSQL")
+	}
+	if _, err := OpenURL("sqlite:///var/lib/rbac.db"); err == nil || !strings.Contains(err.Error(), "not supported") {
+		t.Fatalf("sqlite:// err = %v", err)
+	}
+}
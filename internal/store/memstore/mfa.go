@@ -0,0 +1,99 @@
+package memstore
+
+import (
+	"context"
+
+	"rbac-analyzer/internal/store"
+)
+
+// ---- mfa ----
+
+func (s *Store) mfaIndex(userID string) int {
+	for i, m := range s.st.MFA {
+		if m.UserID == userID {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *Store) GetMFA(ctx context.Context, userID string) (store.UserMFA, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.mfaIndex(userID)
+	if i < 0 {
+		return store.UserMFA{}, store.ErrNotFound
+	}
+	m := s.st.MFA[i]
+	m.RecoveryHashes = append([]string(nil), m.RecoveryHashes...)
+	return m, nil
+}
+
+func (s *Store) PutMFA(ctx context.Context, m store.UserMFA) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m.RecoveryHashes = append([]string{}, m.RecoveryHashes...)
+	if i := s.mfaIndex(m.UserID); i >= 0 {
+		s.st.MFA[i] = m
+	} else {
+		s.st.MFA = append(s.st.MFA, m)
+	}
+	return s.save()
+}
+
+func (s *Store) UseMFAStep(ctx context.Context, userID string, step int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.mfaIndex(userID)
+	if i < 0 || s.st.MFA[i].LastStep >= step {
+		return false, nil
+	}
+	s.st.MFA[i].LastStep = step
+	return true, s.save()
+}
+
+func (s *Store) UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.mfaIndex(userID)
+	if i < 0 || !s.st.MFA[i].Enabled() {
+		return false, nil
+	}
+	hashes := s.st.MFA[i].RecoveryHashes
+	for j, h := range hashes {
+		if h == codeHash {
+			s.st.MFA[i].RecoveryHashes = append(hashes[:j:j], hashes[j+1:]...)
+			return true, s.save()
+		}
+	}
+	return false, nil
+}
+
+func (s *Store) DeleteMFA(ctx context.Context, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.mfaIndex(userID)
+	if i < 0 {
+		return store.ErrNotFound
+	}
+	s.st.MFA = append(s.st.MFA[:i], s.st.MFA[i+1:]...)
+	return s.save()
+}
+
+func (s *Store) SetOrgRequireMFA(ctx context.Context, orgID string, require bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.st.Orgs {
+		if s.st.Orgs[i].ID == orgID {
+			s.st.Orgs[i].RequireMFA = require
+			return s.save()
+		}
+	}
+	return store.ErrNotFound
+}
@@ -0,0 +1,119 @@
+package memstore
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"rbac-analyzer/internal/store"
+)
+
+// ---- sessions ----
+
+func (s *Store) CreateSession(ctx context.Context, ss store.Session) (store.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ss.ID = newID()
+	ss.CreatedAt = now()
+	ss.LastUsedAt = ss.CreatedAt
+	s.st.Sessions = append(s.st.Sessions, ss)
+	return ss, s.save()
+}
+
+func (s *Store) GetSession(ctx context.Context, sessionID string) (store.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, ss := range s.st.Sessions {
+		if ss.ID == sessionID {
+			return ss, nil
+		}
+	}
+	return store.Session{}, store.ErrNotFound
+}
+
+func (s *Store) RotateSession(ctx context.Context, oldHash, newHash string, expiresAt time.Time) (store.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := now()
+	for i := range s.st.Sessions {
+		ss := &s.st.Sessions[i]
+		if ss.RefreshHash != oldHash || !ss.Active(t) {
+			continue
+		}
+		ss.PrevRefreshHash, ss.RefreshHash = ss.RefreshHash, newHash
+		ss.LastUsedAt, ss.ExpiresAt = t, expiresAt
+		return *ss, s.save()
+	}
+	return store.Session{}, store.ErrNotFound
+}
+
+func (s *Store) RevokeReusedSession(ctx context.Context, prevHash string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := now()
+	found := false
+	for i := range s.st.Sessions {
+		ss := &s.st.Sessions[i]
+		if ss.PrevRefreshHash == prevHash && ss.RevokedAt == nil {
+			ss.RevokedAt = &t
+			found = true
+		}
+	}
+	if !found {
+		return false, nil
+	}
+	return true, s.save()
+}
+
+func (s *Store) ListSessions(ctx context.Context, userID string) ([]store.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := now()
+	var out []store.Session
+	for _, ss := range s.st.Sessions {
+		if ss.UserID == userID && ss.Active(t) {
+			out = append(out, ss)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].LastUsedAt.After(out[j].LastUsedAt) })
+	return out, nil
+}
+
+func (s *Store) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.st.Sessions {
+		ss := &s.st.Sessions[i]
+		if ss.ID == sessionID && ss.UserID == userID && ss.RevokedAt == nil {
+			t := now()
+			ss.RevokedAt = &t
+			return s.save()
+		}
+	}
+	return store.ErrNotFound
+}
+
+func (s *Store) RevokeUserSessions(ctx context.Context, userID, exceptID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := now()
+	n := 0
+	for i := range s.st.Sessions {
+		ss := &s.st.Sessions[i]
+		if ss.UserID == userID && ss.ID != exceptID && ss.RevokedAt == nil {
+			ss.RevokedAt = &t
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	return n, s.save()
+}
@@ -0,0 +1,97 @@
+package memstore
+
+import (
+	"context"
+
+	"rbac-analyzer/internal/store"
+)
+
+// ---- sso ----
+
+func (s *Store) ssoIndex(orgID string) int {
+	for i, c := range s.st.SSO {
+		if c.OrgID == orgID {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *Store) GetOrgSSO(ctx context.Context, orgID string) (store.OrgSSO, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i := s.ssoIndex(orgID); i >= 0 {
+		return s.st.SSO[i], nil
+	}
+	return store.OrgSSO{}, store.ErrNotFound
+}
+
+func (s *Store) FindOrgSSOByDomain(ctx context.Context, domain string) (store.OrgSSO, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, c := range s.st.SSO {
+		for _, d := range c.Domains {
+			if d == domain {
+				return c, nil
+			}
+		}
+	}
+	return store.OrgSSO{}, store.ErrNotFound
+}
+
+func (s *Store) PutOrgSSO(ctx context.Context, c store.OrgSSO) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c.UpdatedAt = now()
+	if i := s.ssoIndex(c.OrgID); i >= 0 {
+		s.st.SSO[i] = c
+	} else {
+		s.st.SSO = append(s.st.SSO, c)
+	}
+	return s.save()
+}
+
+func (s *Store) DeleteOrgSSO(ctx context.Context, orgID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.ssoIndex(orgID)
+	if i < 0 {
+		return store.ErrNotFound
+	}
+	s.st.SSO = append(s.st.SSO[:i], s.st.SSO[i+1:]...)
+	return s.save()
+}
+
+func (s *Store) GetUserByIdentity(ctx context.Context, issuer, subject string) (store.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range s.st.Identities {
+		if id.Issuer != issuer || id.Subject != subject {
+			continue
+		}
+		for _, u := range s.st.Users {
+			if u.ID == id.UserID {
+				return u, nil
+			}
+		}
+	}
+	return store.User{}, store.ErrNotFound
+}
+
+func (s *Store) LinkIdentity(ctx context.Context, issuer, subject, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range s.st.Identities {
+		if id.Issuer == issuer && id.Subject == subject {
+			return nil
+		}
+	}
+	s.st.Identities = append(s.st.Identities, identity{Issuer: issuer, Subject: subject, UserID: userID})
+	return s.save()
+}
@@ -0,0 +1,86 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// UserMFA — второй фактор пользователя (TOTP) и хеши кодов восстановления.
+type UserMFA struct {
+	UserID         string
+	Secret         string
+	EnabledAt      *time.Time // nil — подключение начато, но не подтверждено кодом
+	LastStep       int64
+	RecoveryHashes []string
+}
+
+func (m UserMFA) Enabled() bool { return m.EnabledAt != nil }
+
+func (s *Store) GetMFA(ctx context.Context, userID string) (UserMFA, error) {
+	var m UserMFA
+	err := s.DB.QueryRow(ctx,
+		`SELECT user_id, secret, enabled_at, last_step, recovery_hashes FROM user_mfa WHERE user_id=$1`,
+		userID,
+	).Scan(&m.UserID, &m.Secret, &m.EnabledAt, &m.LastStep, &m.RecoveryHashes)
+	if IsNotFound(err) {
+		return UserMFA{}, ErrNotFound
+	}
+	return m, err
+}
+
+// PutMFA создаёт или целиком заменяет запись.
+func (s *Store) PutMFA(ctx context.Context, m UserMFA) error {
+	if m.RecoveryHashes == nil {
+		m.RecoveryHashes = []string{}
+	}
+	_, err := s.DB.Exec(ctx,
+		`INSERT INTO user_mfa(user_id, secret, enabled_at, last_step, recovery_hashes)
+		 VALUES($1,$2,$3,$4,$5)
+		 ON CONFLICT (user_id) DO UPDATE SET
+		   secret=EXCLUDED.secret, enabled_at=EXCLUDED.enabled_at, last_step=EXCLUDED.last_step,
+		   recovery_hashes=EXCLUDED.recovery_hashes, updated_at=now()`,
+		m.UserID, m.Secret, m.EnabledAt, m.LastStep, m.RecoveryHashes,
+	)
+	return err
+}
+
+// UseMFAStep запоминает шаг принятого TOTP-кода. false — код с этим шагом уже использован.
+func (s *Store) UseMFAStep(ctx context.Context, userID string, step int64) (bool, error) {
+	tag, err := s.DB.Exec(ctx,
+		`UPDATE user_mfa SET last_step=$2, updated_at=now() WHERE user_id=$1 AND last_step < $2`,
+		userID, step,
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// UseRecoveryCode гасит код восстановления. false — такого неиспользованного кода нет.
+func (s *Store) UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error) {
+	tag, err := s.DB.Exec(ctx,
+		`UPDATE user_mfa SET recovery_hashes=array_remove(recovery_hashes, $2), updated_at=now()
+		 WHERE user_id=$1 AND enabled_at IS NOT NULL AND $2 = ANY(recovery_hashes)`,
+		userID, codeHash,
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (s *Store) DeleteMFA(ctx context.Context, userID string) error {
+	tag, err := s.DB.Exec(ctx, `DELETE FROM user_mfa WHERE user_id=$1`, userID)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *Store) SetOrgRequireMFA(ctx context.Context, orgID string, require bool) error {
+	tag, err := s.DB.Exec(ctx, `UPDATE orgs SET require_mfa=$2 WHERE id=$1`, orgID, require)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return err
+}
@@ -15,19 +15,26 @@ type User struct {
 	PasswordHash string
 	IsAdmin      bool
 	CreatedAt    time.Time
+
+	// EmailVerified — адрес подтверждён по ссылке из письма (или приглашением, или IdP).
+	EmailVerified bool
 }
 
 type Org struct {
 	ID   string
 	Name string
+
+	// RequireMFA — участникам без двухфакторной аутентификации закрыт доступ к данным организации.
+	RequireMFA bool
 }
 
 type Cluster struct {
-	ID        string
-	OrgID     string
-	Name      string
-	Notes     string
-	CreatedAt time.Time
+	ID         string
+	OrgID      string
+	Name       string
+	Notes      string
+	Restricted bool // видят только owner/admin организации и получившие грант
+	CreatedAt  time.Time
 }
 
 type Scan struct {
@@ -36,6 +43,20 @@ type Scan struct {
 	ClusterID string
 	CreatedAt time.Time
 	Source    string
+
+	SnapshotSHA256 string // исходный YAML в snapshots; "" у сканов, загруженных до снимков
+}
+
+// NewScan — загруженный скан вместе со снимком и результатом анализа.
+type NewScan struct {
+	OrgID     string
+	ClusterID string
+	Source    string
+
+	SnapshotSHA256 string // снимок из PutSnapshot; "" — без снимка
+	Summary        any
+	Full           any
+	EngineVersion  string
 }
 
 type Subscription struct {
@@ -96,7 +117,7 @@ func (s *Store) CreateUser(ctx context.Context, email, passwordHash string) (Use
 	err := s.DB.QueryRow(ctx,
 		`INSERT INTO users(email, password_hash)
 		 VALUES($1,$2)
-		 RETURNING id, email, password_hash, is_admin, created_at`,
+		 RETURNING id, email, password_hash, is_admin, created_at, email_verified_at IS NOT NULL`,
 		email, passwordHash,
 	).Scan(
 		&u.ID,
@@ -104,6 +125,7 @@ func (s *Store) CreateUser(ctx context.Context, email, passwordHash string) (Use
 		&u.PasswordHash,
 		&u.IsAdmin,
 		&u.CreatedAt,
+		&u.EmailVerified,
 	)
 	return u, err
 }
@@ -111,7 +133,7 @@ func (s *Store) CreateUser(ctx context.Context, email, passwordHash string) (Use
 func (s *Store) GetUserByEmail(ctx context.Context, email string) (User, error) {
 	var u User
 	err := s.DB.QueryRow(ctx,
-		`SELECT id, email, password_hash, is_admin, created_at FROM users WHERE email=$1`,
+		`SELECT id, email, password_hash, is_admin, created_at, email_verified_at IS NOT NULL FROM users WHERE email=$1`,
 		email,
 	).Scan(
 		&u.ID,
@@ -119,6 +141,7 @@ func (s *Store) GetUserByEmail(ctx context.Context, email string) (User, error)
 		&u.PasswordHash,
 		&u.IsAdmin,
 		&u.CreatedAt,
+		&u.EmailVerified,
 	)
 	return u, err
 }
@@ -158,17 +181,12 @@ func (s *Store) CreateOrgForOwner(ctx context.Context, ownerUserID, orgName stri
 	return org, nil
 }
 
-func (s *Store) GetOwnerOrg(ctx context.Context, userID string) (Org, error) {
+func (s *Store) GetOrg(ctx context.Context, orgID string) (Org, error) {
 	var org Org
 	err := s.DB.QueryRow(ctx,
-		`SELECT o.id, o.name
-		 FROM orgs o
-		 JOIN org_members m ON m.org_id=o.id
-		 WHERE m.user_id=$1
-		 ORDER BY o.created_at ASC
-		 LIMIT 1`,
-		userID,
-	).Scan(&org.ID, &org.Name)
+		`SELECT id, name, require_mfa FROM orgs WHERE id=$1`,
+		orgID,
+	).Scan(&org.ID, &org.Name, &org.RequireMFA)
 	return org, err
 }
 
@@ -188,25 +206,52 @@ func (s *Store) PlanMaxClusters(ctx context.Context, planID string) (int, error)
 	return max, err
 }
 
+// PlanMaxScansPerDay — сколько сканов организация на плане planID может загрузить за сутки.
+func (s *Store) PlanMaxScansPerDay(ctx context.Context, planID string) (int, error) {
+	var max int
+	err := s.DB.QueryRow(ctx, `SELECT max_scans_per_day FROM plans WHERE id=$1`, planID).Scan(&max)
+	return max, err
+}
+
 func (s *Store) CountClusters(ctx context.Context, orgID string) (int, error) {
 	var c int
 	err := s.DB.QueryRow(ctx, `SELECT COUNT(*) FROM clusters WHERE org_id=$1`, orgID).Scan(&c)
 	return c, err
 }
 
-func (s *Store) CreateCluster(ctx context.Context, orgID, name, notes string) (Cluster, error) {
+func (s *Store) CreateCluster(ctx context.Context, orgID, name, notes string, restricted bool) (Cluster, error) {
 	var c Cluster
 	err := s.DB.QueryRow(ctx,
-		`INSERT INTO clusters(org_id, name, notes) VALUES($1,$2,$3)
-		 RETURNING id, org_id, name, notes, created_at`,
-		orgID, name, notes,
-	).Scan(&c.ID, &c.OrgID, &c.Name, &c.Notes, &c.CreatedAt)
+		`INSERT INTO clusters(org_id, name, notes, restricted) VALUES($1,$2,$3,$4)
+		 RETURNING id, org_id, name, notes, restricted, created_at`,
+		orgID, name, notes, restricted,
+	).Scan(&c.ID, &c.OrgID, &c.Name, &c.Notes, &c.Restricted, &c.CreatedAt)
 	return c, err
 }
 
+func (s *Store) GetCluster(ctx context.Context, clusterID string) (Cluster, error) {
+	var c Cluster
+	err := s.DB.QueryRow(ctx,
+		`SELECT id, org_id, name, notes, restricted, created_at FROM clusters WHERE id=$1`,
+		clusterID,
+	).Scan(&c.ID, &c.OrgID, &c.Name, &c.Notes, &c.Restricted, &c.CreatedAt)
+	return c, err
+}
+
+func (s *Store) SetClusterRestricted(ctx context.Context, clusterID string, restricted bool) error {
+	tag, err := s.DB.Exec(ctx,
+		`UPDATE clusters SET restricted=$2 WHERE id=$1`,
+		clusterID, restricted,
+	)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
 func (s *Store) ListClusters(ctx context.Context, orgID string) ([]Cluster, error) {
 	rows, err := s.DB.Query(ctx,
-		`SELECT id, org_id, name, notes, created_at FROM clusters WHERE org_id=$1 ORDER BY created_at DESC`,
+		`SELECT id, org_id, name, notes, restricted, created_at FROM clusters WHERE org_id=$1 ORDER BY created_at DESC`,
 		orgID,
 	)
 	if err != nil {
@@ -217,7 +262,7 @@ func (s *Store) ListClusters(ctx context.Context, orgID string) ([]Cluster, erro
 	var out []Cluster
 	for rows.Next() {
 		var c Cluster
-		if err := rows.Scan(&c.ID, &c.OrgID, &c.Name, &c.Notes, &c.CreatedAt); err != nil {
+		if err := rows.Scan(&c.ID, &c.OrgID, &c.Name, &c.Notes, &c.Restricted, &c.CreatedAt); err != nil {
 			return nil, err
 		}
 		out = append(out, c)
@@ -235,22 +280,92 @@ func (s *Store) CreateScan(ctx context.Context, orgID, clusterID, source string)
 	return sc, err
 }
 
-func (s *Store) UpsertScanResult(ctx context.Context, scanID string, summary any, full any) error {
+// CreateScanWithinQuota создаёт скан вместе со снимком и результатом, если с since
+// у организации меньше max сканов, иначе возвращает ErrQuotaExceeded. Подсчёт и вставки
+// идут в одной транзакции под блокировкой строки организации: параллельные загрузки
+// квоту не превысят, а при ошибке не остаётся скана без результата, занявшего квоту.
+func (s *Store) CreateScanWithinQuota(ctx context.Context, ns NewScan, since time.Time, max int) (Scan, error) {
+	sumB, _ := json.Marshal(ns.Summary)
+	fullB, _ := json.Marshal(ns.Full)
+
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return Scan{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM orgs WHERE id=$1 FOR UPDATE`, ns.OrgID); err != nil {
+		return Scan{}, err
+	}
+	var c int
+	if err := tx.QueryRow(ctx,
+		`SELECT COUNT(*) FROM scans WHERE org_id=$1 AND created_at >= $2`,
+		ns.OrgID, since,
+	).Scan(&c); err != nil {
+		return Scan{}, err
+	}
+	if c >= max {
+		return Scan{}, ErrQuotaExceeded
+	}
+
+	var sc Scan
+	if err := tx.QueryRow(ctx,
+		`INSERT INTO scans(org_id, cluster_id, source, snapshot_sha256) VALUES($1,$2,$3,NULLIF($4,''))
+		 RETURNING id, org_id, cluster_id, created_at, source, COALESCE(snapshot_sha256, '')`,
+		ns.OrgID, ns.ClusterID, ns.Source, ns.SnapshotSHA256,
+	).Scan(&sc.ID, &sc.OrgID, &sc.ClusterID, &sc.CreatedAt, &sc.Source, &sc.SnapshotSHA256); err != nil {
+		return Scan{}, err
+	}
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO scan_results(scan_id, summary, full_report, engine_version, analyzed_at)
+		 VALUES($1,$2,$3,$4,now())`,
+		sc.ID, sumB, fullB, ns.EngineVersion,
+	); err != nil {
+		return Scan{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return Scan{}, err
+	}
+	return sc, nil
+}
+
+func (s *Store) GetScan(ctx context.Context, scanID string) (Scan, error) {
+	var sc Scan
+	err := s.DB.QueryRow(ctx,
+		`SELECT id, org_id, cluster_id, created_at, source, COALESCE(snapshot_sha256, '') FROM scans WHERE id=$1`,
+		scanID,
+	).Scan(&sc.ID, &sc.OrgID, &sc.ClusterID, &sc.CreatedAt, &sc.Source, &sc.SnapshotSHA256)
+	return sc, err
+}
+
+// UpsertScanResult сохраняет результат анализа и версию движка, которой он посчитан.
+func (s *Store) UpsertScanResult(ctx context.Context, scanID string, summary any, full any, engineVersion string) error {
 	sumB, _ := json.Marshal(summary)
 	fullB, _ := json.Marshal(full)
 
 	_, err := s.DB.Exec(ctx,
-		`INSERT INTO scan_results(scan_id, summary, full_report)
-		 VALUES($1,$2,$3)
-		 ON CONFLICT (scan_id) DO UPDATE SET summary=EXCLUDED.summary, full_report=EXCLUDED.full_report`,
-		scanID, sumB, fullB,
+		`INSERT INTO scan_results(scan_id, summary, full_report, engine_version, analyzed_at)
+		 VALUES($1,$2,$3,$4,now())
+		 ON CONFLICT (scan_id) DO UPDATE SET
+		   summary=EXCLUDED.summary,
+		   full_report=EXCLUDED.full_report,
+		   engine_version=EXCLUDED.engine_version,
+		   analyzed_at=EXCLUDED.analyzed_at`,
+		scanID, sumB, fullB, engineVersion,
 	)
 	return err
 }
 
+// CountScansSince — сколько сканов организации создано начиная с since.
+func (s *Store) CountScansSince(ctx context.Context, orgID string, since time.Time) (int, error) {
+	var c int
+	err := s.DB.QueryRow(ctx, `SELECT COUNT(*) FROM scans WHERE org_id=$1 AND created_at >= $2`, orgID, since).Scan(&c)
+	return c, err
+}
+
 func (s *Store) ListScans(ctx context.Context, orgID, clusterID string) ([]Scan, error) {
 	rows, err := s.DB.Query(ctx,
-		`SELECT id, org_id, cluster_id, created_at, source
+		`SELECT id, org_id, cluster_id, created_at, source, COALESCE(snapshot_sha256, '')
 		 FROM scans
 		 WHERE org_id=$1 AND cluster_id=$2
 		 ORDER BY created_at DESC
@@ -265,7 +380,7 @@ func (s *Store) ListScans(ctx context.Context, orgID, clusterID string) ([]Scan,
 	var out []Scan
 	for rows.Next() {
 		var sc Scan
-		if err := rows.Scan(&sc.ID, &sc.OrgID, &sc.ClusterID, &sc.CreatedAt, &sc.Source); err != nil {
+		if err := rows.Scan(&sc.ID, &sc.OrgID, &sc.ClusterID, &sc.CreatedAt, &sc.Source, &sc.SnapshotSHA256); err != nil {
 			return nil, err
 		}
 		out = append(out, sc)
@@ -291,11 +406,11 @@ func (s *Store) GetScanReport(ctx context.Context, scanID string) (map[string]an
 }
 
 func IsNotFound(err error) bool {
-	return errors.Is(err, pgx.ErrNoRows)
+	return errors.Is(err, pgx.ErrNoRows) || errors.Is(err, ErrNotFound)
 }
 func (s *Store) ListUsers(ctx context.Context) ([]User, error) {
 	rows, err := s.DB.Query(ctx,
-		`SELECT id, email, password_hash, is_admin, created_at
+		`SELECT id, email, password_hash, is_admin, created_at, email_verified_at IS NOT NULL
 		 FROM users
 		 ORDER BY created_at DESC`,
 	)
@@ -307,7 +422,7 @@ func (s *Store) ListUsers(ctx context.Context) ([]User, error) {
 	var out []User
 	for rows.Next() {
 		var u User
-		if err := rows.Scan(&u.ID, &u.Email, &u.PasswordHash, &u.IsAdmin, &u.CreatedAt); err != nil {
+		if err := rows.Scan(&u.ID, &u.Email, &u.PasswordHash, &u.IsAdmin, &u.CreatedAt, &u.EmailVerified); err != nil {
 			return nil, err
 		}
 		out = append(out, u)
@@ -346,7 +461,7 @@ func (s *Store) ListAllUsers(ctx context.Context) ([]User, error) {
 
 func (s *Store) ListAllOrgs(ctx context.Context) ([]Org, error) {
 	rows, err := s.DB.Query(ctx,
-		`SELECT id, name FROM orgs ORDER BY created_at DESC`,
+		`SELECT id, name, require_mfa FROM orgs ORDER BY created_at DESC`,
 	)
 	if err != nil {
 		return nil, err
@@ -356,7 +471,7 @@ func (s *Store) ListAllOrgs(ctx context.Context) ([]Org, error) {
 	var out []Org
 	for rows.Next() {
 		var o Org
-		if err := rows.Scan(&o.ID, &o.Name); err != nil {
+		if err := rows.Scan(&o.ID, &o.Name, &o.RequireMFA); err != nil {
 			return nil, err
 		}
 		out = append(out, o)
@@ -372,19 +487,6 @@ func (s *Store) ToggleAdmin(ctx context.Context, userID string) error {
 	)
 	return err
 }
-func (s *Store) AddAdminAuditLog(
-	ctx context.Context,
-	actorUserID string,
-	targetUserID string,
-	action string,
-) error {
-	_, err := s.DB.Exec(ctx,
-		`INSERT INTO admin_audit_log(actor_user_id, target_user_id, action)
-		 VALUES ($1,$2,$3)`,
-		actorUserID, targetUserID, action,
-	)
-	return err
-}
 
 type AdminAuditEvent struct {
 	ID          string
@@ -0,0 +1,150 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNotFound — запись не найдена (для бэкендов без pgx; IsNotFound понимает оба варианта).
+var ErrNotFound = errors.New("not found")
+
+// ErrQuotaExceeded — лимит плана уже выбран.
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// UserRepo — пользователи, флаг администратора, пароль и токены из писем.
+type UserRepo interface {
+	CreateUser(ctx context.Context, email, passwordHash string) (User, error)
+	GetUserByEmail(ctx context.Context, email string) (User, error)
+	GetUser(ctx context.Context, userID string) (User, error)
+	ToggleAdmin(ctx context.Context, userID string) error
+	AdminListUsers(ctx context.Context, limit int) ([]AdminUserRow, error)
+	AdminSetUserAdmin(ctx context.Context, userID string, isAdmin bool) error
+
+	SetPassword(ctx context.Context, userID, passwordHash string) error
+	MarkEmailVerified(ctx context.Context, userID string) error
+	CreateUserToken(ctx context.Context, t UserToken) error
+	ConsumeUserToken(ctx context.Context, purpose, tokenHash string) (UserToken, error)
+}
+
+// OrgRepo — организации, подписки и планы.
+type OrgRepo interface {
+	CreateOrgForOwner(ctx context.Context, ownerUserID, orgName string) (Org, error)
+	GetOrg(ctx context.Context, orgID string) (Org, error)
+	GetSubscription(ctx context.Context, orgID string) (Subscription, error)
+	PlanMaxClusters(ctx context.Context, planID string) (int, error)
+	PlanMaxScansPerDay(ctx context.Context, planID string) (int, error)
+	AdminListOrgs(ctx context.Context, limit int) ([]AdminOrgRow, error)
+	AdminSetOrgPlan(ctx context.Context, orgID string, planID string) error
+	SetOrgRequireMFA(ctx context.Context, orgID string, require bool) error
+}
+
+// MemberRepo — участники организаций, роли и приглашения.
+type MemberRepo interface {
+	ListUserOrgs(ctx context.Context, userID string) ([]Membership, error)
+	GetMembership(ctx context.Context, orgID, userID string) (Membership, error)
+	ListMembers(ctx context.Context, orgID string) ([]Member, error)
+	AddMember(ctx context.Context, orgID, userID, role string) error
+	SetMemberRole(ctx context.Context, orgID, userID, role string) error
+	RemoveMember(ctx context.Context, orgID, userID string) error
+	TransferOwnership(ctx context.Context, orgID, fromUserID, toUserID string) error
+
+	CreateInvitation(ctx context.Context, inv Invitation) (Invitation, error)
+	ListInvitations(ctx context.Context, orgID string) ([]Invitation, error)
+	GetInvitationByTokenHash(ctx context.Context, tokenHash string) (Invitation, error)
+	AcceptInvitation(ctx context.Context, invitationID, userID string) (Membership, error)
+	CloseInvitation(ctx context.Context, orgID, invitationID, status string) error
+}
+
+// ClusterRepo — кластеры организации.
+type ClusterRepo interface {
+	CountClusters(ctx context.Context, orgID string) (int, error)
+	CreateCluster(ctx context.Context, orgID, name, notes string, restricted bool) (Cluster, error)
+	GetCluster(ctx context.Context, clusterID string) (Cluster, error)
+	ListClusters(ctx context.Context, orgID string) ([]Cluster, error)
+	SetClusterRestricted(ctx context.Context, clusterID string, restricted bool) error
+}
+
+// GrantRepo — команды организации и гранты на кластеры.
+type GrantRepo interface {
+	ListTeams(ctx context.Context, orgID string) ([]Team, error)
+	CreateTeam(ctx context.Context, orgID, name string) (Team, error)
+	DeleteTeam(ctx context.Context, orgID, teamID string) error
+	AddTeamMember(ctx context.Context, teamID, userID string) error
+	RemoveTeamMember(ctx context.Context, teamID, userID string) error
+
+	ListClusterGrants(ctx context.Context, orgID string) ([]ClusterGrant, error)
+	SetClusterGrant(ctx context.Context, g ClusterGrant) error
+	DeleteClusterGrant(ctx context.Context, clusterID, subjectType, subjectID string) error
+}
+
+// SessionRepo — сессии пользователей (refresh tokens).
+type SessionRepo interface {
+	CreateSession(ctx context.Context, ss Session) (Session, error)
+	GetSession(ctx context.Context, sessionID string) (Session, error)
+	RotateSession(ctx context.Context, oldHash, newHash string, expiresAt time.Time) (Session, error)
+	RevokeReusedSession(ctx context.Context, prevHash string) (bool, error)
+	ListSessions(ctx context.Context, userID string) ([]Session, error)
+	RevokeSession(ctx context.Context, userID, sessionID string) error
+	RevokeUserSessions(ctx context.Context, userID, exceptID string) (int, error)
+}
+
+// MFARepo — второй фактор пользователей (TOTP и коды восстановления).
+type MFARepo interface {
+	GetMFA(ctx context.Context, userID string) (UserMFA, error)
+	PutMFA(ctx context.Context, m UserMFA) error
+	UseMFAStep(ctx context.Context, userID string, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error)
+	DeleteMFA(ctx context.Context, userID string) error
+}
+
+// SSORepo — IdP организаций и привязка учётных записей IdP к пользователям.
+type SSORepo interface {
+	GetOrgSSO(ctx context.Context, orgID string) (OrgSSO, error)
+	FindOrgSSOByDomain(ctx context.Context, domain string) (OrgSSO, error)
+	PutOrgSSO(ctx context.Context, c OrgSSO) error
+	DeleteOrgSSO(ctx context.Context, orgID string) error
+
+	GetUserByIdentity(ctx context.Context, issuer, subject string) (User, error)
+	LinkIdentity(ctx context.Context, issuer, subject, userID string) error
+}
+
+// ScanRepo — сканы, их результаты и исходные снимки.
+type ScanRepo interface {
+	CreateScan(ctx context.Context, orgID, clusterID, source string) (Scan, error)
+	CreateScanWithinQuota(ctx context.Context, ns NewScan, since time.Time, max int) (Scan, error)
+	GetScan(ctx context.Context, scanID string) (Scan, error)
+	ListScans(ctx context.Context, orgID, clusterID string) ([]Scan, error)
+	CountScansSince(ctx context.Context, orgID string, since time.Time) (int, error)
+	UpsertScanResult(ctx context.Context, scanID string, summary any, full any, engineVersion string) error
+	GetScanReport(ctx context.Context, scanID string) (map[string]any, map[string]any, error)
+
+	PutSnapshot(ctx context.Context, content []byte) (string, error)
+	GetSnapshot(ctx context.Context, sha string) ([]byte, error)
+	SetScanSnapshot(ctx context.Context, scanID, sha string) error
+	ListStaleScans(ctx context.Context, engineVersion string, offset, limit int) ([]StaleScan, error)
+	CountStaleScans(ctx context.Context, engineVersion string) (int, error)
+}
+
+// AuditRepo — журнал действий администраторов.
+type AuditRepo interface {
+	AddAdminAudit(ctx context.Context, adminUserID, action, targetType, targetID string, meta map[string]any) error
+	ListAdminAudit(ctx context.Context, limit int) ([]AdminAuditEvent, error)
+}
+
+// Repository — всё хранилище сервера. Реализации: *Store (PostgreSQL, pgx)
+// и memstore.Store (в памяти, с необязательным сохранением в файл).
+type Repository interface {
+	UserRepo
+	SessionRepo
+	MFARepo
+	OrgRepo
+	MemberRepo
+	ClusterRepo
+	GrantRepo
+	SSORepo
+	ScanRepo
+	AuditRepo
+}
+
+var _ Repository = (*Store)(nil)
@@ -0,0 +1,139 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// Session — вход пользователя на устройстве; живёт, пока обновляется refresh token.
+type Session struct {
+	ID              string     `json:"id"`
+	UserID          string     `json:"userId"`
+	RefreshHash     string     `json:"-"`
+	PrevRefreshHash string     `json:"-"`
+	UserAgent       string     `json:"userAgent"`
+	IP              string     `json:"ip"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	LastUsedAt      time.Time  `json:"lastUsedAt"`
+	ExpiresAt       time.Time  `json:"expiresAt"`
+	RevokedAt       *time.Time `json:"revokedAt,omitempty"`
+}
+
+// Active — сессия не отозвана и не истекла.
+func (s Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+func (s *Store) GetUser(ctx context.Context, userID string) (User, error) {
+	var u User
+	err := s.DB.QueryRow(ctx,
+		`SELECT id, email, password_hash, is_admin, created_at, email_verified_at IS NOT NULL FROM users WHERE id=$1`,
+		userID,
+	).Scan(&u.ID, &u.Email, &u.PasswordHash, &u.IsAdmin, &u.CreatedAt, &u.EmailVerified)
+	return u, err
+}
+
+const sessionColumns = `id, user_id, refresh_hash, COALESCE(prev_refresh_hash, ''), user_agent, ip, created_at, last_used_at, expires_at, revoked_at`
+
+func scanSession(row rowScanner) (Session, error) {
+	var ss Session
+	err := row.Scan(&ss.ID, &ss.UserID, &ss.RefreshHash, &ss.PrevRefreshHash, &ss.UserAgent, &ss.IP,
+		&ss.CreatedAt, &ss.LastUsedAt, &ss.ExpiresAt, &ss.RevokedAt)
+	return ss, err
+}
+
+func (s *Store) CreateSession(ctx context.Context, ss Session) (Session, error) {
+	return scanSession(s.DB.QueryRow(ctx,
+		`INSERT INTO sessions(user_id, refresh_hash, user_agent, ip, expires_at)
+		 VALUES($1,$2,$3,$4,$5)
+		 RETURNING `+sessionColumns,
+		ss.UserID, ss.RefreshHash, ss.UserAgent, ss.IP, ss.ExpiresAt,
+	))
+}
+
+func (s *Store) GetSession(ctx context.Context, sessionID string) (Session, error) {
+	return scanSession(s.DB.QueryRow(ctx,
+		`SELECT `+sessionColumns+` FROM sessions WHERE id=$1`,
+		sessionID,
+	))
+}
+
+// RotateSession заменяет refresh token действующей сессии: oldHash -> newHash.
+// ErrNotFound — токен не текущий (устарел, отозван или истёк).
+func (s *Store) RotateSession(ctx context.Context, oldHash, newHash string, expiresAt time.Time) (Session, error) {
+	ss, err := scanSession(s.DB.QueryRow(ctx,
+		`UPDATE sessions
+		 SET prev_refresh_hash=refresh_hash, refresh_hash=$2, last_used_at=now(), expires_at=$3
+		 WHERE refresh_hash=$1 AND revoked_at IS NULL AND expires_at > now()
+		 RETURNING `+sessionColumns,
+		oldHash, newHash, expiresAt,
+	))
+	if IsNotFound(err) {
+		return Session{}, ErrNotFound
+	}
+	return ss, err
+}
+
+// RevokeReusedSession отзывает сессию, чей предыдущий refresh token предъявлен повторно.
+// Возвращает false, если такой сессии нет.
+func (s *Store) RevokeReusedSession(ctx context.Context, prevHash string) (bool, error) {
+	tag, err := s.DB.Exec(ctx,
+		`UPDATE sessions SET revoked_at=now()
+		 WHERE prev_refresh_hash=$1 AND revoked_at IS NULL`,
+		prevHash,
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ListSessions — действующие сессии пользователя, последние использованные первыми.
+func (s *Store) ListSessions(ctx context.Context, userID string) ([]Session, error) {
+	rows, err := s.DB.Query(ctx,
+		`SELECT `+sessionColumns+`
+		 FROM sessions
+		 WHERE user_id=$1 AND revoked_at IS NULL AND expires_at > now()
+		 ORDER BY last_used_at DESC`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Session
+	for rows.Next() {
+		ss, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, ss)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	tag, err := s.DB.Exec(ctx,
+		`UPDATE sessions SET revoked_at=now()
+		 WHERE id=$1 AND user_id=$2 AND revoked_at IS NULL`,
+		sessionID, userID,
+	)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+// RevokeUserSessions отзывает все сессии пользователя, кроме exceptID (может быть "").
+func (s *Store) RevokeUserSessions(ctx context.Context, userID, exceptID string) (int, error) {
+	tag, err := s.DB.Exec(ctx,
+		`UPDATE sessions SET revoked_at=now()
+		 WHERE user_id=$1 AND revoked_at IS NULL AND id::text <> $2`,
+		userID, exceptID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
@@ -0,0 +1,127 @@
+package store
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+)
+
+// PutSnapshot сохраняет исходный YAML скана (gzip) под sha256 содержимого.
+// Если такой снимок уже есть, повторно он не пишется.
+func (s *Store) PutSnapshot(ctx context.Context, content []byte) (string, error) {
+	sum := sha256.Sum256(content)
+	sha := hex.EncodeToString(sum[:])
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(content); err != nil {
+		return "", err
+	}
+	if err := gz.Close(); err != nil {
+		return "", err
+	}
+
+	_, err := s.DB.Exec(ctx,
+		`INSERT INTO snapshots(sha256, encoding, size_bytes, content)
+		 VALUES($1,'gzip',$2,$3)
+		 ON CONFLICT (sha256) DO NOTHING`,
+		sha, len(content), buf.Bytes(),
+	)
+	return sha, err
+}
+
+// GetSnapshot возвращает распакованный YAML снимка.
+func (s *Store) GetSnapshot(ctx context.Context, sha string) ([]byte, error) {
+	var encoding string
+	var content []byte
+	err := s.DB.QueryRow(ctx,
+		`SELECT encoding, content FROM snapshots WHERE sha256=$1`,
+		sha,
+	).Scan(&encoding, &content)
+	if err != nil {
+		return nil, err
+	}
+
+	switch encoding {
+	case "gzip":
+		gz, err := gzip.NewReader(bytes.NewReader(content))
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		return io.ReadAll(gz)
+	case "identity":
+		return content, nil
+	default:
+		return nil, fmt.Errorf("snapshot %s: unknown encoding %q", sha, encoding)
+	}
+}
+
+// SetScanSnapshot привязывает снимок к скану.
+func (s *Store) SetScanSnapshot(ctx context.Context, scanID, sha string) error {
+	_, err := s.DB.Exec(ctx,
+		`UPDATE scans SET snapshot_sha256=$1 WHERE id=$2`,
+		sha, scanID,
+	)
+	return err
+}
+
+// StaleScan — скан со снимком, результат которого посчитан другой версией движка.
+type StaleScan struct {
+	ScanID         string
+	SnapshotSHA256 string
+	EngineVersion  string
+}
+
+// ListStaleScans — сканы со снимком, у которых нет результата или он посчитан не engineVersion
+// (от старых к новым, offset — сколько первых пропустить). Старые сканы без снимка
+// пересчитать нельзя, они не возвращаются.
+func (s *Store) ListStaleScans(ctx context.Context, engineVersion string, offset, limit int) ([]StaleScan, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+
+	rows, err := s.DB.Query(ctx,
+		`SELECT sc.id, sc.snapshot_sha256, COALESCE(r.engine_version, '')
+		 FROM scans sc
+		 LEFT JOIN scan_results r ON r.scan_id=sc.id
+		 WHERE sc.snapshot_sha256 IS NOT NULL
+		   AND COALESCE(r.engine_version, '') <> $1
+		 ORDER BY sc.created_at ASC, sc.id ASC
+		 OFFSET $2
+		 LIMIT $3`,
+		engineVersion, offset, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []StaleScan
+	for rows.Next() {
+		var st StaleScan
+		if err := rows.Scan(&st.ScanID, &st.SnapshotSHA256, &st.EngineVersion); err != nil {
+			return nil, err
+		}
+		out = append(out, st)
+	}
+	return out, rows.Err()
+}
+
+// CountStaleScans — сколько сканов ещё нужно пересчитать.
+func (s *Store) CountStaleScans(ctx context.Context, engineVersion string) (int, error) {
+	var c int
+	err := s.DB.QueryRow(ctx,
+		`SELECT COUNT(*)
+		 FROM scans sc
+		 LEFT JOIN scan_results r ON r.scan_id=sc.id
+		 WHERE sc.snapshot_sha256 IS NOT NULL
+		   AND COALESCE(r.engine_version, '') <> $1`,
+		engineVersion,
+	).Scan(&c)
+	return c, err
+}
@@ -0,0 +1,113 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// OrgSSO — IdP организации (OpenID Connect) и сопоставление групп IdP с ролями.
+type OrgSSO struct {
+	OrgID        string   `json:"orgId"`
+	Issuer       string   `json:"issuer"`
+	ClientID     string   `json:"clientId"`
+	ClientSecret string   `json:"-"`
+	Domains      []string `json:"domains"`     // email-домены, по которым находится организация
+	GroupsClaim  string   `json:"groupsClaim"` // claim ID token со списком групп
+
+	// GroupRoles — группа IdP -> роль в организации (admin/member); при каждом входе
+	// роль участника выставляется по высшей из его групп. Без подходящей группы новый
+	// участник получает DefaultRole, а при пустой DefaultRole вход запрещён.
+	GroupRoles  map[string]string `json:"groupRoles"`
+	DefaultRole string            `json:"defaultRole"`
+
+	// Enforce — участники организации входят только через SSO.
+	Enforce   bool      `json:"enforce"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// GroupRole — высшая роль из групп пользователя по GroupRoles ("" — ни одна группа не подошла).
+func (c OrgSSO) GroupRole(groups []string) string {
+	role := ""
+	for _, g := range groups {
+		if r := c.GroupRoles[g]; r != "" && !RoleAtLeast(role, r) {
+			role = r
+		}
+	}
+	return role
+}
+
+const orgSSOColumns = `org_id, issuer, client_id, client_secret, domains, groups_claim, group_roles, default_role, enforce, updated_at`
+
+func scanOrgSSO(row rowScanner) (OrgSSO, error) {
+	var c OrgSSO
+	var roles []byte
+	err := row.Scan(&c.OrgID, &c.Issuer, &c.ClientID, &c.ClientSecret, &c.Domains, &c.GroupsClaim,
+		&roles, &c.DefaultRole, &c.Enforce, &c.UpdatedAt)
+	if err != nil {
+		return OrgSSO{}, err
+	}
+	return c, json.Unmarshal(roles, &c.GroupRoles)
+}
+
+func (s *Store) GetOrgSSO(ctx context.Context, orgID string) (OrgSSO, error) {
+	return scanOrgSSO(s.DB.QueryRow(ctx,
+		`SELECT `+orgSSOColumns+` FROM org_sso WHERE org_id=$1`,
+		orgID,
+	))
+}
+
+// FindOrgSSOByDomain — организация, у которой в domains есть домен email.
+func (s *Store) FindOrgSSOByDomain(ctx context.Context, domain string) (OrgSSO, error) {
+	return scanOrgSSO(s.DB.QueryRow(ctx,
+		`SELECT `+orgSSOColumns+` FROM org_sso WHERE $1 = ANY(domains) ORDER BY updated_at LIMIT 1`,
+		domain,
+	))
+}
+
+func (s *Store) PutOrgSSO(ctx context.Context, c OrgSSO) error {
+	roles, err := json.Marshal(c.GroupRoles)
+	if err != nil {
+		return err
+	}
+	_, err = s.DB.Exec(ctx,
+		`INSERT INTO org_sso(org_id, issuer, client_id, client_secret, domains, groups_claim, group_roles, default_role, enforce)
+		 VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9)
+		 ON CONFLICT (org_id) DO UPDATE SET
+		   issuer=EXCLUDED.issuer, client_id=EXCLUDED.client_id, client_secret=EXCLUDED.client_secret,
+		   domains=EXCLUDED.domains, groups_claim=EXCLUDED.groups_claim, group_roles=EXCLUDED.group_roles,
+		   default_role=EXCLUDED.default_role, enforce=EXCLUDED.enforce, updated_at=now()`,
+		c.OrgID, c.Issuer, c.ClientID, c.ClientSecret, c.Domains, c.GroupsClaim, roles, c.DefaultRole, c.Enforce,
+	)
+	return err
+}
+
+func (s *Store) DeleteOrgSSO(ctx context.Context, orgID string) error {
+	tag, err := s.DB.Exec(ctx, `DELETE FROM org_sso WHERE org_id=$1`, orgID)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+// GetUserByIdentity — пользователь, связанный с учётной записью IdP (iss + sub).
+func (s *Store) GetUserByIdentity(ctx context.Context, issuer, subject string) (User, error) {
+	var u User
+	err := s.DB.QueryRow(ctx,
+		`SELECT u.id, u.email, u.password_hash, u.is_admin, u.created_at, u.email_verified_at IS NOT NULL
+		 FROM user_identities i
+		 JOIN users u ON u.id=i.user_id
+		 WHERE i.issuer=$1 AND i.subject=$2`,
+		issuer, subject,
+	).Scan(&u.ID, &u.Email, &u.PasswordHash, &u.IsAdmin, &u.CreatedAt, &u.EmailVerified)
+	return u, err
+}
+
+func (s *Store) LinkIdentity(ctx context.Context, issuer, subject, userID string) error {
+	_, err := s.DB.Exec(ctx,
+		`INSERT INTO user_identities(issuer, subject, user_id) VALUES($1,$2,$3)
+		 ON CONFLICT (issuer, subject) DO NOTHING`,
+		issuer, subject, userID,
+	)
+	return err
+}
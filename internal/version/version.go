@@ -0,0 +1,5 @@
+package version
+
+// Version — версия сборки, подставляется через
+// go build -ldflags "-X rbac-analyzer/internal/version.Version=v1.2.3".
+var Version = "dev"
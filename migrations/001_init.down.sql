@@ -0,0 +1,8 @@
+-- 001_init.down.sql
+
+DROP TABLE IF EXISTS scan_results;
+DROP TABLE IF EXISTS scans;
+DROP TABLE IF EXISTS clusters;
+DROP TABLE IF EXISTS org_members;
+DROP TABLE IF EXISTS orgs;
+DROP TABLE IF EXISTS users;
@@ -1,4 +1,4 @@
--- 001_init.sql
+-- 001_init.up.sql
 
 CREATE EXTENSION IF NOT EXISTS pgcrypto;
 
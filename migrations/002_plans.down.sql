@@ -0,0 +1,4 @@
+-- 002_plans.down.sql
+
+DROP TABLE IF EXISTS subscriptions;
+DROP TABLE IF EXISTS plans;
@@ -1,4 +1,4 @@
--- 002_plans.sql
+-- 002_plans.up.sql
 
 CREATE TABLE IF NOT EXISTS plans (
   id TEXT PRIMARY KEY,            -- free/pro/enterprise
@@ -0,0 +1,3 @@
+-- 003_scans_history.down.sql
+
+DROP INDEX IF EXISTS idx_scans_cluster_created;
@@ -0,0 +1,6 @@
+-- 003_scans_history.up.sql
+-- История сканов кластера (список и diff) читается по created_at.
+-- Раньше здесь был черновик отдельной таблицы scans (BIGSERIAL id, rbac_yaml), несовместимый
+-- с 001_init; исходный YAML теперь хранится в snapshots (004).
+
+CREATE INDEX IF NOT EXISTS idx_scans_cluster_created ON scans(cluster_id, created_at DESC);
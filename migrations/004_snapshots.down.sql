@@ -0,0 +1,8 @@
+-- 004_snapshots.down.sql
+
+DROP INDEX IF EXISTS idx_scan_results_engine;
+DROP INDEX IF EXISTS idx_scans_snapshot;
+ALTER TABLE scan_results DROP COLUMN IF EXISTS analyzed_at;
+ALTER TABLE scan_results DROP COLUMN IF EXISTS engine_version;
+ALTER TABLE scans DROP COLUMN IF EXISTS snapshot_sha256;
+DROP TABLE IF EXISTS snapshots;
@@ -0,0 +1,20 @@
+-- 004_snapshots.up.sql
+-- Исходный YAML сканов: сжатый gzip, адресуется sha256 содержимого (одинаковые загрузки хранятся один раз).
+-- По нему старые сканы пересчитываются, когда меняются правила анализа.
+
+CREATE TABLE IF NOT EXISTS snapshots (
+  sha256     TEXT PRIMARY KEY,            -- sha256 несжатого содержимого (hex)
+  encoding   TEXT NOT NULL DEFAULT 'gzip',
+  size_bytes BIGINT NOT NULL,             -- размер до сжатия
+  content    BYTEA NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE scans ADD COLUMN IF NOT EXISTS snapshot_sha256 TEXT REFERENCES snapshots(sha256);
+
+-- Какой версией движка посчитан результат и когда
+ALTER TABLE scan_results ADD COLUMN IF NOT EXISTS engine_version TEXT NOT NULL DEFAULT '';
+ALTER TABLE scan_results ADD COLUMN IF NOT EXISTS analyzed_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE INDEX IF NOT EXISTS idx_scans_snapshot ON scans(snapshot_sha256);
+CREATE INDEX IF NOT EXISTS idx_scan_results_engine ON scan_results(engine_version);
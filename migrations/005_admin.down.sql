@@ -0,0 +1,4 @@
+-- 005_admin.down.sql
+
+DROP TABLE IF EXISTS admin_audit_log;
+ALTER TABLE users DROP COLUMN IF EXISTS is_admin;
@@ -0,0 +1,16 @@
+-- 005_admin.up.sql
+-- Флаг администратора и журнал действий админов (нужны логину и /api/admin/*).
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_admin BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS admin_audit_log (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  admin_user_id UUID NOT NULL,          -- без FK: журнал переживает удаление пользователя
+  action TEXT NOT NULL,                 -- toggle_admin/reanalyze_scans/...
+  target_type TEXT NOT NULL DEFAULT '', -- user/org/engine
+  target_id TEXT NOT NULL DEFAULT '',
+  meta JSONB NOT NULL DEFAULT '{}',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_admin_audit_created ON admin_audit_log(created_at DESC);
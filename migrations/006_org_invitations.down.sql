@@ -0,0 +1,4 @@
+-- 006_org_invitations.down.sql
+
+DROP INDEX IF EXISTS idx_org_members_user;
+DROP TABLE IF EXISTS org_invitations;
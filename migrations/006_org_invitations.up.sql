@@ -0,0 +1,18 @@
+-- 006_org_invitations.up.sql
+-- Приглашения в организацию по email. Токен хранится только в виде sha256.
+
+CREATE TABLE IF NOT EXISTS org_invitations (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  org_id UUID NOT NULL REFERENCES orgs(id) ON DELETE CASCADE,
+  email TEXT NOT NULL,
+  role TEXT NOT NULL DEFAULT 'member',   -- admin/member
+  token_hash TEXT NOT NULL UNIQUE,
+  invited_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  status TEXT NOT NULL DEFAULT 'pending', -- pending/accepted/declined/revoked
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  expires_at TIMESTAMPTZ NOT NULL,
+  responded_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_org_invitations_org ON org_invitations(org_id, status);
+CREATE INDEX IF NOT EXISTS idx_org_members_user ON org_members(user_id);
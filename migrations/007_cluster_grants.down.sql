@@ -0,0 +1,6 @@
+-- 007_cluster_grants.down.sql
+
+DROP TABLE IF EXISTS cluster_grants;
+DROP TABLE IF EXISTS team_members;
+DROP TABLE IF EXISTS teams;
+ALTER TABLE clusters DROP COLUMN IF EXISTS restricted;
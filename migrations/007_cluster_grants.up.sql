@@ -0,0 +1,35 @@
+-- 007_cluster_grants.up.sql
+-- Доступ к кластерам внутри организации: команды и гранты viewer/uploader/admin.
+-- Кластер с restricted=true видят только owner/admin организации и те, у кого есть грант.
+
+ALTER TABLE clusters ADD COLUMN IF NOT EXISTS restricted BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS teams (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  org_id UUID NOT NULL REFERENCES orgs(id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  UNIQUE (org_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS team_members (
+  team_id UUID NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (team_id, user_id)
+);
+
+-- subject_id — users.id или teams.id (по subject_type), поэтому без внешнего ключа:
+-- гранты удаляются вместе с командой / при удалении участника из организации.
+CREATE TABLE IF NOT EXISTS cluster_grants (
+  cluster_id UUID NOT NULL REFERENCES clusters(id) ON DELETE CASCADE,
+  subject_type TEXT NOT NULL CHECK (subject_type IN ('user', 'team')),
+  subject_id UUID NOT NULL,
+  role TEXT NOT NULL CHECK (role IN ('viewer', 'uploader', 'admin')),
+  created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (cluster_id, subject_type, subject_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_cluster_grants_subject ON cluster_grants(subject_type, subject_id);
+CREATE INDEX IF NOT EXISTS idx_team_members_user ON team_members(user_id);
@@ -0,0 +1,4 @@
+-- 008_sso.down.sql
+
+DROP TABLE IF EXISTS user_identities;
+DROP TABLE IF EXISTS org_sso;
@@ -0,0 +1,33 @@
+-- 008_sso.up.sql
+-- Вход через OpenID Connect: у каждой организации может быть свой IdP.
+-- Пользователь, созданный при первом входе через SSO, не имеет пароля (password_hash = '').
+
+CREATE TABLE IF NOT EXISTS org_sso (
+  org_id UUID PRIMARY KEY REFERENCES orgs(id) ON DELETE CASCADE,
+  issuer TEXT NOT NULL,
+  client_id TEXT NOT NULL,
+  client_secret TEXT NOT NULL DEFAULT '',
+  -- email-домены, по которым вход через SSO находит организацию
+  domains TEXT[] NOT NULL DEFAULT '{}',
+  groups_claim TEXT NOT NULL DEFAULT 'groups',
+  -- группа IdP -> роль в организации (admin/member)
+  group_roles JSONB NOT NULL DEFAULT '{}',
+  -- роль без подходящей группы; '' — такой пользователь не входит
+  default_role TEXT NOT NULL DEFAULT '',
+  -- участникам организации запрещён вход по паролю
+  enforce BOOLEAN NOT NULL DEFAULT false,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_org_sso_domains ON org_sso USING GIN (domains);
+
+-- учётная запись у IdP (iss + sub) -> пользователь
+CREATE TABLE IF NOT EXISTS user_identities (
+  issuer TEXT NOT NULL,
+  subject TEXT NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (issuer, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user ON user_identities(user_id);
@@ -0,0 +1,3 @@
+-- 009_sessions.down.sql
+
+DROP TABLE IF EXISTS sessions;
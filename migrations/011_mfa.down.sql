@@ -0,0 +1,4 @@
+-- 011_mfa.down.sql
+
+ALTER TABLE orgs DROP COLUMN IF EXISTS require_mfa;
+DROP TABLE IF EXISTS user_mfa;
@@ -0,0 +1,16 @@
+-- 011_mfa.up.sql
+-- Двухфакторная аутентификация (TOTP). Пока enabled_at пуст, секрет ждёт подтверждения
+-- первым кодом. last_step — шаг последнего принятого кода (повторно код не принимается).
+-- Коды восстановления хранятся только как sha256.
+
+CREATE TABLE IF NOT EXISTS user_mfa (
+  user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  secret TEXT NOT NULL,
+  enabled_at TIMESTAMPTZ,
+  last_step BIGINT NOT NULL DEFAULT 0,
+  recovery_hashes TEXT[] NOT NULL DEFAULT '{}',
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- политика организации: без MFA участники не видят её данные
+ALTER TABLE orgs ADD COLUMN IF NOT EXISTS require_mfa BOOLEAN NOT NULL DEFAULT false;
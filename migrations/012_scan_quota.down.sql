@@ -0,0 +1,4 @@
+-- 012_scan_quota.down.sql
+
+DROP INDEX IF EXISTS idx_scans_org_created;
+ALTER TABLE plans DROP COLUMN IF EXISTS max_scans_per_day;
@@ -0,0 +1,8 @@
+-- 012_scan_quota.up.sql
+-- Квота загрузок сканов по плану: сколько сканов организация может загрузить за сутки (UTC).
+
+ALTER TABLE plans ADD COLUMN IF NOT EXISTS max_scans_per_day INT NOT NULL DEFAULT 20;
+UPDATE plans SET max_scans_per_day = 1000 WHERE id = 'pro';
+UPDATE plans SET max_scans_per_day = 1000000 WHERE id = 'enterprise';
+
+CREATE INDEX IF NOT EXISTS idx_scans_org_created ON scans(org_id, created_at);
@@ -0,0 +1,8 @@
+// Package migrations — SQL-миграции схемы сервера, встроенные в бинарник.
+// Файлы: NNN_name.up.sql и NNN_name.down.sql; применяет их internal/migrate.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS